@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+)
+
+// Runs statements without the REPL, line by line. Results are written to stdout and errors
+// to stderr, or both as JSON to stdout. Unless keepGoing is set, the first error stops the
+// batch and is returned.
+// define x = 3\nx^2 -> 9
+func batch(input io.Reader, keepGoing bool, jsonOutput bool) error {
+	var failed error
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		cmd := strings.TrimSpace(scanner.Text())
+		// Empty lines and comments are skipped i.e.: # constants
+		if cmd == "" || strings.HasPrefix(cmd, "#") {
+			continue
+		}
+
+		switch cmd {
+		case "exit":
+			return failed
+		case "clear":
+			continue
+		case "help":
+			if !jsonOutput {
+				help()
+			}
+			continue
+		}
+
+		var err error
+		if jsonOutput {
+			var result jsonResult
+			result, err = evaluateJSON(cmd)
+			writeJSON(result)
+		} else {
+			var output string
+			output, err = session.Eval(cmd)
+			if err != nil {
+				report(os.Stderr, err, cmd)
+			} else {
+				cfmt.Printf("%v\n", output)
+			}
+		}
+		if err != nil {
+			if !keepGoing {
+				return err
+			}
+			failed = err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		cfmt.Fprintf(os.Stderr, "{{Error:}}::red|bold Unable to read input, %v.\n", err)
+		return err
+	}
+	return failed
+}
+
+// Runs the statements of files in order, - reads from stdin.
+func batchFiles(paths []string, keepGoing bool, jsonOutput bool) error {
+	var failed error
+	for _, path := range paths {
+		input := io.Reader(os.Stdin)
+		var file *os.File
+		if path != "-" {
+			var err error
+			file, err = os.Open(path)
+			if err != nil {
+				cfmt.Fprintf(os.Stderr, "{{Error:}}::red|bold Unable to open file, %v.\n", err)
+				return err
+			}
+			input = file
+		}
+
+		err := batch(input, keepGoing, jsonOutput)
+		// Each file is closed before the next one is opened.
+		if file != nil {
+			file.Close()
+		}
+		if err != nil {
+			if !keepGoing {
+				return err
+			}
+			failed = err
+		}
+	}
+	return failed
+}
+
+// Statements given with -e, the flag can be repeated.
+type statements []string
+
+func (s *statements) String() string {
+	return strings.Join(*s, "\n")
+}
+
+func (s *statements) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// Checks if a file is a terminal, instead of a pipe or a redirected file.
+func isTerminal(file *os.File) bool {
+	stat, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"lambdacalc/engine"
+	"lambdacalc/shared"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Discards the output of a batch for the rest of the test.
+func discardOutput(t *testing.T) {
+	t.Helper()
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = null, null
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		null.Close()
+	})
+}
+
+// Returns the sorted names of the definitions of the session.
+func definedNames() []string {
+	res := []string{}
+	for name := range session.Definitions() {
+		res = append(res, name)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// The first error stops a batch, unless keepGoing is set. exit ends it without an error.
+func TestBatch(t *testing.T) {
+	discardOutput(t)
+	tests := []struct {
+		input     string
+		keepGoing bool
+		fails     bool
+		defined   []string
+	}{
+		{"define x = 3\n\n# comment\nx^2", false, false, []string{"x"}},
+		{"define x = 3\n1 +\ndefine y = 2", false, true, []string{"x"}},
+		{"define x = 3\n1 +\ndefine y = 2", true, true, []string{"x", "y"}},
+		{"define x = 3\nexit\ndefine y = 2", false, false, []string{"x"}},
+		{"clear\nhelp\ndefine x = 3", false, false, []string{"x"}},
+	}
+	for _, jsonOutput := range []bool{false, true} {
+		for _, test := range tests {
+			session = engine.New(shared.GetDefualtConfig())
+			err := batch(strings.NewReader(test.input), test.keepGoing, jsonOutput)
+			if (err != nil) != test.fails {
+				t.Errorf("%q json %v: got error %v, want failing %v", test.input, jsonOutput, err, test.fails)
+			}
+			if got := definedNames(); !slices.Equal(got, test.defined) {
+				t.Errorf("%q json %v: got definitions %v, want %v", test.input, jsonOutput, got, test.defined)
+			}
+		}
+	}
+}
+
+// Files share one session and are read in order.
+func TestBatchFiles(t *testing.T) {
+	discardOutput(t)
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	if err := os.WriteFile(first, []byte("define x = 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("define y = x + 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	session = engine.New(shared.GetDefualtConfig())
+	if err := batchFiles([]string{first, second}, false, false); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if got := definedNames(); !slices.Equal(got, []string{"x", "y"}) {
+		t.Errorf("got definitions %v, want [x y]", got)
+	}
+
+	if err := batchFiles([]string{filepath.Join(dir, "missing.txt"), first}, true, false); err == nil {
+		t.Errorf("missing file: want an error")
+	}
+}
@@ -0,0 +1,438 @@
+package calculus
+
+import (
+	"lambdacalc/interpreter"
+	"lambdacalc/numeric"
+	"lambdacalc/shared"
+	"lambdacalc/simplifier"
+	"math"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+)
+
+// Name of the variable standing for the inner function of a substitution, it can not be typed.
+const SUBSTITUTE = "_u"
+
+// Largest integer power of a sum, that is multiplied out to integrate it. (x + 1)^3
+const MAX_EXPANSION = 16
+
+// Number of intervals the integrand is sampled in, to find poles between the bounds of an integral.
+const POLE_SAMPLES = 256
+
+// Factor by which the integrand has to exceed its sampled values close to a point, to have a pole there.
+const POLE_GROWTH = 1e10
+
+// Integrate a tree by the given variable. Defined variables and functions are inlined first,
+// the antiderivative is returned without a constant and simplified with the unwind and rewind rules.
+// x^2 + cos(x) -> 1/3 x^3 + sin(x)
+func Integrate(s *shared.State, node *shared.Node, variable string) (*shared.Node, error) {
+	inlined, err := inline(s, shared.Clone(node), variable, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	unwound, err := simplifier.Simplify(s, roots(inlined), simplifier.UNWIND)
+	if err != nil {
+		return nil, err
+	}
+
+	integral, ok := integrate(s, unwound, variable)
+	if !ok {
+		return nil, shared.NewError("cannot integrate symbolically", "Unable to integrate, cannot integrate symbolically.")
+	}
+
+	// Debug
+	if s.Conf.Options["show_debug_process"] {
+		cfmt.Printf("{{Debug:}}::cyan|bold Antiderivative: %s\n", shared.PrintATree(integral))
+	}
+
+	unwound, err = simplifier.Simplify(s, integral, simplifier.UNWIND)
+	if err != nil {
+		return nil, err
+	}
+	return simplifier.Simplify(s, unwound, simplifier.REWIND)
+}
+
+// Integrate a tree by the given variable between two bounds, F(to) - F(from).
+// If the integrand has a pole between the bounds, the integral is improper and no result is returned.
+// x^2 from 0 to 3 -> 9, 1/x from -1 to 1 -> the integral diverges at x = 0
+func IntegrateDefinite(s *shared.State, node *shared.Node, variable string, from *shared.Node, to *shared.Node) (float64, error) {
+	integral, err := Integrate(s, node, variable)
+	if err != nil {
+		return 0, err
+	}
+
+	a, err := interpreter.Evaluate(s, from)
+	if err != nil {
+		return 0, err
+	}
+	b, err := interpreter.Evaluate(s, to)
+	if err != nil {
+		return 0, err
+	}
+	inlined, err := inline(s, shared.Clone(node), variable, 0)
+	if err != nil {
+		return 0, err
+	}
+	if pole, ok := findPole(s, inlined, variable, min(a, b), max(a, b)); ok {
+		if diverges(s, integral, variable, pole, min(a, b), max(a, b)) {
+			return 0, shared.NewError("divergent integral", "Unable to integrate, the integral diverges at %s = %v.", variable, pole)
+		}
+		return 0, shared.NewError("improper integral", "Unable to integrate, the integrand is not finite at %s = %v.", variable, pole).WithHint("the integral is improper, try nintegrate")
+	}
+
+	upper, err := interpreter.Evaluate(s, replace(shared.Clone(integral), map[string]*shared.Node{variable: to}))
+	if err != nil {
+		return 0, err
+	}
+	lower, err := interpreter.Evaluate(s, replace(shared.Clone(integral), map[string]*shared.Node{variable: from}))
+	if err != nil {
+		return 0, err
+	}
+	return upper - lower, nil
+}
+
+// Searches the integrand for a point between the bounds where it is not finite. The integrand is
+// sampled, around the largest values the interval is narrowed down to see if the value grows without bound.
+// 1/x from -1 to 1 -> 0, tan(x) from 0 to 3 -> 1.5707963267948966
+func findPole(s *shared.State, node *shared.Node, variable string, from float64, to float64) (float64, bool) {
+	f := numeric.Bind(s, node, variable)
+	xs := make([]float64, POLE_SAMPLES+1)
+	ys := make([]float64, POLE_SAMPLES+1)
+	largest := 1.0
+	for i := range xs {
+		xs[i] = from + (to-from)*float64(i)/POLE_SAMPLES
+		y, err := f(xs[i])
+		if err != nil {
+			return xs[i], true
+		}
+		ys[i] = math.Abs(y)
+		largest = max(largest, ys[i])
+	}
+
+	for i := range xs {
+		// Only local maxima can be close to a pole.
+		if (i > 0 && ys[i-1] > ys[i]) || (i < POLE_SAMPLES && ys[i+1] > ys[i]) {
+			continue
+		}
+		x, y, ok := peak(f, xs[max(i-1, 0)], xs[min(i+1, POLE_SAMPLES)])
+		if !ok || y > POLE_GROWTH*largest {
+			return x, true
+		}
+	}
+	return 0, false
+}
+
+// Narrows an interval down to the largest absolute value of a function with a golden section search.
+// Reports false, if the function is not finite at a point on the way.
+func peak(f numeric.Function, lo float64, hi float64) (float64, float64, bool) {
+	ratio := (math.Sqrt(5) - 1) / 2
+	for i := 0; i < 100 && lo < hi; i++ {
+		a, b := hi-ratio*(hi-lo), lo+ratio*(hi-lo)
+		ya, err := f(a)
+		if err != nil {
+			return a, 0, false
+		}
+		yb, err := f(b)
+		if err != nil {
+			return b, 0, false
+		}
+		if math.Abs(ya) > math.Abs(yb) {
+			hi = b
+		} else {
+			lo = a
+		}
+	}
+	x := lo + (hi-lo)/2
+	y, err := f(x)
+	return x, math.Abs(y), err == nil
+}
+
+// Checks if the antiderivative grows without bound towards a pole. Approaching the pole in steps
+// of 1000, the steps of a convergent integral shrink, those of ln|x| or 1/x do not.
+func diverges(s *shared.State, integral *shared.Node, variable string, pole float64, from float64, to float64) bool {
+	F := numeric.Bind(s, integral, variable)
+	scale := max(1, math.Abs(pole))
+	for _, side := range []float64{-1, 1} {
+		if (side < 0 && pole <= from) || (side > 0 && pole >= to) {
+			continue
+		}
+		values := []float64{}
+		for _, h := range []float64{1e-3, 1e-6, 1e-9} {
+			y, err := F(pole + side*h*scale)
+			if err != nil {
+				return true
+			}
+			values = append(values, y)
+		}
+		if math.Abs(values[2]-values[1]) > 0.5*math.Abs(values[1]-values[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the antiderivative of a simplified tree without simplifying it.
+// Reports false, if no rule matches.
+func integrate(s *shared.State, node *shared.Node, variable string) (*shared.Node, bool) {
+	// Constant parts are multiplied by the variable. i.e.: 2a -> 2ax
+	if !shared.ContainsVariable(node, variable) {
+		return shared.Multiply(shared.Clone(node), shared.VariableNode(variable)), true
+	}
+
+	switch node.OperationType {
+	case shared.VARIABLE:
+		// x -> 1/2 x^2
+		return shared.Multiply(shared.NumberNode(0.5), shared.Power(shared.VariableNode(variable), shared.NumberNode(2.0))), true
+	case shared.PLUS:
+		// ∫ f + g = ∫ f + ∫ g
+		addends := []*shared.Node{}
+		for _, val := range node.Associative {
+			res, ok := integrate(s, val, variable)
+			if !ok {
+				return nil, false
+			}
+			addends = append(addends, res)
+		}
+		return shared.Add(addends...), true
+	case shared.MINUS:
+		// ∫ f - g = ∫ f - ∫ g
+		a, ok := integrate(s, node.LNode, variable)
+		if !ok {
+			return nil, false
+		}
+		b, ok := integrate(s, node.RNode, variable)
+		if !ok {
+			return nil, false
+		}
+		return shared.Add(a, shared.Negate(b)), true
+	case shared.MULTIPLY:
+		// ∫ c * f = c * ∫ f
+		constants, dependent := split(node.Associative, variable)
+
+		var res *shared.Node
+		ok := false
+		if len(dependent) == 1 {
+			res, ok = integrate(s, dependent[0], variable)
+		} else {
+			res, ok = substitute(s, dependent, variable)
+		}
+		if !ok {
+			return expand(s, node, variable)
+		}
+		return shared.Multiply(append(constants, res)...), true
+	case shared.DIVIDE:
+		return integrate(s, shared.Multiply(shared.Clone(node.LNode), shared.Reciprocal(shared.Clone(node.RNode))), variable)
+	case shared.POWER, shared.FUNCTION:
+		if res, ok := elementary(s, node, variable); ok {
+			return res, true
+		}
+		if res, ok := substitute(s, []*shared.Node{node}, variable); ok {
+			return res, true
+		}
+		return expand(s, node, variable)
+	}
+	return nil, false
+}
+
+// Antiderivatives of powers and functions, that are called with the variable itself.
+// x^n -> x^(n+1) * (n+1)^-1, 2^x -> 2^x * ln(2)^-1, sin(x) -> -cos(x)
+func elementary(s *shared.State, node *shared.Node, variable string) (*shared.Node, bool) {
+	switch node.OperationType {
+	case shared.POWER:
+		base, exponent := node.LNode, node.RNode
+		switch {
+		case isVariable(base, variable) && !shared.ContainsVariable(exponent, variable):
+			// x^-1 -> ln(|x|)
+			if exponent.OperationType == shared.NUMBER && exponent.Value == -1 {
+				return shared.FunctionNode("ln", shared.FunctionNode("abs", shared.VariableNode(variable))), true
+			}
+			return shared.Multiply(
+				shared.Power(shared.VariableNode(variable), offset(s, exponent, 1)),
+				invert(s, offset(s, exponent, 1)),
+			), true
+		case isVariable(exponent, variable) && !shared.ContainsVariable(base, variable):
+			// e^x -> e^x
+			if isEuler(s, base) {
+				return shared.Clone(node), true
+			}
+			return shared.Multiply(shared.Clone(node), shared.Reciprocal(shared.FunctionNode("ln", shared.Clone(base)))), true
+		}
+	case shared.FUNCTION:
+		rule, ok := integrals[node.Variable]
+		if !ok || len(node.Associative) != 1 || !isVariable(node.Associative[0], variable) {
+			return nil, false
+		}
+		return rule(shared.VariableNode(variable)), true
+	}
+	return nil, false
+}
+
+// A possible substitution u = inner, where outer is the integrand written in u.
+type substitution struct {
+	inner *shared.Node
+	outer *shared.Node
+}
+
+// Integrates a product of factors by substitution. One factor has to be a function of an inner tree u,
+// the other factors have to be the derivative of u up to a constant.
+// 2x * cos(x^2) with u = x^2 -> sin(x^2)
+func substitute(s *shared.State, factors []*shared.Node, variable string) (*shared.Node, bool) {
+	for i, val := range factors {
+		rest := []*shared.Node{}
+		for j, other := range factors {
+			if i != j {
+				rest = append(rest, other)
+			}
+		}
+
+		for _, sub := range substitutions(val, variable, len(factors) > 1) {
+			d, ok := derivative(s, sub.inner, variable)
+			if !ok {
+				continue
+			}
+
+			// The remaining factors have to match du, constant factors are divided out.
+			constants, dependent := split(flatten(d), variable)
+			if !shared.IsEqual(shared.Multiply(rest...), shared.Multiply(dependent...)) {
+				continue
+			}
+
+			integral, ok := integrate(s, sub.outer, SUBSTITUTE)
+			if !ok {
+				continue
+			}
+			res := replace(integral, map[string]*shared.Node{SUBSTITUTE: sub.inner})
+			if len(constants) == 1 {
+				res = shared.Multiply(res, shared.Reciprocal(constants[0]))
+			} else if len(constants) > 1 {
+				res = shared.Multiply(res, shared.Reciprocal(shared.Multiply(constants...)))
+			}
+			return res, true
+		}
+	}
+	return nil, false
+}
+
+// Possible substitutions for a factor, the inner parts of functions and powers.
+// With whole set, the factor itself may be substituted. sin(x)^2 -> u = sin(x), u^2
+func substitutions(node *shared.Node, variable string, whole bool) []substitution {
+	res := []substitution{}
+	switch node.OperationType {
+	case shared.FUNCTION:
+		if len(node.Associative) == 1 && shared.ContainsVariable(node.Associative[0], variable) && !isVariable(node.Associative[0], variable) {
+			res = append(res, substitution{
+				inner: node.Associative[0],
+				outer: shared.FunctionNode(node.Variable, shared.VariableNode(SUBSTITUTE)),
+			})
+		}
+	case shared.POWER:
+		inBase := shared.ContainsVariable(node.LNode, variable)
+		inExponent := shared.ContainsVariable(node.RNode, variable)
+		if inBase && !inExponent && !isVariable(node.LNode, variable) {
+			res = append(res, substitution{
+				inner: node.LNode,
+				outer: shared.Power(shared.VariableNode(SUBSTITUTE), shared.Clone(node.RNode)),
+			})
+		} else if !inBase && inExponent && !isVariable(node.RNode, variable) {
+			res = append(res, substitution{
+				inner: node.RNode,
+				outer: shared.Power(shared.Clone(node.LNode), shared.VariableNode(SUBSTITUTE)),
+			})
+		}
+	}
+	if whole && !isVariable(node, variable) {
+		res = append(res, substitution{
+			inner: node,
+			outer: shared.VariableNode(SUBSTITUTE),
+		})
+	}
+	return res
+}
+
+// Multiplies out integer powers of sums and integrates the result, if that changed the tree.
+// (x + 1)^2 -> x^2 + 2x + 1
+func expand(s *shared.State, node *shared.Node, variable string) (*shared.Node, bool) {
+	expanded, changed := expandPowers(shared.Clone(node))
+	if !changed {
+		return nil, false
+	}
+	unwound, err := simplifier.Simplify(s, expanded, simplifier.UNWIND)
+	if err != nil || shared.IsEqual(unwound, node) {
+		return nil, false
+	}
+	return integrate(s, unwound, variable)
+}
+
+// Replaces integer powers of sums by a product of the sum. Reports, if anything was replaced.
+func expandPowers(node *shared.Node) (*shared.Node, bool) {
+	if node == nil {
+		return nil, false
+	}
+
+	if node.OperationType == shared.POWER && node.LNode.OperationType == shared.PLUS && node.RNode.OperationType == shared.NUMBER {
+		n := node.RNode.Value
+		if n >= 2 && n <= MAX_EXPANSION && n == float64(int(n)) {
+			factors := []*shared.Node{}
+			for i := 0; i < int(n); i++ {
+				factors = append(factors, shared.Clone(node.LNode))
+			}
+			return shared.Multiply(factors...), true
+		}
+	}
+
+	changed := false
+	var ok bool
+	node.LNode, ok = expandPowers(node.LNode)
+	changed = changed || ok
+	node.RNode, ok = expandPowers(node.RNode)
+	changed = changed || ok
+	for i, val := range node.Associative {
+		node.Associative[i], ok = expandPowers(val)
+		changed = changed || ok
+	}
+	return node, changed
+}
+
+// Splits factors into copies of the constant factors and the factors depending on the variable.
+func split(factors []*shared.Node, variable string) ([]*shared.Node, []*shared.Node) {
+	constants := []*shared.Node{}
+	dependent := []*shared.Node{}
+	for _, val := range factors {
+		if shared.ContainsVariable(val, variable) {
+			dependent = append(dependent, shared.Clone(val))
+		} else {
+			constants = append(constants, shared.Clone(val))
+		}
+	}
+	return constants, dependent
+}
+
+// Returns the factors of a product, other trees are a single factor.
+func flatten(node *shared.Node) []*shared.Node {
+	if node.OperationType == shared.MULTIPLY {
+		return node.Associative
+	}
+	return []*shared.Node{node}
+}
+
+// Replaces roots by powers. sqrt(x, 3) -> x^(1/3)
+func roots(node *shared.Node) *shared.Node {
+	if node == nil {
+		return nil
+	}
+	if node.OperationType == shared.SQRT {
+		return shared.Power(roots(node.RNode), shared.Reciprocal(roots(node.LNode)))
+	}
+
+	node.LNode = roots(node.LNode)
+	node.RNode = roots(node.RNode)
+	for i, val := range node.Associative {
+		node.Associative[i] = roots(val)
+	}
+	return node
+}
+
+func isVariable(node *shared.Node, variable string) bool {
+	return node.OperationType == shared.VARIABLE && node.Variable == variable
+}
@@ -0,0 +1,104 @@
+package calculus
+
+import (
+	"lambdacalc/interpreter"
+	"lambdacalc/shared"
+	"math"
+	"testing"
+)
+
+func TestIntegrate(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"x^3", "((x^4)*0.25)"},
+		{"x^-2", "((x^-1)*-1)"},
+		{"y", "(y*x)"},
+		{"sin(x)", "(-1*cos(x))"},
+		{"cos(x)", "sin(x)"},
+		{"1/x", "ln(abs(x))"},
+		{"ln(x)", "(x*(ln(x)-1))"},
+		{"sqrt(x)", "((x^1.5)*0.6666666666666666)"},
+		{"2x / (x^2 + 1)", "ln(abs(((x^2)+1)))"},
+	}
+	for _, test := range tests {
+		got, err := Integrate(state, parse(t, test.expression), "x")
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.expression, err)
+			continue
+		}
+		if res := shared.PrintATree(got); res != test.want {
+			t.Errorf("%s: got %s, want %s", test.expression, res, test.want)
+		}
+	}
+}
+
+// Evaluates a tree with a value of x for a test.
+func evaluateAt(t *testing.T, node *shared.Node, x float64) float64 {
+	t.Helper()
+	res, err := interpreter.EvaluateWith(state, node, map[string]float64{"x": x})
+	if err != nil {
+		t.Fatalf("%s: unable to evaluate at %v, %v", shared.PrintATree(node), x, err)
+	}
+	return res
+}
+
+// Derivatives of the integrals are the integrated functions again.
+func TestIntegrateDerivative(t *testing.T) {
+	for _, expression := range []string{"3*x^2 + 2*x + 1", "e^(2*x)", "e^x", "x^-2", "ln(x)", "cos(x)"} {
+		integral, err := Integrate(state, parse(t, expression), "x")
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", expression, err)
+			continue
+		}
+		derivative, err := Differentiate(state, integral, "x")
+		if err != nil {
+			t.Errorf("%s: unable to differentiate %s, %v", expression, shared.PrintATree(integral), err)
+			continue
+		}
+		for _, x := range []float64{0.5, 1, 2} {
+			want := evaluateAt(t, parse(t, expression), x)
+			got := evaluateAt(t, derivative, x)
+			if math.Abs(got-want) > 1e-9 {
+				t.Errorf("%s at %v: derivative of the integral is %v, want %v", expression, x, got, want)
+			}
+		}
+	}
+}
+
+func TestIntegrateErrors(t *testing.T) {
+	for _, expression := range []string{"x*e^x", "sin(x^2)"} {
+		if _, err := Integrate(state, parse(t, expression), "x"); err == nil {
+			t.Errorf("%s: want an error", expression)
+		}
+	}
+}
+
+// Definite integrals over a pole are an error instead of a wrong number.
+func TestIntegrateDefinite(t *testing.T) {
+	tests := []struct {
+		expression string
+		from, to   string
+		want       float64
+		code       string
+	}{
+		{"x^2", "0", "3", 9, ""},
+		{"sin(x)", "0", "pi", 2, ""},
+		{"e^x", "0", "1", math.E - 1, ""},
+		{"1/x", "-1", "1", 0, "divergent integral"},
+		{"1/x^2", "0", "1", 0, "divergent integral"},
+	}
+	for _, test := range tests {
+		got, err := IntegrateDefinite(state, parse(t, test.expression), "x", parse(t, test.from), parse(t, test.to))
+		if test.code != "" {
+			if err == nil || shared.ErrorCode(err) != test.code {
+				t.Errorf("%s from %s to %s: got %v %v, want %s", test.expression, test.from, test.to, got, err, test.code)
+			}
+			continue
+		}
+		if err != nil || math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s from %s to %s: got %v %v, want %v", test.expression, test.from, test.to, got, err, test.want)
+		}
+	}
+}
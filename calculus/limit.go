@@ -0,0 +1,366 @@
+package calculus
+
+import (
+	"lambdacalc/interpreter"
+	"lambdacalc/numeric"
+	"lambdacalc/shared"
+	"lambdacalc/simplifier"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+)
+
+// Direction a limit is approached from.
+const (
+	BOTH = iota
+	LEFT
+	RIGHT
+)
+
+// Maximum number of times L'Hôpital's rule is applied to a quotient.
+const MAX_LHOPITAL = 6
+
+// Number of points the limit is estimated with numerically, the last one is 10^-n away from the point.
+const LIMIT_SAMPLES = 10
+
+// Values larger than this are counted as infinite, when estimating a limit numerically.
+const LIMIT_INFINITY = 1e6
+
+// Functions that jump at integers.
+var jumps = []string{"floor", "ceil", "round"}
+
+// A limit towards a point from one side.
+type approach struct {
+	state    *shared.State
+	variable string
+	point    float64
+	side     float64 // -1 from the left, 1 from the right
+}
+
+// Find the limit of a tree, as the variable approaches a point. The point may be infinite.
+// The limits of the branches are combined, indeterminate forms like 0/0 use L'Hôpital's rule
+// and everything else is estimated numerically. Infinite limits are returned as infinity.
+// sin(x)/x as x -> 0 -> 1
+func Limit(s *shared.State, node *shared.Node, variable string, point float64, direction int) (float64, error) {
+	for _, name := range s.FreeVariables(node) {
+		if name != variable {
+			return 0, shared.NewError("undefined variable", "Unable to find limit, undefined variable '%s'.", name)
+		}
+	}
+
+	inlined, err := inline(s, shared.Clone(node), variable, 0)
+	if err != nil {
+		return 0, err
+	}
+	unwound, err := simplifier.Simplify(s, inlined, simplifier.UNWIND)
+	if err != nil {
+		return 0, err
+	}
+
+	// Infinity can only be approached from one side.
+	if math.IsInf(point, 1) {
+		direction = LEFT
+	} else if math.IsInf(point, -1) {
+		direction = RIGHT
+	}
+
+	if direction != BOTH {
+		side := 1.0
+		if direction == LEFT {
+			side = -1.0
+		}
+		return (&approach{state: s, variable: variable, point: point, side: side}).find(unwound)
+	}
+
+	left, err := (&approach{state: s, variable: variable, point: point, side: -1}).find(unwound)
+	if err != nil {
+		return 0, err
+	}
+	right, err := (&approach{state: s, variable: variable, point: point, side: 1}).find(unwound)
+	if err != nil {
+		return 0, err
+	}
+	// Infinite limits differ by their sign, the tolerance would compare Inf > Inf.
+	differ := math.IsInf(left, 0) || math.IsInf(right, 0)
+	if !differ {
+		differ = math.Abs(left-right) > math.Sqrt(numeric.Tolerance(s))*max(1, math.Abs(right))
+	}
+	if left != right && differ {
+		return 0, shared.NewError("limit does not exist", "Unable to find limit, the limit from the left is %s and from the right %s.", FormatLimit(left), FormatLimit(right))
+	}
+	return right, nil
+}
+
+// Formats a limit, infinite limits are written as ∞.
+func FormatLimit(value float64) string {
+	if math.IsInf(value, 1) {
+		return "∞"
+	} else if math.IsInf(value, -1) {
+		return "-∞"
+	} else if value == 0 {
+		// Limits approached from below are -0.
+		return "0"
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// Finds the limit symbolically, if that fails it is estimated numerically.
+func (p *approach) find(node *shared.Node) (float64, error) {
+	if res := p.limit(node, 0); !math.IsNaN(res) {
+		return res, nil
+	}
+
+	// Debug
+	if p.state.Conf.Options["show_debug_process"] {
+		cfmt.Printf("{{Debug:}}::cyan|bold No symbolic limit of %s, estimating it.\n", shared.PrintATree(node))
+	}
+	return p.estimate(node)
+}
+
+// Returns a point close to the limit point on the approached side, scale is the distance.
+// For infinite points the reciprocal of the scale is used. 0.001 -> 1000
+func (p *approach) near(scale float64) float64 {
+	if math.IsInf(p.point, 0) {
+		return -p.side / scale
+	}
+	return p.point + p.side*scale*max(1, math.Abs(p.point))
+}
+
+// Returns the limit of a tree by combining the limits of its branches, NaN if it is unknown.
+// Limits that are zero carry the sign of the side they are approached from. i.e.: x - 1 as x -> 1- is -0
+func (p *approach) limit(node *shared.Node, depth int) float64 {
+	if !shared.ContainsVariable(node, p.variable) {
+		res, err := interpreter.Evaluate(p.state, node)
+		if err != nil {
+			return math.NaN()
+		}
+		return res
+	}
+
+	switch node.OperationType {
+	case shared.VARIABLE:
+		if p.point == 0 {
+			return math.Copysign(0, p.side)
+		}
+		return p.point
+	case shared.PLUS:
+		res := 0.0
+		for _, val := range node.Associative {
+			res += p.limit(val, depth)
+		}
+		if res == 0 {
+			return p.signedZero(node)
+		}
+		return res
+	case shared.MINUS:
+		res := p.limit(node.LNode, depth) - p.limit(node.RNode, depth)
+		if res == 0 {
+			return p.signedZero(node)
+		}
+		return res
+	case shared.MULTIPLY:
+		res := 1.0
+		for _, val := range node.Associative {
+			res *= p.limit(val, depth)
+		}
+		// 0 * ∞, which includes 0/0 and ∞/∞
+		if math.IsNaN(res) {
+			return p.lhopital(node, depth)
+		}
+		return res
+	case shared.DIVIDE:
+		return p.limit(shared.Multiply(node.LNode, shared.Reciprocal(node.RNode)), depth)
+	case shared.POWER:
+		base := p.limit(node.LNode, depth)
+		exponent := p.limit(node.RNode, depth)
+
+		// 1^∞, 0^0 and ∞^0 are written as e^(g * ln(f))
+		inBase := shared.ContainsVariable(node.LNode, p.variable)
+		inExponent := shared.ContainsVariable(node.RNode, p.variable)
+		if (inBase && base == 1 && math.IsInf(exponent, 0)) || (inExponent && exponent == 0 && (base == 0 || math.IsInf(base, 0))) {
+			return math.Exp(p.limit(shared.Multiply(node.RNode, shared.FunctionNode("ln", node.LNode)), depth))
+		}
+		// Roots of negative numbers are not real. i.e.: x^0.5 as x -> 0-
+		if math.Signbit(base) && exponent != math.Trunc(exponent) {
+			return math.NaN()
+		}
+		return math.Pow(base, exponent)
+	case shared.SQRT:
+		degree := p.limit(node.LNode, depth)
+		radicand := p.limit(node.RNode, depth)
+		// Odd roots of negative numbers stay real.
+		if math.Signbit(radicand) && math.Mod(degree, 2) != 1 && math.Mod(degree, 2) != -1 {
+			return math.NaN()
+		} else if math.Signbit(radicand) {
+			return -math.Pow(-radicand, 1/degree)
+		}
+		return math.Pow(radicand, 1/degree)
+	case shared.FUNCTION:
+		params := []float64{}
+		for _, val := range node.Associative {
+			params = append(params, p.limit(val, depth))
+		}
+		return p.function(node.Variable, params)
+	}
+	return math.NaN()
+}
+
+// Returns the limit of a built-in function at the limits of its parameters.
+func (p *approach) function(name string, params []float64) float64 {
+	builtin, ok := shared.Builtins[name]
+	if !ok || !builtin.Accepts(len(params)) || slices.ContainsFunc(params, math.IsNaN) {
+		return math.NaN()
+	}
+
+	// Rounding jumps at integers, the side decides the result.
+	if slices.Contains(jumps, name) && params[0] == math.Trunc(params[0]) {
+		return math.NaN()
+	}
+	// ln(0) = -∞
+	if (name == "ln" || name == "log2" || (name == "log" && len(params) == 1)) && params[0] == 0 && !math.Signbit(params[0]) {
+		return math.Inf(-1)
+	}
+
+	res, err := builtin.Evaluate(params)
+	if err != nil {
+		return math.NaN()
+	}
+	return res
+}
+
+// Returns a zero with the sign of the tree close to the point.
+func (p *approach) signedZero(node *shared.Node) float64 {
+	res, err := numeric.Bind(p.state, node, p.variable)(p.near(1e-8))
+	if err == nil && res < 0 {
+		return math.Copysign(0, -1)
+	}
+	return 0
+}
+
+// Applies L'Hôpital's rule to a product with an indeterminate limit.
+// The product is split into a quotient f/g, where f and g both approach 0 or ∞, then the limit of f'/g' is used.
+// In products like 0 * ∞ the factors approaching 0, or otherwise the ones approaching ∞, are moved into the denominator.
+// x * ln(x) -> ln(x) / x^-1
+func (p *approach) lhopital(node *shared.Node, depth int) float64 {
+	if depth >= MAX_LHOPITAL {
+		return math.NaN()
+	}
+
+	numerator, denominator := []*shared.Node{}, []*shared.Node{}
+	for _, val := range node.Associative {
+		if val.OperationType == shared.POWER && val.RNode.OperationType == shared.NUMBER && val.RNode.Value < 0 {
+			denominator = append(denominator, shared.Power(val.LNode, shared.NumberNode(-val.RNode.Value)))
+		} else {
+			numerator = append(numerator, val)
+		}
+	}
+	if len(denominator) > 0 {
+		return p.quotient(numerator, denominator, depth)
+	}
+
+	for _, moved := range []func(float64) bool{isZero, isInfinite} {
+		numerator, denominator = []*shared.Node{}, []*shared.Node{}
+		for _, val := range node.Associative {
+			if moved(p.limit(val, depth+1)) {
+				denominator = append(denominator, shared.Reciprocal(val))
+			} else {
+				numerator = append(numerator, val)
+			}
+		}
+		if len(denominator) == 0 {
+			continue
+		}
+		if res := p.quotient(numerator, denominator, depth); !math.IsNaN(res) {
+			return res
+		}
+	}
+	return math.NaN()
+}
+
+// Returns the limit of f/g, for 0/0 and ∞/∞ L'Hôpital's rule is applied.
+func (p *approach) quotient(numerator []*shared.Node, denominator []*shared.Node, depth int) float64 {
+	f, g := shared.NumberNode(1.0), shared.Multiply(denominator...)
+	if len(numerator) > 0 {
+		f = shared.Multiply(numerator...)
+	}
+
+	a, b := p.limit(f, depth+1), p.limit(g, depth+1)
+	if !(a == 0 && b == 0) && !(math.IsInf(a, 0) && math.IsInf(b, 0)) {
+		return a / b
+	}
+
+	df, ok := derivative(p.state, f, p.variable)
+	if !ok {
+		return math.NaN()
+	}
+	dg, ok := derivative(p.state, g, p.variable)
+	if !ok {
+		return math.NaN()
+	}
+	quotient, err := simplifier.Simplify(p.state, shared.Multiply(df, invertProduct(dg)), simplifier.UNWIND)
+	if err != nil {
+		return math.NaN()
+	}
+
+	// Debug
+	if p.state.Conf.Options["show_debug_process"] {
+		cfmt.Printf("{{Debug:}}::cyan|bold L'Hôpital: %s\n", shared.PrintATree(quotient))
+	}
+	return p.limit(quotient, depth+1)
+}
+
+func isZero(x float64) bool {
+	return x == 0
+}
+
+func isInfinite(x float64) bool {
+	return math.IsInf(x, 0)
+}
+
+// Estimates the limit from values closer and closer to the point. The values are extrapolated,
+// assuming their error shrinks with the distance, the result is rounded to the digits that agree.
+// Values that keep growing are an infinite limit.
+func (p *approach) estimate(node *shared.Node) (float64, error) {
+	f := numeric.Bind(p.state, node, p.variable)
+
+	values := []float64{}
+	for k := 1; k <= LIMIT_SAMPLES; k++ {
+		if res, err := f(p.near(math.Pow(10, -float64(k)))); err == nil {
+			values = append(values, res)
+		}
+	}
+	if len(values) < 3 {
+		return 0, shared.NewError("undefined limit", "Unable to find limit, the expression is not defined close to %s.", FormatLimit(p.point))
+	}
+
+	n := len(values)
+	a, b, c := values[n-3], values[n-2], values[n-1]
+	if math.Abs(c) > LIMIT_INFINITY && math.Abs(c) > math.Abs(b) && math.Abs(b) > math.Abs(a) && (a > 0) == (c > 0) {
+		return math.Copysign(math.Inf(1), c), nil
+	}
+
+	// Richardson extrapolation, the error of each value is about 10 times the next one.
+	best, difference := 0.0, math.Inf(1)
+	previous := math.NaN()
+	for i := 1; i < n; i++ {
+		extrapolated := (10*values[i] - values[i-1]) / 9
+		if d := math.Abs(extrapolated - previous); d < difference {
+			best, difference = extrapolated, d
+		}
+		previous = extrapolated
+	}
+	if !(difference <= math.Sqrt(numeric.Tolerance(p.state))*max(1, math.Abs(best))) {
+		return 0, shared.NewError("no convergence", "Unable to find limit, the values do not converge close to %s.", FormatLimit(p.point))
+	}
+
+	// Only keep the digits that agree, values that can not be told apart from zero are zero.
+	if math.Abs(best) <= difference {
+		return 0, nil
+	} else if difference > 0 {
+		digits := int(-math.Log10(difference / max(1, math.Abs(best))))
+		best, _ = strconv.ParseFloat(strconv.FormatFloat(best, 'g', max(digits, 1), 64), 64)
+	}
+	return best, nil
+}
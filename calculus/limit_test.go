@@ -0,0 +1,53 @@
+package calculus
+
+import (
+	"lambdacalc/shared"
+	"math"
+	"testing"
+)
+
+func TestLimit(t *testing.T) {
+	tests := []struct {
+		expression string
+		point      float64
+		direction  int
+		want       float64
+	}{
+		{"x^2", 2, BOTH, 4},
+		{"sin(x)/x", 0, BOTH, 1},
+		{"(x^2-1)/(x-1)", 1, BOTH, 2},
+		{"(1+1/x)^x", math.Inf(1), BOTH, math.E},
+		{"1/x", 0, LEFT, math.Inf(-1)},
+		{"1/x", 0, RIGHT, math.Inf(1)},
+		{"1/x^2", 0, BOTH, math.Inf(1)},
+	}
+	for _, test := range tests {
+		got, err := Limit(state, parse(t, test.expression), "x", test.point, test.direction)
+		if err != nil {
+			t.Errorf("%s at %v: unexpected error %v", test.expression, test.point, err)
+			continue
+		}
+		if got != test.want && math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s at %v: got %v, want %v", test.expression, test.point, got, test.want)
+		}
+	}
+}
+
+// Limits that differ from both sides are an error, that names both of them.
+func TestLimitSides(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"1/x", "Unable to find limit, the limit from the left is -∞ and from the right ∞."},
+		{"abs(x)/x", "Unable to find limit, the limit from the left is -1 and from the right 1."},
+	}
+	for _, test := range tests {
+		if _, err := Limit(state, parse(t, test.expression), "x", 0, BOTH); err == nil || err.Error() != test.want {
+			t.Errorf("%s: got %v, want %s", test.expression, err, test.want)
+		}
+	}
+	if _, err := Limit(state, parse(t, "x + y"), "x", 0, BOTH); err == nil || shared.ErrorCode(err) != "undefined variable" {
+		t.Errorf("x + y: got %v, want undefined variable", err)
+	}
+}
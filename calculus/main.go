@@ -0,0 +1,271 @@
+package calculus
+
+import (
+	"lambdacalc/interpreter"
+	"lambdacalc/shared"
+	"lambdacalc/simplifier"
+	"math/big"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+)
+
+// Differentiate a tree by the given variable. Defined variables and functions are inlined first,
+// the derivative is simplified with the unwind and rewind rules.
+// x^2 + sin(x) -> 2x + cos(x)
+func Differentiate(s *shared.State, node *shared.Node, variable string) (*shared.Node, error) {
+	inlined, err := inline(s, shared.Clone(node), variable, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	derivative, err := derive(s, inlined, variable)
+	if err != nil {
+		return nil, err
+	}
+
+	// Debug
+	if s.Conf.Options["show_debug_process"] {
+		cfmt.Printf("{{Debug:}}::cyan|bold Derivative: %s\n", shared.PrintATree(derivative))
+	}
+
+	unwound, err := simplifier.Simplify(s, derivative, simplifier.UNWIND)
+	if err != nil {
+		return nil, err
+	}
+	return simplifier.Simplify(s, unwound, simplifier.REWIND)
+}
+
+// Returns the derivative of a tree without simplifying it.
+func derive(s *shared.State, node *shared.Node, variable string) (*shared.Node, error) {
+	// Constant parts vanish. i.e.: 2a -> 0
+	if !shared.ContainsVariable(node, variable) {
+		return shared.NumberNode(0.0), nil
+	}
+
+	switch node.OperationType {
+	case shared.VARIABLE:
+		// x -> 1
+		return shared.NumberNode(1.0), nil
+	case shared.PLUS:
+		// (f + g)' = f' + g'
+		addends := []*shared.Node{}
+		for _, val := range node.Associative {
+			d, err := derive(s, val, variable)
+			if err != nil {
+				return nil, err
+			}
+			addends = append(addends, d)
+		}
+		return shared.Add(addends...), nil
+	case shared.MINUS:
+		// (f - g)' = f' - g'
+		a, err := derive(s, node.LNode, variable)
+		if err != nil {
+			return nil, err
+		}
+		b, err := derive(s, node.RNode, variable)
+		if err != nil {
+			return nil, err
+		}
+		return shared.Add(a, shared.Negate(b)), nil
+	case shared.MULTIPLY:
+		// (f * g * h)' = f' * g * h + f * g' * h + f * g * h'
+		addends := []*shared.Node{}
+		for i, val := range node.Associative {
+			if !shared.ContainsVariable(val, variable) {
+				continue
+			}
+			d, err := derive(s, val, variable)
+			if err != nil {
+				return nil, err
+			}
+			factors := []*shared.Node{}
+			for j, other := range node.Associative {
+				if i == j {
+					factors = append(factors, d)
+				} else {
+					factors = append(factors, shared.Clone(other))
+				}
+			}
+			addends = append(addends, shared.Multiply(factors...))
+		}
+		return shared.Add(addends...), nil
+	case shared.DIVIDE:
+		// (f / g)' = (f' * g - f * g') * g^-2
+		a, err := derive(s, node.LNode, variable)
+		if err != nil {
+			return nil, err
+		}
+		b, err := derive(s, node.RNode, variable)
+		if err != nil {
+			return nil, err
+		}
+		return shared.Multiply(
+			shared.Add(
+				shared.Multiply(a, shared.Clone(node.RNode)),
+				shared.Negate(shared.Multiply(shared.Clone(node.LNode), b)),
+			),
+			shared.Power(shared.Clone(node.RNode), shared.NumberNode(-2.0)),
+		), nil
+	case shared.POWER:
+		return derivePower(s, node.LNode, node.RNode, variable)
+	case shared.SQRT:
+		// sqrt(f, n) = f^(1/n)
+		return derivePower(s, node.RNode, shared.Reciprocal(shared.Clone(node.LNode)), variable)
+	case shared.FUNCTION:
+		return deriveFunction(s, node, variable)
+	default:
+		return nil, shared.NewError("unexpected symbol", "Unable to differentiate, unexpected symbole.")
+	}
+}
+
+// Returns the derivative of a tree simplified with the unwind rules, without printing errors.
+// Reports false, if the tree can not be differentiated.
+func derivative(s *shared.State, node *shared.Node, variable string) (*shared.Node, bool) {
+	if !differentiable(node) {
+		return nil, false
+	}
+	d, err := derive(s, shared.Clone(node), variable)
+	if err != nil {
+		return nil, false
+	}
+	d, err = simplifier.Simplify(s, d, simplifier.UNWIND)
+	if err != nil {
+		return nil, false
+	}
+	return d, true
+}
+
+// Checks if all functions in a tree have a derivative, so it can be differentiated silently.
+func differentiable(node *shared.Node) bool {
+	if node == nil {
+		return true
+	}
+	if node.OperationType == shared.FUNCTION {
+		if _, ok := derivatives[node.Variable]; !ok {
+			return false
+		}
+		if builtin, ok := shared.Builtins[node.Variable]; ok && !builtin.Accepts(len(node.Associative)) {
+			return false
+		}
+	}
+	if !differentiable(node.LNode) || !differentiable(node.RNode) {
+		return false
+	}
+	for _, val := range node.Associative {
+		if !differentiable(val) {
+			return false
+		}
+	}
+	return true
+}
+
+// Derivative of f^g, which depends on where the variable appears.
+func derivePower(s *shared.State, base *shared.Node, exponent *shared.Node, variable string) (*shared.Node, error) {
+	inBase := shared.ContainsVariable(base, variable)
+	inExponent := shared.ContainsVariable(exponent, variable)
+
+	var a, b *shared.Node
+	var err error
+	if inBase {
+		a, err = derive(s, base, variable)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if inExponent {
+		b, err = derive(s, exponent, variable)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case !inExponent:
+		// (f^n)' = n * f^(n-1) * f'
+		return shared.Multiply(
+			shared.Clone(exponent),
+			shared.Power(shared.Clone(base), offset(s, exponent, -1)),
+			a,
+		), nil
+	case !inBase && isEuler(s, base):
+		// (e^g)' = e^g * g'
+		return shared.Multiply(shared.Power(shared.Clone(base), shared.Clone(exponent)), b), nil
+	case !inBase:
+		// (a^g)' = a^g * ln(a) * g'
+		return shared.Multiply(
+			shared.Power(shared.Clone(base), shared.Clone(exponent)),
+			shared.FunctionNode("ln", shared.Clone(base)),
+			b,
+		), nil
+	default:
+		// (f^g)' = f^g * (g' * ln(f) + g * f' * f^-1)
+		return shared.Multiply(
+			shared.Power(shared.Clone(base), shared.Clone(exponent)),
+			shared.Add(
+				shared.Multiply(b, shared.FunctionNode("ln", shared.Clone(base))),
+				shared.Multiply(shared.Clone(exponent), a, shared.Reciprocal(shared.Clone(base))),
+			),
+		), nil
+	}
+}
+
+// Returns n + delta, numerical exponents are calculated directly. 3^-1 - 1 -> -2 * 3^-1
+func offset(s *shared.State, exponent *shared.Node, delta int64) *shared.Node {
+	if exponent.OperationType == shared.NUMBER {
+		return shared.NumberNode(exponent.Value + float64(delta))
+	}
+	if s.Conf.KeepDecimals() {
+		if val, exact, err := interpreter.EvaluateExact(s, exponent); err == nil && exact {
+			return shared.RationalNode(val.Add(val, big.NewRat(delta, 1)))
+		}
+	}
+	return shared.Add(shared.Clone(exponent), shared.NumberNode(float64(delta)))
+}
+
+// Returns 1/n, numerical values are calculated directly. 4 * 3^-1 -> 3 * 4^-1
+func invert(s *shared.State, node *shared.Node) *shared.Node {
+	if node.OperationType == shared.NUMBER && node.Value != 0 && !s.Conf.KeepDecimals() {
+		return shared.NumberNode(1 / node.Value)
+	}
+	if s.Conf.KeepDecimals() {
+		if val, exact, err := interpreter.EvaluateExact(s, node); err == nil && exact && val.Sign() != 0 {
+			return shared.RationalNode(val.Inv(val))
+		}
+	}
+	return shared.Reciprocal(shared.Clone(node))
+}
+
+// Checks if a node is Euler's number, either as the constant or its value.
+func isEuler(s *shared.State, node *shared.Node) bool {
+	e, ok := s.Conf.Constants["e"]
+	if !ok {
+		return false
+	}
+	switch node.OperationType {
+	case shared.NUMBER:
+		return node.Value == e
+	case shared.VARIABLE:
+		_, defined := s.Variables[node.Variable]
+		return node.Variable == "e" && !defined
+	}
+	return false
+}
+
+// Derivative of a built-in function with the chain rule.
+// f(g)' = f'(g) * g'
+func deriveFunction(s *shared.State, node *shared.Node, variable string) (*shared.Node, error) {
+	rule, ok := derivatives[node.Variable]
+	if !ok {
+		return nil, shared.NewError("not differentiable", "Unable to differentiate, '%s' is not differentiable.", node.Variable)
+	}
+	if builtin, ok := shared.Builtins[node.Variable]; ok && !builtin.Accepts(len(node.Associative)) {
+		return nil, shared.NewError("unmatched parameters", "Unable to differentiate, function '%s' does not accept %v parameters.", node.Variable, len(node.Associative))
+	}
+
+	params := []*shared.Node{}
+	for _, val := range node.Associative {
+		params = append(params, shared.Clone(val))
+	}
+	return rule(s, params, variable)
+}
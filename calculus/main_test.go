@@ -0,0 +1,60 @@
+package calculus
+
+import (
+	"lambdacalc/lexer"
+	"lambdacalc/parser"
+	"lambdacalc/shared"
+	"testing"
+)
+
+// State of the tests, with the default config. Tests defining names remove them again.
+var state = shared.NewState(shared.GetDefualtConfig())
+
+// Parses an expression for a test.
+func parse(t *testing.T, input string) *shared.Node {
+	t.Helper()
+	lexed, err := lexer.LexTokens(state, input)
+	if err != nil {
+		t.Fatalf("%s: unable to lex, %v", input, err)
+	}
+	parsed, err := parser.Parse(state, lexed)
+	if err != nil {
+		t.Fatalf("%s: unable to parse, %v", input, err)
+	}
+	return parsed
+}
+
+func TestDifferentiate(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"x^3", "(3*(x^2))"},
+		{"5", "0"},
+		{"y*x", "y"},
+		{"sin(x)", "cos(x)"},
+		{"x*sin(x)", "(sin(x)+(x*cos(x)))"},
+		{"sin(x)^2", "(2*sin(x)*cos(x))"},
+		{"ln(x)", "(x^-1)"},
+		{"1/x", "(-1*(x^-2))"},
+		{"sqrt(x)", "(0.5*(x^-0.5))"},
+		{"tan(x)", "(cos(x)^-2)"},
+		{"x^x", "(((x^x)*ln(x))+(x^x))"},
+	}
+	for _, test := range tests {
+		got, err := Differentiate(state, parse(t, test.expression), "x")
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.expression, err)
+			continue
+		}
+		if res := shared.PrintATree(got); res != test.want {
+			t.Errorf("%s: got %s, want %s", test.expression, res, test.want)
+		}
+	}
+}
+
+func TestDifferentiateUndefinedFunction(t *testing.T) {
+	if _, err := Differentiate(state, parse(t, "f(x)"), "x"); err == nil || shared.ErrorCode(err) != "undefined function" {
+		t.Errorf("f(x): got %v, want undefined function", err)
+	}
+}
@@ -0,0 +1,220 @@
+package calculus
+
+import (
+	"lambdacalc/shared"
+)
+
+// Rule Type, returns the derivative of a built-in function called with the given parameters.
+type DerivativeRule func(s *shared.State, params []*shared.Node, variable string) (*shared.Node, error)
+
+// Applies the chain rule to a function with a single parameter.
+// outer is the derivative of the function, evaluated at the parameter.
+func chain(outer func(u *shared.Node) *shared.Node) DerivativeRule {
+	return func(s *shared.State, params []*shared.Node, variable string) (*shared.Node, error) {
+		d, err := derive(s, params[0], variable)
+		if err != nil {
+			return nil, err
+		}
+		return shared.Multiply(outer(params[0]), d), nil
+	}
+}
+
+// Functions that are linear, so the derivative can be moved inside. re(f)' = re(f')
+func linear(name string) DerivativeRule {
+	return func(s *shared.State, params []*shared.Node, variable string) (*shared.Node, error) {
+		d, err := derive(s, params[0], variable)
+		if err != nil {
+			return nil, err
+		}
+		return shared.FunctionNode(name, d), nil
+	}
+}
+
+// 1 - u^2
+func oneMinusSquare(u *shared.Node) *shared.Node {
+	return shared.Add(shared.NumberNode(1.0), shared.Negate(shared.Power(u, shared.NumberNode(2.0))))
+}
+
+// Derivatives of the built-in functions, functions without a rule can not be differentiated.
+// The rules call derive themselves, so the map is filled in init.
+var derivatives map[string]DerivativeRule
+
+func init() {
+	derivatives = map[string]DerivativeRule{
+		// Trigonometry
+		"sin": chain(func(u *shared.Node) *shared.Node {
+			// sin(u)' = cos(u)
+			return shared.FunctionNode("cos", u)
+		}),
+		"cos": chain(func(u *shared.Node) *shared.Node {
+			// cos(u)' = -sin(u)
+			return shared.Negate(shared.FunctionNode("sin", u))
+		}),
+		"tan": chain(func(u *shared.Node) *shared.Node {
+			// tan(u)' = cos(u)^-2
+			return shared.Power(shared.FunctionNode("cos", u), shared.NumberNode(-2.0))
+		}),
+		"asin": chain(func(u *shared.Node) *shared.Node {
+			// asin(u)' = (1 - u^2)^-0.5
+			return shared.Power(oneMinusSquare(u), shared.NumberNode(-0.5))
+		}),
+		"acos": chain(func(u *shared.Node) *shared.Node {
+			// acos(u)' = -(1 - u^2)^-0.5
+			return shared.Negate(shared.Power(oneMinusSquare(u), shared.NumberNode(-0.5)))
+		}),
+		"atan": chain(func(u *shared.Node) *shared.Node {
+			// atan(u)' = (1 + u^2)^-1
+			return shared.Reciprocal(shared.Add(shared.NumberNode(1.0), shared.Power(u, shared.NumberNode(2.0))))
+		}),
+		"atan2": func(s *shared.State, params []*shared.Node, variable string) (*shared.Node, error) {
+			// atan2(y, x)' = (x * y' - y * x') * (x^2 + y^2)^-1
+			y, x := params[0], params[1]
+			dy, err := derive(s, y, variable)
+			if err != nil {
+				return nil, err
+			}
+			dx, err := derive(s, x, variable)
+			if err != nil {
+				return nil, err
+			}
+			return shared.Multiply(
+				shared.Add(shared.Multiply(shared.Clone(x), dy), shared.Negate(shared.Multiply(shared.Clone(y), dx))),
+				shared.Reciprocal(shared.Add(shared.Power(shared.Clone(x), shared.NumberNode(2.0)), shared.Power(shared.Clone(y), shared.NumberNode(2.0)))),
+			), nil
+		},
+		"sinh": chain(func(u *shared.Node) *shared.Node {
+			// sinh(u)' = cosh(u)
+			return shared.FunctionNode("cosh", u)
+		}),
+		"cosh": chain(func(u *shared.Node) *shared.Node {
+			// cosh(u)' = sinh(u)
+			return shared.FunctionNode("sinh", u)
+		}),
+		"tanh": chain(func(u *shared.Node) *shared.Node {
+			// tanh(u)' = cosh(u)^-2
+			return shared.Power(shared.FunctionNode("cosh", u), shared.NumberNode(-2.0))
+		}),
+		"asinh": chain(func(u *shared.Node) *shared.Node {
+			// asinh(u)' = (u^2 + 1)^-0.5
+			return shared.Power(shared.Add(shared.Power(u, shared.NumberNode(2.0)), shared.NumberNode(1.0)), shared.NumberNode(-0.5))
+		}),
+		"acosh": chain(func(u *shared.Node) *shared.Node {
+			// acosh(u)' = (u^2 - 1)^-0.5
+			return shared.Power(shared.Add(shared.Power(u, shared.NumberNode(2.0)), shared.NumberNode(-1.0)), shared.NumberNode(-0.5))
+		}),
+		"atanh": chain(func(u *shared.Node) *shared.Node {
+			// atanh(u)' = (1 - u^2)^-1
+			return shared.Reciprocal(oneMinusSquare(u))
+		}),
+
+		// Logarithms and exponentials
+		"ln": chain(func(u *shared.Node) *shared.Node {
+			// ln(u)' = u^-1
+			return shared.Reciprocal(u)
+		}),
+		"log2": chain(func(u *shared.Node) *shared.Node {
+			// log2(u)' = (u * ln(2))^-1
+			return shared.Reciprocal(shared.Multiply(u, shared.FunctionNode("ln", shared.NumberNode(2.0))))
+		}),
+		"exp": chain(func(u *shared.Node) *shared.Node {
+			// exp(u)' = exp(u)
+			return shared.FunctionNode("exp", u)
+		}),
+		"log": func(s *shared.State, params []*shared.Node, variable string) (*shared.Node, error) {
+			// log(u, b) = ln(u) * ln(b)^-1
+			base := shared.NumberNode(10.0)
+			if len(params) == 2 {
+				base = params[1]
+			}
+			return derive(s, shared.Multiply(shared.FunctionNode("ln", params[0]), shared.Reciprocal(shared.FunctionNode("ln", base))), variable)
+		},
+
+		// Rounding
+		"abs": chain(func(u *shared.Node) *shared.Node {
+			// abs(u)' = u * abs(u)^-1
+			return shared.Multiply(shared.Clone(u), shared.Reciprocal(shared.FunctionNode("abs", u)))
+		}),
+		// Rounded values only change at jumps, where they are not differentiable.
+		"floor": chain(func(u *shared.Node) *shared.Node { return shared.NumberNode(0.0) }),
+		"ceil":  chain(func(u *shared.Node) *shared.Node { return shared.NumberNode(0.0) }),
+		"round": chain(func(u *shared.Node) *shared.Node { return shared.NumberNode(0.0) }),
+
+		// Complex numbers
+		"re":   linear("re"),
+		"im":   linear("im"),
+		"conj": linear("conj"),
+	}
+}
+
+// Rule Type, returns the antiderivative of a built-in function called with a single variable.
+type IntegralRule func(u *shared.Node) *shared.Node
+
+// u * f(u) - g(u), the antiderivative of inverse functions and logarithms.
+func byParts(name string, u *shared.Node, rest *shared.Node) *shared.Node {
+	return shared.Add(shared.Multiply(shared.Clone(u), shared.FunctionNode(name, shared.Clone(u))), shared.Negate(rest))
+}
+
+// Antiderivatives of the built-in functions, functions without a rule can not be integrated.
+var integrals = map[string]IntegralRule{
+	// Trigonometry
+	"sin": func(u *shared.Node) *shared.Node {
+		// ∫ sin(u) = -cos(u)
+		return shared.Negate(shared.FunctionNode("cos", u))
+	},
+	"cos": func(u *shared.Node) *shared.Node {
+		// ∫ cos(u) = sin(u)
+		return shared.FunctionNode("sin", u)
+	},
+	"tan": func(u *shared.Node) *shared.Node {
+		// ∫ tan(u) = -ln(|cos(u)|)
+		return shared.Negate(shared.FunctionNode("ln", shared.FunctionNode("abs", shared.FunctionNode("cos", u))))
+	},
+	"asin": func(u *shared.Node) *shared.Node {
+		// ∫ asin(u) = u * asin(u) + (1 - u^2)^0.5
+		return byParts("asin", u, shared.Negate(shared.Power(oneMinusSquare(shared.Clone(u)), shared.NumberNode(0.5))))
+	},
+	"acos": func(u *shared.Node) *shared.Node {
+		// ∫ acos(u) = u * acos(u) - (1 - u^2)^0.5
+		return byParts("acos", u, shared.Power(oneMinusSquare(shared.Clone(u)), shared.NumberNode(0.5)))
+	},
+	"atan": func(u *shared.Node) *shared.Node {
+		// ∫ atan(u) = u * atan(u) - 0.5 * ln(1 + u^2)
+		return byParts("atan", u, shared.Multiply(shared.NumberNode(0.5), shared.FunctionNode("ln", shared.Add(shared.NumberNode(1.0), shared.Power(shared.Clone(u), shared.NumberNode(2.0))))))
+	},
+	"sinh": func(u *shared.Node) *shared.Node {
+		// ∫ sinh(u) = cosh(u)
+		return shared.FunctionNode("cosh", u)
+	},
+	"cosh": func(u *shared.Node) *shared.Node {
+		// ∫ cosh(u) = sinh(u)
+		return shared.FunctionNode("sinh", u)
+	},
+	"tanh": func(u *shared.Node) *shared.Node {
+		// ∫ tanh(u) = ln(cosh(u))
+		return shared.FunctionNode("ln", shared.FunctionNode("cosh", u))
+	},
+
+	// Logarithms and exponentials
+	"ln": func(u *shared.Node) *shared.Node {
+		// ∫ ln(u) = u * ln(u) - u
+		return byParts("ln", u, shared.Clone(u))
+	},
+	"log2": func(u *shared.Node) *shared.Node {
+		// ∫ log2(u) = (u * ln(u) - u) * ln(2)^-1
+		return shared.Multiply(byParts("ln", u, shared.Clone(u)), shared.Reciprocal(shared.FunctionNode("ln", shared.NumberNode(2.0))))
+	},
+	"log": func(u *shared.Node) *shared.Node {
+		// ∫ log(u) = (u * ln(u) - u) * ln(10)^-1
+		return shared.Multiply(byParts("ln", u, shared.Clone(u)), shared.Reciprocal(shared.FunctionNode("ln", shared.NumberNode(10.0))))
+	},
+	"exp": func(u *shared.Node) *shared.Node {
+		// ∫ exp(u) = exp(u)
+		return shared.FunctionNode("exp", u)
+	},
+
+	// Rounding
+	"abs": func(u *shared.Node) *shared.Node {
+		// ∫ |u| = 0.5 * u * |u|
+		return shared.Multiply(shared.NumberNode(0.5), shared.Clone(u), shared.FunctionNode("abs", u))
+	},
+}
@@ -0,0 +1,176 @@
+package calculus
+
+import (
+	"lambdacalc/interpreter"
+	"lambdacalc/shared"
+	"lambdacalc/simplifier"
+	"math"
+	"math/big"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+)
+
+// Derivatives smaller than this are rounding errors of zero.
+const ROUNDING_ERROR = 1e-14
+
+// Taylor polynomial of a tree around a point up to the given order, as a sum of
+// f^(k)(a) / k! * (x - a)^k. Symbolic points and parameters are kept in the coefficients.
+// sin(x) around x = 0 to order 5 -> x - 1/6 x^3 + 1/120 x^5
+func Series(s *shared.State, node *shared.Node, variable string, point *shared.Node, order int) (*shared.Node, error) {
+	if order < 0 {
+		return nil, shared.NewError("negative order", "Unable to expand series, the order has to be positive.")
+	}
+
+	derivative, err := inline(s, shared.Clone(node), variable, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Numerical points are written as a number. -1 -> (x + 1)
+	point = foldConstants(s, shared.Clone(point))
+
+	// x - a, or x around 0
+	shift := shared.VariableNode(variable)
+	if point.OperationType == shared.NUMBER && point.Value != 0 {
+		shift = shared.Add(shared.VariableNode(variable), negative(point))
+	} else if point.OperationType != shared.NUMBER {
+		shift = shared.Add(shared.VariableNode(variable), shared.Negate(shared.Clone(point)))
+	}
+
+	terms := []*shared.Node{}
+	factorial := big.NewInt(1)
+	for k := 0; k <= order; k++ {
+		if k > 0 {
+			derivative, err = Differentiate(s, derivative, variable)
+			if err != nil {
+				return nil, err
+			}
+			factorial.Mul(factorial, big.NewInt(int64(k)))
+		}
+
+		coefficient, err := taylorCoefficient(s, derivative, variable, point, factorial, k)
+		if err != nil {
+			return nil, err
+		}
+		if coefficient.OperationType == shared.NUMBER && coefficient.Value == 0 {
+			continue
+		}
+
+		// The terms are kept in powers of x - a, simplifying them would multiply them out.
+		power := shared.Clone(shift)
+		if k > 1 {
+			power = shared.Power(power, shared.NumberNode(float64(k)))
+		}
+		switch {
+		case k == 0:
+			terms = append(terms, coefficient)
+		case coefficient.OperationType == shared.NUMBER && coefficient.Value == 1:
+			terms = append(terms, power)
+		case coefficient.OperationType == shared.MULTIPLY:
+			// Numbers are written first. cos(b) * -0.5 * (x - b)^2 -> -0.5 * cos(b) * (x - b)^2
+			numbers, factors := []*shared.Node{}, []*shared.Node{}
+			for _, val := range coefficient.Associative {
+				if val.OperationType == shared.NUMBER {
+					numbers = append(numbers, val)
+				} else {
+					factors = append(factors, val)
+				}
+			}
+			terms = append(terms, shared.Multiply(append(append(numbers, factors...), power)...))
+		default:
+			terms = append(terms, shared.Multiply(coefficient, power))
+		}
+	}
+
+	// Debug
+	if s.Conf.Options["show_debug_process"] {
+		cfmt.Printf("{{Debug:}}::cyan|bold Series: %s\n", shared.PrintATree(shared.Add(terms...)))
+	}
+
+	switch len(terms) {
+	case 0:
+		return shared.NumberNode(0.0), nil
+	case 1:
+		return terms[0], nil
+	}
+	return shared.Add(terms...), nil
+}
+
+// Returns the negative of a number, keeping its exact value. 2 -> -2
+func negative(node *shared.Node) *shared.Node {
+	if r := node.Rational; r != nil {
+		return shared.RationalNode(new(big.Rat).Neg(r))
+	}
+	return shared.NumberNode(-node.Value)
+}
+
+// Returns f^(k)(a) / k!, numerical values are calculated directly.
+func taylorCoefficient(s *shared.State, derivative *shared.Node, variable string, point *shared.Node, factorial *big.Int, k int) (*shared.Node, error) {
+	value := replace(shared.Clone(derivative), map[string]*shared.Node{variable: point})
+	if len(s.FreeVariables(value)) > 0 {
+		// Simplified twice, so constants like sin(a * 0) can be folded.
+		unwound, err := simplifier.Simplify(s, value, simplifier.UNWIND)
+		if err != nil {
+			return nil, err
+		}
+		folded, err := simplifier.Simplify(s, foldConstants(s, unwound), simplifier.UNWIND)
+		if err != nil {
+			return nil, err
+		}
+		if folded.OperationType == shared.NUMBER && folded.Value == 0 {
+			return folded, nil
+		}
+		if k < 2 {
+			return folded, nil
+		}
+		return simplifier.Simplify(s, shared.Multiply(folded, shared.Reciprocal(shared.RationalNode(new(big.Rat).SetInt(factorial)))), simplifier.UNWIND)
+	}
+
+	if s.Conf.KeepDecimals() {
+		if val, exact, err := interpreter.EvaluateExact(s, value); err == nil && exact {
+			return shared.RationalNode(val.Quo(val, new(big.Rat).SetInt(factorial))), nil
+		}
+	}
+
+	res, err := interpreter.Evaluate(s, value)
+	if err != nil || math.IsNaN(res) || math.IsInf(res, 0) {
+		if k == 0 {
+			return nil, shared.NewError("undefined derivative", "Unable to expand series, the expression is not defined at %s = %s.", variable, shared.PrintATree(point))
+		}
+		return nil, shared.NewError("undefined derivative", "Unable to expand series, the derivative of order %v is not defined at %s = %s.", k, variable, shared.PrintATree(point))
+	}
+	// Rounding errors of values that are zero. i.e.: sin(pi)
+	if math.Abs(res) < ROUNDING_ERROR {
+		return shared.NumberNode(0.0), nil
+	}
+	// Integer derivatives stay exact fractions. i.e.: cos(0) / 3!
+	if s.Conf.KeepDecimals() && res == math.Trunc(res) {
+		val := new(big.Rat).SetFloat64(res)
+		return shared.RationalNode(val.Quo(val, new(big.Rat).SetInt(factorial))), nil
+	}
+	f, _ := new(big.Float).SetInt(factorial).Float64()
+	return shared.NumberNode(res / f), nil
+}
+
+// Replaces parts of a tree without undefined variables by their value. cos(0) * a -> a
+func foldConstants(s *shared.State, node *shared.Node) *shared.Node {
+	if node == nil {
+		return nil
+	}
+	if len(s.FreeVariables(node)) == 0 && node.OperationType != shared.NUMBER {
+		if s.Conf.KeepDecimals() {
+			if val, exact, err := interpreter.EvaluateExact(s, node); err == nil && exact {
+				return shared.RationalNode(val)
+			}
+		} else if res, err := interpreter.Evaluate(s, node); err == nil && !math.IsNaN(res) && !math.IsInf(res, 0) {
+			return shared.NumberNode(res)
+		}
+	}
+
+	node.LNode = foldConstants(s, node.LNode)
+	node.RNode = foldConstants(s, node.RNode)
+	for i, val := range node.Associative {
+		node.Associative[i] = foldConstants(s, val)
+	}
+	return node
+}
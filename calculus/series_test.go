@@ -0,0 +1,35 @@
+package calculus
+
+import (
+	"lambdacalc/shared"
+	"testing"
+)
+
+// Series are written in powers of x - a and negative terms after a minus.
+func TestSeries(t *testing.T) {
+	tests := []struct {
+		expression string
+		point      string
+		order      int
+		want       string
+	}{
+		{"x^3", "2", 5, "(8+(12*(x-2))+(6*((x-2)^2))+((x-2)^3))"},
+		{"ln(x)", "1", 3, "((x-1)-(0.5*((x-1)^2))+(0.3333333333333333*((x-1)^3)))"},
+		{"1/x", "-1", 2, "(-1-(x+1)-((x+1)^2))"},
+		{"sin(x)", "0", 5, "(x-(0.16666666666666666*(x^3))+(0.008333333333333333*(x^5)))"},
+		{"x^2", "a", 3, "((a^2)+(2*a*(x-a))+((x-a)^2))"},
+		{"cos(x)", "b", 2, "(cos(b)-(sin(b)*(x-b))-(0.5*cos(b)*((x-b)^2)))"},
+		{"5", "3", 5, "5"},
+		{"x^4", "0", 2, "0"},
+	}
+	for _, test := range tests {
+		got, err := Series(state, parse(t, test.expression), "x", parse(t, test.point), test.order)
+		if err != nil {
+			t.Errorf("%s around %s: unexpected error %v", test.expression, test.point, err)
+			continue
+		}
+		if res := shared.PrintATree(got); res != test.want {
+			t.Errorf("%s around %s: got %s, want %s", test.expression, test.point, res, test.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package calculus
+
+import (
+	"lambdacalc/interpreter"
+	"lambdacalc/shared"
+)
+
+// Returns the reciprocal of a product by inverting each factor, so the simplifier can collect them.
+// 2 * x^-2 -> 2^-1 * x^2
+func invertProduct(node *shared.Node) *shared.Node {
+	factors := []*shared.Node{}
+	for _, val := range flatten(node) {
+		if val.OperationType == shared.POWER && val.RNode.OperationType == shared.NUMBER {
+			factors = append(factors, shared.Power(shared.Clone(val.LNode), shared.NumberNode(-val.RNode.Value)))
+		} else {
+			factors = append(factors, shared.Reciprocal(shared.Clone(val)))
+		}
+	}
+	return shared.Multiply(factors...)
+}
+
+// Replace defined variables, except the given one, by their value and calls of user defined
+// functions by their equation, so the tree only depends on built-in functions.
+// f(x) = x^2 -> f(2y) = (2y)^2
+func inline(s *shared.State, node *shared.Node, variable string, depth int) (*shared.Node, error) {
+	if node == nil {
+		return nil, nil
+	}
+	if depth >= interpreter.MAX_CALL_DEPTH {
+		return nil, shared.NewError("maximum call depth exceeded", "Unable to inline functions, exceeded the maximum call depth of %v.", interpreter.MAX_CALL_DEPTH)
+	}
+
+	switch node.OperationType {
+	case shared.VARIABLE:
+		if val, ok := s.Variables[node.Variable]; ok && node.Variable != variable {
+			return inline(s, shared.Clone(&val), variable, depth+1)
+		}
+		return node, nil
+	case shared.FUNCTION:
+		for i, val := range node.Associative {
+			res, err := inline(s, val, variable, depth)
+			if err != nil {
+				return nil, err
+			}
+			node.Associative[i] = res
+		}
+		if _, ok := shared.Builtins[node.Variable]; ok {
+			return node, nil
+		}
+
+		function, ok := s.Functions[node.Variable]
+		if !ok {
+			return nil, shared.NewError("undefined function", "Unable to inline functions, undefined function '%s'.", node.Variable)
+		}
+		if len(function.Parameters) != len(node.Associative) {
+			return nil, shared.NewError("unmatched parameters", "Unable to inline functions, function '%s' expects %v parameters but got %v.", node.Variable, len(function.Parameters), len(node.Associative))
+		}
+
+		arguments := map[string]*shared.Node{}
+		for i, param := range function.Parameters {
+			arguments[param.Variable] = node.Associative[i]
+		}
+		return inline(s, replace(shared.Clone(function.Equation), arguments), variable, depth+1)
+	}
+
+	var err error
+	node.LNode, err = inline(s, node.LNode, variable, depth)
+	if err != nil {
+		return nil, err
+	}
+	node.RNode, err = inline(s, node.RNode, variable, depth)
+	if err != nil {
+		return nil, err
+	}
+	for i, val := range node.Associative {
+		node.Associative[i], err = inline(s, val, variable, depth)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// Replace variables by copies of the given trees, all variables are replaced at once.
+// x + y with x -> y, y -> 2 -> y + 2
+func replace(node *shared.Node, values map[string]*shared.Node) *shared.Node {
+	if node == nil {
+		return nil
+	}
+	if node.OperationType == shared.VARIABLE {
+		if val, ok := values[node.Variable]; ok {
+			return shared.Clone(val)
+		}
+		return node
+	}
+
+	node.LNode = replace(node.LNode, values)
+	node.RNode = replace(node.RNode, values)
+	for i, val := range node.Associative {
+		node.Associative[i] = replace(val, values)
+	}
+	return node
+}
@@ -0,0 +1,237 @@
+package engine
+
+import (
+	"context"
+	"lambdacalc/latex"
+	"lambdacalc/lexer"
+	"lambdacalc/parser"
+	"lambdacalc/shared"
+	"lambdacalc/simplifier"
+)
+
+// Context of the engine with its own config, variables and functions. Definitions and options of
+// one context are never visible in another. The state of a context is passed to the packages
+// calculating its statements, so contexts can be used at the same time from multiple goroutines.
+// Statements of the same context take turns, a long statement makes the next one wait until it
+// is done or its context.Context is canceled, use the methods taking one to limit it.
+//
+//	ctx := engine.New(shared.GetDefualtConfig())
+//	ctx.Define("x", "3")
+//	ctx.Eval("2x + x") -> 9
+type Context struct {
+	state *shared.State
+	// Taken while the context is used, so its definitions are changed by one statement at a time.
+	busy chan struct{}
+}
+
+// Trees of a calculation. The simplified tree is nil, if the expression was calculated
+// without simplifying, i.e.: matrices and units. The value is the result as a number, exact and
+// precise results are approximated. It is nil if the result is no number, i.e.: a matrix or 2 m
+type Calculation struct {
+	Parsed     *shared.Node
+	Simplified *shared.Node
+	Result     *shared.Node
+	Value      *complex128
+}
+
+// Result of a statement, the calculation is only set if the statement was calculated.
+type Result struct {
+	Output      string
+	Calculation *Calculation
+}
+
+// Creates a context with a copy of the config, missing options use their default value.
+func New(conf shared.Config) *Context {
+	conf = conf.Clone()
+	shared.FillDefaults(&conf)
+	return &Context{
+		state: shared.NewState(conf),
+		busy:  make(chan struct{}, 1),
+	}
+}
+
+// Runs a function with the state of the context, the calculation stops once ctx is done.
+// Waiting for the context stops as well.
+func (c *Context) use(ctx context.Context, run func(s *shared.State) error) (err error) {
+	select {
+	case c.busy <- struct{}{}:
+		defer func() { <-c.busy }()
+	case <-ctx.Done():
+		return shared.NewError("canceled", "Unable to finish calculation, the calculation was canceled.")
+	}
+
+	s := c.state.With(ctx)
+	defer func() {
+		if r := recover(); r != nil {
+			if !shared.IsCancellation(r) {
+				panic(r)
+			}
+			err = s.Canceled()
+		}
+	}()
+	if err := run(s); err != nil {
+		return err
+	}
+	// Results of a canceled calculation may be incomplete.
+	return s.Canceled()
+}
+
+// Takes the context to read or change the context itself.
+func (c *Context) lock() func() {
+	c.busy <- struct{}{}
+	return func() { <-c.busy }
+}
+
+// Runs a statement like a line of the REPL and returns its output.
+// define x = 3 -> Variable defined., 2x + x -> 9
+func (c *Context) Eval(statement string) (string, error) {
+	result, err := c.Evaluate(statement)
+	return result.Output, err
+}
+
+// Runs a statement and returns its output together with the trees of the calculation.
+func (c *Context) Evaluate(statement string) (Result, error) {
+	return c.EvaluateContext(context.Background(), statement)
+}
+
+// Evaluates a statement like Evaluate, the calculation stops with an error once ctx is done.
+func (c *Context) EvaluateContext(ctx context.Context, statement string) (Result, error) {
+	result := Result{Output: "", Calculation: nil}
+	err := c.use(ctx, func(s *shared.State) error {
+		output, calculation, err := read(s, statement)
+		result.Output = output
+		result.Calculation = calculation
+		return err
+	})
+	return result, err
+}
+
+// Defines a variable or function. Positions of errors are given in the expression, errors in
+// front of it in the declaration.
+// Define("f(x)", "x^2") -> Function defined.
+func (c *Context) Define(declaration string, expression string) (string, error) {
+	return c.DefineContext(context.Background(), declaration, expression)
+}
+
+// Defines a variable or function like Define, it stops with an error once ctx is done.
+func (c *Context) DefineContext(ctx context.Context, declaration string, expression string) (string, error) {
+	output := ""
+	err := c.use(ctx, func(s *shared.State) error {
+		// The equal sign is read from the config of the context.
+		statement := "define " + declaration + " " + s.Conf.Symbols["equal"] + " "
+		res, _, err := read(s, statement+expression)
+		output = res
+		if e, ok := err.(*shared.Error); ok && e.Span != nil && e.Span.Start < len(statement) {
+			return shared.Shift(err, -len("define "))
+		}
+		return shared.Shift(err, -len(statement))
+	})
+	return output, err
+}
+
+// Removes a variable or function. Drop("x") -> Variable deleted.
+func (c *Context) Drop(name string) (string, error) {
+	return c.DropContext(context.Background(), name)
+}
+
+// Removes a variable or function like Drop, it stops with an error once ctx is done.
+func (c *Context) DropContext(ctx context.Context, name string) (string, error) {
+	res, err := c.EvaluateContext(ctx, "drop "+name)
+	return res.Output, err
+}
+
+// Returns the defined variables and functions with their values.
+// x -> 3, f(x) -> (x^2)
+func (c *Context) Definitions() map[string]string {
+	definitions, _ := c.DefinitionsContext(context.Background())
+	return definitions
+}
+
+// Returns the definitions like Definitions, waiting for the engine stops with an error once ctx is done.
+func (c *Context) DefinitionsContext(ctx context.Context) (map[string]string, error) {
+	definitions := map[string]string{}
+	err := c.use(ctx, func(s *shared.State) error {
+		for name, val := range s.Variables {
+			definitions[name] = shared.PrintATree(s.Inline(&val))
+		}
+		for name, function := range s.Functions {
+			definitions[shared.PrintATree(shared.FunctionNode(name, function.Parameters...))] = shared.PrintATree(function.Equation)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return definitions, nil
+}
+
+// Simplifies an expression without calculating it. 2x + x -> (3*x)
+func (c *Context) Simplify(expression string) (string, error) {
+	return c.SimplifyContext(context.Background(), expression)
+}
+
+// Simplifies an expression like Simplify, it stops with an error once ctx is done.
+func (c *Context) SimplifyContext(ctx context.Context, expression string) (string, error) {
+	res := ""
+	err := c.use(ctx, func(s *shared.State) error {
+		tokenize := tokenizer(lexer.LexSymbols)
+		if s.Conf.Options["latex_input"] {
+			tokenize = latex.LexTokens
+		}
+		lexed, err := tokenize(s, expression)
+		if err != nil {
+			return err
+		}
+		parsed, err := parser.Parse(s, lexed)
+		if err != nil {
+			return err
+		}
+		unwound, err := simplifier.Simplify(s, parsed, simplifier.UNWIND)
+		if err != nil {
+			return err
+		}
+		rewound, err := simplifier.Simplify(s, unwound, simplifier.REWIND)
+		if err != nil {
+			return err
+		}
+		res = shared.PrintATree(s.Inline(rewound))
+		return nil
+	})
+	return res, err
+}
+
+// Solves an equation, or a system separated by semicolons, by its variables. Positions of errors
+// are given in the equation.
+// Solve("2x + 3 = 7", "x") -> x = 2
+func (c *Context) Solve(equation string, variables string) (string, error) {
+	return c.SolveContext(context.Background(), equation, variables)
+}
+
+// Solves an equation like Solve, it stops with an error once ctx is done.
+func (c *Context) SolveContext(ctx context.Context, equation string, variables string) (string, error) {
+	res, err := c.EvaluateContext(ctx, "solve "+equation+" for "+variables)
+	return res.Output, shared.Shift(err, -len("solve "))
+}
+
+// Renders an expression or equation as LaTeX. x^2/2 -> \frac{x^{2}}{2}
+func (c *Context) Latex(expression string) (string, error) {
+	return c.LatexContext(context.Background(), expression)
+}
+
+// Renders an expression like Latex, it stops with an error once ctx is done.
+func (c *Context) LatexContext(ctx context.Context, expression string) (string, error) {
+	res, err := c.EvaluateContext(ctx, "latex "+expression)
+	return res.Output, shared.Shift(err, -len("latex "))
+}
+
+// Returns a copy of the config of the context.
+func (c *Context) Config() shared.Config {
+	defer c.lock()()
+	return c.state.Conf.Clone()
+}
+
+// Turns an option of the context on or off. SetOption("exact", true)
+func (c *Context) SetOption(name string, value bool) {
+	defer c.lock()()
+	c.state.Conf.Options[name] = value
+}
@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"context"
+	"lambdacalc/shared"
+	"testing"
+	"time"
+)
+
+// Results with more digits than a float64 holds keep all of them with a higher precision.
+func TestPrecisionKeepsDigits(t *testing.T) {
+	conf := shared.GetDefualtConfig()
+	conf.Settings["precision"] = 50
+	ctx := New(conf)
+
+	tests := map[string]string{
+		"10^30+1":                      "1000000000000000000000000000001",
+		"123456789012345678901 + 1":    "123456789012345678902",
+		"(10^30+1) - 10^30":            "1",
+		"1000000000000000000000000001": "1000000000000000000000000001",
+	}
+	for input, want := range tests {
+		got, err := ctx.Eval(input)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", input, err)
+		} else if got != want {
+			t.Errorf("%s = %s, want %s", input, got, want)
+		}
+	}
+}
+
+// Definitions and options of a context are not visible in another.
+func TestContextsAreSeparate(t *testing.T) {
+	a, b := New(shared.GetDefualtConfig()), New(shared.GetDefualtConfig())
+	if _, err := a.Define("x", "3"); err != nil {
+		t.Fatalf("define: unexpected error %v", err)
+	}
+	a.SetOption("exact", true)
+
+	if got, err := a.Eval("x/2"); err != nil || got != "3/2 ≈ 1.5" {
+		t.Errorf("x/2 in a: got %q %v, want 3/2 ≈ 1.5", got, err)
+	}
+	if _, err := b.Eval("x/2"); shared.ErrorCode(err) != "undefined variable" {
+		t.Errorf("x/2 in b: got %v, want undefined variable", err)
+	}
+	if b.Config().Options["exact"] {
+		t.Errorf("exact in b: got true, want false")
+	}
+}
+
+// Errors of a definition are marked in the expression, errors in front of it in the declaration.
+func TestDefineContextSpans(t *testing.T) {
+	tests := []struct {
+		declaration string
+		expression  string
+		code        string
+		span        shared.Span
+	}{
+		{"y", "1; drop y", "unrecognized character", shared.Span{Start: 1, End: 2}},
+		{"y", "2 + * 3", "unexpected token", shared.Span{Start: 4, End: 5}},
+		{"y$", "2", "unrecognized character", shared.Span{Start: 1, End: 2}},
+		{"f(x", "2", "missing closing parenthesis", shared.Span{Start: 4, End: 5}},
+	}
+	for _, test := range tests {
+		_, err := New(shared.GetDefualtConfig()).Define(test.declaration, test.expression)
+		e, ok := err.(*shared.Error)
+		if !ok || e.Code != test.code || e.Span == nil {
+			t.Errorf("%s = %s: got %v, want %s", test.declaration, test.expression, err, test.code)
+			continue
+		}
+		if *e.Span != test.span {
+			t.Errorf("%s = %s: got span %v, want %v", test.declaration, test.expression, *e.Span, test.span)
+		}
+	}
+}
+
+// A long statement of one context does not hold up the statements of another one and stops,
+// once its context.Context is canceled.
+func TestContextsRunAtTheSameTime(t *testing.T) {
+	slow, other := New(shared.GetDefualtConfig()), New(shared.GetDefualtConfig())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := slow.EvaluateContext(ctx, "expand (x+y+z+1)^40")
+		done <- err
+	}()
+
+	// The expansion takes far longer than the timeout, if the contexts took turns.
+	short, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	if got, err := other.EvaluateContext(short, "1 + 1"); err != nil || got.Output != "2" {
+		t.Errorf("1 + 1 while expanding: got %q %v, want 2", got.Output, err)
+	}
+	other.Config()
+
+	cancel()
+	if err := <-done; shared.ErrorCode(err) != "canceled" {
+		t.Errorf("expand: got %v, want canceled", err)
+	}
+}
@@ -0,0 +1,1229 @@
+package engine
+
+import (
+	"lambdacalc/shared"
+
+	"lambdacalc/calculus"
+	"lambdacalc/interpreter"
+	"lambdacalc/latex"
+	"lambdacalc/lexer"
+	"lambdacalc/numeric"
+	"lambdacalc/parser"
+	"lambdacalc/polynomial"
+	"lambdacalc/simplifier"
+	"lambdacalc/solver"
+
+	"math"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+)
+
+// Splits the input into tokens, either the lexer or the LaTeX front-end.
+type tokenizer func(s *shared.State, input string, names ...string) ([]shared.Token, error)
+
+// Statements working with symbols, letters after a number are variables and not units in them.
+// factor 4 m^2 - 1, expand 2 s (s + 1)
+var symbolic = []string{
+	"solve", "diff", "integrate", "limit", "series", "polydiv", "polygcd", "together", "apart",
+	"cancel", "expand", "factor", "nintegrate", "nsolve", "latex",
+}
+
+// Reads a statement and runs it on the state. Returns the output and the trees of the
+// calculation, which are only set if the statement was calculated.
+func read(s *shared.State, cmd string) (string, *Calculation, error) {
+	tokenize := tokenizer(lexer.LexTokens)
+	if command, _, _ := strings.Cut(cmd, " "); slices.Contains(symbolic, command) {
+		tokenize = lexer.LexSymbols
+	}
+	declare := lexer.LexDeclaration
+	statement := cmd
+
+	// latex: \frac{1}{2} reads the rest of the line as LaTeX.
+	rest, found := strings.CutPrefix(cmd, "latex:")
+	if found || s.Conf.Options["latex_input"] {
+		cmd = strings.TrimSpace(rest)
+		tokenize = latex.LexTokens
+		declare = func(s *shared.State, input string) ([]shared.Token, error) {
+			return latex.LexTokens(s, input)
+		}
+	}
+
+	// Positions of errors are given in the whole statement, not in the part that was read.
+	tokenize = locate(statement, tokenize)
+	lexDeclaration := declare
+	declare = func(s *shared.State, input string) ([]shared.Token, error) {
+		return locate(statement, func(s *shared.State, input string, names ...string) ([]shared.Token, error) {
+			return lexDeclaration(s, input)
+		})(s, input)
+	}
+
+	i := 0
+	str := ""
+
+	// VariableOccurrence := []string{}
+
+	for i < len(cmd) && unicode.IsLetter(rune(cmd[i])) {
+		str += string(cmd[i])
+		i += 1
+	}
+	switch str {
+	case "define":
+		if i >= len(cmd)-1 {
+			return "", nil, shared.NewError("incomplete define statement", "Unable to define variable, incomplete define statement.")
+		}
+
+		// The declared names are read as whole identifiers, so they can be longer than one letter.
+		declaration, equation, found := strings.Cut(cmd[i:], s.Conf.Symbols["equal"])
+		if !found {
+			return "", nil, shared.NewError("incomplete define statement", "Unable to define variable, incomplete define statement.")
+		}
+
+		lexed, err := declare(s, declaration)
+		if err != nil {
+			return "", nil, err
+		}
+
+		// The declared name and parameters are known names in the equation.
+		names := []string{}
+		for _, val := range lexed {
+			if val.TokenType == shared.VARIABLE {
+				names = append(names, val.Variable)
+			}
+		}
+
+		lexedEquation, err := tokenize(s, equation, names...)
+		if err != nil {
+			return "", nil, err
+		}
+
+		// The equal sign was cut from the statement, its token points to where it was.
+		lexed = append(lexed, shared.Token{
+			TokenType: shared.EQUAL,
+			Value:     0.0,
+			Variable:  "",
+			Offset:    max(strings.Index(statement, cmd[i:]), 0) + len(declaration),
+			Rational:  nil,
+			Text:      s.Conf.Symbols["equal"],
+		})
+		lexed = append(lexed, lexedEquation...)
+
+		if len(lexed) <= 2 {
+			return "", nil, shared.NewError("incomplete define statement", "Unable to define variable, incomplete define statement.")
+		}
+
+		parsed, err := parser.SearchParse(s, lexed, parser.ASSERTION)
+		if err != nil {
+			return "", nil, err
+		}
+
+		// Check if all parameters are variables.
+		if parsed.OperationType == shared.EQUAL && parsed.LNode.OperationType == shared.FUNCTION {
+			if _, ok := shared.Builtins[parsed.LNode.Variable]; ok {
+				return "", nil, shared.NewError("redefined built-in function", "Unable to define function, '%s' is a built-in function.", parsed.LNode.Variable)
+			}
+			for _, val := range parsed.LNode.Associative {
+				if val.OperationType != shared.VARIABLE {
+					return "", nil, shared.NewError("unallowed operation in function declaration", "Unable to define function, functions can only be declared with variables as parameters.")
+				}
+			}
+		}
+		// atr := treerebuilder.AssociativeTreeRebuild(&parsed)
+		// Matrices and units are stored as they are, the simplifier would reorder their products.
+		simplified := parsed
+		if !s.ContainsMatrix(parsed.RNode) && !s.ContainsUnit(parsed.RNode) {
+			simplified, err = simplifier.Simplify(s, parsed, simplifier.UNWIND)
+			if err != nil {
+				return "", nil, err
+			}
+		}
+
+		if parsed.OperationType == shared.EQUAL && parsed.LNode.OperationType == shared.VARIABLE {
+			if parsed.LNode.Variable == s.Conf.ImaginaryUnit() {
+				return "", nil, shared.NewError("redefined imaginary unit", "Unable to define variable, '%s' is the imaginary unit.", parsed.LNode.Variable)
+			}
+			previous, defined := s.Variables[parsed.LNode.Variable]
+			s.Variables[parsed.LNode.Variable] = *simplified.RNode
+			if name := s.SelfDependentVariable(); name != "" {
+				if defined {
+					s.Variables[parsed.LNode.Variable] = previous
+				} else {
+					delete(s.Variables, parsed.LNode.Variable)
+				}
+				return "", nil, shared.NewError("recursive definition", "Unable to define variable, '%s' would depend on itself.", name)
+			}
+			return "Variable defined.", nil, nil
+		} else if parsed.OperationType == shared.EQUAL && parsed.LNode.OperationType == shared.FUNCTION {
+			previous, defined := s.Functions[parsed.LNode.Variable]
+			s.Functions[parsed.LNode.Variable] = shared.Function{
+				Parameters: simplified.LNode.Associative,
+				Equation:   simplified.RNode,
+			}
+			// A function may call itself, but no variable may depend on itself through it.
+			if name := s.SelfDependentVariable(); name != "" {
+				if defined {
+					s.Functions[parsed.LNode.Variable] = previous
+				} else {
+					delete(s.Functions, parsed.LNode.Variable)
+				}
+				return "", nil, shared.NewError("recursive definition", "Unable to define function, '%s' would depend on itself.", name)
+			}
+			return "Function defined.", nil, nil
+		} else {
+			return "", nil, shared.NewError("incorrect assertion", "Unable to define variable or function, incorrect assertion statement.")
+		}
+	case "drop":
+		if i >= len(cmd)-1 {
+			return "", nil, shared.NewError("incomplete drop statement", "Unable to drop variable, incomplete drop statement.")
+		}
+
+		lexed, err := tokenize(s, cmd[i:])
+		if err != nil {
+			return "", nil, err
+		}
+		if len(lexed) == 0 {
+			return "", nil, shared.NewError("incomplete drop statement", "Unable to drop variable, incomplete drop statement.")
+		}
+
+		// Only a name can be dropped, a function may be written with its parameters. i.e.: f(x)
+		parsed, err := parser.Parse(s, lexed)
+		if err != nil {
+			return "", nil, err
+		}
+		if parsed.OperationType != shared.VARIABLE && parsed.OperationType != shared.FUNCTION {
+			return "", nil, shared.NewError("not a name", "Unable to drop variable, expecting the name of a variable or function.")
+		}
+
+		if _, ok := s.Variables[parsed.Variable]; ok {
+			delete(s.Variables, parsed.Variable)
+			return "Variable deleted.", nil, nil
+		} else if _, ok := s.Functions[parsed.Variable]; ok {
+			delete(s.Functions, parsed.Variable)
+			return "Variable deleted.", nil, nil
+		} else {
+			return "", nil, shared.NewError("no variable to drop", "Unable to drop variable, the variable you are trying to drop does not exist in the current context.")
+		}
+	case "solve":
+		if i >= len(cmd)-1 {
+			return "", nil, shared.NewError("incomplete solve statement", "Unable to solve equation, incomplete solve statement.")
+		}
+
+		// solve 2x + 3 = 7 for x
+		equation, variable := cutVariable(cmd[i:], "for")
+
+		// solve 2x + y = 5; x - y = 1 for x, y
+		if strings.Contains(equation, ";") || strings.Contains(variable, ",") {
+			res, err := solveSystem(s, equation, variable, tokenize, declare)
+			return res, nil, err
+		}
+
+		// The variable we solve for is a known name in the equation.
+		names := []string{}
+		if variable != "" {
+			names = append(names, variable)
+		}
+
+		lexed, err := tokenize(s, equation, names...)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if len(lexed) <= 2 {
+			return "", nil, shared.NewError("incomplete solve statement", "Unable to solve equation, incomplete solve statement.")
+		}
+
+		parsed, err := parser.SearchParse(s, lexed, parser.ASSERTION)
+		if err != nil {
+			return "", nil, err
+		}
+
+		variable, err = findVariable(s, parsed, variable, declare, "solve equation", "for")
+		if err != nil {
+			return "", nil, err
+		}
+
+		solution, err := solver.Solve(s, parsed, variable)
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch solution.State {
+		case solver.NONE:
+			return "No solution.", nil, nil
+		case solver.NO_REAL:
+			return "No real solution.", nil, nil
+		case solver.INFINITE:
+			return "Infinitely many solutions.", nil, nil
+		default:
+			lines := []string{}
+			for _, val := range solution.Values {
+				lines = append(lines, solution.Variable+" = "+formatValue(s, val))
+			}
+
+			// Show the solutions as LaTeX below the result.
+			if s.Conf.Options["show_latex"] {
+				for _, val := range solution.Values {
+					lines = append(lines, latex.Render(shared.VariableNode(solution.Variable))+" = "+latex.Render(val))
+				}
+			}
+			return strings.Join(lines, "\n"), nil, nil
+		}
+	case "diff":
+		if i >= len(cmd)-1 {
+			return "", nil, shared.NewError("incomplete diff statement", "Unable to differentiate, incomplete diff statement.")
+		}
+
+		// diff x^2 + sin(x) by x
+		expression, variable := cutVariable(cmd[i:], "by")
+		if err := checkExpression(expression, "differentiate"); err != nil {
+			return "", nil, err
+		}
+
+		names := []string{}
+		if variable != "" {
+			names = append(names, variable)
+		}
+
+		lexed, err := tokenize(s, expression, names...)
+		if err != nil {
+			return "", nil, err
+		}
+
+		parsed, err := parser.Parse(s, lexed)
+		if err != nil {
+			return "", nil, err
+		}
+
+		variable, err = findVariable(s, parsed, variable, declare, "differentiate", "by")
+		if err != nil {
+			return "", nil, err
+		}
+
+		derivative, err := calculus.Differentiate(s, parsed, variable)
+		if err != nil {
+			return "", nil, err
+		}
+
+		res := shared.PrintATree(derivative)
+		// Show the derivative as LaTeX below the result.
+		if s.Conf.Options["show_latex"] {
+			res += "\n\\frac{d}{d" + variable + "} " + latex.Render(parsed) + " = " + latex.Render(derivative)
+		}
+		return res, nil, nil
+	case "integrate":
+		if i >= len(cmd)-1 {
+			return "", nil, shared.NewError("incomplete integrate statement", "Unable to integrate, incomplete integrate statement.")
+		}
+
+		// integrate x^2 by x from 0 to 3
+		parsed, variable, bounds, err := parseIntegral(s, cmd[i:], tokenize, declare, "integrate")
+		if err != nil {
+			return "", nil, err
+		}
+
+		if bounds == nil {
+			integral, err := calculus.Integrate(s, parsed, variable)
+			if err != nil {
+				return "", nil, err
+			}
+
+			res := shared.PrintATree(integral) + " + C"
+			// Show the integral as LaTeX below the result.
+			if s.Conf.Options["show_latex"] {
+				res += "\n\\int " + latex.Render(parsed) + " \\, d" + variable + " = " + latex.Render(integral) + " + C"
+			}
+			return res, nil, nil
+		}
+
+		res, err := calculus.IntegrateDefinite(s, parsed, variable, bounds[0], bounds[1])
+		if err != nil {
+			return "", nil, err
+		}
+		return strconv.FormatFloat(res, 'f', -1, 64), nil, nil
+	case "limit":
+		if i >= len(cmd)-1 {
+			return "", nil, shared.NewError("incomplete limit statement", "Unable to find limit, incomplete limit statement.")
+		}
+
+		// limit sin(x)/x as x -> 0
+		expression, target := cutVariable(cmd[i:], "as")
+		if err := checkExpression(expression, "find limit"); err != nil {
+			return "", nil, err
+		}
+		variable, point, found := strings.Cut(target, "->")
+		if !found {
+			return "", nil, shared.NewError("incomplete limit statement", "Unable to find limit, expected 'as x -> a'.")
+		}
+		variable = strings.TrimSpace(variable)
+
+		names := []string{}
+		if variable != "" {
+			names = append(names, variable)
+		}
+
+		parsed, err := parseExpression(s, expression, tokenize, names...)
+		if err != nil {
+			return "", nil, err
+		}
+
+		variable, err = findVariable(s, parsed, variable, declare, "find limit", "as")
+		if err != nil {
+			return "", nil, err
+		}
+
+		value, direction, err := parsePoint(s, point, tokenize)
+		if err != nil {
+			return "", nil, err
+		}
+
+		res, err := calculus.Limit(s, parsed, variable, value, direction)
+		if err != nil {
+			return "", nil, err
+		}
+		return calculus.FormatLimit(res), nil, nil
+	case "series":
+		if i >= len(cmd)-1 {
+			return "", nil, shared.NewError("incomplete series statement", "Unable to expand series, incomplete series statement.")
+		}
+
+		// series sin(x) around x = 0 to order 5
+		statement, order := cutVariable(cmd[i:], "to order")
+		expression, around := cutVariable(statement, "around")
+		if err := checkExpression(expression, "expand series"); err != nil {
+			return "", nil, err
+		}
+		variable, point, _ := strings.Cut(around, s.Conf.Symbols["equal"])
+		variable = strings.TrimSpace(variable)
+
+		// The default order is 5.
+		n := 5
+		if order != "" {
+			val, err := strconv.Atoi(order)
+			if err != nil {
+				return "", nil, shared.NewError("invalid order", "Unable to expand series, '%s' is not an order.", order)
+			}
+			n = val
+		}
+
+		names := []string{}
+		if variable != "" {
+			names = append(names, variable)
+		}
+
+		parsed, err := parseExpression(s, expression, tokenize, names...)
+		if err != nil {
+			return "", nil, err
+		}
+
+		variable, err = findVariable(s, parsed, variable, declare, "expand series", "around")
+		if err != nil {
+			return "", nil, err
+		}
+
+		// Without a point the series is expanded around 0.
+		center := shared.NumberNode(0.0)
+		if strings.TrimSpace(point) != "" {
+			center, err = parseExpression(s, point, tokenize)
+			if err != nil {
+				return "", nil, err
+			}
+		}
+
+		series, err := calculus.Series(s, parsed, variable, center, n)
+		if err != nil {
+			return "", nil, err
+		}
+
+		res := shared.PrintATree(series)
+		// Show the series as LaTeX below the result.
+		if s.Conf.Options["show_latex"] {
+			res += "\n" + latex.Render(series)
+		}
+		return res, nil, nil
+	case "polydiv":
+		if i >= len(cmd)-1 {
+			return "", nil, shared.NewError("incomplete polydiv statement", "Unable to divide polynomials, incomplete polydiv statement.")
+		}
+
+		// polydiv x^3 - 1 by x - 1
+		dividend, divisor := cutVariable(cmd[i:], "by")
+		if divisor == "" {
+			return "", nil, shared.NewError("missing divisor", "Unable to divide polynomials, missing divisor after 'by'.")
+		}
+
+		p, err := parsePolynomials(s, []string{dividend, divisor}, "divide polynomials", tokenize)
+		if err != nil {
+			return "", nil, err
+		}
+
+		quotient, remainder, ok := p[0].DivMod(p[1])
+		if !ok {
+			return "", nil, shared.DivisionByZero()
+		}
+
+		res := "q = " + shared.PrintATree(quotient.ToNode()) + "\nr = " + shared.PrintATree(remainder.ToNode())
+		// Show the division as LaTeX below the result.
+		if s.Conf.Options["show_latex"] {
+			res += "\n" + latex.Render(p[0].ToNode()) + " = \\left(" + latex.Render(p[1].ToNode()) + "\\right) \\cdot \\left(" + latex.Render(quotient.ToNode()) + "\\right) + " + latex.Render(remainder.ToNode())
+		}
+		return res, nil, nil
+	case "polygcd":
+		if i >= len(cmd)-1 {
+			return "", nil, shared.NewError("incomplete polygcd statement", "Unable to find gcd, incomplete polygcd statement.")
+		}
+
+		// polygcd x^2 - 1, x^2 + 2x + 1
+		p, err := parsePolynomials(s, splitArguments(cmd[i:]), "find gcd", tokenize)
+		if err != nil {
+			return "", nil, err
+		}
+
+		gcd := p[0]
+		for _, val := range p[1:] {
+			gcd = polynomial.GCD(gcd, val)
+		}
+
+		res := shared.PrintATree(gcd.ToNode())
+		// Show the gcd as LaTeX below the result.
+		if s.Conf.Options["show_latex"] {
+			res += "\n" + latex.Render(gcd.ToNode())
+		}
+		return res, nil, nil
+	case "together", "apart", "cancel":
+		if i >= len(cmd)-1 {
+			return "", nil, shared.NewError("incomplete "+str+" statement", "Unable to transform fraction, incomplete %s statement.", str)
+		}
+
+		// together 1/x + 1/(x + 1)
+		parsed, err := parseExpression(s, cmd[i:], tokenize)
+		if err != nil {
+			return "", nil, err
+		}
+
+		mode := map[string]int{"together": simplifier.TOGETHER, "apart": simplifier.APART, "cancel": simplifier.CANCEL}[str]
+		simplified, err := simplifier.SimplifyFractions(s, shared.Clone(parsed), mode)
+		if err != nil {
+			return "", nil, err
+		}
+
+		// Defined variables are kept by the simplifier, they are written with their value.
+		res := shared.PrintATree(s.Inline(simplified))
+		// Show the result as LaTeX below the result.
+		if s.Conf.Options["show_latex"] {
+			res += "\n" + latex.Render(simplified)
+		}
+		return res, nil, nil
+	case "expand":
+		if i >= len(cmd)-1 {
+			return "", nil, shared.NewError("incomplete expand statement", "Unable to expand, incomplete expand statement.")
+		}
+
+		// expand (x + 1)^2
+		parsed, err := parseExpression(s, cmd[i:], tokenize)
+		if err != nil {
+			return "", nil, err
+		}
+
+		expanded, err := polynomial.Expand(s, parsed)
+		if err != nil {
+			return "", nil, err
+		}
+
+		res := shared.PrintATree(expanded)
+		// Show the polynomial as LaTeX below the result.
+		if s.Conf.Options["show_latex"] {
+			res += "\n" + latex.Render(expanded)
+		}
+		return res, nil, nil
+	case "factor":
+		if i >= len(cmd)-1 {
+			return "", nil, shared.NewError("incomplete factor statement", "Unable to factor, incomplete factor statement.")
+		}
+
+		// factor x^2 - 4
+		parsed, err := parseExpression(s, cmd[i:], tokenize)
+		if err != nil {
+			return "", nil, err
+		}
+
+		factored, err := polynomial.Factor(s, parsed)
+		if err != nil {
+			return "", nil, err
+		}
+
+		res := shared.PrintATree(factored)
+		// Show the factors as LaTeX below the result.
+		if s.Conf.Options["show_latex"] {
+			res += "\n" + latex.Render(factored)
+		}
+		return res, nil, nil
+	case "nintegrate":
+		if i >= len(cmd)-1 {
+			return "", nil, shared.NewError("incomplete nintegrate statement", "Unable to integrate numerically, incomplete nintegrate statement.")
+		}
+
+		// nintegrate sin(x)^2 by x from 0 to pi
+		parsed, variable, bounds, err := parseIntegral(s, cmd[i:], tokenize, declare, "integrate numerically")
+		if err != nil {
+			return "", nil, err
+		}
+		if bounds == nil {
+			return "", nil, shared.NewError("missing bounds", "Unable to integrate numerically, expected bounds with 'from' and 'to'.")
+		}
+
+		values := []float64{}
+		for _, bound := range bounds {
+			val, err := interpreter.Evaluate(s, bound)
+			if err != nil {
+				return "", nil, err
+			}
+			values = append(values, val)
+		}
+
+		res, err := numeric.Integrate(s, parsed, variable, values[0], values[1])
+		if err != nil {
+			return "", nil, err
+		}
+		return strconv.FormatFloat(res, 'f', -1, 64), nil, nil
+	case "nsolve":
+		if i >= len(cmd)-1 {
+			return "", nil, shared.NewError("incomplete nsolve statement", "Unable to solve numerically, incomplete nsolve statement.")
+		}
+
+		// nsolve cos(x) = x for x near 1
+		statement, near := cutVariable(cmd[i:], "near")
+		equation, variable := cutVariable(statement, "for")
+		if err := checkExpression(equation, "solve numerically"); err != nil {
+			return "", nil, err
+		}
+
+		names := []string{}
+		if variable != "" {
+			names = append(names, variable)
+		}
+
+		lexed, err := tokenize(s, equation, names...)
+		if err != nil {
+			return "", nil, err
+		}
+
+		// Without an equal sign the expression is solved for zero.
+		var parsed *shared.Node
+		if slices.ContainsFunc(lexed, func(t shared.Token) bool { return t.TokenType == shared.EQUAL }) {
+			parsed, err = parser.SearchParse(s, lexed, parser.ASSERTION)
+		} else {
+			parsed, err = parser.Parse(s, lexed)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		variable, err = findVariable(s, parsed, variable, declare, "solve numerically", "for")
+		if err != nil {
+			return "", nil, err
+		}
+
+		// The search starts at 0 without a value.
+		start := 0.0
+		if near != "" {
+			node, err := parseExpression(s, near, tokenize)
+			if err != nil {
+				return "", nil, err
+			}
+			start, err = interpreter.Evaluate(s, node)
+			if err != nil {
+				return "", nil, err
+			}
+		}
+
+		res, err := numeric.Solve(s, parsed, variable, start)
+		if err != nil {
+			return "", nil, err
+		}
+		return variable + " = " + strconv.FormatFloat(res, 'f', -1, 64), nil, nil
+	case "list":
+		if len(s.Variables) <= 0 {
+			return "No variables defined.", nil, nil
+		}
+		// Lists all currently stored variables, sorted by name.
+		names := []string{}
+		for key := range s.Variables {
+			names = append(names, key)
+		}
+		slices.Sort(names)
+		lines := []string{}
+		for _, key := range names {
+			val := s.Variables[key]
+			lines = append(lines, "'"+key+"' : "+shared.PrintATree(s.Inline(&val)))
+		}
+		return strings.Join(lines, "\n"), nil, nil
+	case "latex":
+		if i >= len(cmd)-1 {
+			return "", nil, shared.NewError("incomplete latex statement", "Unable to render expression, incomplete latex statement.")
+		}
+
+		lexed, err := tokenize(s, cmd[i:])
+		if err != nil {
+			return "", nil, err
+		}
+		keepConstants(s, lexed)
+
+		// Equations are rendered with both sides.
+		mode := parser.EUQALITY
+		if slices.ContainsFunc(lexed, func(t shared.Token) bool { return t.TokenType == shared.EQUAL }) {
+			mode = parser.ASSERTION
+		}
+
+		parsed, err := parser.SearchParse(s, lexed, mode)
+		if err != nil {
+			return "", nil, err
+		}
+		return latex.Render(parsed), nil, nil
+	default:
+		// 5 km to mi
+		if expression, target := cutVariable(cmd, "to"); target != "" {
+			res, err := convert(s, expression, target, tokenize)
+			return res, nil, err
+		}
+
+		numStr, calculation, err := calc(s, cmd, tokenize)
+		if err != nil {
+			return "", nil, err
+		}
+
+		// Show the calculation as LaTeX below the result.
+		if s.Conf.Options["show_latex"] {
+			numStr += "\n" + latex.Render(calculation.Parsed) + " = " + latex.Render(calculation.Result)
+		}
+		return numStr, calculation, nil
+	}
+}
+
+// Turns constants, that the lexer replaced by their value, back into names. pi -> \pi
+func keepConstants(s *shared.State, tokens []shared.Token) {
+	for i, val := range tokens {
+		if _, ok := s.Conf.Constants[val.Text]; ok && val.TokenType == shared.NUMBER {
+			tokens[i].TokenType = shared.VARIABLE
+			tokens[i].Value = 0.0
+			tokens[i].Variable = val.Text
+		}
+	}
+}
+
+// Converts the value of an expression with units into the target unit.
+// 5 km to mi -> 3.106855961186669 mi
+func convert(s *shared.State, expression string, target string, tokenize tokenizer) (string, error) {
+	unit, err := s.Conf.ParseUnit(target)
+	if err != nil {
+		return "", shared.NewError(shared.ErrorCode(err), "Unable to convert units, '%s' is not a unit.", target)
+	}
+
+	if err := checkExact(s, "units"); err != nil {
+		return "", err
+	}
+
+	parsed, err := parseExpression(s, expression, tokenize)
+	if err != nil {
+		return "", err
+	}
+	result, err := interpreter.EvaluateQuantity(s, parsed)
+	if err != nil {
+		return "", err
+	}
+	if result.Dimension != unit.Dimension {
+		return "", shared.NewError("incompatible units", "Unable to convert units, '%s' can not be converted to '%s'.", result.Dimension, target)
+	}
+
+	value := result.Value / unit.Factor
+	str := formatQuantity(value, target, false)
+	if s.Conf.Options["show_latex"] {
+		str += "\n" + latex.Render(parsed) + " = " + latex.Render(quantityNode(value, target, false))
+	}
+	return str, nil
+}
+
+// Writes a value with its unit, values without unit are plain numbers. 2160 m
+func formatQuantity(value float64, unit string, dimensionless bool) string {
+	str := strconv.FormatFloat(value, 'f', -1, 64)
+	if dimensionless {
+		return str
+	}
+	return str + " " + unit
+}
+
+// Returns the tree of a value with its unit. 2160 m -> 2160 * m
+func quantityNode(value float64, unit string, dimensionless bool) *shared.Node {
+	if dimensionless {
+		return shared.NumberNode(value)
+	}
+	return &shared.Node{
+		OperationType: shared.MULTIPLY,
+		Value:         0.0,
+		Variable:      "",
+		LNode:         nil,
+		RNode:         nil,
+		Associative: []*shared.Node{shared.NumberNode(value), {
+			OperationType: shared.UNIT,
+			Value:         0.0,
+			Variable:      unit,
+			LNode:         nil,
+			RNode:         nil,
+			Associative:   nil,
+			Rational:      nil,
+		}},
+		Rational: nil,
+	}
+}
+
+// Moves the positions of tokens and errors from a part of the statement to the whole statement.
+// solve x + * 3 = 0 for x, reading x + * 3 = 0 -> the '*' is at 10 instead of 4
+func locate(statement string, tokenize tokenizer) tokenizer {
+	return func(s *shared.State, input string, names ...string) ([]shared.Token, error) {
+		offset := max(strings.Index(statement, input), 0)
+		tokens, err := tokenize(s, input, names...)
+		if err != nil {
+			return nil, shared.Shift(err, offset)
+		}
+		for i := range tokens {
+			tokens[i].Offset += offset
+		}
+		return tokens, nil
+	}
+}
+
+// Splits a statement at the last keyword into the expression and the variable after it.
+// 2x + 3 = 7 for x -> 2x + 3 = 7, x
+func cutVariable(statement string, keyword string) (string, string) {
+	if j := strings.LastIndex(statement, " "+keyword+" "); j != -1 {
+		return statement[:j], strings.TrimSpace(statement[j+len(keyword)+2:])
+	}
+	return statement, ""
+}
+
+// Solves a system of linear equations separated by semicolons. Without variables after 'for'
+// the system is solved by all undefined variables in the order they appear.
+func solveSystem(s *shared.State, equations string, variables string, tokenize tokenizer, declare func(*shared.State, string) ([]shared.Token, error)) (string, error) {
+	names := []string{}
+	if variables != "" {
+		for _, val := range splitArguments(variables) {
+			names = append(names, strings.TrimSpace(val))
+		}
+	}
+
+	parsed := []*shared.Node{}
+	for _, equation := range strings.Split(equations, ";") {
+		if strings.TrimSpace(equation) == "" {
+			continue
+		}
+		lexed, err := tokenize(s, equation, names...)
+		if err != nil {
+			return "", err
+		}
+		if len(lexed) <= 2 {
+			return "", shared.NewError("incomplete solve statement", "Unable to solve system, incomplete equation '%s'.", strings.TrimSpace(equation))
+		}
+		node, err := parser.SearchParse(s, lexed, parser.ASSERTION)
+		if err != nil {
+			return "", err
+		}
+		parsed = append(parsed, node)
+	}
+	if len(parsed) == 0 {
+		return "", shared.NewError("incomplete solve statement", "Unable to solve system, missing equation.")
+	}
+
+	if len(names) == 0 {
+		for _, node := range parsed {
+			for _, val := range s.FreeVariables(node) {
+				if !slices.Contains(names, val) {
+					names = append(names, val)
+				}
+			}
+		}
+		if len(names) == 0 {
+			return "", shared.NewError("missing variable", "Unable to solve system, specify the variables with 'for'.")
+		}
+	}
+	for i, val := range names {
+		lexed, err := declare(s, val)
+		if err != nil || len(lexed) != 1 || lexed[0].TokenType != shared.VARIABLE {
+			return "", shared.NewError("invalid variable", "Unable to solve system, '%s' is not a variable.", val)
+		}
+		names[i] = lexed[0].Variable
+	}
+
+	solution, err := solver.SolveSystem(s, parsed, names)
+	if err != nil {
+		return "", err
+	}
+	if solution.State == solver.NONE {
+		return "No solution.", nil
+	}
+
+	// Free variables can take any value, the others are given in terms of them.
+	lines := []string{}
+	if solution.State == solver.INFINITE {
+		lines = append(lines, "Infinitely many solutions, free: "+strings.Join(solution.Free, ", "))
+	}
+	for j, val := range solution.Values {
+		if !slices.Contains(solution.Free, solution.Variables[j]) {
+			lines = append(lines, solution.Variables[j]+" = "+formatValue(s, val))
+		}
+	}
+
+	// Show the solutions as LaTeX below the result.
+	if s.Conf.Options["show_latex"] {
+		for j, val := range solution.Values {
+			if !slices.Contains(solution.Free, solution.Variables[j]) {
+				lines = append(lines, latex.Render(shared.VariableNode(solution.Variables[j]))+" = "+latex.Render(val))
+			}
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Splits a list at the commas, that are not inside of parentheses.
+// x^2 - 1, max(x, 2) -> x^2 - 1, max(x, 2)
+func splitArguments(list string) []string {
+	res := []string{}
+	depth := 0
+	start := 0
+	for j, c := range list {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				res = append(res, list[start:j])
+				start = j + 1
+			}
+		}
+	}
+	return append(res, list[start:])
+}
+
+// Lexes, parses and converts expressions into polynomials, every one of them has to be given.
+// x, , x -> Unable to find gcd, missing expression.
+func parsePolynomials(s *shared.State, inputs []string, action string, tokenize tokenizer) ([]*polynomial.Polynomial, error) {
+	for _, val := range inputs {
+		if err := checkExpression(val, action); err != nil {
+			return nil, err
+		}
+	}
+
+	res := []*polynomial.Polynomial{}
+	for _, val := range inputs {
+		parsed, err := parseExpression(s, val, tokenize)
+		if err != nil {
+			return nil, err
+		}
+		p, err := polynomial.FromNode(s, parsed)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+// Checks that the part of a statement before its keyword is not empty. diff by x
+func checkExpression(expression string, action string) error {
+	if strings.TrimSpace(expression) == "" {
+		return shared.NewError("missing expression", "Unable to %s, missing expression.", action)
+	}
+	return nil
+}
+
+// Lexes and parses a single expression.
+func parseExpression(s *shared.State, input string, tokenize tokenizer, names ...string) (*shared.Node, error) {
+	lexed, err := tokenize(s, input, names...)
+	if err != nil {
+		return nil, err
+	}
+	return parser.Parse(s, lexed)
+}
+
+// Reads the point of a limit, infinity is written as inf or ∞. A trailing + or - approaches it from one side.
+// 0+ -> 0 from the right, -inf -> -∞
+func parsePoint(s *shared.State, point string, tokenize tokenizer) (float64, int, error) {
+	point = strings.TrimSpace(point)
+	direction := calculus.BOTH
+	if rest, found := strings.CutSuffix(point, "+"); found {
+		point, direction = strings.TrimSpace(rest), calculus.RIGHT
+	} else if rest, found := strings.CutSuffix(point, "-"); found {
+		point, direction = strings.TrimSpace(rest), calculus.LEFT
+	}
+
+	switch strings.TrimPrefix(point, "+") {
+	case "inf", "infinity", "∞":
+		return math.Inf(1), direction, nil
+	case "-inf", "-infinity", "-∞":
+		return math.Inf(-1), direction, nil
+	}
+
+	node, err := parseExpression(s, point, tokenize)
+	if err != nil {
+		return 0, 0, err
+	}
+	value, err := interpreter.Evaluate(s, node)
+	if err != nil {
+		return 0, 0, err
+	}
+	return value, direction, nil
+}
+
+// Reads the expression of an integral with its variable and the optional bounds after 'from' and 'to'.
+// x^2 by x from 0 to 3 -> x^2, x, [0, 3]
+func parseIntegral(s *shared.State, statement string, tokenize tokenizer, declare func(*shared.State, string) ([]shared.Token, error), action string) (*shared.Node, string, []*shared.Node, error) {
+	statement, upper := cutVariable(statement, "to")
+	statement, lower := cutVariable(statement, "from")
+	if (upper == "") != (lower == "") {
+		return nil, "", nil, shared.NewError("incomplete bounds", "Unable to %s, expected both 'from' and 'to'.", action)
+	}
+	expression, variable := cutVariable(statement, "by")
+	if err := checkExpression(expression, action); err != nil {
+		return nil, "", nil, err
+	}
+
+	names := []string{}
+	if variable != "" {
+		names = append(names, variable)
+	}
+
+	parsed, err := parseExpression(s, expression, tokenize, names...)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	variable, err = findVariable(s, parsed, variable, declare, action, "by")
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if upper == "" {
+		return parsed, variable, nil, nil
+	}
+
+	bounds := []*shared.Node{}
+	for _, bound := range []string{lower, upper} {
+		node, err := parseExpression(s, bound, tokenize)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		bounds = append(bounds, node)
+	}
+	return parsed, variable, bounds, nil
+}
+
+// Checks the variable given after a keyword. Without a given variable the only undefined variable is used.
+func findVariable(s *shared.State, parsed *shared.Node, variable string, declare func(*shared.State, string) ([]shared.Token, error), action string, keyword string) (string, error) {
+	if variable == "" {
+		free := s.FreeVariables(parsed)
+		if len(free) != 1 {
+			return "", shared.NewError("missing variable", "Unable to %s, specify the variable with '%s'.", action, keyword)
+		}
+		return free[0], nil
+	}
+
+	// The variable is read like the expression, so x_{2} in LaTeX is x_2.
+	lexed, err := declare(s, variable)
+	if err != nil || len(lexed) != 1 || lexed[0].TokenType != shared.VARIABLE {
+		return "", shared.NewError("invalid variable", "Unable to %s, '%s' is not a variable.", action, variable)
+	}
+	return lexed[0].Variable, nil
+}
+
+// Calculates the result of an expression. Returns the formatted result and the trees of the calculation.
+func calc(s *shared.State, cmd string, tokenize tokenizer) (string, *Calculation, error) {
+	lexed, err := tokenize(s, cmd)
+	if err != nil {
+		return "", nil, err
+	}
+	parsed, err := parser.Parse(s, lexed)
+	if err != nil {
+		return "", nil, err
+	}
+	// The simplifier changes the tree in place, keep the original.
+	original := shared.Clone(parsed)
+	calculation := &Calculation{Parsed: original, Simplified: nil, Result: nil, Value: nil}
+
+	// Matrices are calculated without simplifying, the rules assume commutative multiplication.
+	if s.ContainsMatrix(parsed) {
+		if err := checkExact(s, "matrices"); err != nil {
+			return "", nil, err
+		}
+		result, err := interpreter.EvaluateValue(s, parsed)
+		if err != nil {
+			return "", nil, err
+		}
+		if result.Matrix == nil {
+			calculation.setValue(complex(result.Number, 0))
+			calculation.Result = shared.NumberNode(result.Number)
+			return strconv.FormatFloat(result.Number, 'f', -1, 64), calculation, nil
+		}
+		node := shared.MatrixNode(result.Matrix)
+		calculation.Result = node
+		return shared.PrintATree(node), calculation, nil
+	}
+
+	// Units are calculated without simplifying as well, the result is written in SI units.
+	if s.ContainsUnit(parsed) {
+		if err := checkExact(s, "units"); err != nil {
+			return "", nil, err
+		}
+		result, err := interpreter.EvaluateQuantity(s, parsed)
+		if err != nil {
+			return "", nil, err
+		}
+		if result.Dimension.IsZero() {
+			calculation.setValue(complex(result.Value, 0))
+		}
+		calculation.Result = quantityNode(result.Value, result.Dimension.String(), result.Dimension.IsZero())
+		return formatQuantity(result.Value, result.Dimension.String(), result.Dimension.IsZero()), calculation, nil
+	}
+
+	// Debug
+	if s.Conf.Options["show_debug_process"] {
+		cfmt.Printf("{{Debug:}}::cyan|bold parse result: ")
+		cfmt.Printf(shared.PrintATree(parsed))
+		cfmt.Println("")
+	}
+
+	unwound, err := simplifier.Simplify(s, parsed, simplifier.UNWIND)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Debug
+	if s.Conf.Options["show_debug_process"] {
+		cfmt.Printf("{{Debug:}}::cyan|bold Unwound result: ")
+		cfmt.Printf("%s", shared.PrintATree(unwound))
+		cfmt.Println("")
+	}
+
+	rewound, err := simplifier.Simplify(s, unwound, simplifier.REWIND)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Debug
+	if s.Conf.Options["show_debug_process"] {
+		cfmt.Printf("{{Debug:}}::cyan|bold Rewound result: ")
+		cfmt.Printf("%s", shared.PrintATree(rewound))
+		cfmt.Println("")
+	}
+
+	calculation.Simplified = rewound
+
+	// Complex numbers take priority over exact and precise calculations.
+	if s.Conf.Options["complex"] {
+		result, err := interpreter.EvaluateComplex(s, rewound)
+		if err != nil {
+			return "", nil, err
+		}
+		calculation.setValue(result)
+		calculation.Result = s.Conf.ComplexNode(result)
+		return s.Conf.FormatComplex(result, s.Conf.Options["polar"]), calculation, nil
+	}
+
+	if s.Conf.Options["exact"] {
+		result, exact, err := interpreter.EvaluateExact(s, rewound)
+		if err != nil {
+			return "", nil, err
+		}
+		f, _ := result.Float64()
+		calculation.setValue(complex(f, 0))
+		if !exact {
+			calculation.Result = shared.NumberNode(f)
+			return strconv.FormatFloat(f, 'f', -1, 64), calculation, nil
+		}
+		calculation.Result = shared.RationalNode(result)
+		return formatRational(s, result), calculation, nil
+	}
+
+	if digits := s.Conf.Settings["precision"]; digits > 0 {
+		result, err := interpreter.EvaluatePrecise(s, rewound, digits)
+		if err != nil {
+			return "", nil, err
+		}
+		f, _ := result.Float64()
+		calculation.setValue(complex(f, 0))
+		calculation.Result = shared.NumberNode(f)
+		return result.Text('g', digits), calculation, nil
+	}
+
+	result, err := interpreter.Evaluate(s, rewound)
+	if err != nil {
+		return "", nil, err
+	}
+	calculation.setValue(complex(result, 0))
+	calculation.Result = shared.NumberNode(result)
+	return strconv.FormatFloat(result, 'f', -1, 64), calculation, nil
+}
+
+// Sets the value of the calculation, complex results keep their imaginary part.
+func (c *Calculation) setValue(value complex128) {
+	c.Value = &value
+}
+
+// Matrices and units are calculated with floating point numbers, in exact mode they are an error
+// instead of an approximated result. [1/3, 1/6] -> matrices are not supported in exact mode
+func checkExact(s *shared.State, kind string) error {
+	if s.Conf.Options["exact"] {
+		return shared.NewError("not supported in exact mode", "Unable to calculate output, %s are not supported in exact mode.", kind)
+	}
+	return nil
+}
+
+// Formats an exact result as a reduced fraction, with its decimal approximation if enabled.
+// 7/12 -> 7/12 ≈ 0.5833333333333334
+func formatRational(s *shared.State, r *big.Rat) string {
+	str := shared.FormatRational(r)
+	if !r.IsInt() && s.Conf.Options["show_decimal"] {
+		f, _ := r.Float64()
+		str += " ≈ " + strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return str
+}
+
+// Formats a solution, in exact mode rational solutions are written as fractions
+// and irrational ones are followed by their approximation.
+// With a higher precision numerical solutions are written with all significant digits.
+// In complex mode solutions are written as complex numbers.
+func formatValue(s *shared.State, node *shared.Node) string {
+	if s.Conf.Options["complex"] {
+		if val, err := interpreter.EvaluateComplex(s, node); err == nil {
+			return s.Conf.FormatComplex(val, s.Conf.Options["polar"])
+		}
+		return shared.PrintATree(node)
+	}
+	if digits := s.Conf.Settings["precision"]; digits > 0 && !s.Conf.Options["exact"] {
+		if val, err := interpreter.EvaluatePrecise(s, node, digits); err == nil {
+			return val.Text('g', digits)
+		}
+		return shared.PrintATree(node)
+	}
+	if !s.Conf.Options["exact"] {
+		return shared.PrintATree(node)
+	}
+
+	val, exact, err := interpreter.EvaluateExact(s, node)
+	if err != nil {
+		return shared.PrintATree(node)
+	} else if exact {
+		return formatRational(s, val)
+	}
+
+	str := shared.PrintATree(node)
+	if s.Conf.Options["show_decimal"] {
+		f, _ := val.Float64()
+		str += " ≈ " + strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return str
+}
@@ -0,0 +1,197 @@
+package engine
+
+import (
+	"lambdacalc/shared"
+	"testing"
+)
+
+// Runs statements in a new context, only the last one is checked.
+func run(t *testing.T, statements ...string) (string, error) {
+	t.Helper()
+	ctx := New(shared.GetDefualtConfig())
+	for _, val := range statements[:len(statements)-1] {
+		if _, err := ctx.Eval(val); err != nil {
+			t.Fatalf("%s: unexpected error %v", val, err)
+		}
+	}
+	return ctx.Eval(statements[len(statements)-1])
+}
+
+// Definitions that would make a variable depend on itself are rejected, the previous definition is kept.
+func TestRecursiveDefinitions(t *testing.T) {
+	tests := [][]string{
+		{"define x = x + 1"},
+		{"define x = 3", "define x = x + 1"},
+		{"define a = b", "define b = a"},
+		{"define y = g(1)", "define g(t) = y + t"},
+	}
+	for _, statements := range tests {
+		if _, err := run(t, statements...); shared.ErrorCode(err) != "recursive definition" {
+			t.Errorf("%v: got %v, want recursive definition", statements, err)
+		}
+	}
+
+	valid := map[string][]string{
+		"3":       {"define x = 3", "define x = x + 1", "x"},
+		"6":       {"define f(x) = x * 2", "define x = f(3)", "x"},
+		"'x' : 3": {"define x = 3", "define x = x + 1", "list"},
+	}
+	for want, statements := range valid {
+		ctx := New(shared.GetDefualtConfig())
+		got := ""
+		for _, val := range statements {
+			got, _ = ctx.Eval(val)
+		}
+		if got != want {
+			t.Errorf("%v: got %q, want %q", statements, got, want)
+		}
+	}
+}
+
+// Statements without an expression before their keyword are an error.
+func TestMissingExpression(t *testing.T) {
+	tests := []string{
+		"diff by x",
+		"diff  by x",
+		"integrate by x",
+		"integrate by x from 0 to 1",
+		"nintegrate by x from 0 to 1",
+		"nsolve for x near 1",
+		"nsolve for x",
+		"limit as x -> 0",
+		"series around x = 0",
+		"series around x = 0 to order 3",
+		"polydiv by x",
+		"polydiv  by x - 1",
+		"polygcd ,",
+		"polygcd x^2 - 1,",
+		"polygcd , x",
+		"polygcd x, , x",
+	}
+	for _, statement := range tests {
+		if _, err := run(t, statement); err == nil || shared.ErrorCode(err) != "missing expression" {
+			t.Errorf("%s: got %v, want missing expression", statement, err)
+		}
+	}
+}
+
+// Matrices and units are an error in exact mode, instead of being approximated with floats.
+func TestExactMatricesAndUnits(t *testing.T) {
+	tests := []string{
+		"[1/3, 1/6] + [1/6, 1/6]",
+		"det([[1, 2], [3, 4]])",
+		"1 m / 3",
+		"5 km to mi",
+	}
+	for _, statement := range tests {
+		ctx := New(shared.GetDefualtConfig())
+		if _, err := ctx.Eval(statement); err != nil {
+			t.Errorf("%s: unexpected error %v", statement, err)
+		}
+		ctx.SetOption("exact", true)
+		if _, err := ctx.Eval(statement); err == nil || shared.ErrorCode(err) != "not supported in exact mode" {
+			t.Errorf("%s: got %v, want not supported in exact mode", statement, err)
+		}
+	}
+}
+
+// Errors of calls mark the whole call and stay inside of the input, names that only start
+// like a built-in function are no call of it.
+func TestCallSpans(t *testing.T) {
+	tests := []struct {
+		statement string
+		code      string
+		span      *shared.Span
+	}{
+		{"sin 0", "missing parameters", &shared.Span{Start: 0, End: 3}},
+		{"inv 2", "missing parameters", &shared.Span{Start: 0, End: 3}},
+		{"sqrt(1,2,3)", "unmatched parameters", &shared.Span{Start: 0, End: 11}},
+		{"1 + inv(2, 3)", "unmatched parameters", &shared.Span{Start: 4, End: 13}},
+		{"inverse(2)", "undefined function", nil},
+		{"inverse([[1,2],[3,4]])", "undefined function", nil},
+		{"exponent", "undefined variable", nil},
+	}
+	for _, test := range tests {
+		_, err := run(t, test.statement)
+		e, ok := err.(*shared.Error)
+		if !ok || e.Code != test.code {
+			t.Errorf("%s: got %v, want %s", test.statement, err, test.code)
+			continue
+		}
+		if (e.Span == nil) != (test.span == nil) || (test.span != nil && *e.Span != *test.span) {
+			t.Errorf("%s: got span %v, want %v", test.statement, e.Span, test.span)
+		}
+		if e.Span != nil && e.Span.End > len(test.statement) {
+			t.Errorf("%s: span %v ends behind the input", test.statement, *e.Span)
+		}
+	}
+}
+
+// Systems without any equation are an error instead of a crash.
+func TestSolveEmptySystem(t *testing.T) {
+	for _, statement := range []string{"solve ; for x", "solve ;;", "solve ; ; for x, y"} {
+		if _, err := run(t, statement); err == nil || shared.ErrorCode(err) != "incomplete solve statement" {
+			t.Errorf("%s: got %v, want incomplete solve statement", statement, err)
+		}
+	}
+}
+
+// The equal sign of a definition is marked where it was written.
+func TestDefineSpans(t *testing.T) {
+	tests := []struct {
+		statement string
+		code      string
+		span      shared.Span
+	}{
+		{"define f( = 2", "unexpected token", shared.Span{Start: 10, End: 11}},
+		{"define f(x = 2", "missing closing parenthesis", shared.Span{Start: 11, End: 12}},
+		{"latex: define f( = 2", "unexpected token", shared.Span{Start: 17, End: 18}},
+	}
+	for _, test := range tests {
+		_, err := run(t, test.statement)
+		e, ok := err.(*shared.Error)
+		if !ok || e.Code != test.code || e.Span == nil {
+			t.Errorf("%s: got %v, want %s", test.statement, err, test.code)
+			continue
+		}
+		if *e.Span != test.span {
+			t.Errorf("%s: got span %v, want %v", test.statement, *e.Span, test.span)
+		}
+	}
+}
+
+// The defined and dropped names are read from the tree, not from the first token.
+func TestDefineAndDropNames(t *testing.T) {
+	if res, err := run(t, "define (x) = 2", "x"); err != nil || res != "2" {
+		t.Errorf("define (x) = 2: got %q, %v, want 2", res, err)
+	}
+	if _, err := run(t, "define 2x = 3"); err == nil || shared.ErrorCode(err) != "incorrect assertion" {
+		t.Errorf("define 2x = 3: got %v, want incorrect assertion", err)
+	}
+	for _, statement := range []string{"drop 3", "drop x y", "drop 2x"} {
+		if _, err := run(t, "define x = 2", statement); err == nil || shared.ErrorCode(err) != "not a name" {
+			t.Errorf("%s: got %v, want not a name", statement, err)
+		}
+	}
+	if _, err := run(t, "define f(x) = x", "drop f(x)", "f(2)"); err == nil || shared.ErrorCode(err) != "undefined function" {
+		t.Errorf("drop f(x): got %v, want undefined function", err)
+	}
+}
+
+// Constants are rendered by their name instead of their value.
+func TestLatexConstants(t *testing.T) {
+	tests := []struct {
+		statement string
+		want      string
+	}{
+		{"latex e^x", "e^{x}"},
+		{"latex 2 pi r", `2 \cdot \pi \cdot r`},
+		{`latex: latex \pi r^2`, `\pi \cdot r^{2}`},
+		{"latex 2.5 x", `2.5 \cdot x`},
+	}
+	for _, test := range tests {
+		if res, err := run(t, test.statement); err != nil || res != test.want {
+			t.Errorf("%s: got %q, %v, want %q", test.statement, res, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,615 @@
+package interpreter
+
+import (
+	"errors"
+	"lambdacalc/shared"
+	"math"
+	"math/big"
+)
+
+// Elementary functions on big.Float, calculated to the precision given in bits.
+// Intermediate results use extra bits, so rounding errors do not reach the result.
+
+// Number of extra bits used for intermediate results.
+const GUARD_BITS = 64
+
+// Largest argument of exp, larger results do not fit into the exponent of a big.Float.
+const MAX_EXP_ARGUMENT = 1e9
+
+func newFloat(prec uint) *big.Float {
+	return new(big.Float).SetPrec(prec)
+}
+
+func bigInt(x int64, prec uint) *big.Float {
+	return newFloat(prec).SetInt64(x)
+}
+
+// Exponent of a number in base 2. 8 -> 4, 0.25 -> -1
+func exponent(x *big.Float) int {
+	return x.MantExp(nil)
+}
+
+// Checks if a term of a series is too small to change the result.
+func negligible(term *big.Float, sum *big.Float, prec uint) bool {
+	if term.Sign() == 0 {
+		return true
+	}
+	if sum.Sign() == 0 {
+		return exponent(term) < -int(prec)
+	}
+	return exponent(term) < exponent(sum)-int(prec)
+}
+
+// Converts a number to a big.Float, using the decimal it is written as. 0.1 -> 0.1000...
+func bigNumber(value float64, prec uint) (*big.Float, error) {
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return nil, errors.New("not a finite number")
+	}
+	return newFloat(prec).SetRat(shared.Rational(value)), nil
+}
+
+// pi with Machin's formula: pi = 16 atan(1/5) - 4 atan(1/239)
+func bigPi(prec uint) *big.Float {
+	p := prec + GUARD_BITS
+	a := atanSeries(newFloat(p).Quo(bigInt(1, p), bigInt(5, p)), p)
+	b := atanSeries(newFloat(p).Quo(bigInt(1, p), bigInt(239, p)), p)
+	a.Mul(a, bigInt(16, p))
+	b.Mul(b, bigInt(4, p))
+	return a.Sub(a, b).SetPrec(prec)
+}
+
+// Golden ratio: phi = (1 + sqrt(5)) / 2
+func bigPhi(prec uint) *big.Float {
+	p := prec + GUARD_BITS
+	res := newFloat(p).Sqrt(bigInt(5, p))
+	res.Add(res, bigInt(1, p))
+	return res.Quo(res, bigInt(2, p)).SetPrec(prec)
+}
+
+// Taylor series of atan, only converges fast for small x.
+// atan(x) = x - x^3/3 + x^5/5 - ...
+func atanSeries(x *big.Float, prec uint) *big.Float {
+	sum := newFloat(prec).Set(x)
+	power := newFloat(prec).Set(x)
+	square := newFloat(prec).Mul(x, x)
+	for n := int64(1); ; n++ {
+		power.Mul(power, square).Neg(power)
+		term := newFloat(prec).Quo(power, bigInt(2*n+1, prec))
+		if negligible(term, sum, prec) {
+			return sum
+		}
+		sum.Add(sum, term)
+	}
+}
+
+// e^x, the argument is halved until the series converges fast and the result is squared again.
+func bigExp(x *big.Float, prec uint) (*big.Float, error) {
+	if x.Sign() == 0 {
+		return bigInt(1, prec), nil
+	}
+	if f, _ := x.Float64(); f > MAX_EXP_ARGUMENT {
+		return nil, errors.New("result too large")
+	} else if f < -MAX_EXP_ARGUMENT {
+		return newFloat(prec), nil
+	}
+
+	// x = r * 2^k with |r| < 2^-8
+	k := max(0, exponent(x)+8)
+	p := prec + GUARD_BITS + uint(k)
+	r := newFloat(p).SetMantExp(x, -k)
+
+	// e^r = 1 + r + r^2/2! + ...
+	sum := bigInt(1, p)
+	term := bigInt(1, p)
+	for n := int64(1); ; n++ {
+		term.Mul(term, r).Quo(term, bigInt(n, p))
+		if negligible(term, sum, p) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+
+	for i := 0; i < k; i++ {
+		sum.Mul(sum, sum)
+	}
+	return sum.SetPrec(prec), nil
+}
+
+// Natural logarithm, x = m * 2^e -> ln(x) = ln(m) + e * ln(2)
+func bigLn(x *big.Float, prec uint) (*big.Float, error) {
+	if x.Sign() <= 0 {
+		return nil, errors.New("logarithm of a non positive number")
+	}
+	p := prec + GUARD_BITS
+	mant := newFloat(p)
+	e := x.MantExp(mant)
+
+	res, err := lnNewton(mant, p)
+	if err != nil {
+		return nil, err
+	}
+	if e != 0 {
+		ln2, err := lnNewton(bigInt(2, p), p)
+		if err != nil {
+			return nil, err
+		}
+		res.Add(res, ln2.Mul(ln2, bigInt(int64(e), p)))
+	}
+	return res.SetPrec(prec), nil
+}
+
+// Logarithm of a number close to 1 with Halley's method on e^y = x.
+// y = y + 2 * (x - e^y) / (x + e^y)
+func lnNewton(x *big.Float, prec uint) (*big.Float, error) {
+	f, _ := x.Float64()
+	y := newFloat(prec).SetFloat64(math.Log(f))
+	for i := 0; i < 100; i++ {
+		ey, err := bigExp(y, prec)
+		if err != nil {
+			return nil, err
+		}
+		step := newFloat(prec).Sub(x, ey)
+		step.Mul(step, bigInt(2, prec)).Quo(step, newFloat(prec).Add(x, ey))
+		y.Add(y, step)
+		if negligible(step, y, prec-8) {
+			break
+		}
+	}
+	return y, nil
+}
+
+// Reduces an angle into [-pi, pi].
+func reduceAngle(x *big.Float, prec uint) *big.Float {
+	// Large angles need more digits of pi.
+	p := prec + GUARD_BITS + uint(max(0, exponent(x)))
+	tau := bigPi(p)
+	tau.Mul(tau, bigInt(2, p))
+
+	turns := newFloat(p).Quo(x, tau)
+	rounded, _ := turns.Add(turns, newFloat(p).SetFloat64(0.5)).Int(nil)
+	// Int truncates towards zero, floor(turns + 0.5) is the nearest whole turn.
+	if turns.Sign() < 0 && !turns.IsInt() {
+		rounded.Sub(rounded, big.NewInt(1))
+	}
+
+	res := newFloat(p).SetInt(rounded)
+	res.Mul(res, tau)
+	return res.Sub(newFloat(p).Set(x), res)
+}
+
+// Taylor series of sin and cos, start is x for sin and 1 for cos.
+// sin(x) = x - x^3/3! + ..., cos(x) = 1 - x^2/2! + ...
+func trigSeries(x *big.Float, start int64, prec uint) *big.Float {
+	p := prec + GUARD_BITS
+	r := reduceAngle(x, p)
+	square := newFloat(p).Mul(r, r)
+
+	term := bigInt(1, p)
+	if start == 1 {
+		term.Set(r)
+	}
+	sum := newFloat(p).Set(term)
+	for n := start + 1; ; n += 2 {
+		term.Mul(term, square).Neg(term).Quo(term, bigInt(n*(n+1), p))
+		// The result is at most 1, so terms below the precision do not matter.
+		if term.Sign() == 0 || exponent(term) < -int(p) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	return sum.SetPrec(prec)
+}
+
+func bigSin(x *big.Float, prec uint) *big.Float {
+	return trigSeries(x, 1, prec)
+}
+
+func bigCos(x *big.Float, prec uint) *big.Float {
+	return trigSeries(x, 0, prec)
+}
+
+// atan(x), large arguments use atan(x) = ±pi/2 - atan(1/x), small arguments are
+// halved with atan(x) = 2 atan(x / (1 + sqrt(1 + x^2))) until the series converges fast.
+func bigAtan(x *big.Float, prec uint) *big.Float {
+	p := prec + GUARD_BITS
+	if x.Sign() == 0 {
+		return newFloat(prec)
+	}
+
+	if newFloat(p).Abs(x).Cmp(bigInt(1, p)) > 0 {
+		half := bigPi(p)
+		half.Quo(half, bigInt(int64(2*x.Sign()), p))
+		inverse := bigAtan(newFloat(p).Quo(bigInt(1, p), x), p)
+		return half.Sub(half, inverse).SetPrec(prec)
+	}
+
+	r := newFloat(p).Set(x)
+	factor := int64(1)
+	for exponent(r) > -3 {
+		root := newFloat(p).Mul(r, r)
+		root.Add(root, bigInt(1, p)).Sqrt(root).Add(root, bigInt(1, p))
+		r.Quo(r, root)
+		factor *= 2
+	}
+	res := atanSeries(r, p)
+	return res.Mul(res, bigInt(factor, p)).SetPrec(prec)
+}
+
+// asin(x) = atan(x / sqrt(1 - x^2))
+func bigAsin(x *big.Float, prec uint) (*big.Float, error) {
+	p := prec + GUARD_BITS
+	switch newFloat(p).Abs(x).Cmp(bigInt(1, p)) {
+	case 1:
+		return nil, errors.New("parameter out of domain")
+	case 0:
+		half := bigPi(p)
+		half.Quo(half, bigInt(int64(2*x.Sign()), p))
+		return half.SetPrec(prec), nil
+	}
+	root := newFloat(p).Mul(x, x)
+	root.Sub(bigInt(1, p), root).Sqrt(root)
+	return bigAtan(root.Quo(x, root), prec), nil
+}
+
+// Raises a number to a power, integer exponents are calculated by squaring.
+func bigPow(a, b *big.Float, prec uint) (*big.Float, error) {
+	if b.IsInt() && exponent(b) <= 32 {
+		n, _ := b.Int64()
+		if a.Sign() == 0 {
+			if n < 0 {
+				return nil, errors.New("divide by 0")
+			} else if n == 0 {
+				return bigInt(1, prec), nil
+			}
+			return newFloat(prec), nil
+		}
+
+		// Every squaring can lose a bit.
+		p := prec + GUARD_BITS + 64
+		res := bigInt(1, p)
+		base := newFloat(p).Set(a)
+		for m := n; m != 0; m /= 2 {
+			if m%2 != 0 {
+				res.Mul(res, base)
+			}
+			base.Mul(base, base)
+		}
+		if n < 0 {
+			res.Quo(bigInt(1, p), res)
+		}
+		return res.SetPrec(prec), nil
+	}
+
+	switch a.Sign() {
+	case -1:
+		return nil, errors.New("result is not a real number")
+	case 0:
+		if b.Sign() < 0 {
+			return nil, errors.New("divide by 0")
+		}
+		return newFloat(prec), nil
+	}
+
+	// a^b = e^(b * ln(a))
+	p := prec + GUARD_BITS + uint(max(0, exponent(b)))
+	ln, err := bigLn(a, p)
+	if err != nil {
+		return nil, err
+	}
+	return bigExp(ln.Mul(ln, b), prec)
+}
+
+// The a-th root of b, odd roots of negative numbers are real.
+func bigRoot(a, b *big.Float, prec uint) (*big.Float, error) {
+	if b.Sign() >= 0 && a.Cmp(bigInt(2, prec)) == 0 {
+		return newFloat(prec).Sqrt(b), nil
+	}
+
+	p := prec + GUARD_BITS
+	inverse := newFloat(p).Quo(bigInt(1, p), a)
+	if b.Sign() < 0 {
+		odd := false
+		if a.IsInt() {
+			n, _ := a.Int(nil)
+			odd = n.Bit(0) == 1
+		}
+		if !odd {
+			return nil, errors.New("negative sqrt")
+		}
+		res, err := bigPow(newFloat(p).Neg(b), inverse, prec)
+		if err != nil {
+			return nil, err
+		}
+		return res.Neg(res), nil
+	}
+	return bigPow(b, inverse, prec)
+}
+
+// Rounds a number down to an integer. 3.5 -> 3, -3.5 -> -4
+func bigFloor(x *big.Float) *big.Int {
+	res, _ := x.Int(nil)
+	if x.Sign() < 0 && !x.IsInt() {
+		res.Sub(res, big.NewInt(1))
+	}
+	return res
+}
+
+// Converts the parameters of integer functions like gcd.
+func bigIntegers(params []*big.Float, name string) ([]*big.Int, error) {
+	res := []*big.Int{}
+	for _, val := range params {
+		if !val.IsInt() {
+			return nil, errors.New(name + " of a non integer")
+		}
+		n, _ := val.Int(nil)
+		res = append(res, n)
+	}
+	return res, nil
+}
+
+// Wraps a function with a single parameter, that does not fail.
+func bigUnary(f func(x *big.Float, prec uint) *big.Float) func([]*big.Float, uint) (*big.Float, error) {
+	return func(params []*big.Float, prec uint) (*big.Float, error) {
+		return f(params[0], prec), nil
+	}
+}
+
+// Wraps a logarithm with a constant base.
+func bigLog(base int64) func([]*big.Float, uint) (*big.Float, error) {
+	return func(params []*big.Float, prec uint) (*big.Float, error) {
+		p := prec + GUARD_BITS
+		res, err := bigLn(params[0], p)
+		if err != nil {
+			return nil, err
+		}
+		divisor, err := bigLn(bigInt(base, p), p)
+		if err != nil {
+			return nil, err
+		}
+		return res.Quo(res, divisor).SetPrec(prec), nil
+	}
+}
+
+// Versions of the built-in functions with arbitrary precision.
+var preciseBuiltins = map[string]func(params []*big.Float, prec uint) (*big.Float, error){
+	// Trigonometry
+	"sin": bigUnary(bigSin),
+	"cos": bigUnary(bigCos),
+	"tan": func(params []*big.Float, prec uint) (*big.Float, error) {
+		p := prec + GUARD_BITS
+		cos := bigCos(params[0], p)
+		if cos.Sign() == 0 {
+			return nil, errors.New("parameter out of domain")
+		}
+		return bigSin(params[0], p).Quo(bigSin(params[0], p), cos).SetPrec(prec), nil
+	},
+	"asin": func(params []*big.Float, prec uint) (*big.Float, error) {
+		return bigAsin(params[0], prec)
+	},
+	"acos": func(params []*big.Float, prec uint) (*big.Float, error) {
+		// acos(x) = pi/2 - asin(x)
+		p := prec + GUARD_BITS
+		res, err := bigAsin(params[0], p)
+		if err != nil {
+			return nil, err
+		}
+		half := bigPi(p)
+		half.Quo(half, bigInt(2, p))
+		return half.Sub(half, res).SetPrec(prec), nil
+	},
+	"atan": bigUnary(bigAtan),
+	"atan2": func(params []*big.Float, prec uint) (*big.Float, error) {
+		y, x := params[0], params[1]
+		p := prec + GUARD_BITS
+		switch x.Sign() {
+		case 1:
+			return bigAtan(newFloat(p).Quo(y, x), prec), nil
+		case -1:
+			res := bigAtan(newFloat(p).Quo(y, x), p)
+			if y.Sign() >= 0 {
+				return res.Add(res, bigPi(p)).SetPrec(prec), nil
+			}
+			return res.Sub(res, bigPi(p)).SetPrec(prec), nil
+		}
+		half := bigPi(p)
+		half.Quo(half, bigInt(2, p))
+		return half.Mul(half, bigInt(int64(y.Sign()), p)).SetPrec(prec), nil
+	},
+	"sinh": func(params []*big.Float, prec uint) (*big.Float, error) {
+		// sinh(x) = (e^x - e^-x) / 2
+		p := prec + GUARD_BITS
+		a, err := bigExp(params[0], p)
+		if err != nil {
+			return nil, err
+		}
+		b := newFloat(p).Quo(bigInt(1, p), a)
+		return a.Sub(a, b).Quo(a, bigInt(2, p)).SetPrec(prec), nil
+	},
+	"cosh": func(params []*big.Float, prec uint) (*big.Float, error) {
+		// cosh(x) = (e^x + e^-x) / 2
+		p := prec + GUARD_BITS
+		a, err := bigExp(params[0], p)
+		if err != nil {
+			return nil, err
+		}
+		b := newFloat(p).Quo(bigInt(1, p), a)
+		return a.Add(a, b).Quo(a, bigInt(2, p)).SetPrec(prec), nil
+	},
+	"tanh": func(params []*big.Float, prec uint) (*big.Float, error) {
+		// tanh(x) = (e^2x - 1) / (e^2x + 1), large values are 1.
+		p := prec + GUARD_BITS
+		if f, _ := params[0].Float64(); math.Abs(f) > float64(p) {
+			return bigInt(int64(params[0].Sign()), prec), nil
+		}
+		a, err := bigExp(newFloat(p).Mul(params[0], bigInt(2, p)), p)
+		if err != nil {
+			return nil, err
+		}
+		b := newFloat(p).Add(a, bigInt(1, p))
+		return a.Sub(a, bigInt(1, p)).Quo(a, b).SetPrec(prec), nil
+	},
+	"asinh": func(params []*big.Float, prec uint) (*big.Float, error) {
+		// asinh(x) = ln(|x| + sqrt(x^2 + 1)) with the sign of x
+		p := prec + GUARD_BITS
+		x := newFloat(p).Abs(params[0])
+		root := newFloat(p).Mul(x, x)
+		root.Add(root, bigInt(1, p)).Sqrt(root)
+		res, err := bigLn(root.Add(root, x), p)
+		if err != nil {
+			return nil, err
+		}
+		if params[0].Sign() < 0 {
+			res.Neg(res)
+		}
+		return res.SetPrec(prec), nil
+	},
+	"acosh": func(params []*big.Float, prec uint) (*big.Float, error) {
+		// acosh(x) = ln(x + sqrt(x^2 - 1))
+		p := prec + GUARD_BITS
+		if params[0].Cmp(bigInt(1, p)) < 0 {
+			return nil, errors.New("parameter out of domain")
+		}
+		root := newFloat(p).Mul(params[0], params[0])
+		root.Sub(root, bigInt(1, p)).Sqrt(root)
+		return bigLn(root.Add(root, params[0]), prec)
+	},
+	"atanh": func(params []*big.Float, prec uint) (*big.Float, error) {
+		// atanh(x) = ln((1 + x) / (1 - x)) / 2
+		p := prec + GUARD_BITS
+		if newFloat(p).Abs(params[0]).Cmp(bigInt(1, p)) >= 0 {
+			return nil, errors.New("parameter out of domain")
+		}
+		a := newFloat(p).Add(bigInt(1, p), params[0])
+		a.Quo(a, newFloat(p).Sub(bigInt(1, p), params[0]))
+		res, err := bigLn(a, p)
+		if err != nil {
+			return nil, err
+		}
+		return res.Quo(res, bigInt(2, p)).SetPrec(prec), nil
+	},
+
+	// Logarithms and exponentials
+	"ln": func(params []*big.Float, prec uint) (*big.Float, error) {
+		return bigLn(params[0], prec)
+	},
+	"log2": bigLog(2),
+	"exp": func(params []*big.Float, prec uint) (*big.Float, error) {
+		return bigExp(params[0], prec)
+	},
+	"log": func(params []*big.Float, prec uint) (*big.Float, error) {
+		if len(params) == 1 {
+			return bigLog(10)(params, prec)
+		}
+		if params[1].Sign() <= 0 || params[1].Cmp(bigInt(1, prec)) == 0 {
+			return nil, errors.New("invalid logarithm base")
+		}
+		p := prec + GUARD_BITS
+		res, err := bigLn(params[0], p)
+		if err != nil {
+			return nil, err
+		}
+		base, err := bigLn(params[1], p)
+		if err != nil {
+			return nil, err
+		}
+		return res.Quo(res, base).SetPrec(prec), nil
+	},
+
+	// Rounding
+	"abs": func(params []*big.Float, prec uint) (*big.Float, error) {
+		return newFloat(prec).Abs(params[0]), nil
+	},
+	"floor": func(params []*big.Float, prec uint) (*big.Float, error) {
+		return newFloat(prec).SetInt(bigFloor(params[0])), nil
+	},
+	"ceil": func(params []*big.Float, prec uint) (*big.Float, error) {
+		// ceil(x) = -floor(-x)
+		res := bigFloor(newFloat(prec).Neg(params[0]))
+		return newFloat(prec).SetInt(res.Neg(res)), nil
+	},
+	"round": func(params []*big.Float, prec uint) (*big.Float, error) {
+		// Halves are rounded away from zero like math.Round.
+		x := newFloat(prec + 1).Abs(params[0])
+		res := bigFloor(x.Add(x, newFloat(prec).SetFloat64(0.5)))
+		if params[0].Sign() < 0 {
+			res.Neg(res)
+		}
+		return newFloat(prec).SetInt(res), nil
+	},
+
+	// Complex numbers
+	"re": func(params []*big.Float, prec uint) (*big.Float, error) {
+		return newFloat(prec).Set(params[0]), nil
+	},
+	"im": func(params []*big.Float, prec uint) (*big.Float, error) {
+		return newFloat(prec), nil
+	},
+	"conj": func(params []*big.Float, prec uint) (*big.Float, error) {
+		return newFloat(prec).Set(params[0]), nil
+	},
+	"arg": func(params []*big.Float, prec uint) (*big.Float, error) {
+		if params[0].Sign() < 0 {
+			return bigPi(prec), nil
+		}
+		return newFloat(prec), nil
+	},
+
+	// Comparison
+	"min": func(params []*big.Float, prec uint) (*big.Float, error) {
+		res := params[0]
+		for _, val := range params[1:] {
+			if val.Cmp(res) < 0 {
+				res = val
+			}
+		}
+		return newFloat(prec).Set(res), nil
+	},
+	"max": func(params []*big.Float, prec uint) (*big.Float, error) {
+		res := params[0]
+		for _, val := range params[1:] {
+			if val.Cmp(res) > 0 {
+				res = val
+			}
+		}
+		return newFloat(prec).Set(res), nil
+	},
+
+	// Number theory
+	"gcd": func(params []*big.Float, prec uint) (*big.Float, error) {
+		integers, err := bigIntegers(params, "gcd")
+		if err != nil {
+			return nil, err
+		}
+		res := new(big.Int)
+		for _, val := range integers {
+			res.GCD(nil, nil, res, val.Abs(val))
+		}
+		return newFloat(prec).SetInt(res), nil
+	},
+	"lcm": func(params []*big.Float, prec uint) (*big.Float, error) {
+		integers, err := bigIntegers(params, "lcm")
+		if err != nil {
+			return nil, err
+		}
+		res := big.NewInt(1)
+		for _, val := range integers {
+			if val.Sign() == 0 {
+				return newFloat(prec), nil
+			}
+			val.Abs(val)
+			divisor := new(big.Int).GCD(nil, nil, res, val)
+			res.Mul(res, val).Quo(res, divisor)
+		}
+		return newFloat(prec).SetInt(res), nil
+	},
+	"factorial": func(params []*big.Float, prec uint) (*big.Float, error) {
+		if !params[0].IsInt() || params[0].Sign() < 0 {
+			return nil, errors.New("factorial of a negative or non integer number")
+		}
+		n, _ := params[0].Int64()
+		if !params[0].IsInt() || n > shared.MAX_EXACT_EXPONENT {
+			return nil, errors.New("factorial too large")
+		}
+		return newFloat(prec).SetInt(new(big.Int).MulRange(1, n)), nil
+	},
+}
@@ -0,0 +1,282 @@
+package interpreter
+
+import (
+	"errors"
+	"lambdacalc/shared"
+	"math"
+	"math/cmplx"
+)
+
+// Local variables of a function call in complex mode.
+type complexScope map[string]complex128
+
+// Largest integer exponent that is calculated by multiplication, so i^2 is exactly -1.
+const MAX_INTEGER_EXPONENT = 1024
+
+// Evaluate a tree with complex numbers, the imaginary unit is a variable.
+// Real operations are calculated like in Evaluate, only results without a real value become complex.
+// sqrt(-4) -> 2i, (-8)^(1/3) -> 1 + 1.7320508075688772i
+func EvaluateComplex(s *shared.State, node *shared.Node) (complex128, error) {
+	return evaluateComplex(s, node, nil, 0)
+}
+
+func evaluateComplex(s *shared.State, node *shared.Node, local complexScope, depth int) (complex128, error) {
+	switch node.OperationType {
+	case shared.NUMBER:
+		return complex(node.Value, 0), nil
+	case shared.VARIABLE:
+		// Parameters shadow global variables.
+		if val, ok := local[node.Variable]; ok {
+			return val, nil
+		}
+		if node.Variable == s.Conf.ImaginaryUnit() {
+			return 1i, nil
+		} else if val, ok := s.Variables[node.Variable]; ok {
+			if err := enterVariable(node, depth); err != nil {
+				return 0, err
+			}
+			return evaluateComplex(s, &val, nil, depth+1)
+		} else if val, ok := s.Conf.Constants[node.Variable]; ok {
+			return complex(val, 0), nil
+		}
+		return 0, shared.NewError("undefined variable", "Unable to calculate output, undefined variable '%s'.", node.Variable)
+	case shared.PLUS:
+		a := complex128(0)
+		for _, val := range node.Associative {
+			b, err := evaluateComplex(s, val, local, depth)
+			if err != nil {
+				return 0, err
+			}
+			a = a + b
+		}
+		return a, nil
+	case shared.MINUS:
+		a, err := evaluateComplex(s, node.LNode, local, depth)
+		if err != nil {
+			return 0, err
+		}
+		b, err := evaluateComplex(s, node.RNode, local, depth)
+		if err != nil {
+			return 0, err
+		}
+		return a - b, nil
+	case shared.MULTIPLY:
+		a := complex128(1)
+		for _, val := range node.Associative {
+			b, err := evaluateComplex(s, val, local, depth)
+			if err != nil {
+				return 0, err
+			}
+			a = a * b
+		}
+		return a, nil
+	case shared.DIVIDE:
+		a, err := evaluateComplex(s, node.LNode, local, depth)
+		if err != nil {
+			return 0, err
+		}
+		b, err := evaluateComplex(s, node.RNode, local, depth)
+		if err != nil {
+			return 0, err
+		}
+		if b == 0 {
+			return 0, shared.DivisionByZero()
+		}
+		return a / b, nil
+	case shared.POWER:
+		a, err := evaluateComplex(s, node.LNode, local, depth)
+		if err != nil {
+			return 0, err
+		}
+		b, err := evaluateComplex(s, node.RNode, local, depth)
+		if err != nil {
+			return 0, err
+		}
+		// A power of zero with a negative real part divides by zero. i/0 = i * 0^-1
+		if a == 0 && real(b) < 0 {
+			return 0, shared.DivisionByZero()
+		}
+		return complexPow(a, b), nil
+	case shared.SQRT:
+		a, err := evaluateComplex(s, node.LNode, local, depth)
+		if err != nil {
+			return 0, err
+		}
+		b, err := evaluateComplex(s, node.RNode, local, depth)
+		if err != nil {
+			return 0, err
+		}
+		return complexRoot(a, b), nil
+	case shared.FUNCTION:
+		return callComplex(s, node, local, depth)
+	default:
+		return 0, shared.NewError("unexpected error", "Unable to calculate output, unexpected symbole.")
+	}
+}
+
+// Raises a to the power of b using the principal branch.
+// Integer exponents are multiplied out, so the result does not pick up rounding errors. i^2 -> -1
+func complexPow(a, b complex128) complex128 {
+	if imag(a) == 0 && imag(b) == 0 {
+		if res := math.Pow(real(a), real(b)); !math.IsNaN(res) {
+			return complex(res, 0)
+		}
+	}
+
+	if n := real(b); imag(b) == 0 && n == math.Trunc(n) && math.Abs(n) <= MAX_INTEGER_EXPONENT && a != 0 {
+		res := complex128(1)
+		for i := 0; i < int(math.Abs(n)); i++ {
+			res *= a
+		}
+		if n < 0 {
+			return 1 / res
+		}
+		return res
+	}
+	return cmplx.Pow(a, b)
+}
+
+// Calculates the a-th root of b, odd roots of negative numbers stay real like in real mode.
+// sqrt(-4) -> 2i, sqrt(-8, 3) -> -2
+func complexRoot(a, b complex128) complex128 {
+	if imag(a) == 0 && imag(b) == 0 {
+		if real(b) >= 0 || math.Mod(real(a), 2) == 1 || math.Mod(real(a), 2) == -1 {
+			res, _ := root(real(a), real(b))
+			return complex(res, 0)
+		}
+		if real(a) == 2 {
+			return cmplx.Sqrt(b)
+		}
+	}
+	return complexPow(b, 1/a)
+}
+
+// Evaluate a user defined function by binding the arguments to its parameters.
+func callComplex(s *shared.State, node *shared.Node, local complexScope, depth int) (complex128, error) {
+	if builtin, ok := shared.Builtins[node.Variable]; ok {
+		return callComplexBuiltin(s, node, builtin, local, depth)
+	}
+
+	function, err := lookupFunction(s, node, depth)
+	if err != nil {
+		return 0, err
+	}
+
+	arguments := make(complexScope)
+	for i, param := range function.Parameters {
+		val, err := evaluateComplex(s, node.Associative[i], local, depth)
+		if err != nil {
+			return 0, err
+		}
+		arguments[param.Variable] = val
+	}
+	return evaluateComplex(s, function.Equation, arguments, depth+1)
+}
+
+// Evaluate a built-in function. Real parameters use the real function as long as its result is real,
+// otherwise the complex version is used. Functions like floor only accept real parameters.
+func callComplexBuiltin(s *shared.State, node *shared.Node, builtin shared.Builtin, local complexScope, depth int) (complex128, error) {
+	if !builtin.Accepts(len(node.Associative)) {
+		return 0, shared.NewError("unmatched parameters", "Unable to calculate output, function '%s' does not accept %v parameters.", node.Variable, len(node.Associative))
+	}
+
+	params := []complex128{}
+	floats := []float64{}
+	isReal := true
+	for _, val := range node.Associative {
+		a, err := evaluateComplex(s, val, local, depth)
+		if err != nil {
+			return 0, err
+		}
+		params = append(params, a)
+		floats = append(floats, real(a))
+		isReal = isReal && imag(a) == 0
+	}
+
+	var err error
+	if isReal {
+		var res float64
+		res, err = builtin.Evaluate(floats)
+		if err == nil && !math.IsNaN(res) {
+			return complex(res, 0), nil
+		}
+	}
+
+	if f, ok := complexBuiltins[node.Variable]; ok {
+		var res complex128
+		res, err = f(params)
+		if err == nil {
+			return res, nil
+		}
+	} else if !isReal {
+		err = errors.New("complex parameter")
+	}
+
+	return 0, shared.NewError(err.Error(), "Unable to calculate output, %v in '%s'.", err, node.Variable)
+}
+
+// Wraps a complex function with a single parameter.
+func complexUnary(f func(complex128) complex128) func([]complex128) (complex128, error) {
+	return func(params []complex128) (complex128, error) {
+		return f(params[0]), nil
+	}
+}
+
+// Wraps a complex logarithm, which is not defined for zero.
+func complexLogarithm(base float64) func([]complex128) (complex128, error) {
+	return func(params []complex128) (complex128, error) {
+		if params[0] == 0 {
+			return 0, errors.New("logarithm of zero")
+		}
+		return cmplx.Log(params[0]) / complex(math.Log(base), 0), nil
+	}
+}
+
+// Complex versions of the built-in functions, using the principal branch.
+var complexBuiltins = map[string]func(params []complex128) (complex128, error){
+	// Trigonometry
+	"sin":   complexUnary(cmplx.Sin),
+	"cos":   complexUnary(cmplx.Cos),
+	"tan":   complexUnary(cmplx.Tan),
+	"asin":  complexUnary(cmplx.Asin),
+	"acos":  complexUnary(cmplx.Acos),
+	"atan":  complexUnary(cmplx.Atan),
+	"sinh":  complexUnary(cmplx.Sinh),
+	"cosh":  complexUnary(cmplx.Cosh),
+	"tanh":  complexUnary(cmplx.Tanh),
+	"asinh": complexUnary(cmplx.Asinh),
+	"acosh": complexUnary(cmplx.Acosh),
+	"atanh": complexUnary(cmplx.Atanh),
+
+	// Logarithms and exponentials
+	"ln":   complexLogarithm(math.E),
+	"log2": complexLogarithm(2),
+	"exp":  complexUnary(cmplx.Exp),
+	"log": func(params []complex128) (complex128, error) {
+		if params[0] == 0 {
+			return 0, errors.New("logarithm of zero")
+		}
+		if len(params) == 1 {
+			return cmplx.Log10(params[0]), nil
+		}
+		if params[1] == 0 || params[1] == 1 {
+			return 0, errors.New("invalid logarithm base")
+		}
+		return cmplx.Log(params[0]) / cmplx.Log(params[1]), nil
+	},
+
+	// Complex numbers
+	"abs": func(params []complex128) (complex128, error) {
+		return complex(cmplx.Abs(params[0]), 0), nil
+	},
+	"re": func(params []complex128) (complex128, error) {
+		return complex(real(params[0]), 0), nil
+	},
+	"im": func(params []complex128) (complex128, error) {
+		return complex(imag(params[0]), 0), nil
+	},
+	"conj": complexUnary(cmplx.Conj),
+	"arg": func(params []complex128) (complex128, error) {
+		return complex(cmplx.Phase(params[0]), 0), nil
+	},
+}
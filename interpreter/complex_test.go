@@ -0,0 +1,54 @@
+package interpreter
+
+import (
+	"lambdacalc/shared"
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestEvaluateComplex(t *testing.T) {
+	state.Conf.Options["complex"] = true
+	defer delete(state.Conf.Options, "complex")
+
+	tests := []struct {
+		input string
+		want  complex128
+	}{
+		{"i^2", -1},
+		{"sqrt(-4)", 2i},
+		{"(1+i)*(1-i)", 2},
+		{"(2+3i)/(1-i)", -0.5 + 2.5i},
+		{"1/i", -1i},
+		{"abs(3+4i)", 5},
+		{"re(2+3i)", 2},
+		{"im(2+3i)", 3},
+		{"conj(2+3i)", 2 - 3i},
+		{"arg(i)", math.Pi / 2},
+		{"e^(i*pi)", -1},
+		{"(-1)^0.5", 1i},
+		{"ln(-1)", complex(0, math.Pi)},
+		{"sin(i)", complex(0, math.Sinh(1))},
+	}
+	for _, test := range tests {
+		got, err := EvaluateComplex(state, parse(t, test.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.input, err)
+			continue
+		}
+		if cmplx.Abs(got-test.want) > 1e-12 {
+			t.Errorf("%s: got %v, want %v", test.input, got, test.want)
+		}
+	}
+}
+
+func TestComplexDivisionByZero(t *testing.T) {
+	state.Conf.Options["complex"] = true
+	defer delete(state.Conf.Options, "complex")
+
+	for _, input := range []string{"1/0", "0/0", "i/0", "(1+i)/(i-i)", "0^(-1+i)"} {
+		if _, err := EvaluateComplex(state, parse(t, input)); err == nil || shared.ErrorCode(err) != "divide by 0" {
+			t.Errorf("%s: got %v, want divide by 0", input, err)
+		}
+	}
+}
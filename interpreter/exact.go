@@ -0,0 +1,208 @@
+package interpreter
+
+import (
+	"lambdacalc/shared"
+	"math"
+	"math/big"
+)
+
+// Local variables of a function call in exact mode.
+type rationalScope map[string]*big.Rat
+
+// State of an exact evaluation, exact is cleared as soon as a value had to be approximated.
+type exactEvaluation struct {
+	state *shared.State
+	exact bool
+}
+
+// Evaluate a tree with rational numbers. The second result is false, if an irrational
+// operation made it necessary to approximate the result. i.e.: sqrt(2) or sin(1)
+// 1/3 * 3 -> 1, true
+func EvaluateExact(s *shared.State, node *shared.Node) (*big.Rat, bool, error) {
+	e := &exactEvaluation{
+		state: s,
+		exact: true,
+	}
+	res, err := e.evaluate(node, nil, 0)
+	if err != nil {
+		return nil, false, err
+	}
+	return res, e.exact, nil
+}
+
+// Converts an approximated value back into a rational.
+func (e *exactEvaluation) approximate(value float64) (*big.Rat, error) {
+	e.exact = false
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return nil, shared.NewError("not a finite number", "Unable to calculate output, result is not a finite number.")
+	}
+	return new(big.Rat).SetFloat64(value), nil
+}
+
+func toFloat(r *big.Rat) float64 {
+	f, _ := r.Float64()
+	return f
+}
+
+func (e *exactEvaluation) evaluate(node *shared.Node, local rationalScope, depth int) (*big.Rat, error) {
+	switch node.OperationType {
+	case shared.NUMBER:
+		if r := shared.NumberRational(node); r != nil {
+			return r, nil
+		}
+		return e.approximate(node.Value)
+	case shared.VARIABLE:
+		// Parameters shadow global variables.
+		if val, ok := local[node.Variable]; ok {
+			return val, nil
+		}
+		if val, ok := e.state.Variables[node.Variable]; ok {
+			if err := enterVariable(node, depth); err != nil {
+				return nil, err
+			}
+			return e.evaluate(&val, nil, depth+1)
+		} else if val, ok := e.state.Conf.Constants[node.Variable]; ok {
+			// Constants like pi are only known approximately.
+			return e.approximate(val)
+		}
+		return nil, shared.NewError("undefined variable", "Unable to calculate output, undefined variable '%s'.", node.Variable)
+	case shared.PLUS:
+		a := new(big.Rat)
+		for _, val := range node.Associative {
+			b, err := e.evaluate(val, local, depth)
+			if err != nil {
+				return nil, err
+			}
+			a.Add(a, b)
+		}
+		return a, nil
+	case shared.MINUS:
+		a, err := e.evaluate(node.LNode, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		b, err := e.evaluate(node.RNode, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Rat).Sub(a, b), nil
+	case shared.MULTIPLY:
+		a := big.NewRat(1, 1)
+		for _, val := range node.Associative {
+			b, err := e.evaluate(val, local, depth)
+			if err != nil {
+				return nil, err
+			}
+			a.Mul(a, b)
+		}
+		return a, nil
+	case shared.DIVIDE:
+		a, err := e.evaluate(node.LNode, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		b, err := e.evaluate(node.RNode, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		if b.Sign() == 0 {
+			return nil, shared.DivisionByZero()
+		}
+		return new(big.Rat).Quo(a, b), nil
+	case shared.POWER:
+		a, err := e.evaluate(node.LNode, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		b, err := e.evaluate(node.RNode, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		res, ok, err := shared.RationalPower(a, b)
+		if err != nil {
+			return nil, shared.DivisionByZero()
+		} else if ok {
+			return res, nil
+		}
+		return e.approximate(math.Pow(toFloat(a), toFloat(b)))
+	case shared.SQRT:
+		a, err := e.evaluate(node.LNode, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		b, err := e.evaluate(node.RNode, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		if a.IsInt() && a.Num().IsInt64() {
+			if res, ok := shared.RationalRoot(b, a.Num().Int64()); ok {
+				return res, nil
+			}
+		}
+		res, err := root(toFloat(a), toFloat(b))
+		if err != nil {
+			return nil, err
+		}
+		return e.approximate(res)
+	case shared.FUNCTION:
+		return e.call(node, local, depth)
+	default:
+		return nil, shared.NewError("unexpected error", "Unable to calculate output, unexpected symbole.")
+	}
+}
+
+// Evaluate a user defined function by binding the arguments to its parameters.
+func (e *exactEvaluation) call(node *shared.Node, local rationalScope, depth int) (*big.Rat, error) {
+	if builtin, ok := shared.Builtins[node.Variable]; ok {
+		return e.callBuiltin(node, builtin, local, depth)
+	}
+
+	function, err := lookupFunction(e.state, node, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	arguments := make(rationalScope)
+	for i, param := range function.Parameters {
+		val, err := e.evaluate(node.Associative[i], local, depth)
+		if err != nil {
+			return nil, err
+		}
+		arguments[param.Variable] = val
+	}
+	return e.evaluate(function.Equation, arguments, depth+1)
+}
+
+// Evaluate a built-in function, functions without an exact version are approximated.
+func (e *exactEvaluation) callBuiltin(node *shared.Node, builtin shared.Builtin, local rationalScope, depth int) (*big.Rat, error) {
+	if !builtin.Accepts(len(node.Associative)) {
+		return nil, shared.NewError("unmatched parameters", "Unable to calculate output, function '%s' does not accept %v parameters.", node.Variable, len(node.Associative))
+	}
+
+	params := []*big.Rat{}
+	for _, val := range node.Associative {
+		a, err := e.evaluate(val, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, a)
+	}
+
+	if exact, ok := shared.RationalBuiltins[node.Variable]; ok {
+		res, err := exact(params)
+		if err != nil {
+			return nil, shared.NewError(err.Error(), "Unable to calculate output, %v in '%s'.", err, node.Variable)
+		}
+		return res, nil
+	}
+
+	floats := []float64{}
+	for _, val := range params {
+		floats = append(floats, toFloat(val))
+	}
+	res, err := builtin.Evaluate(floats)
+	if err != nil {
+		return nil, shared.NewError(err.Error(), "Unable to calculate output, %v in '%s'.", err, node.Variable)
+	}
+	return e.approximate(res)
+}
@@ -0,0 +1,34 @@
+package interpreter
+
+import "testing"
+
+// Rational results are exact, irrational ones are approximated and reported as not exact.
+func TestEvaluateExact(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		exact bool
+	}{
+		{"1/3 + 1/6", "1/2", true},
+		{"0.1 + 0.2", "3/10", true},
+		{"2^-2", "1/4", true},
+		{"8^(2/3)", "4", true},
+		{"(-8)^(1/3)", "-2", true},
+		{"10^30 + 1", "1000000000000000000000000000001", true},
+		{"floor(7/2)", "3", true},
+		{"round(-7/2)", "-4", true},
+		{"abs(-1/3)", "1/3", true},
+		{"factorial(20)", "2432902008176640000", true},
+		{"2^0.5", "6369051672525773/4503599627370496", false},
+	}
+	for _, test := range tests {
+		got, exact, err := EvaluateExact(state, parse(t, test.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.input, err)
+			continue
+		}
+		if got.RatString() != test.want || exact != test.exact {
+			t.Errorf("%s: got %s exact %v, want %s exact %v", test.input, got.RatString(), exact, test.want, test.exact)
+		}
+	}
+}
@@ -1,33 +1,77 @@
 package interpreter
 
 import (
-	"errors"
 	"lambdacalc/shared"
 	"math"
-
-	"github.com/i582/cfmt/cmd/cfmt"
 )
 
-func Evaluate(node *shared.Node, silent bool) (float64, error) {
+// Maximum depth of nested function calls and variables, prevents endless recursion.
+const MAX_CALL_DEPTH = 256
+
+// Local variables of a function call, mapping parameter names to their values.
+type scope map[string]float64
+
+func Evaluate(s *shared.State, node *shared.Node) (float64, error) {
+	// Matrices are allowed, as long as the result is a number. i.e.: det(A)
+	if s.ContainsMatrix(node) {
+		val, err := EvaluateValue(s, node)
+		if err != nil {
+			return 0, err
+		}
+		if val.Matrix != nil {
+			return 0, shared.NewError("not a number", "Unable to calculate output, expected a number but got a %s.", describe(val))
+		}
+		return val.Number, nil
+	}
+	// Units are allowed, as long as they cancel out. i.e.: 1 km / 1 m
+	if s.ContainsUnit(node) {
+		val, err := EvaluateQuantity(s, node)
+		if err != nil {
+			return 0, err
+		}
+		if !val.Dimension.IsZero() {
+			return 0, shared.NewError("not a number", "Unable to calculate output, expected a number but got a value in '%s'.", val.Dimension)
+		}
+		return val.Value, nil
+	}
+	return evaluate(s, node, nil, 0)
+}
+
+// Evaluate a tree with values for some of its variables, they shadow defined variables.
+// x^2 with x = 3 -> 9
+func EvaluateWith(s *shared.State, node *shared.Node, values map[string]float64) (float64, error) {
+	// Numeric methods evaluate a tree many times, they stop with the statement.
+	if err := s.Canceled(); err != nil {
+		return 0, err
+	}
+	return evaluate(s, node, scope(values), 0)
+}
+
+func evaluate(s *shared.State, node *shared.Node, local scope, depth int) (float64, error) {
 	switch node.OperationType {
 	case shared.NUMBER:
 		return node.Value, nil
 
 	case shared.VARIABLE:
-		if val, ok := shared.Variables[node.Variable]; ok {
-			a, err := Evaluate(&val, silent)
-			if err != nil {
+		// Parameters shadow global variables.
+		if val, ok := local[node.Variable]; ok {
+			return val, nil
+		}
+		if val, ok := s.Variables[node.Variable]; ok {
+			if err := enterVariable(node, depth); err != nil {
 				return 0, err
 			}
-			return a, nil
+			return evaluate(s, &val, nil, depth+1)
+		} else if val, ok := s.Conf.Constants[node.Variable]; ok {
+			// Constants are only kept as variables in exact mode.
+			return val, nil
 		} else {
-			cfmt.Printf("{{Error:}}::red|bold Unable to calculate output, undefined variable '%s'.\n", node.Variable)
-			return 0, errors.New("undefined variable")
+			return 0, shared.NewError("undefined variable", "Unable to calculate output, undefined variable '%s'.", node.Variable)
 		}
 	case shared.PLUS:
 		a := 0.0
 		for _, val := range node.Associative {
-			b, err := Evaluate(val, silent)
+			b, err := evaluate(s, val, local, depth)
 			if err != nil {
 				return 0, err
 			}
@@ -35,11 +79,11 @@ func Evaluate(node *shared.Node, silent bool) (float64, error) {
 		}
 		return a, nil
 	case shared.MINUS:
-		a, err := Evaluate(node.LNode, silent)
+		a, err := evaluate(s, node.LNode, local, depth)
 		if err != nil {
 			return 0, err
 		}
-		b, err := Evaluate(node.RNode, silent)
+		b, err := evaluate(s, node.RNode, local, depth)
 		if err != nil {
 			return 0, err
 		}
@@ -47,7 +91,7 @@ func Evaluate(node *shared.Node, silent bool) (float64, error) {
 	case shared.MULTIPLY:
 		a := 1.0
 		for _, val := range node.Associative {
-			b, err := Evaluate(val, silent)
+			b, err := evaluate(s, val, local, depth)
 			if err != nil {
 				return 0, err
 			}
@@ -55,51 +99,138 @@ func Evaluate(node *shared.Node, silent bool) (float64, error) {
 		}
 		return a, nil
 	case shared.DIVIDE:
-		a, err := Evaluate(node.LNode, silent)
+		a, err := evaluate(s, node.LNode, local, depth)
 		if err != nil {
 			return 0, err
 		}
-		b, err := Evaluate(node.RNode, silent)
+		b, err := evaluate(s, node.RNode, local, depth)
 		if err != nil {
 			return 0, err
 		}
 		if b == 0.0 {
-			if !silent {
-				cfmt.Printf("{{Error:}}::red|bold Unable to calculate output, devision by zero.\n")
-			}
-			return 0, errors.New("divide by 0")
+			return 0, shared.DivisionByZero()
 		}
 		return a / b, nil
 	case shared.POWER:
-		a, err := Evaluate(node.LNode, silent)
+		a, err := evaluate(s, node.LNode, local, depth)
 		if err != nil {
 			return 0, err
 		}
-		b, err := Evaluate(node.RNode, silent)
+		b, err := evaluate(s, node.RNode, local, depth)
 		if err != nil {
 			return 0, err
 		}
+		// A negative power of zero divides by zero. 0^-1 = 1/0
+		if a == 0 && b < 0 {
+			return 0, shared.DivisionByZero()
+		}
 		return math.Pow(a, b), nil
 	case shared.SQRT:
-		a, err := Evaluate(node.LNode, silent)
+		a, err := evaluate(s, node.LNode, local, depth)
 		if err != nil {
 			return 0, err
 		}
-		b, err := Evaluate(node.RNode, silent)
+		b, err := evaluate(s, node.RNode, local, depth)
 		if err != nil {
 			return 0, err
 		}
-		if b <= 0 {
-			if !silent {
-				cfmt.Printf("{{Error:}}::red|bold Unable to calculate output, result has no real solution.\n")
-			}
-			return 0, errors.New("negative sqrt")
-		}
-		return math.Pow(b, (1 / a)), nil
+		return root(a, b)
+	case shared.FUNCTION:
+		return call(s, node, local, depth)
+	case shared.VECTOR, shared.MATRIX:
+		return 0, shared.NewError("not a number", "Unable to calculate output, expected a number but got a matrix.")
+	case shared.UNIT:
+		return 0, shared.NewError("unexpected unit", "Unable to calculate output, unexpected unit '%s'.", node.Variable)
 	default:
-		if !silent {
-			cfmt.Printf("{{Error:}}::red|bold Unable to calculate output, unexpected symbole.\n")
+		return 0, shared.NewError("unexpected error", "Unable to calculate output, unexpected symbole.")
+	}
+}
+
+// Calculates the a-th root of b.
+func root(a, b float64) (float64, error) {
+	if a == 3 {
+		return math.Cbrt(b), nil
+	} else if b < 0 {
+		// Odd roots of negative numbers are real.
+		if math.Mod(a, 2) == 1 || math.Mod(a, 2) == -1 {
+			return -math.Pow(-b, (1 / a)), nil
+		}
+		return 0, shared.NewError("negative sqrt", "Unable to calculate output, result has no real solution.")
+	}
+	return math.Pow(b, (1 / a)), nil
+}
+
+// Evaluate a user defined function by binding the arguments to its parameters.
+// Arguments are evaluated in the scope of the caller.
+func call(s *shared.State, node *shared.Node, local scope, depth int) (float64, error) {
+	if builtin, ok := shared.Builtins[node.Variable]; ok {
+		return callBuiltin(s, node, builtin, local, depth)
+	}
+
+	function, err := lookupFunction(s, node, depth)
+	if err != nil {
+		return 0, err
+	}
+
+	arguments := make(scope)
+	for i, param := range function.Parameters {
+		val, err := evaluate(s, node.Associative[i], local, depth)
+		if err != nil {
+			return 0, err
+		}
+		arguments[param.Variable] = val
+	}
+	return evaluate(s, function.Equation, arguments, depth+1)
+}
+
+// Returns the user defined function a call refers to, shared by all evaluators. Fails if it is
+// undefined, gets the wrong number of parameters, is nested deeper than MAX_CALL_DEPTH or the
+// statement was canceled.
+func lookupFunction(s *shared.State, node *shared.Node, depth int) (shared.Function, error) {
+	function, ok := s.Functions[node.Variable]
+	if !ok {
+		return shared.Function{}, shared.NewError("undefined function", "Unable to calculate output, undefined function '%s'.", node.Variable)
+	}
+	if len(function.Parameters) != len(node.Associative) {
+		return shared.Function{}, shared.NewError("unmatched parameters", "Unable to calculate output, function '%s' expects %v parameters but got %v.", node.Variable, len(function.Parameters), len(node.Associative))
+	}
+	if depth >= MAX_CALL_DEPTH {
+		return shared.Function{}, shared.NewError("maximum call depth exceeded", "Unable to calculate output, function '%s' exceeded the maximum call depth of %v.", node.Variable, MAX_CALL_DEPTH)
+	}
+	if err := s.Canceled(); err != nil {
+		return shared.Function{}, err
+	}
+	return function, nil
+}
+
+// Checks a defined variable before its value is evaluated, shared by all evaluators. Reading a
+// variable counts like a call, so variables defined by each other stop at MAX_CALL_DEPTH.
+// a = b with b = a
+func enterVariable(node *shared.Node, depth int) error {
+	if depth >= MAX_CALL_DEPTH {
+		return shared.NewError("maximum call depth exceeded", "Unable to calculate output, variable '%s' exceeded the maximum call depth of %v.", node.Variable, MAX_CALL_DEPTH)
+	}
+	return nil
+}
+
+// Evaluate a built-in function with the evaluated parameters.
+func callBuiltin(s *shared.State, node *shared.Node, builtin shared.Builtin, local scope, depth int) (float64, error) {
+	if !builtin.Accepts(len(node.Associative)) {
+		return 0, shared.NewError("unmatched parameters", "Unable to calculate output, function '%s' does not accept %v parameters.", node.Variable, len(node.Associative))
+	}
+
+	params := []float64{}
+	for _, val := range node.Associative {
+		a, err := evaluate(s, val, local, depth)
+		if err != nil {
+			return 0, err
 		}
-		return 0, errors.New("unexpected error")
+		params = append(params, a)
+	}
+
+	res, err := builtin.Evaluate(params)
+	if err != nil {
+		return 0, shared.NewError(err.Error(), "Unable to calculate output, %v in '%s'.", err, node.Variable)
 	}
+	return res, nil
 }
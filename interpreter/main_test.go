@@ -0,0 +1,192 @@
+package interpreter
+
+import (
+	"lambdacalc/lexer"
+	"lambdacalc/parser"
+	"lambdacalc/shared"
+	"math"
+	"testing"
+)
+
+// State of the tests, with the default config. Tests defining names remove them again.
+var state = shared.NewState(shared.GetDefualtConfig())
+
+// Parses an expression for a test.
+func parse(t *testing.T, input string) *shared.Node {
+	t.Helper()
+	lexed, err := lexer.LexTokens(state, input)
+	if err != nil {
+		t.Fatalf("%s: unable to lex, %v", input, err)
+	}
+	parsed, err := parser.Parse(state, lexed)
+	if err != nil {
+		t.Fatalf("%s: unable to parse, %v", input, err)
+	}
+	return parsed
+}
+
+// Defines a function for a test, it is removed once the test is done.
+func define(t *testing.T, name string, parameters []string, equation string) {
+	t.Helper()
+	function := shared.Function{Parameters: []*shared.Node{}, Equation: parse(t, equation)}
+	for _, val := range parameters {
+		function.Parameters = append(function.Parameters, shared.VariableNode(val))
+	}
+	state.Functions[name] = function
+	t.Cleanup(func() { delete(state.Functions, name) })
+}
+
+// Every evaluator, only returning the error.
+var evaluators = map[string]func(node *shared.Node) error{
+	"float": func(node *shared.Node) error {
+		_, err := evaluate(state, node, nil, 0)
+		return err
+	},
+	"exact": func(node *shared.Node) error {
+		_, _, err := EvaluateExact(state, node)
+		return err
+	},
+	"precise": func(node *shared.Node) error {
+		_, err := EvaluatePrecise(state, node, 30)
+		return err
+	},
+	"complex": func(node *shared.Node) error {
+		_, err := EvaluateComplex(state, node)
+		return err
+	},
+	"matrix": func(node *shared.Node) error {
+		_, err := EvaluateValue(state, node)
+		return err
+	},
+	"units": func(node *shared.Node) error {
+		_, err := EvaluateQuantity(state, node)
+		return err
+	},
+}
+
+// User defined functions are called with their own parameters, which shadow global variables.
+func TestFunctions(t *testing.T) {
+	define(t, "f", []string{"x"}, "x^2 + 1")
+	define(t, "g", []string{"x", "y"}, "f(x) * y")
+	define(t, "h", []string{"y"}, "x + y")
+	state.Variables["x"] = *shared.NumberNode(10.0)
+	defer delete(state.Variables, "x")
+
+	tests := []struct {
+		input string
+		want  float64
+	}{
+		{"f(2)", 5},
+		{"f(f(1))", 5},
+		{"g(2, 3)", 15},
+		{"g(x, 1)", 101},
+		{"h(1)", 11},
+		{"f(x) + x", 111},
+	}
+	for _, test := range tests {
+		got, err := Evaluate(state, parse(t, test.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.input, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: got %v, want %v", test.input, got, test.want)
+		}
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+	}{
+		{"sin(0)", 0},
+		{"cos(pi)", -1},
+		{"ln(e)", 1},
+		{"log(100)", 2},
+		{"log(8, 2)", 3},
+		{"log2(8)", 3},
+		{"exp(0)", 1},
+		{"abs(-3)", 3},
+		{"floor(2.5)", 2},
+		{"ceil(2.5)", 3},
+		{"round(-2.5)", -3},
+		{"min(3, 1, 2)", 1},
+		{"max(3, 1, 2)", 3},
+		{"sqrt(16)", 4},
+		{"factorial(5)", 120},
+		{"gcd(12, 18)", 6},
+		{"lcm(4, 6)", 12},
+	}
+	for _, test := range tests {
+		got, err := Evaluate(state, parse(t, test.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.input, err)
+			continue
+		}
+		if math.Abs(got-test.want) > 1e-12 {
+			t.Errorf("%s: got %v, want %v", test.input, got, test.want)
+		}
+	}
+}
+
+// Parameters outside of the domain of a function are an error instead of NaN.
+func TestBuiltinDomains(t *testing.T) {
+	for _, input := range []string{"asin(2)", "ln(0)", "ln(-1)", "sqrt(-1)", "factorial(-1)"} {
+		if got, err := Evaluate(state, parse(t, input)); err == nil {
+			t.Errorf("%s: got %v, want an error", input, got)
+		}
+	}
+}
+
+// Calls of user defined functions fail the same way in every evaluator.
+func TestCallErrors(t *testing.T) {
+	define(t, "f", []string{"x"}, "x + 1")
+	define(t, "g", []string{"x"}, "g(x) + 1")
+
+	// The parser already rejects f(1, 2), so the call is built directly.
+	tests := map[string]struct {
+		node *shared.Node
+		want string
+	}{
+		"h(1)":    {parse(t, "h(1)"), "undefined function"},
+		"f(1, 2)": {shared.FunctionNode("f", shared.NumberNode(1), shared.NumberNode(2)), "unmatched parameters"},
+		"g(1)":    {parse(t, "g(1)"), "maximum call depth exceeded"},
+	}
+	for name, evaluate := range evaluators {
+		for input, test := range tests {
+			if err := evaluate(test.node); err == nil || shared.ErrorCode(err) != test.want {
+				t.Errorf("%s %s: got %v, want %s", name, input, err, test.want)
+			}
+		}
+	}
+}
+
+// Variables defined by each other stop at the maximum depth in every evaluator, instead of
+// overflowing the stack.
+func TestRecursiveVariables(t *testing.T) {
+	state.Variables["a"] = *shared.VariableNode("b")
+	state.Variables["b"] = *shared.VariableNode("a")
+	defer delete(state.Variables, "a")
+	defer delete(state.Variables, "b")
+
+	for name, evaluate := range evaluators {
+		if err := evaluate(shared.VariableNode("a")); err == nil || shared.ErrorCode(err) != "maximum call depth exceeded" {
+			t.Errorf("%s: got %v, want maximum call depth exceeded", name, err)
+		}
+	}
+}
+
+// A division by zero has the same message in every evaluator.
+// The parser writes a/b as a * b^-1, so the negative power of zero is the division.
+func TestDivisionByZero(t *testing.T) {
+	for _, input := range []string{"1/0", "0/0", "0^-1", "1 / (2 - 2)"} {
+		node := parse(t, input)
+		for name, evaluate := range evaluators {
+			err := evaluate(node)
+			if err == nil || shared.ErrorCode(err) != "divide by 0" || err.Error() != "Unable to calculate output, division by zero." {
+				t.Errorf("%s %s: got %v, want divide by 0", name, input, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,530 @@
+package interpreter
+
+import (
+	"lambdacalc/shared"
+	"math"
+	"math/big"
+)
+
+// Result of a calculation with matrices, numbers have no matrix.
+type Value struct {
+	Number float64
+	Matrix *shared.Matrix
+}
+
+// Local variables of a function call, that may hold matrices.
+type valueScope map[string]Value
+
+// Evaluate a tree, whose result may be a matrix or a vector.
+// [[1, 2], [3, 4]] * [1, 1] -> [3, 7]
+func EvaluateValue(s *shared.State, node *shared.Node) (Value, error) {
+	return evaluateValue(s, node, nil, 0)
+}
+
+// Describes a value for error messages. i.e.: number, 2x2 matrix
+func describe(v Value) string {
+	if v.Matrix == nil {
+		return "number"
+	}
+	return v.Matrix.Dimension()
+}
+
+func mismatch(action string, a, b Value) error {
+	return shared.NewError("dimension mismatch", "Unable to calculate output, dimension mismatch, can not %s a %s and a %s.", action, describe(a), describe(b))
+}
+
+func evaluateValue(s *shared.State, node *shared.Node, local valueScope, depth int) (Value, error) {
+	switch node.OperationType {
+	case shared.NUMBER:
+		return Value{Number: node.Value, Matrix: nil}, nil
+	case shared.VARIABLE:
+		// Parameters shadow global variables.
+		if val, ok := local[node.Variable]; ok {
+			return val, nil
+		}
+		if val, ok := s.Variables[node.Variable]; ok {
+			if err := enterVariable(node, depth); err != nil {
+				return Value{}, err
+			}
+			return evaluateValue(s, &val, nil, depth+1)
+		} else if val, ok := s.Conf.Constants[node.Variable]; ok {
+			return Value{Number: val, Matrix: nil}, nil
+		}
+		return Value{}, shared.NewError("undefined variable", "Unable to calculate output, undefined variable '%s'.", node.Variable)
+	case shared.PLUS:
+		res := Value{Number: 0.0, Matrix: nil}
+		for i, val := range node.Associative {
+			b, err := evaluateValue(s, val, local, depth)
+			if err != nil {
+				return Value{}, err
+			}
+			if i == 0 {
+				res = b
+				continue
+			}
+			res, err = addValues(res, b, 1)
+			if err != nil {
+				return Value{}, err
+			}
+		}
+		return res, nil
+	case shared.MINUS:
+		b, err := evaluateValue(s, node.RNode, local, depth)
+		if err != nil {
+			return Value{}, err
+		}
+		// -A is parsed as 0 - A
+		if node.LNode.OperationType == shared.NUMBER && node.LNode.Value == 0 {
+			return scaleValue(b, -1), nil
+		}
+		a, err := evaluateValue(s, node.LNode, local, depth)
+		if err != nil {
+			return Value{}, err
+		}
+		return addValues(a, b, -1)
+	case shared.MULTIPLY:
+		res := Value{Number: 1.0, Matrix: nil}
+		for i, val := range node.Associative {
+			b, err := evaluateValue(s, val, local, depth)
+			if err != nil {
+				return Value{}, err
+			}
+			if i == 0 {
+				res = b
+				continue
+			}
+			res, err = multiplyValues(res, b)
+			if err != nil {
+				return Value{}, err
+			}
+		}
+		return res, nil
+	case shared.DIVIDE:
+		a, err := evaluateValue(s, node.LNode, local, depth)
+		if err != nil {
+			return Value{}, err
+		}
+		b, err := evaluateValue(s, node.RNode, local, depth)
+		if err != nil {
+			return Value{}, err
+		}
+		if b.Matrix == nil {
+			if b.Number == 0 {
+				return Value{}, shared.DivisionByZero()
+			}
+			return scaleValue(a, 1/b.Number), nil
+		}
+		inverse, err := powerValue(b, Value{Number: -1.0, Matrix: nil})
+		if err != nil {
+			return Value{}, err
+		}
+		return multiplyValues(a, inverse)
+	case shared.POWER:
+		a, err := evaluateValue(s, node.LNode, local, depth)
+		if err != nil {
+			return Value{}, err
+		}
+		b, err := evaluateValue(s, node.RNode, local, depth)
+		if err != nil {
+			return Value{}, err
+		}
+		return powerValue(a, b)
+	case shared.SQRT:
+		a, err := evaluateValue(s, node.LNode, local, depth)
+		if err != nil {
+			return Value{}, err
+		}
+		b, err := evaluateValue(s, node.RNode, local, depth)
+		if err != nil {
+			return Value{}, err
+		}
+		if a.Matrix != nil || b.Matrix != nil {
+			return Value{}, shared.NewError("root of a matrix", "Unable to calculate output, the root of a %s is not defined.", describe(b))
+		}
+		res, err := root(a.Number, b.Number)
+		return Value{Number: res, Matrix: nil}, err
+	case shared.FUNCTION:
+		return callValue(s, node, local, depth)
+	case shared.VECTOR:
+		m := shared.NewMatrix(len(node.Associative), 1)
+		m.Vector = true
+		for i, val := range node.Associative {
+			a, err := evaluateElement(s, val, local, depth)
+			if err != nil {
+				return Value{}, err
+			}
+			m.Values[i][0] = a
+		}
+		return Value{Number: 0.0, Matrix: m}, nil
+	case shared.MATRIX:
+		m := shared.NewMatrix(len(node.Associative), len(node.Associative[0].Associative))
+		for i, row := range node.Associative {
+			for j, val := range row.Associative {
+				a, err := evaluateElement(s, val, local, depth)
+				if err != nil {
+					return Value{}, err
+				}
+				m.Values[i][j] = a
+			}
+		}
+		return Value{Number: 0.0, Matrix: m}, nil
+	default:
+		return Value{}, shared.NewError("unexpected error", "Unable to calculate output, unexpected symbole.")
+	}
+}
+
+// Evaluates an element of a matrix or a vector, which has to be a number.
+func evaluateElement(s *shared.State, node *shared.Node, local valueScope, depth int) (float64, error) {
+	a, err := evaluateValue(s, node, local, depth)
+	if err != nil {
+		return 0, err
+	}
+	if a.Matrix != nil {
+		return 0, shared.NewError("nested matrix", "Unable to calculate output, the elements of a matrix have to be numbers, got a %s.", describe(a))
+	}
+	return a.Number, nil
+}
+
+// Adds or subtracts two numbers or two matrices of the same dimension element-wise.
+func addValues(a, b Value, sign float64) (Value, error) {
+	if a.Matrix == nil && b.Matrix == nil {
+		return Value{Number: a.Number + sign*b.Number, Matrix: nil}, nil
+	}
+	if a.Matrix == nil || b.Matrix == nil || a.Matrix.Rows() != b.Matrix.Rows() || a.Matrix.Columns() != b.Matrix.Columns() {
+		if sign < 0 {
+			return Value{}, mismatch("subtract", a, b)
+		}
+		return Value{}, mismatch("add", a, b)
+	}
+
+	res := shared.NewMatrix(a.Matrix.Rows(), a.Matrix.Columns())
+	res.Vector = a.Matrix.Vector && b.Matrix.Vector
+	for i, row := range a.Matrix.Values {
+		for j, val := range row {
+			res.Values[i][j] = val + sign*b.Matrix.Values[i][j]
+		}
+	}
+	return Value{Number: 0.0, Matrix: res}, nil
+}
+
+// Multiplies every element by a number.
+func scaleValue(a Value, factor float64) Value {
+	if a.Matrix == nil {
+		return Value{Number: a.Number * factor, Matrix: nil}
+	}
+	res := shared.NewMatrix(a.Matrix.Rows(), a.Matrix.Columns())
+	res.Vector = a.Matrix.Vector
+	for i, row := range a.Matrix.Values {
+		for j, val := range row {
+			// Adding 0 turns -0 into 0.
+			res.Values[i][j] = val*factor + 0
+		}
+	}
+	return Value{Number: 0.0, Matrix: res}
+}
+
+// Multiplies numbers, scales matrices or calculates the matrix product. A vector on the
+// right is a column, a vector on the left a row. Two vectors need dot or cross.
+func multiplyValues(a, b Value) (Value, error) {
+	switch {
+	case a.Matrix == nil:
+		return scaleValue(b, a.Number), nil
+	case b.Matrix == nil:
+		return scaleValue(a, b.Number), nil
+	case a.Matrix.Vector && b.Matrix.Vector:
+		return Value{}, shared.NewError("vector product", "Unable to calculate output, vectors are multiplied with dot or cross.")
+	}
+
+	left := a.Matrix
+	if left.Vector {
+		left = transpose(left)
+	}
+	if left.Columns() != b.Matrix.Rows() {
+		return Value{}, mismatch("multiply", a, b)
+	}
+
+	res := product(left, b.Matrix)
+	if a.Matrix.Vector {
+		res = transpose(res)
+		res.Vector = true
+	} else if b.Matrix.Vector {
+		res.Vector = true
+	}
+	return Value{Number: 0.0, Matrix: res}, nil
+}
+
+// Raises a number to a power or a square matrix to an integer power, -1 is the inverse.
+func powerValue(a, b Value) (Value, error) {
+	if b.Matrix != nil {
+		return Value{}, shared.NewError("matrix exponent", "Unable to calculate output, the exponent has to be a number, got a %s.", describe(b))
+	}
+	if a.Matrix == nil {
+		if a.Number == 0 && b.Number < 0 {
+			return Value{}, shared.DivisionByZero()
+		}
+		return Value{Number: math.Pow(a.Number, b.Number), Matrix: nil}, nil
+	}
+	if a.Matrix.Vector || a.Matrix.Rows() != a.Matrix.Columns() {
+		return Value{}, shared.NewError("not a square matrix", "Unable to calculate output, only square matrices have powers, got a %s.", describe(a))
+	}
+	if b.Number != math.Trunc(b.Number) || math.IsInf(b.Number, 0) {
+		return Value{}, shared.NewError("non integer matrix power", "Unable to calculate output, matrices can only be raised to integer powers.")
+	}
+
+	base := a.Matrix
+	n := int(b.Number)
+	if n < 0 {
+		inverse, ok := invert(base)
+		if !ok {
+			return Value{}, shared.NewError("singular matrix", "Unable to calculate output, the matrix is singular and has no inverse.")
+		}
+		base = inverse
+		n = -n
+	}
+
+	// Repeated squaring.
+	res := identity(base.Rows())
+	for n > 0 {
+		if n%2 == 1 {
+			res = product(res, base)
+		}
+		base = product(base, base)
+		n /= 2
+	}
+	return Value{Number: 0.0, Matrix: res}, nil
+}
+
+// Evaluate a function call with matrix values. Matrix functions take matrices and vectors,
+// other built-in functions only numbers, user defined functions any value.
+func callValue(s *shared.State, node *shared.Node, local valueScope, depth int) (Value, error) {
+	params := []Value{}
+	for _, val := range node.Associative {
+		a, err := evaluateValue(s, val, local, depth)
+		if err != nil {
+			return Value{}, err
+		}
+		params = append(params, a)
+	}
+
+	if _, ok := shared.MatrixBuiltins[node.Variable]; ok {
+		return callMatrixBuiltin(node.Variable, params)
+	}
+
+	if builtin, ok := shared.Builtins[node.Variable]; ok {
+		if !builtin.Accepts(len(params)) {
+			return Value{}, shared.NewError("unmatched parameters", "Unable to calculate output, function '%s' does not accept %v parameters.", node.Variable, len(params))
+		}
+		numbers := []float64{}
+		for _, val := range params {
+			if val.Matrix != nil {
+				return Value{}, shared.NewError("matrix parameter", "Unable to calculate output, function '%s' expects numbers but got a %s.", node.Variable, describe(val))
+			}
+			numbers = append(numbers, val.Number)
+		}
+		res, err := builtin.Evaluate(numbers)
+		if err != nil {
+			return Value{}, shared.NewError(err.Error(), "Unable to calculate output, %v in '%s'.", err, node.Variable)
+		}
+		return Value{Number: res, Matrix: nil}, nil
+	}
+
+	function, err := lookupFunction(s, node, depth)
+	if err != nil {
+		return Value{}, err
+	}
+
+	arguments := make(valueScope)
+	for i, param := range function.Parameters {
+		arguments[param.Variable] = params[i]
+	}
+	return evaluateValue(s, function.Equation, arguments, depth+1)
+}
+
+// Evaluate transpose, det, inv, rank, dot and cross.
+func callMatrixBuiltin(name string, params []Value) (Value, error) {
+	for _, val := range params {
+		if val.Matrix == nil {
+			return Value{}, shared.NewError("number parameter", "Unable to calculate output, function '%s' expects a matrix or a vector but got a number.", name)
+		}
+	}
+	m := params[0].Matrix
+
+	switch name {
+	case "transpose":
+		return Value{Number: 0.0, Matrix: transpose(m)}, nil
+	case "rank":
+		return Value{Number: float64(rank(m)), Matrix: nil}, nil
+	case "det", "inv":
+		if m.Vector || m.Rows() != m.Columns() {
+			return Value{}, shared.NewError("not a square matrix", "Unable to calculate output, function '%s' expects a square matrix but got a %s.", name, describe(params[0]))
+		}
+		if name == "det" {
+			return Value{Number: determinant(m), Matrix: nil}, nil
+		}
+		return powerValue(params[0], Value{Number: -1.0, Matrix: nil})
+	}
+
+	// dot and cross take two vectors.
+	a, b := params[0], params[1]
+	if !a.Matrix.Vector || !b.Matrix.Vector || a.Matrix.Rows() != b.Matrix.Rows() || (name == "cross" && a.Matrix.Rows() != 3) {
+		if name == "cross" {
+			return Value{}, shared.NewError("dimension mismatch", "Unable to calculate output, function 'cross' expects two vectors of length 3 but got a %s and a %s.", describe(a), describe(b))
+		}
+		return Value{}, mismatch("take the dot product of", a, b)
+	}
+
+	u, v := a.Matrix.Values, b.Matrix.Values
+	if name == "cross" {
+		res := shared.NewMatrix(3, 1)
+		res.Vector = true
+		res.Values[0][0] = u[1][0]*v[2][0] - u[2][0]*v[1][0]
+		res.Values[1][0] = u[2][0]*v[0][0] - u[0][0]*v[2][0]
+		res.Values[2][0] = u[0][0]*v[1][0] - u[1][0]*v[0][0]
+		return Value{Number: 0.0, Matrix: res}, nil
+	}
+	res := 0.0
+	for i := range u {
+		res += u[i][0] * v[i][0]
+	}
+	return Value{Number: res, Matrix: nil}, nil
+}
+
+// Swaps rows and columns, a vector becomes a matrix with a single row.
+func transpose(m *shared.Matrix) *shared.Matrix {
+	res := shared.NewMatrix(m.Columns(), m.Rows())
+	for i, row := range m.Values {
+		for j, val := range row {
+			res.Values[j][i] = val
+		}
+	}
+	return res
+}
+
+func product(a, b *shared.Matrix) *shared.Matrix {
+	res := shared.NewMatrix(a.Rows(), b.Columns())
+	for i := range res.Values {
+		for j := range res.Values[i] {
+			for k := 0; k < a.Columns(); k++ {
+				res.Values[i][j] += a.Values[i][k] * b.Values[k][j]
+			}
+		}
+	}
+	return res
+}
+
+func identity(n int) *shared.Matrix {
+	res := shared.NewMatrix(n, n)
+	for i := range res.Values {
+		res.Values[i][i] = 1
+	}
+	return res
+}
+
+// Converts the values into rationals, so eliminations are exact and singular matrices are detected
+// without rounding errors. Decimals are read as their shortest fraction. 0.1 -> 1/10
+func rationals(m *shared.Matrix) ([][]*big.Rat, bool) {
+	res := make([][]*big.Rat, m.Rows())
+	for i, row := range m.Values {
+		for _, val := range row {
+			r := shared.Rational(val)
+			if r == nil {
+				return nil, false
+			}
+			res[i] = append(res[i], r)
+		}
+	}
+	return res, true
+}
+
+// Brings the values into row echelon form and returns the number of pivots. With invert the
+// pivot rows are normalized and the columns are cleared above the pivots as well.
+// The sign is -1, if an odd number of rows was swapped.
+func eliminate(values [][]*big.Rat, columns int, invert bool) (int, int) {
+	sign := 1
+	r := 0
+	for j := 0; j < columns && r < len(values); j++ {
+		i := r
+		for i < len(values) && values[i][j].Sign() == 0 {
+			i++
+		}
+		if i == len(values) {
+			continue
+		}
+		if i != r {
+			values[r], values[i] = values[i], values[r]
+			sign = -sign
+		}
+
+		if invert {
+			inverse := new(big.Rat).Inv(values[r][j])
+			for k := range values[r] {
+				values[r][k].Mul(values[r][k], inverse)
+			}
+		}
+		for i := range values {
+			if i == r || (i < r && !invert) || values[i][j].Sign() == 0 {
+				continue
+			}
+			factor := new(big.Rat).Quo(values[i][j], values[r][j])
+			for k := range values[i] {
+				values[i][k].Sub(values[i][k], new(big.Rat).Mul(factor, values[r][k]))
+			}
+		}
+		r++
+	}
+	return r, sign
+}
+
+// Determinant as the product of the pivots of the row echelon form.
+func determinant(m *shared.Matrix) float64 {
+	values, ok := rationals(m)
+	if !ok {
+		return math.NaN()
+	}
+	pivots, sign := eliminate(values, m.Columns(), false)
+	if pivots < m.Rows() {
+		return 0
+	}
+	res := big.NewRat(int64(sign), 1)
+	for i := range values {
+		res.Mul(res, values[i][i])
+	}
+	f, _ := res.Float64()
+	return f
+}
+
+// Inverse with Gauss-Jordan elimination of [m | I]. Reports false, if the matrix is singular.
+func invert(m *shared.Matrix) (*shared.Matrix, bool) {
+	n := m.Rows()
+	values, ok := rationals(m)
+	if !ok {
+		return nil, false
+	}
+	for i := range values {
+		for j := 0; j < n; j++ {
+			values[i] = append(values[i], new(big.Rat))
+		}
+		values[i][n+i].SetInt64(1)
+	}
+	if pivots, _ := eliminate(values, n, true); pivots < n {
+		return nil, false
+	}
+
+	res := shared.NewMatrix(n, n)
+	for i := range values {
+		for j := range res.Values[i] {
+			res.Values[i][j], _ = values[i][n+j].Float64()
+		}
+	}
+	return res, true
+}
+
+// Number of linearly independent rows.
+func rank(m *shared.Matrix) int {
+	values, ok := rationals(m)
+	if !ok {
+		return 0
+	}
+	pivots, _ := eliminate(values, m.Columns(), false)
+	return pivots
+}
@@ -0,0 +1,59 @@
+package interpreter
+
+import (
+	"fmt"
+	"lambdacalc/shared"
+	"testing"
+)
+
+func TestEvaluateValue(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"[1, 2] + [3, 4]", "[4, 6]"},
+		{"2 * [1, 2]", "[2, 4]"},
+		{"[[1, 2], [3, 4]] * [1, 1]", "[3, 7]"},
+		{"[[1, 2], [3, 4]]^2", "[[7, 10], [15, 22]]"},
+		{"[[1, 2], [3, 4]]^-1", "[[-2, 1], [1.5, -0.5]]"},
+		{"inv([[1, 2], [3, 4]])", "[[-2, 1], [1.5, -0.5]]"},
+		{"transpose([[1, 2], [3, 4]])", "[[1, 3], [2, 4]]"},
+		{"cross([1, 0, 0], [0, 1, 0])", "[0, 0, 1]"},
+		{"det([[1, 2], [3, 4]])", "-2"},
+		{"dot([1, 2, 3], [4, 5, 6])", "32"},
+		{"rank([[1, 2], [2, 4]])", "1"},
+	}
+	for _, test := range tests {
+		got, err := EvaluateValue(state, parse(t, test.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.input, err)
+			continue
+		}
+		res := fmt.Sprint(got.Number)
+		if got.Matrix != nil {
+			res = shared.PrintATree(shared.MatrixNode(got.Matrix))
+		}
+		if res != test.want {
+			t.Errorf("%s: got %s, want %s", test.input, res, test.want)
+		}
+	}
+}
+
+func TestEvaluateValueErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"[1, 2] + [1, 2, 3]", "Unable to calculate output, dimension mismatch, can not add a vector of length 2 and a vector of length 3."},
+		{"[1, 2] * [3, 4]", "Unable to calculate output, vectors are multiplied with dot or cross."},
+		{"inv([[1, 2], [2, 4]])", "Unable to calculate output, the matrix is singular and has no inverse."},
+		{"det([1, 2])", "Unable to calculate output, function 'det' expects a square matrix but got a vector of length 2."},
+		{"[1, 2] / 0", "Unable to calculate output, division by zero."},
+		{"[0, 1] * 0^-1", "Unable to calculate output, division by zero."},
+	}
+	for _, test := range tests {
+		if _, err := EvaluateValue(state, parse(t, test.input)); err == nil || err.Error() != test.want {
+			t.Errorf("%s: got %v, want %s", test.input, err, test.want)
+		}
+	}
+}
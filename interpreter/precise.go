@@ -0,0 +1,211 @@
+package interpreter
+
+import (
+	"lambdacalc/shared"
+	"math"
+	"math/big"
+)
+
+// Local variables of a function call with a higher precision.
+type preciseScope map[string]*big.Float
+
+// State of an evaluation with a higher precision, prec is the precision in bits.
+type preciseEvaluation struct {
+	state *shared.State
+	prec  uint
+}
+
+// Constants that are calculated to the requested precision instead of using the config value.
+var preciseConstants = map[string]func(prec uint) *big.Float{
+	"pi":  bigPi,
+	"phi": bigPhi,
+	"e": func(prec uint) *big.Float {
+		res, _ := bigExp(bigInt(1, prec), prec)
+		return res
+	},
+}
+
+// Returns the number of bits needed for the given number of significant decimal digits.
+func PrecisionBits(digits int) uint {
+	return uint(math.Ceil(float64(digits) * math.Log2(10)))
+}
+
+// Evaluate a tree with big.Float to the given number of significant digits.
+// Numbers are read as the decimal they are written as and constants like pi are calculated.
+// 1/3 -> 0.3333333333333333333333333333333333333333333333333...
+func EvaluatePrecise(s *shared.State, node *shared.Node, digits int) (*big.Float, error) {
+	e := &preciseEvaluation{
+		state: s,
+		prec:  PrecisionBits(digits) + GUARD_BITS,
+	}
+	return e.evaluate(node, nil, 0)
+}
+
+func (e *preciseEvaluation) evaluate(node *shared.Node, local preciseScope, depth int) (*big.Float, error) {
+	switch node.OperationType {
+	case shared.NUMBER:
+		if node.Rational != nil {
+			return newFloat(e.prec).SetRat(node.Rational), nil
+		}
+		res, err := bigNumber(node.Value, e.prec)
+		if err != nil {
+			return nil, shared.NewError(err.Error(), "Unable to calculate output, result is not a finite number.")
+		}
+		return res, nil
+	case shared.VARIABLE:
+		// Parameters shadow global variables.
+		if val, ok := local[node.Variable]; ok {
+			return val, nil
+		}
+		if val, ok := e.state.Variables[node.Variable]; ok {
+			if err := enterVariable(node, depth); err != nil {
+				return nil, err
+			}
+			return e.evaluate(&val, nil, depth+1)
+		} else if constant, ok := preciseConstants[node.Variable]; ok {
+			return constant(e.prec), nil
+		} else if val, ok := e.state.Conf.Constants[node.Variable]; ok {
+			// Other constants are as precise as the config.
+			return bigNumber(val, e.prec)
+		}
+		return nil, shared.NewError("undefined variable", "Unable to calculate output, undefined variable '%s'.", node.Variable)
+	case shared.PLUS:
+		a := newFloat(e.prec)
+		for _, val := range node.Associative {
+			b, err := e.evaluate(val, local, depth)
+			if err != nil {
+				return nil, err
+			}
+			a.Add(a, b)
+		}
+		return a, nil
+	case shared.MINUS:
+		a, err := e.evaluate(node.LNode, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		b, err := e.evaluate(node.RNode, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		return newFloat(e.prec).Sub(a, b), nil
+	case shared.MULTIPLY:
+		a := bigInt(1, e.prec)
+		for _, val := range node.Associative {
+			b, err := e.evaluate(val, local, depth)
+			if err != nil {
+				return nil, err
+			}
+			a.Mul(a, b)
+		}
+		return a, nil
+	case shared.DIVIDE:
+		a, err := e.evaluate(node.LNode, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		b, err := e.evaluate(node.RNode, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		if b.Sign() == 0 {
+			return nil, shared.DivisionByZero()
+		}
+		return newFloat(e.prec).Quo(a, b), nil
+	case shared.POWER:
+		a, err := e.evaluate(node.LNode, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		b, err := e.evaluate(node.RNode, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		res, err := bigPow(a, b, e.prec)
+		if err != nil && err.Error() == "divide by 0" {
+			return nil, shared.DivisionByZero()
+		} else if err != nil {
+			return nil, shared.NewError(err.Error(), "Unable to calculate output, %v.", err)
+		}
+		return res, nil
+	case shared.SQRT:
+		a, err := e.evaluate(node.LNode, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		b, err := e.evaluate(node.RNode, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		res, err := bigRoot(a, b, e.prec)
+		if err != nil {
+			return nil, shared.NewError(err.Error(), "Unable to calculate output, result has no real solution.")
+		}
+		return res, nil
+	case shared.FUNCTION:
+		return e.call(node, local, depth)
+	default:
+		return nil, shared.NewError("unexpected error", "Unable to calculate output, unexpected symbole.")
+	}
+}
+
+// Evaluate a user defined function by binding the arguments to its parameters.
+func (e *preciseEvaluation) call(node *shared.Node, local preciseScope, depth int) (*big.Float, error) {
+	if builtin, ok := shared.Builtins[node.Variable]; ok {
+		return e.callBuiltin(node, builtin, local, depth)
+	}
+
+	function, err := lookupFunction(e.state, node, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	arguments := make(preciseScope)
+	for i, param := range function.Parameters {
+		val, err := e.evaluate(node.Associative[i], local, depth)
+		if err != nil {
+			return nil, err
+		}
+		arguments[param.Variable] = val
+	}
+	return e.evaluate(function.Equation, arguments, depth+1)
+}
+
+// Evaluate a built-in function, functions without a precise version fall back to float64.
+func (e *preciseEvaluation) callBuiltin(node *shared.Node, builtin shared.Builtin, local preciseScope, depth int) (*big.Float, error) {
+	if !builtin.Accepts(len(node.Associative)) {
+		return nil, shared.NewError("unmatched parameters", "Unable to calculate output, function '%s' does not accept %v parameters.", node.Variable, len(node.Associative))
+	}
+
+	params := []*big.Float{}
+	for _, val := range node.Associative {
+		a, err := e.evaluate(val, local, depth)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, a)
+	}
+
+	if precise, ok := preciseBuiltins[node.Variable]; ok {
+		res, err := precise(params, e.prec)
+		if err != nil {
+			return nil, shared.NewError(err.Error(), "Unable to calculate output, %v in '%s'.", err, node.Variable)
+		}
+		return res, nil
+	}
+
+	floats := []float64{}
+	for _, val := range params {
+		f, _ := val.Float64()
+		floats = append(floats, f)
+	}
+	res, err := builtin.Evaluate(floats)
+	if err != nil {
+		return nil, shared.NewError(err.Error(), "Unable to calculate output, %v in '%s'.", err, node.Variable)
+	}
+	val, err := bigNumber(res, e.prec)
+	if err != nil {
+		return nil, shared.NewError(err.Error(), "Unable to calculate output, result is not a finite number.")
+	}
+	return val, nil
+}
@@ -0,0 +1,37 @@
+package interpreter
+
+import (
+	"testing"
+)
+
+// Results keep the requested number of digits, constants and functions included.
+func TestEvaluatePrecise(t *testing.T) {
+	// Constants are only kept as names with a precision, otherwise the lexer writes them as numbers.
+	state.Conf.Settings["precision"] = 30
+	defer delete(state.Conf.Settings, "precision")
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1/3", "0.333333333333333333333333333333"},
+		{"0.1 + 0.2", "0.3"},
+		{"2^0.5", "1.41421356237309504880168872421"},
+		{"pi", "3.14159265358979323846264338328"},
+		{"e", "2.71828182845904523536028747135"},
+		{"exp(1)", "2.71828182845904523536028747135"},
+		{"ln(2)", "0.693147180559945309417232121458"},
+		{"sin(1)", "0.84147098480789650665250232163"},
+		{"2^100", "1.26765060022822940149670320538e+30"},
+	}
+	for _, test := range tests {
+		got, err := EvaluatePrecise(state, parse(t, test.input), 30)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.input, err)
+			continue
+		}
+		if res := got.Text('g', 30); res != test.want {
+			t.Errorf("%s: got %s, want %s", test.input, res, test.want)
+		}
+	}
+}
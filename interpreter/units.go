@@ -0,0 +1,211 @@
+package interpreter
+
+import (
+	"lambdacalc/shared"
+	"math"
+)
+
+// Result of a calculation with units, the value is given in SI base units.
+// 3 km -> 3000 [0 1 0 0 0 0 0]
+type Quantity struct {
+	Value     float64
+	Dimension shared.Dimension
+}
+
+// Local variables of a function call, that may have units.
+type quantityScope map[string]Quantity
+
+// Evaluate a tree with units, every value carries its dimension.
+// 12 m/s * 3 min -> 2160 m
+func EvaluateQuantity(s *shared.State, node *shared.Node) (Quantity, error) {
+	return evaluateQuantity(s, node, nil, 0)
+}
+
+func incompatible(action string, a, b Quantity) error {
+	return shared.NewError("incompatible units", "Unable to calculate output, can not %s '%s' and '%s', the units are incompatible.", action, a.Dimension, b.Dimension)
+}
+
+// Multiplies the dimension by a rational exponent, fails if an exponent would not be an integer.
+// m^2, 0.5 -> m
+func scaleDimension(d shared.Dimension, exponent float64) (shared.Dimension, bool) {
+	res := shared.Dimension{}
+	for i, val := range d {
+		scaled := float64(val) * exponent
+		if scaled != math.Trunc(scaled) {
+			return res, false
+		}
+		res[i] = int(scaled)
+	}
+	return res, true
+}
+
+func evaluateQuantity(s *shared.State, node *shared.Node, local quantityScope, depth int) (Quantity, error) {
+	switch node.OperationType {
+	case shared.NUMBER:
+		return Quantity{Value: node.Value, Dimension: shared.Dimension{}}, nil
+	case shared.UNIT:
+		unit, err := s.Conf.ParseUnit(node.Variable)
+		if err != nil {
+			return Quantity{}, shared.NewError(err.Error(), "Unable to calculate output, unknown unit '%s'.", node.Variable)
+		}
+		return Quantity{Value: unit.Factor, Dimension: unit.Dimension}, nil
+	case shared.VARIABLE:
+		// Parameters shadow global variables.
+		if val, ok := local[node.Variable]; ok {
+			return val, nil
+		}
+		if val, ok := s.Variables[node.Variable]; ok {
+			if err := enterVariable(node, depth); err != nil {
+				return Quantity{}, err
+			}
+			return evaluateQuantity(s, &val, nil, depth+1)
+		} else if val, ok := s.Conf.Constants[node.Variable]; ok {
+			return Quantity{Value: val, Dimension: shared.Dimension{}}, nil
+		}
+		return Quantity{}, shared.NewError("undefined variable", "Unable to calculate output, undefined variable '%s'.", node.Variable)
+	case shared.PLUS:
+		res := Quantity{}
+		for i, val := range node.Associative {
+			b, err := evaluateQuantity(s, val, local, depth)
+			if err != nil {
+				return Quantity{}, err
+			}
+			if i > 0 && b.Dimension != res.Dimension {
+				return Quantity{}, incompatible("add", res, b)
+			}
+			res = Quantity{Value: res.Value + b.Value, Dimension: b.Dimension}
+		}
+		return res, nil
+	case shared.MINUS:
+		b, err := evaluateQuantity(s, node.RNode, local, depth)
+		if err != nil {
+			return Quantity{}, err
+		}
+		// -x is parsed as 0 - x
+		if node.LNode.OperationType == shared.NUMBER && node.LNode.Value == 0 {
+			return Quantity{Value: -b.Value, Dimension: b.Dimension}, nil
+		}
+		a, err := evaluateQuantity(s, node.LNode, local, depth)
+		if err != nil {
+			return Quantity{}, err
+		}
+		if a.Dimension != b.Dimension {
+			return Quantity{}, incompatible("subtract", a, b)
+		}
+		return Quantity{Value: a.Value - b.Value, Dimension: a.Dimension}, nil
+	case shared.MULTIPLY:
+		res := Quantity{Value: 1.0, Dimension: shared.Dimension{}}
+		for _, val := range node.Associative {
+			b, err := evaluateQuantity(s, val, local, depth)
+			if err != nil {
+				return Quantity{}, err
+			}
+			res.Value *= b.Value
+			for i := range res.Dimension {
+				res.Dimension[i] += b.Dimension[i]
+			}
+		}
+		return res, nil
+	case shared.DIVIDE:
+		a, err := evaluateQuantity(s, node.LNode, local, depth)
+		if err != nil {
+			return Quantity{}, err
+		}
+		b, err := evaluateQuantity(s, node.RNode, local, depth)
+		if err != nil {
+			return Quantity{}, err
+		}
+		if b.Value == 0.0 {
+			return Quantity{}, shared.DivisionByZero()
+		}
+		res := Quantity{Value: a.Value / b.Value, Dimension: a.Dimension}
+		for i := range res.Dimension {
+			res.Dimension[i] -= b.Dimension[i]
+		}
+		return res, nil
+	case shared.POWER:
+		a, err := evaluateQuantity(s, node.LNode, local, depth)
+		if err != nil {
+			return Quantity{}, err
+		}
+		b, err := evaluateQuantity(s, node.RNode, local, depth)
+		if err != nil {
+			return Quantity{}, err
+		}
+		if !b.Dimension.IsZero() {
+			return Quantity{}, shared.NewError("exponent with unit", "Unable to calculate output, the exponent has to be a number without unit, got '%s'.", b.Dimension)
+		}
+		if a.Value == 0 && b.Value < 0 {
+			return Quantity{}, shared.DivisionByZero()
+		}
+		dimension, ok := scaleDimension(a.Dimension, b.Value)
+		if !ok {
+			return Quantity{}, shared.NewError("fractional unit", "Unable to calculate output, '%s' can not be raised to the power of %v.", a.Dimension, b.Value)
+		}
+		return Quantity{Value: math.Pow(a.Value, b.Value), Dimension: dimension}, nil
+	case shared.SQRT:
+		a, err := evaluateQuantity(s, node.LNode, local, depth)
+		if err != nil {
+			return Quantity{}, err
+		}
+		b, err := evaluateQuantity(s, node.RNode, local, depth)
+		if err != nil {
+			return Quantity{}, err
+		}
+		dimension, ok := scaleDimension(b.Dimension, 1/a.Value)
+		if !a.Dimension.IsZero() || !ok {
+			return Quantity{}, shared.NewError("fractional unit", "Unable to calculate output, the root of '%s' has no unit.", b.Dimension)
+		}
+		res, err := root(a.Value, b.Value)
+		return Quantity{Value: res, Dimension: dimension}, err
+	case shared.FUNCTION:
+		return callQuantity(s, node, local, depth)
+	default:
+		return Quantity{}, shared.NewError("unexpected error", "Unable to calculate output, unexpected symbole.")
+	}
+}
+
+// Evaluate a function call with units. abs, min and max keep the unit of their parameters,
+// other built-in functions only take numbers without unit.
+func callQuantity(s *shared.State, node *shared.Node, local quantityScope, depth int) (Quantity, error) {
+	params := []Quantity{}
+	for _, val := range node.Associative {
+		a, err := evaluateQuantity(s, val, local, depth)
+		if err != nil {
+			return Quantity{}, err
+		}
+		params = append(params, a)
+	}
+
+	if builtin, ok := shared.Builtins[node.Variable]; ok {
+		if !builtin.Accepts(len(params)) {
+			return Quantity{}, shared.NewError("unmatched parameters", "Unable to calculate output, function '%s' does not accept %v parameters.", node.Variable, len(params))
+		}
+
+		keepsUnit := node.Variable == "abs" || node.Variable == "min" || node.Variable == "max"
+		values := []float64{}
+		for _, val := range params {
+			if val.Dimension != params[0].Dimension || (!keepsUnit && !val.Dimension.IsZero()) {
+				return Quantity{}, shared.NewError("incompatible units", "Unable to calculate output, function '%s' does not accept the unit '%s'.", node.Variable, val.Dimension)
+			}
+			values = append(values, val.Value)
+		}
+
+		res, err := builtin.Evaluate(values)
+		if err != nil {
+			return Quantity{}, shared.NewError(err.Error(), "Unable to calculate output, %v in '%s'.", err, node.Variable)
+		}
+		return Quantity{Value: res, Dimension: params[0].Dimension}, nil
+	}
+
+	function, err := lookupFunction(s, node, depth)
+	if err != nil {
+		return Quantity{}, err
+	}
+
+	arguments := make(quantityScope)
+	for i, param := range function.Parameters {
+		arguments[param.Variable] = params[i]
+	}
+	return evaluateQuantity(s, function.Equation, arguments, depth+1)
+}
@@ -0,0 +1,54 @@
+package interpreter
+
+import (
+	"math"
+	"testing"
+)
+
+// Values are calculated in SI base units and keep their dimension.
+func TestEvaluateQuantity(t *testing.T) {
+	tests := []struct {
+		input string
+		value float64
+		unit  string
+	}{
+		{"3 km", 3000, "m"},
+		{"100 cm", 1, "m"},
+		{"5 ft", 1.524, "m"},
+		{"1 h", 3600, "s"},
+		{"1 km + 1 m", 1001, "m"},
+		{"2 m * 3 m", 6, "m^2"},
+		{"(4 m^2)^0.5", 2, "m"},
+		{"12 m/s * 3 min", 2160, "m"},
+		{"1 N / 1 kg", 1, "m/s^2"},
+		{"1 kWh", 3.6e6, "J"},
+	}
+	for _, test := range tests {
+		got, err := EvaluateQuantity(state, parse(t, test.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.input, err)
+			continue
+		}
+		if math.Abs(got.Value-test.value) > 1e-9*math.Abs(test.value) || got.Dimension.String() != test.unit {
+			t.Errorf("%s: got %v %s, want %v %s", test.input, got.Value, got.Dimension, test.value, test.unit)
+		}
+	}
+}
+
+func TestEvaluateQuantityErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1 m + 1 s", "Unable to calculate output, can not add 'm' and 's', the units are incompatible."},
+		{"(2 m)^0.5", "Unable to calculate output, 'm' can not be raised to the power of 0.5."},
+		{"sin(1 m)", "Unable to calculate output, function 'sin' does not accept the unit 'm'."},
+		{"1 m / 0", "Unable to calculate output, division by zero."},
+		{"0 m / (0 s)", "Unable to calculate output, division by zero."},
+	}
+	for _, test := range tests {
+		if _, err := EvaluateQuantity(state, parse(t, test.input)); err == nil || err.Error() != test.want {
+			t.Errorf("%s: got %v, want %s", test.input, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"lambdacalc/engine"
+	"lambdacalc/shared"
+	"math/cmplx"
+	"os"
+	"strings"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+)
+
+// Result of a statement in the JSON output. The trees are only written for calculations.
+type jsonResult struct {
+	Input      string       `json:"input"`
+	Parsed     *shared.Node `json:"parsed,omitempty"`
+	Simplified *shared.Node `json:"simplified,omitempty"`
+	Result     string       `json:"result,omitempty"`
+	Value      *float64     `json:"value,omitempty"`
+	Real       *float64     `json:"real,omitempty"`
+	Imag       *float64     `json:"imag,omitempty"`
+	Error      *jsonError   `json:"error,omitempty"`
+}
+
+// Error in the JSON output, the column starts at 1. Position and hint are left out if they are unknown.
+type jsonError struct {
+	Kind    string       `json:"kind"`
+	Message string       `json:"message"`
+	Column  int          `json:"column,omitempty"`
+	Span    *shared.Span `json:"span,omitempty"`
+	Hint    string       `json:"hint,omitempty"`
+}
+
+// Evaluates a statement into its JSON result.
+// 2x+x with x = 2 -> {"input":"2x+x","parsed":{...},"simplified":{...},"result":"6","value":6}
+func evaluateJSON(cmd string) (jsonResult, error) {
+	res, err := session.Evaluate(cmd)
+	result := jsonResult{
+		Input:      cmd,
+		Parsed:     nil,
+		Simplified: nil,
+		Result:     "",
+		Value:      nil,
+		Real:       nil,
+		Imag:       nil,
+		Error:      nil,
+	}
+	if err != nil {
+		result.Error = newJSONError(err, cmd)
+		return result, err
+	}
+
+	result.Result = strings.TrimSpace(res.Output)
+	result.setCalculation(res.Calculation)
+	return result, nil
+}
+
+// Adds the trees of a calculation, the value is left out if it is no finite number.
+// Complex results are written as their real and imaginary part instead. 2+3i -> "real":2,"imag":3
+func (result *jsonResult) setCalculation(calculation *engine.Calculation) {
+	if calculation == nil {
+		return
+	}
+	result.Parsed = calculation.Parsed
+	result.Simplified = calculation.Simplified
+	if calculation.Value == nil || cmplx.IsInf(*calculation.Value) || cmplx.IsNaN(*calculation.Value) {
+		return
+	}
+	re, im := real(*calculation.Value), imag(*calculation.Value)
+	if im == 0 {
+		result.Value = &re
+	} else {
+		result.Real, result.Imag = &re, &im
+	}
+}
+
+// Errors of the engine keep their kind, position and hint. Other errors are their own kind.
+// The column counts the characters of the input.
+func newJSONError(err error, input string) *jsonError {
+	var engineError *shared.Error
+	if errors.As(err, &engineError) {
+		return &jsonError{Kind: engineError.Code, Message: engineError.Message, Column: engineError.Column(input), Span: engineError.Span, Hint: engineError.Hint}
+	}
+	return &jsonError{Kind: shared.ErrorCode(err), Message: err.Error(), Column: 0, Span: nil, Hint: ""}
+}
+
+// Writes a JSON result as a single line.
+func writeJSON(result jsonResult) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(result); err != nil {
+		cfmt.Fprintf(os.Stderr, "{{Error:}}::red|bold Unable to write JSON, %v.\n", err)
+	}
+}
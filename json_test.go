@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"lambdacalc/engine"
+	"lambdacalc/shared"
+	"strings"
+	"testing"
+)
+
+// JSON results are built from the result and the error of the engine.
+func TestEvaluateJSON(t *testing.T) {
+	session = engine.New(shared.GetDefualtConfig())
+	tests := []struct {
+		input  string
+		result string
+		kind   string
+		span   *shared.Span
+	}{
+		{"2 + 2", "4", "", nil},
+		{"define x = 3", "Variable defined.", "", nil},
+		{"2x + x", "9", "", nil},
+		{"2 + ?", "", "unrecognized character", &shared.Span{Start: 4, End: 5}},
+		{"sin 0", "", "missing parameters", &shared.Span{Start: 0, End: 3}},
+		{"f(2)", "", "undefined function", nil},
+		{"y + 1", "", "undefined variable", nil},
+		{"diff by x", "", "missing expression", nil},
+	}
+	for _, test := range tests {
+		res, err := evaluateJSON(test.input)
+		if res.Input != test.input || res.Result != test.result {
+			t.Errorf("%s: got result %q, want %q", test.input, res.Result, test.result)
+		}
+		if test.kind == "" {
+			if err != nil || res.Error != nil {
+				t.Errorf("%s: unexpected error %v", test.input, err)
+			}
+			continue
+		}
+		if err == nil || res.Error == nil || res.Error.Kind != test.kind || res.Error.Message != err.Error() {
+			t.Errorf("%s: got %+v, want %s", test.input, res.Error, test.kind)
+			continue
+		}
+		if (res.Error.Span == nil) != (test.span == nil) || (test.span != nil && *res.Error.Span != *test.span) {
+			t.Errorf("%s: got span %v, want %v", test.input, res.Error.Span, test.span)
+		}
+	}
+}
+
+// Exact and precise results have an approximated value, complex results a real and imaginary part.
+func TestEvaluateJSONValues(t *testing.T) {
+	value := func(v float64) *float64 { return &v }
+	tests := []struct {
+		option   string
+		settings map[string]int
+		input    string
+		value    *float64
+		real     *float64
+		imag     *float64
+	}{
+		{"exact", nil, "1/4", value(0.25), nil, nil},
+		{"exact", nil, "2", value(2), nil, nil},
+		{"", map[string]int{"precision": 30}, "1/4", value(0.25), nil, nil},
+		{"complex", nil, "2+3i", nil, value(2), value(3)},
+		{"complex", nil, "i^2", value(-1), nil, nil},
+		{"", nil, "[1, 2]", nil, nil, nil},
+		{"", nil, "2 m", nil, nil, nil},
+	}
+	for _, test := range tests {
+		conf := shared.GetDefualtConfig()
+		if test.option != "" {
+			conf.Options[test.option] = true
+		}
+		for name, val := range test.settings {
+			conf.Settings[name] = val
+		}
+		session = engine.New(conf)
+		res, err := evaluateJSON(test.input)
+		if err != nil {
+			t.Errorf("%s %s: unexpected error %v", test.option, test.input, err)
+			continue
+		}
+		for _, val := range []struct {
+			name      string
+			got, want *float64
+		}{{"value", res.Value, test.value}, {"real", res.Real, test.real}, {"imag", res.Imag, test.imag}} {
+			if (val.got == nil) != (val.want == nil) || (val.want != nil && *val.got != *val.want) {
+				t.Errorf("%s %s: got %s %v, want %v", test.option, test.input, val.name, val.got, val.want)
+			}
+		}
+	}
+}
+
+// Errors, that are no errors of the engine, are their own kind.
+func TestNewJSONError(t *testing.T) {
+	res := newJSONError(errors.New("broken pipe"), "2")
+	if res.Kind != "broken pipe" || res.Message != "broken pipe" || res.Span != nil {
+		t.Errorf("got %+v", res)
+	}
+}
+
+// Errors are written with their mark and hint, other errors only with their message.
+func TestReport(t *testing.T) {
+	tests := []struct {
+		err   error
+		input string
+		want  []string
+	}{
+		{shared.NewError("unexpected token", "Unable to parse tokens, unexpected '*'.").At(4, 5), "2 + * 3", []string{"2 + * 3", "    ^ unexpected '*'", "Error: Unable to parse tokens, unexpected '*'."}},
+		{shared.NewError("missing parameters", "Unable to parse tokens, sin expects parameters.").WithHint("write sin(x)"), "sin 0", []string{"Error: Unable to parse tokens, sin expects parameters.", "Hint: write sin(x)."}},
+		{errors.New("broken pipe"), "2", []string{"Error: broken pipe."}},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		report(&buf, test.err, test.input)
+		if got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"); strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+			t.Errorf("%s: got %q, want %q", test.input, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package latex
+
+import (
+	"lambdacalc/shared"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Precedence of the nodes, used to decide where parentheses are needed.
+const (
+	SUM      = iota // a + b
+	PRODUCT  = iota // a * b
+	PREFIX   = iota // -a
+	EXPONENT = iota // a^b
+	ATOM     = iota // a
+)
+
+// Greek letters that are written as commands i.e.: theta -> \theta
+var greekLetters = []string{
+	"alpha", "beta", "gamma", "delta", "epsilon", "varepsilon", "zeta", "eta", "theta", "vartheta",
+	"iota", "kappa", "lambda", "mu", "nu", "xi", "pi", "varpi", "rho", "varrho", "sigma", "varsigma",
+	"tau", "upsilon", "phi", "varphi", "chi", "psi", "omega",
+	"Gamma", "Delta", "Theta", "Lambda", "Xi", "Pi", "Sigma", "Upsilon", "Phi", "Psi", "Omega",
+}
+
+// Built-in functions that have their own command in LaTeX.
+var functionCommands = map[string]string{
+	"sin":  `\sin`,
+	"cos":  `\cos`,
+	"tan":  `\tan`,
+	"asin": `\arcsin`,
+	"acos": `\arccos`,
+	"atan": `\arctan`,
+	"sinh": `\sinh`,
+	"cosh": `\cosh`,
+	"tanh": `\tanh`,
+	"ln":   `\ln`,
+	"log":  `\log`,
+	"log2": `\log_{2}`,
+	"exp":  `\exp`,
+	"min":  `\min`,
+	"max":  `\max`,
+	"gcd":  `\gcd`,
+	"det":  `\det`,
+}
+
+// Render an expression tree as LaTeX.
+// (x + 1) * y^-1 -> \frac{x + 1}{y}
+func Render(node *shared.Node) string {
+	if node == nil {
+		return ""
+	}
+
+	switch node.OperationType {
+	case shared.NUMBER:
+		return shared.FormatNumber(node)
+	case shared.VARIABLE:
+		return renderName(node.Variable)
+	case shared.EQUAL:
+		return Render(node.LNode) + " = " + Render(node.RNode)
+	case shared.PLUS:
+		str := ""
+		for i, val := range node.Associative {
+			if i == 0 {
+				str += wrap(val, SUM)
+			} else if negated := negate(val); negated != nil {
+				str += " - " + wrap(negated, PRODUCT)
+			} else {
+				str += " + " + wrap(val, SUM)
+			}
+		}
+		return str
+	case shared.MINUS:
+		if isZero(node.LNode) {
+			// -(2 * x) = -2 * x, but -(-x) needs parentheses.
+			if precedence(node.RNode) == PREFIX {
+				return `-\left(` + Render(node.RNode) + `\right)`
+			}
+			return "-" + wrap(node.RNode, PRODUCT)
+		}
+		return wrap(node.LNode, SUM) + " - " + wrap(node.RNode, PRODUCT)
+	case shared.MULTIPLY:
+		return renderProduct(node.Associative)
+	case shared.DIVIDE:
+		return `\frac{` + Render(node.LNode) + `}{` + Render(node.RNode) + `}`
+	case shared.POWER:
+		if node.RNode.OperationType == shared.NUMBER && node.RNode.Value == 0.5 {
+			return `\sqrt{` + Render(node.LNode) + `}`
+		} else if _, ok := negativeExponent(node); ok {
+			return renderProduct([]*shared.Node{node})
+		}
+		return wrap(node.LNode, ATOM) + `^{` + Render(node.RNode) + `}`
+	case shared.SQRT:
+		if node.LNode.OperationType == shared.NUMBER && node.LNode.Value == 2 {
+			return `\sqrt{` + Render(node.RNode) + `}`
+		}
+		return `\sqrt[` + Render(node.LNode) + `]{` + Render(node.RNode) + `}`
+	case shared.FUNCTION:
+		return renderFunction(node)
+	case shared.UNIT:
+		return `\mathrm{` + strings.ReplaceAll(node.Variable, "*", `\,`) + `}`
+	case shared.VECTOR:
+		// Vectors are drawn as a column.
+		rows := []string{}
+		for _, val := range node.Associative {
+			rows = append(rows, Render(val))
+		}
+		return `\begin{pmatrix}` + strings.Join(rows, ` \\ `) + `\end{pmatrix}`
+	case shared.MATRIX:
+		rows := []string{}
+		for _, row := range node.Associative {
+			elements := []string{}
+			for _, val := range row.Associative {
+				elements = append(elements, Render(val))
+			}
+			rows = append(rows, strings.Join(elements, " & "))
+		}
+		return `\begin{pmatrix}` + strings.Join(rows, ` \\ `) + `\end{pmatrix}`
+	}
+	return ""
+}
+
+// Returns the precedence of a node.
+func precedence(node *shared.Node) int {
+	switch node.OperationType {
+	case shared.NUMBER:
+		if node.Value < 0 {
+			return PREFIX
+		}
+		return ATOM
+	case shared.PLUS, shared.EQUAL:
+		return SUM
+	case shared.MINUS:
+		if isZero(node.LNode) {
+			return PREFIX
+		}
+		return SUM
+	case shared.MULTIPLY, shared.DIVIDE:
+		// Fractions are drawn as a block.
+		if _, denominator := splitFraction(node.Associative); node.OperationType == shared.DIVIDE || len(denominator) > 0 {
+			return ATOM
+		}
+		return PRODUCT
+	case shared.POWER:
+		if _, ok := negativeExponent(node); ok || (node.RNode.OperationType == shared.NUMBER && node.RNode.Value == 0.5) {
+			return ATOM
+		}
+		return EXPONENT
+	default:
+		return ATOM
+	}
+}
+
+// Render a node and put it in parentheses if its precedence is lower than required.
+func wrap(node *shared.Node, required int) string {
+	if precedence(node) < required {
+		return `\left(` + Render(node) + `\right)`
+	}
+	return Render(node)
+}
+
+func isZero(node *shared.Node) bool {
+	return node != nil && node.OperationType == shared.NUMBER && node.Value == 0
+}
+
+// Returns the positive version of a negative addend, or nil if it is not negative.
+// -2 -> 2, -x -> x, -2 * x -> 2 * x
+func negate(node *shared.Node) *shared.Node {
+	switch node.OperationType {
+	case shared.NUMBER:
+		if node.Value < 0 {
+			return shared.NumberNode(-node.Value)
+		}
+	case shared.MINUS:
+		if isZero(node.LNode) {
+			return node.RNode
+		}
+	case shared.MULTIPLY:
+		if len(node.Associative) > 0 {
+			if first := negate(node.Associative[0]); first != nil && node.Associative[0].OperationType == shared.NUMBER {
+				factors := slices.Clone(node.Associative)
+				factors[0] = first
+				// 1 * x -> x
+				if first.Value == 1 && len(factors) > 1 {
+					factors = factors[1:]
+				}
+				return shared.Multiply(factors...)
+			}
+		}
+	}
+	return nil
+}
+
+// Separates factors with negative exponents into the denominator, ones are left out.
+// x * y^-1 * z^-2 -> [x], [y, z^2]
+func splitFraction(factors []*shared.Node) ([]*shared.Node, []*shared.Node) {
+	numerator := []*shared.Node{}
+	denominator := []*shared.Node{}
+	for _, val := range factors {
+		if exponent, ok := negativeExponent(val); ok {
+			if exponent == -1 {
+				denominator = append(denominator, val.LNode)
+			} else {
+				denominator = append(denominator, shared.Power(val.LNode, shared.NumberNode(-exponent)))
+			}
+		} else if val.OperationType != shared.NUMBER || val.Value != 1 {
+			numerator = append(numerator, val)
+		}
+	}
+	return numerator, denominator
+}
+
+// Returns the exponent of a power with a negative number as exponent.
+// x^-2 -> -2, true
+func negativeExponent(node *shared.Node) (float64, bool) {
+	if node.OperationType != shared.POWER {
+		return 0, false
+	}
+	exponent := node.RNode
+	if exponent.OperationType == shared.NUMBER && exponent.Value < 0 {
+		return exponent.Value, true
+	} else if exponent.OperationType == shared.MINUS && isZero(exponent.LNode) && exponent.RNode.OperationType == shared.NUMBER && exponent.RNode.Value > 0 {
+		return -exponent.RNode.Value, true
+	}
+	return 0, false
+}
+
+// Render the factors of a multiplication, factors with negative exponents form a fraction.
+func renderProduct(factors []*shared.Node) string {
+	numerator, denominator := splitFraction(factors)
+	if len(denominator) == 0 {
+		return joinFactors(factors)
+	}
+
+	return `\frac{` + renderPart(numerator) + `}{` + renderPart(denominator) + `}`
+}
+
+// Render the numerator or denominator of a fraction, which do not need parentheses.
+func renderPart(factors []*shared.Node) string {
+	switch len(factors) {
+	case 0:
+		return "1"
+	case 1:
+		return Render(factors[0])
+	default:
+		return joinFactors(factors)
+	}
+}
+
+// Join factors with \cdot, every factor is separated the same way.
+// 2 * x * y -> 2 \cdot x \cdot y
+func joinFactors(factors []*shared.Node) string {
+	str := ""
+	for i, val := range factors {
+		// Negative factors are only allowed without parentheses at the front.
+		required := PRODUCT
+		if i > 0 {
+			str += ` \cdot `
+			required = EXPONENT
+		}
+		str += wrap(val, required)
+	}
+	return str
+}
+
+// Render a name of a variable, greek letters are replaced by their command and
+// underscores or trailing digits become a subscript.
+// theta -> \theta, v_0 -> v_{0}, x1 -> x_{1}, mass -> \mathrm{mass}
+func renderName(name string) string {
+	base, subscript, found := strings.Cut(name, "_")
+	if !found {
+		trimmed := strings.TrimRightFunc(name, unicode.IsDigit)
+		if trimmed != name && trimmed != "" {
+			base, subscript, found = trimmed, name[len(trimmed):], true
+		}
+	}
+
+	str := renderWord(base)
+	if found {
+		str += `_{` + renderWord(subscript) + `}`
+	}
+	return str
+}
+
+// Render a single word of a name.
+func renderWord(word string) string {
+	if slices.Contains(greekLetters, word) {
+		return `\` + word
+	}
+	if len(word) > 1 && !isNumeric(word) {
+		return `\mathrm{` + word + `}`
+	}
+	return word
+}
+
+func isNumeric(str string) bool {
+	for _, c := range str {
+		if !unicode.IsDigit(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Render a function call, built-in functions use their LaTeX notation if there is one.
+func renderFunction(node *shared.Node) string {
+	params := []string{}
+	for _, val := range node.Associative {
+		params = append(params, Render(val))
+	}
+	joined := strings.Join(params, ", ")
+
+	switch node.Variable {
+	case "abs":
+		return `\left|` + joined + `\right|`
+	case "floor":
+		return `\left\lfloor ` + joined + ` \right\rfloor`
+	case "ceil":
+		return `\left\lceil ` + joined + ` \right\rceil`
+	case "factorial":
+		if len(node.Associative) == 1 {
+			return wrap(node.Associative[0], ATOM) + "!"
+		}
+	case "log":
+		// log(x, b) -> \log_{b}(x)
+		if len(node.Associative) == 2 {
+			return `\log_{` + params[1] + `}\left(` + params[0] + `\right)`
+		}
+	}
+
+	name := ""
+	if command, ok := functionCommands[node.Variable]; ok {
+		name = command
+	} else if _, ok := shared.Builtins[node.Variable]; ok {
+		name = `\operatorname{` + node.Variable + `}`
+	} else {
+		name = renderName(node.Variable)
+	}
+	return name + `\left(` + joined + `\right)`
+}
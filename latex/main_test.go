@@ -0,0 +1,69 @@
+package latex
+
+import (
+	"lambdacalc/lexer"
+	"lambdacalc/parser"
+	"lambdacalc/shared"
+	"testing"
+)
+
+// State of the tests, with the default config. Tests defining names remove them again.
+var state = shared.NewState(shared.GetDefualtConfig())
+
+// Parses an expression for a test.
+func parse(t *testing.T, input string) *shared.Node {
+	t.Helper()
+	lexed, err := lexer.LexTokens(state, input)
+	if err != nil {
+		t.Fatalf("%s: unable to lex, %v", input, err)
+	}
+	parsed, err := parser.Parse(state, lexed)
+	if err != nil {
+		t.Fatalf("%s: unable to parse, %v", input, err)
+	}
+	return parsed
+}
+
+// Factors are always separated by \cdot and only the necessary parentheses are kept.
+func TestRender(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"2*x*y", `2 \cdot x \cdot y`},
+		{"2*3", `2 \cdot 3`},
+		{"x*2", `x \cdot 2`},
+		{"2*sin(x)", `2 \cdot \sin\left(x\right)`},
+		{"(x+1)/2*sqrt(y, 3)", `\frac{\left(x + 1\right) \cdot \sqrt[3]{y}}{2}`},
+		{"x^2/2", `\frac{x^{2}}{2}`},
+		{"x - 2*y", `x - 2 \cdot y`},
+		{"-x*y", `-x \cdot y`},
+		{"x*(-y)", `x \cdot \left(-y\right)`},
+		{"(x+1)*(x-1)", `\left(x + 1\right) \cdot \left(x - 1\right)`},
+	}
+	for _, test := range tests {
+		if got := Render(parse(t, test.input)); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.input, got, test.want)
+		}
+	}
+}
+
+// Rendered products are read back as the same expression.
+func TestRenderRoundTrip(t *testing.T) {
+	for _, input := range []string{"2*x*y", "2*3*x", "x^2*y*z", "(x+1)*(x-1)"} {
+		rendered := Render(parse(t, input))
+		lexed, err := LexTokens(state, rendered)
+		if err != nil {
+			t.Errorf("%s: unable to lex %s, %v", input, rendered, err)
+			continue
+		}
+		parsed, err := parser.Parse(state, lexed)
+		if err != nil {
+			t.Errorf("%s: unable to parse %s, %v", input, rendered, err)
+			continue
+		}
+		if got, want := shared.PrintATree(parsed), shared.PrintATree(parse(t, input)); got != want {
+			t.Errorf("%s: read back as %s, want %s", input, got, want)
+		}
+	}
+}
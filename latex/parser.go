@@ -0,0 +1,442 @@
+package latex
+
+import (
+	"lambdacalc/lexer"
+	"lambdacalc/shared"
+	"slices"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The LaTeX front-end translates LaTeX into the same tokens the lexer produces,
+// so the result can be read by the parser.
+// \frac{a + 1}{2} -> ((a + 1) / (2))
+
+type scanner struct {
+	state  *shared.State
+	input  string
+	index  int
+	start  int
+	tokens []shared.Token
+}
+
+// Built-in functions by their LaTeX command.
+var functionNames = map[string]string{
+	"sin":    "sin",
+	"cos":    "cos",
+	"tan":    "tan",
+	"arcsin": "asin",
+	"arccos": "acos",
+	"arctan": "atan",
+	"sinh":   "sinh",
+	"cosh":   "cosh",
+	"tanh":   "tanh",
+	"ln":     "ln",
+	"log":    "log",
+	"exp":    "exp",
+	"min":    "min",
+	"max":    "max",
+	"gcd":    "gcd",
+}
+
+// Commands that only change the spacing.
+var spacingCommands = []string{",", ";", ":", "!", " ", "quad", "qquad", "displaystyle"}
+
+// Splits a LaTeX expression into tokens.
+// The names are only accepted to match the signature of lexer.LexTokens,
+// LaTeX already marks multi letter names with \mathrm{...}.
+func LexTokens(state *shared.State, input string, names ...string) ([]shared.Token, error) {
+	s := scanner{
+		state:  state,
+		input:  input,
+		index:  0,
+		start:  0,
+		tokens: []shared.Token{},
+	}
+
+	if err := s.group(0); err != nil {
+		return nil, err
+	}
+	return s.tokens, nil
+}
+
+func (s *scanner) hasNext() bool {
+	return s.index < len(s.input)
+}
+
+func (s *scanner) current() byte {
+	return s.input[s.index]
+}
+
+func (s *scanner) skipSpace() {
+	for s.hasNext() && unicode.IsSpace(rune(s.current())) {
+		s.index++
+	}
+}
+
+func (s *scanner) emit(tokenType int) {
+	s.tokens = append(s.tokens, shared.Token{
+		TokenType: tokenType,
+		Value:     0.0,
+		Variable:  "",
+		Offset:    s.start,
+		Rational:  nil,
+		Text:      "",
+	})
+}
+
+// Add an operator, that was read from the input since the start of the element. i.e.: \cdot
+func (s *scanner) emitOperator(tokenType int) {
+	s.emit(tokenType)
+	s.tokens[len(s.tokens)-1].Text = s.input[s.start:s.index]
+}
+
+func (s *scanner) emitFunction(name string) {
+	s.tokens = append(s.tokens, shared.Token{
+		TokenType: shared.FUNCTION,
+		Value:     0.0,
+		Variable:  name,
+		Offset:    s.start,
+		Rational:  nil,
+		Text:      "",
+	})
+}
+
+// Scan tokens until the closing character of a group, or the end of the input if end is 0.
+func (s *scanner) group(end byte) error {
+	for {
+		s.skipSpace()
+		if !s.hasNext() {
+			if end != 0 {
+				return shared.NewError("missing closing bracket", "Unable to read LaTeX, missing closing '%c'.", end).At(s.index, s.index)
+			}
+			return nil
+		}
+
+		c := s.current()
+		if end != 0 && c == end {
+			s.index++
+			return nil
+		}
+
+		if err := s.next(); err != nil {
+			return err
+		}
+	}
+}
+
+// Scan the next element of the input, its tokens start at the current position.
+func (s *scanner) next() error {
+	s.start = s.index
+	c := s.current()
+	// Letters may take multiple bytes. i.e.: π
+	r, size := utf8.DecodeRuneInString(s.input[s.index:])
+	switch {
+	case unicode.IsDigit(rune(c)) || c == '.':
+		return s.number(false)
+	case unicode.IsLetter(r):
+		s.index += size
+		s.name(string(r))
+		return nil
+	case c == '\\':
+		return s.command()
+	case c == '{':
+		s.index++
+		s.emit(shared.LPARENTHESES)
+		if err := s.group('}'); err != nil {
+			return err
+		}
+		s.emit(shared.RPARENTHESES)
+		return nil
+	case c == '^':
+		s.index++
+		s.emit(shared.POWER)
+		return s.argument()
+	case c == '|':
+		// |x| -> abs(x)
+		s.index++
+		s.emitFunction("abs")
+		s.emit(shared.LPARENTHESES)
+		if err := s.group('|'); err != nil {
+			return err
+		}
+		s.emit(shared.RPARENTHESES)
+		return nil
+	}
+
+	operators := map[byte]int{
+		'+': shared.PLUS,
+		'-': shared.MINUS,
+		'*': shared.MULTIPLY,
+		'/': shared.DIVIDE,
+		'=': shared.EQUAL,
+		',': shared.COMMA,
+		'(': shared.LPARENTHESES,
+		'[': shared.LPARENTHESES,
+		')': shared.RPARENTHESES,
+		']': shared.RPARENTHESES,
+	}
+	if tokenType, ok := operators[c]; ok {
+		s.index++
+		s.emitOperator(tokenType)
+		return nil
+	}
+
+	return shared.NewError("unrecognized character", "Unable to read LaTeX, unrecognized character '%c'.", r).At(s.index, s.index+size)
+}
+
+// Scan a number, in arguments only a single digit belongs to the number. i.e.: x^23 = x^2 * 3
+func (s *scanner) number(single bool) error {
+	start := s.index
+	for s.hasNext() && (unicode.IsDigit(rune(s.current())) || s.current() == '.') {
+		s.index++
+		if single {
+			break
+		}
+	}
+
+	num, err := strconv.ParseFloat(s.input[start:s.index], 64)
+	if err != nil {
+		return shared.NewError("number parsing", "Unable to read LaTeX, invalid number '%s'.", s.input[start:s.index]).At(start, s.index)
+	}
+	s.tokens = append(s.tokens, shared.Token{
+		TokenType: shared.NUMBER,
+		Value:     num,
+		Variable:  "",
+		Offset:    start,
+		Rational:  nil,
+		Text:      s.input[start:s.index],
+	})
+	return nil
+}
+
+// Add a name with an optional subscript. i.e.: v_0 or v_{max}
+func (s *scanner) name(base string) {
+	if s.hasNext() && s.current() == '_' {
+		s.index++
+		base += "_" + s.raw()
+	}
+	token := lexer.NameToken(s.state, base)
+	token.Offset = s.start
+	s.tokens = append(s.tokens, token)
+}
+
+// Read the raw text of an argument, either a group in braces or a single character.
+func (s *scanner) raw() string {
+	s.skipSpace()
+	if !s.hasNext() {
+		return ""
+	}
+	if s.current() != '{' {
+		c := s.current()
+		s.index++
+		return string(c)
+	}
+
+	start := s.index + 1
+	depth := 0
+	for s.hasNext() {
+		switch s.current() {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		s.index++
+		if depth == 0 {
+			return s.input[start : s.index-1]
+		}
+	}
+	return s.input[start:]
+}
+
+// Scan the argument of a command in parentheses, either a group in braces or a single element.
+// \frac12 -> (1) / (2), x^{10} -> x^(10)
+func (s *scanner) argument() error {
+	s.skipSpace()
+	if !s.hasNext() {
+		return shared.NewError("missing argument", "Unable to read LaTeX, missing argument.").At(s.index, s.index)
+	}
+
+	s.emit(shared.LPARENTHESES)
+	if s.current() == '{' {
+		s.index++
+		if err := s.group('}'); err != nil {
+			return err
+		}
+	} else if unicode.IsDigit(rune(s.current())) {
+		if err := s.number(true); err != nil {
+			return err
+		}
+	} else if err := s.next(); err != nil {
+		return err
+	}
+	s.emit(shared.RPARENTHESES)
+	return nil
+}
+
+// Read the name of a command after the backslash. i.e.: \frac -> frac, \, -> ,
+func (s *scanner) commandName() string {
+	s.index++
+	start := s.index
+	for s.hasNext() && unicode.IsLetter(rune(s.current())) {
+		s.index++
+	}
+	// Commands consisting of a single symbol.
+	if s.index == start && s.hasNext() {
+		s.index++
+	}
+	return s.input[start:s.index]
+}
+
+// Scan a command starting with a backslash.
+func (s *scanner) command() error {
+	start := s.index
+	command := s.commandName()
+
+	switch command {
+	case "frac", "dfrac", "tfrac":
+		// \frac{a}{b} -> ((a) / (b))
+		s.emit(shared.LPARENTHESES)
+		if err := s.argument(); err != nil {
+			return err
+		}
+		s.emit(shared.DIVIDE)
+		if err := s.argument(); err != nil {
+			return err
+		}
+		s.emit(shared.RPARENTHESES)
+		return nil
+	case "sqrt":
+		// \sqrt[n]{x} -> sqrt(x, n)
+		s.skipSpace()
+		degree := []shared.Token{}
+		if s.hasNext() && s.current() == '[' {
+			s.index++
+			outer := s.tokens
+			s.tokens = []shared.Token{}
+			if err := s.group(']'); err != nil {
+				return err
+			}
+			degree = s.tokens
+			s.tokens = outer
+		}
+
+		s.tokens = append(s.tokens, shared.Token{
+			TokenType: shared.SQRT,
+			Value:     0.0,
+			Variable:  "",
+			Offset:    s.start,
+			Rational:  nil,
+			Text:      "",
+		})
+		s.emit(shared.LPARENTHESES)
+		if err := s.argument(); err != nil {
+			return err
+		}
+		if len(degree) > 0 {
+			s.emit(shared.COMMA)
+			s.tokens = append(s.tokens, degree...)
+		}
+		s.emit(shared.RPARENTHESES)
+		return nil
+	case "cdot", "times", "ast":
+		s.emitOperator(shared.MULTIPLY)
+		return nil
+	case "div":
+		s.emitOperator(shared.DIVIDE)
+		return nil
+	case "left", "right":
+		return s.delimiter(command == "left")
+	case "lvert":
+		s.emitFunction("abs")
+		s.emit(shared.LPARENTHESES)
+		return nil
+	case "rvert":
+		s.emit(shared.RPARENTHESES)
+		return nil
+	case "mathrm", "text", "operatorname", "mathit":
+		// Names with multiple letters i.e.: \mathrm{mass}
+		s.name(s.raw())
+		return nil
+	}
+
+	if slices.Contains(spacingCommands, command) {
+		return nil
+	}
+
+	if slices.Contains(greekLetters, command) {
+		s.name(command)
+		return nil
+	}
+
+	if name, ok := functionNames[command]; ok {
+		return s.function(name)
+	}
+
+	return shared.NewError("unknown command", "Unable to read LaTeX, unknown command '\\%s'.", command).At(start, s.index)
+}
+
+// Scan the delimiter after \left or \right.
+func (s *scanner) delimiter(left bool) error {
+	s.skipSpace()
+	if !s.hasNext() {
+		return shared.NewError("missing delimiter", "Unable to read LaTeX, missing delimiter.").At(s.index, s.index)
+	}
+
+	delimiter := string(s.current())
+	if s.current() == '\\' {
+		delimiter = s.commandName()
+	} else {
+		s.index++
+	}
+
+	switch delimiter {
+	case "(", "[", "{", "lbrace":
+		s.emit(shared.LPARENTHESES)
+	case ")", "]", "}", "rbrace":
+		s.emit(shared.RPARENTHESES)
+	case "|", "vert", "lvert", "rvert":
+		if left {
+			s.emitFunction("abs")
+			s.emit(shared.LPARENTHESES)
+		} else {
+			s.emit(shared.RPARENTHESES)
+		}
+	case ".":
+		// Invisible delimiter.
+	default:
+		return shared.NewError("unknown delimiter", "Unable to read LaTeX, unknown delimiter '%s'.", delimiter).At(s.index-len(delimiter), s.index)
+	}
+	return nil
+}
+
+// Scan a function command. Without parentheses the next element is its parameter. i.e.: \sin x
+func (s *scanner) function(name string) error {
+	// \log_{2} -> log2, \log_{10} -> log
+	if name == "log" && s.hasNext() && s.current() == '_' {
+		s.index++
+		switch base := s.raw(); base {
+		case "2":
+			name = "log2"
+		case "10":
+			name = "log"
+		case "e":
+			name = "ln"
+		default:
+			return shared.NewError("unsupported logarithm base", "Unable to read LaTeX, unsupported logarithm base '%s'.", base)
+		}
+	}
+	s.emitFunction(name)
+
+	s.skipSpace()
+	if s.hasNext() && (s.current() == '(' || s.current() == '{' || s.current() == '\\' && isLeft(s.input[s.index:])) {
+		return nil
+	}
+	return s.argument()
+}
+
+func isLeft(str string) bool {
+	return len(str) >= 5 && str[:5] == `\left`
+}
@@ -0,0 +1,85 @@
+package latex
+
+import (
+	"lambdacalc/parser"
+	"lambdacalc/shared"
+	"testing"
+)
+
+// LaTeX is read into the same tree as the plain input.
+func TestLexTokens(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`\frac{x+1}{2}`, "((x+1)*(2^-1))"},
+		{`x^{2} + 2x`, "((x^2)+(2*x))"},
+		{`10^{30}`, "(10^30)"},
+		{`\sqrt{x}`, "(2sqx)"},
+		{`\sqrt[3]8`, "(3sq8)"},
+		{`\sin\left(x\right)`, "sin(x)"},
+		{`\sin x`, "sin(x)"},
+		{`\ln{x}`, "ln(x)"},
+		{`\log_2 8`, "log2(8)"},
+		{`\log_{2}(8)`, "log2(8)"},
+		{`|x|`, "abs(x)"},
+		{`\mathrm{mass} \times 2`, "(mass*2)"},
+		{`v_{0} t`, "(v_0*t)"},
+		{`x_1 + x_{12}`, "(x_1+x_12)"},
+		{`\alpha\beta`, "(alpha*beta)"},
+		{`\left(x+1\right)\left(x+2\right)`, "((x+1)*(x+2))"},
+	}
+	for _, test := range tests {
+		tokens, err := LexTokens(state, test.input)
+		if err != nil {
+			t.Errorf("%s: unable to lex, %v", test.input, err)
+			continue
+		}
+		got, err := parser.Parse(state, tokens)
+		if err != nil {
+			t.Errorf("%s: unable to parse, %v", test.input, err)
+			continue
+		}
+		if res := shared.PrintATree(got); res != test.want {
+			t.Errorf("%s: got %s, want %s", test.input, res, test.want)
+		}
+	}
+}
+
+func TestLexTokensErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`\frac{1}{2`, "Unable to read LaTeX, missing closing '}'."},
+		{`\foo{x}`, "Unable to read LaTeX, unknown command '\\foo'."},
+	}
+	for _, test := range tests {
+		if _, err := LexTokens(state, test.input); err == nil || err.Error() != test.want {
+			t.Errorf("%s: got %v, want %s", test.input, err, test.want)
+		}
+	}
+
+	// Operators at the end are missing their second operand like in the plain input.
+	tokens, err := LexTokens(state, `3 \cdot`)
+	if err != nil {
+		t.Fatalf(`3 \cdot: unable to lex, %v`, err)
+	}
+	if _, err := parser.Parse(state, tokens); err == nil || shared.ErrorCode(err) != "missing token" {
+		t.Errorf(`3 \cdot: got %v, want missing token`, err)
+	}
+
+	// Errors name the command as it was written.
+	tokens, err = LexTokens(state, `2 \cdot \cdot 3`)
+	if err != nil {
+		t.Fatalf(`2 \cdot \cdot 3: unable to lex, %v`, err)
+	}
+	_, err = parser.Parse(state, tokens)
+	e, ok := err.(*shared.Error)
+	if want := "Unable to parse tokens, unexpected '\\cdot'."; !ok || e.Message != want || e.Span == nil {
+		t.Fatalf(`2 \cdot \cdot 3: got %v, want %s`, err, want)
+	}
+	if want := (shared.Span{Start: 8, End: 13}); *e.Span != want {
+		t.Errorf(`2 \cdot \cdot 3: got span %v, want %v`, *e.Span, want)
+	}
+}
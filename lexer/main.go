@@ -1,174 +1,376 @@
 package lexer
 
 import (
-	"errors"
 	"lambdacalc/shared"
+	"math/big"
+	"slices"
 	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/i582/cfmt/cmd/cfmt"
 )
 
-func LexTokens(input string) ([]shared.Token, error) {
+// Splits the input into tokens. Additional names are treated like defined variables,
+// so they are not broken into single letters, i.e.: the parameters of a function.
+func LexTokens(s *shared.State, input string, names ...string) ([]shared.Token, error) {
+	return lex(s, input, s.Conf.Options["strict_identifiers"], true, names)
+}
+
+// Splits the input into tokens like LexTokens, but never reads units. Letters after a number
+// are variables, as in symbolic statements. factor 4 m^2 - 1 -> 4 * m^2 - 1
+func LexSymbols(s *shared.State, input string, names ...string) ([]shared.Token, error) {
+	return lex(s, input, s.Conf.Options["strict_identifiers"], false, names)
+}
+
+// Splits a declaration into tokens, always reading whole identifiers as names.
+// define speed = 3 -> speed
+func LexDeclaration(s *shared.State, input string) ([]shared.Token, error) {
+	return lex(s, input, true, false, nil)
+}
+
+func lex(s *shared.State, input string, strict bool, units bool, names []string) ([]shared.Token, error) {
 	i := 0
 	var tokens []shared.Token
 	for i < len(input) {
-		switch rune(input[i]) {
-		case []rune(shared.Conf.Symbols["plus"])[0]:
+		// The input is read by characters, a character may take multiple bytes. i.e.: π
+		c, size := utf8.DecodeRuneInString(input[i:])
+		switch c {
+		case []rune(s.Conf.Symbols["plus"])[0]:
 			token := shared.Token{
 				TokenType: shared.PLUS,
 				Value:     0.0,
 				Variable:  "",
+				Offset:    i,
+				Rational:  nil,
+				Text:      input[i : i+size],
 			}
 			tokens = append(tokens, token)
-			i += 1
-		case []rune(shared.Conf.Symbols["minus"])[0]:
+			i += size
+		case []rune(s.Conf.Symbols["minus"])[0]:
 			token := shared.Token{
 				TokenType: shared.MINUS,
 				Value:     0.0,
 				Variable:  "",
+				Offset:    i,
+				Rational:  nil,
+				Text:      input[i : i+size],
 			}
 			tokens = append(tokens, token)
-			i += 1
-		case []rune(shared.Conf.Symbols["multiply"])[0]:
+			i += size
+		case []rune(s.Conf.Symbols["multiply"])[0]:
 			token := shared.Token{
 				TokenType: shared.MULTIPLY,
 				Value:     0.0,
 				Variable:  "",
+				Offset:    i,
+				Rational:  nil,
+				Text:      input[i : i+size],
 			}
 			tokens = append(tokens, token)
-			i += 1
-		case []rune(shared.Conf.Symbols["divide"])[0]:
+			i += size
+		case []rune(s.Conf.Symbols["divide"])[0]:
 			token := shared.Token{
 				TokenType: shared.DIVIDE,
 				Value:     0.0,
 				Variable:  "",
+				Offset:    i,
+				Rational:  nil,
+				Text:      input[i : i+size],
 			}
 			tokens = append(tokens, token)
-			i += 1
-		case []rune(shared.Conf.Symbols["power"])[0]:
+			i += size
+		case []rune(s.Conf.Symbols["power"])[0]:
 			token := shared.Token{
 				TokenType: shared.POWER,
 				Value:     0.0,
 				Variable:  "",
+				Offset:    i,
+				Rational:  nil,
+				Text:      input[i : i+size],
 			}
 			tokens = append(tokens, token)
-			i += 1
-		case []rune(shared.Conf.Symbols["l_parentheses"])[0]:
+			i += size
+		case []rune(s.Conf.Symbols["l_parentheses"])[0]:
 			token := shared.Token{
 				TokenType: shared.LPARENTHESES,
 				Value:     0.0,
 				Variable:  "",
+				Offset:    i,
+				Rational:  nil,
+				Text:      input[i : i+size],
 			}
 			tokens = append(tokens, token)
-			i += 1
-		case []rune(shared.Conf.Symbols["r_parentheses"])[0]:
+			i += size
+		case []rune(s.Conf.Symbols["r_parentheses"])[0]:
 			token := shared.Token{
 				TokenType: shared.RPARENTHESES,
 				Value:     0.0,
 				Variable:  "",
+				Offset:    i,
+				Rational:  nil,
+				Text:      input[i : i+size],
+			}
+			tokens = append(tokens, token)
+			i += size
+		case []rune(s.Conf.Symbols["l_brackets"])[0]:
+			token := shared.Token{
+				TokenType: shared.LBRACKET,
+				Value:     0.0,
+				Variable:  "",
+				Offset:    i,
+				Rational:  nil,
+				Text:      input[i : i+size],
+			}
+			tokens = append(tokens, token)
+			i += size
+		case []rune(s.Conf.Symbols["r_brackets"])[0]:
+			token := shared.Token{
+				TokenType: shared.RBRACKET,
+				Value:     0.0,
+				Variable:  "",
+				Offset:    i,
+				Rational:  nil,
+				Text:      input[i : i+size],
 			}
 			tokens = append(tokens, token)
-			i += 1
-		case []rune(shared.Conf.Symbols["equal"])[0]:
+			i += size
+		case []rune(s.Conf.Symbols["equal"])[0]:
 			token := shared.Token{
 				TokenType: shared.EQUAL,
 				Value:     0.0,
 				Variable:  "",
+				Offset:    i,
+				Rational:  nil,
+				Text:      input[i : i+size],
 			}
 			tokens = append(tokens, token)
-			i += 1
-		case []rune(shared.Conf.Symbols["parameter_split"])[0]:
+			i += size
+		case []rune(s.Conf.Symbols["parameter_split"])[0]:
 			token := shared.Token{
 				TokenType: shared.COMMA,
 				Value:     0.0,
 				Variable:  "",
+				Offset:    i,
+				Rational:  nil,
+				Text:      input[i : i+size],
 			}
 			tokens = append(tokens, token)
-			i += 1
+			i += size
 		default:
 			// Decode Numbers
-			if unicode.IsNumber(rune(input[i])) || rune(input[i]) == []rune(shared.Conf.Symbols["decimal_split"])[0] {
+			if unicode.IsNumber(c) || c == []rune(s.Conf.Symbols["decimal_split"])[0] {
+				start := i
 				dot := false
 				str := ""
-				for i < len(input) && (unicode.IsNumber(rune(input[i])) || rune(input[i]) == []rune(shared.Conf.Symbols["decimal_split"])[0]) {
-					if dot && rune(input[i]) == []rune(shared.Conf.Symbols["decimal_split"])[0] {
-						cfmt.Printf("{{Error:}}::red|bold Unable to parse number at %v, reading multiple decimal splits.\n", i)
-						return nil, errors.New("multiple decimal splits")
-					} else if rune(input[i]) == []rune(shared.Conf.Symbols["decimal_split"])[0] {
+				for i < len(input) && (unicode.IsNumber(c) || c == []rune(s.Conf.Symbols["decimal_split"])[0]) {
+					if dot && c == []rune(s.Conf.Symbols["decimal_split"])[0] {
+						return nil, shared.NewError("multiple decimal splits", "Unable to parse number, reading multiple decimal splits.").At(i, i+size).WithHint("a number has only one decimal split")
+					} else if c == []rune(s.Conf.Symbols["decimal_split"])[0] {
 						dot = true
 						str += "."
 					} else {
-						str += string(input[i])
+						str += string(c)
 					}
-					i += 1
+					i += size
+					c, size = utf8.DecodeRuneInString(input[i:])
 				}
 				num, err := strconv.ParseFloat(str, 64)
 				if err != nil {
-					cfmt.Printf("{{Error:}}::red|bold Unable to parse number, character-conversion faild.\n")
-					return nil, errors.New("number parsing")
+					return nil, shared.NewError("number parsing", "Unable to parse number, character-conversion faild.").At(start, i)
 				}
-				tokens = append(tokens, shared.Token{
+				token := shared.Token{
 					TokenType: shared.NUMBER,
 					Value:     num,
 					Variable:  "",
-				})
-			} else if unicode.IsSpace(rune(input[i])) {
-				// Skip empty space
-				i += 1
-			} else if unicode.IsLetter(rune(input[i])) {
-				// Search for constants or variables
-				str := ""
-
-				for i < len(input) && unicode.IsLetter(rune(input[i])) {
-					str += string(input[i])
-					i += 1
+					Offset:    start,
+					Rational:  nil,
+					Text:      input[start:i],
+				}
+				// Digits a float64 can not hold are kept. i.e.: 123456789012345678901
+				if exact, ok := new(big.Rat).SetString(str); ok && s.Conf.KeepDecimals() && !shared.IsDecimal(exact) {
+					token.Rational = exact
 				}
+				tokens = append(tokens, token)
 
-				// Check Constants
-				if val, ok := shared.Conf.Constants[str]; ok {
-					tokens = append(tokens, shared.Token{
-						TokenType: shared.NUMBER,
-						Value:     val,
-						Variable:  "",
-					})
-				} else if str == "sqrt" {
-					tokens = append(tokens, shared.Token{
-						TokenType: shared.SQRT,
-						Value:     0.0,
-						Variable:  "",
-					})
-				} else {
-					// Its neither a constant or a sqrt than break the string into single variables.
-					j := 0
-					for j < len(str) {
-						// Debug
-						if shared.Conf.Options["show_debug_process"] {
-							if val, ok := shared.Variables[string(str[j])]; ok {
-								cfmt.Printf("{{Notice:}}::blue|bold found defined variable %s with value: ", string(str[j]))
-								cfmt.Printf("%s", shared.PrintATree(&val))
-								cfmt.Printf(".\n")
-							} else if val, ok := shared.Functions[string(str[j])]; ok {
-								cfmt.Printf("{{Notice:}}::blue|bold found defined variable %s with value: ", string(str[j]))
-								cfmt.Printf("%s", shared.PrintATree(val.Equation))
-								cfmt.Printf(".\n")
-							} else {
-								cfmt.Printf("{{Notice:}}::blue|bold found undefined variable %s.\n", string(str[j]))
-							}
-						}
+				// A unit may follow the number after a space, unless it is a known name. i.e.: 12 m/s, 3 min
+				if units && unicode.IsSpace(c) && size == 1 && startsWith(input[i+1:], unicode.IsLetter) {
+					word := input[i+1:]
+					if j := strings.IndexFunc(word, func(c rune) bool { return !unicode.IsLetter(c) }); j != -1 {
+						word = word[:j]
+					}
+					// Known names are no units, unless it is a function without parentheses. i.e.: 3 min
+					_, builtin := shared.Builtins[word]
+					called := strings.HasPrefix(input[i+1+len(word):], "(")
+					if length := s.Conf.UnitLength(input[i+1:]); length > 0 && (matchName(s, word, names) != word || (builtin && !called)) {
 						tokens = append(tokens, shared.Token{
-							TokenType: shared.VARIABLE,
+							TokenType: shared.UNIT,
 							Value:     0.0,
-							Variable:  string(str[j]),
+							Variable:  input[i+1 : i+1+length],
+							Offset:    i + 1,
+							Rational:  nil,
+							Text:      input[i+1 : i+1+length],
 						})
-						j += 1
+						i += 1 + length
+					}
+				}
+			} else if unicode.IsSpace(c) {
+				// Skip empty space
+				i += size
+			} else if unicode.IsLetter(c) {
+				// Search for constants, functions or variables
+				if strict {
+					// Read the whole identifier as a single name i.e.: theta1 or v_0
+					str := readIdentifier(input[i:])
+					token := NameToken(s, str)
+					token.Offset = i
+					tokens = append(tokens, token)
+					i += len(str)
+				} else {
+					// Break the letters into known names and single variables. i.e.: 2xsin(y) -> 2 * x * sin(y)
+					for i < len(input) && startsWith(input[i:], unicode.IsLetter) {
+						str := matchName(s, input[i:], names)
+						// Built-in functions are only read as a whole identifier. i.e.: inverse is no inv * e * r * s * e
+						if isBuiltin(s, str) && startsWith(input[i+len(str):], isIdentifier) {
+							str = readIdentifier(input[i:])
+						}
+						if str == "" {
+							str = readSubscript(input[i:])
+						}
+						token := NameToken(s, str)
+						token.Offset = i
+						tokens = append(tokens, token)
+						i += len(str)
 					}
 				}
 			} else {
-				cfmt.Printf("{{Error:}}::red|bold Unable to parse symbol at %s, unregocnized character.\n", string(input[i]))
-				return nil, errors.New("number parsing")
+				return nil, shared.NewError("unrecognized character", "Unable to parse symbol, unrecognized character '%c'.", c).At(i, i+size)
 			}
 		}
 	}
 	return tokens, nil
 }
+
+// Checks if a character may appear inside of a name.
+func isIdentifier(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_'
+}
+
+// Checks if the first character of a string matches. An empty string matches nothing.
+func startsWith(str string, match func(rune) bool) bool {
+	c, size := utf8.DecodeRuneInString(str)
+	return size > 0 && match(c)
+}
+
+// Checks if a name is a built-in function or the root.
+func isBuiltin(s *shared.State, name string) bool {
+	_, ok := shared.Builtins[name]
+	return ok || (name != "" && name == s.Conf.Symbols["sqrt"])
+}
+
+// Returns the identifier the string starts with, consisting of letters, digits and underscores.
+func readIdentifier(str string) string {
+	_, j := utf8.DecodeRuneInString(str)
+	for j < len(str) && startsWith(str[j:], isIdentifier) {
+		_, size := utf8.DecodeRuneInString(str[j:])
+		j += size
+	}
+	return str[:j]
+}
+
+// Returns a single letter, including a subscript if one follows. i.e.: x or v_0
+func readSubscript(str string) string {
+	_, size := utf8.DecodeRuneInString(str)
+	if rest, ok := strings.CutPrefix(str[size:], "_"); ok && startsWith(rest, isIdentifier) {
+		return str[:size] + "_" + readIdentifier(rest)
+	}
+	return str[:size]
+}
+
+// Returns the longest known name the string starts with. Known names are built-in functions,
+// constants, the imaginary unit, defined variables and functions and the additionally passed names.
+func matchName(s *shared.State, str string, names []string) string {
+	known := slices.Clone(names)
+	known = append(known, s.Conf.Symbols["sqrt"])
+	if unit := s.Conf.ImaginaryUnit(); unit != "" {
+		known = append(known, unit)
+	}
+	for name := range shared.Builtins {
+		known = append(known, name)
+	}
+	for name := range s.Conf.Constants {
+		known = append(known, name)
+	}
+	for name := range s.Variables {
+		known = append(known, name)
+	}
+	for name := range s.Functions {
+		known = append(known, name)
+	}
+
+	match := ""
+	for _, name := range known {
+		if len(name) > len(match) && strings.HasPrefix(str, name) {
+			match = name
+		}
+	}
+	return match
+}
+
+// Creates the token for a name, depending on whether it is a constant, function or variable.
+// The offset is left to the caller.
+// In exact mode and with a higher precision constants stay variables, because their float value is only an approximation.
+func NameToken(s *shared.State, str string) shared.Token {
+	if val, ok := s.Conf.Constants[str]; ok && !s.Conf.KeepDecimals() {
+		return shared.Token{
+			TokenType: shared.NUMBER,
+			Value:     val,
+			Variable:  "",
+			Offset:    0,
+			Rational:  nil,
+			Text:      str,
+		}
+	} else if str == s.Conf.Symbols["sqrt"] {
+		return shared.Token{
+			TokenType: shared.SQRT,
+			Value:     0.0,
+			Variable:  "",
+			Offset:    0,
+			Rational:  nil,
+			Text:      str,
+		}
+	} else if _, ok := shared.Builtins[str]; ok {
+		return shared.Token{
+			TokenType: shared.FUNCTION,
+			Value:     0.0,
+			Variable:  str,
+			Offset:    0,
+			Rational:  nil,
+			Text:      str,
+		}
+	}
+
+	// Debug
+	if s.Conf.Options["show_debug_process"] {
+		if val, ok := s.Variables[str]; ok {
+			cfmt.Printf("{{Notice:}}::blue|bold found defined variable %s with value: ", str)
+			cfmt.Printf("%s", shared.PrintATree(&val))
+			cfmt.Printf(".\n")
+		} else if val, ok := s.Functions[str]; ok {
+			cfmt.Printf("{{Notice:}}::blue|bold found defined variable %s with value: ", str)
+			cfmt.Printf("%s", shared.PrintATree(val.Equation))
+			cfmt.Printf(".\n")
+		} else {
+			cfmt.Printf("{{Notice:}}::blue|bold found undefined variable %s.\n", str)
+		}
+	}
+	return shared.Token{
+		TokenType: shared.VARIABLE,
+		Value:     0.0,
+		Variable:  str,
+		Offset:    0,
+		Rational:  nil,
+		Text:      str,
+	}
+}
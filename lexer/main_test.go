@@ -0,0 +1,130 @@
+package lexer
+
+import (
+	"lambdacalc/shared"
+	"slices"
+	"testing"
+)
+
+// State of the tests, with the default config and without definitions.
+var state = shared.NewState(shared.GetDefualtConfig())
+
+// Names of the tokens, operators are left out.
+func names(tokens []shared.Token) []string {
+	res := []string{}
+	for _, val := range tokens {
+		if val.TokenType == shared.VARIABLE || val.TokenType == shared.FUNCTION {
+			res = append(res, val.Variable)
+		}
+	}
+	return res
+}
+
+// Letters are split into known names, built-in functions only match whole identifiers.
+func TestLexNames(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"inverse(2)", []string{"inverse"}},
+		{"exponent", []string{"exponent"}},
+		{"inv(A)", []string{"inv", "A"}},
+		{"exp(x)", []string{"exp", "x"}},
+		{"xy", []string{"x", "y"}},
+		{"2xsin(y)", []string{"x", "sin", "y"}},
+		{"sinh(x)", []string{"sinh", "x"}},
+		{"sinx", []string{"sinx"}},
+		{"v_0t", []string{"v_0t"}},
+	}
+	for _, test := range tests {
+		lexed, err := LexTokens(state, test.input)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.input, err)
+			continue
+		}
+		if got := names(lexed); !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.input, got, test.want)
+		}
+	}
+}
+
+// Defined variables and additional names are read as a whole, the rest is split into letters.
+func TestLexDefinedNames(t *testing.T) {
+	defined := shared.NewState(shared.GetDefualtConfig())
+	defined.Variables["mass"] = *shared.NumberNode(1.0)
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"mass*g", []string{"mass", "g"}},
+		{"2massx", []string{"mass", "x"}},
+		{"xmass", []string{"x", "mass"}},
+		{"speed", []string{"speed"}},
+		{"abc", []string{"a", "b", "c"}},
+	}
+	for _, test := range tests {
+		lexed, err := LexTokens(defined, test.input, "speed")
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.input, err)
+			continue
+		}
+		if got := names(lexed); !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.input, got, test.want)
+		}
+	}
+
+	// Declarations always read whole identifiers.
+	lexed, err := LexDeclaration(state, "f(rate, time) = rate*time")
+	if err != nil {
+		t.Fatalf("declaration: unexpected error %v", err)
+	}
+	if got, want := names(lexed), []string{"f", "rate", "time", "rate", "time"}; !slices.Equal(got, want) {
+		t.Errorf("declaration: got %v, want %v", got, want)
+	}
+}
+
+// Characters, that take multiple bytes, are read as a whole. Offsets are still given in bytes.
+func TestLexUnicode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"2 + π", []string{"π"}},
+		{"αβ", []string{"α", "β"}},
+		{"x_α", []string{"x_α"}},
+	}
+	for _, test := range tests {
+		lexed, err := LexTokens(state, test.input)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.input, err)
+			continue
+		}
+		if got := names(lexed); !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.input, got, test.want)
+		}
+	}
+
+	lexed, err := LexDeclaration(state, "θ1 + 2")
+	if err != nil {
+		t.Fatalf("θ1 + 2: unexpected error %v", err)
+	}
+	if got, want := names(lexed), []string{"θ1"}; !slices.Equal(got, want) {
+		t.Errorf("θ1 + 2: got %v, want %v", got, want)
+	}
+	if lexed[1].Offset != 4 {
+		t.Errorf("θ1 + 2: got offset %d of '+', want 4", lexed[1].Offset)
+	}
+
+	_, err = LexTokens(state, "π + €")
+	e, ok := err.(*shared.Error)
+	if !ok || e.Message != "Unable to parse symbol, unrecognized character '€'." || e.Span == nil {
+		t.Fatalf("π + €: got %v, want unrecognized character '€'", err)
+	}
+	if want := (shared.Span{Start: 5, End: 8}); *e.Span != want {
+		t.Errorf("π + €: got span %v, want %v", *e.Span, want)
+	}
+	if got := e.Column("π + €"); got != 5 {
+		t.Errorf("π + €: got column %d, want 5", got)
+	}
+}
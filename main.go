@@ -2,83 +2,142 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"lambdacalc/engine"
 	"lambdacalc/shared"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/i582/cfmt/cmd/cfmt"
 	"github.com/peterh/liner"
 )
 
-// Loading Config into shared Conf variable and starting REPL.
+// Context the statements of the CLI are run in.
+var session *engine.Context
+
+// Loading Config into the session and starting REPL.
+// Statements given with -e, files or piped input are run in batch mode instead, serve starts the HTTP server.
+// lambdacalc -e "2+2", lambdacalc script.lc, echo "x^2" | lambdacalc, lambdacalc serve
 func main() {
-	if err := loadConfig(); err != nil {
-		return
+	var expressions statements
+	flag.Var(&expressions, "e", "evaluate a statement, can be repeated")
+	keepGoing := flag.Bool("keep-going", false, "continue with the next statement after an error")
+	output := flag.String("output", "text", "write results as text or json")
+	flag.Parse()
+
+	if *output != "text" && *output != "json" {
+		cfmt.Fprintf(os.Stderr, "{{Error:}}::red|bold Unknown output '%s', expected text or json.\n", *output)
+		os.Exit(2)
+	}
+	jsonOutput := *output == "json"
+
+	// Colors are only written to the terminal, not into pipes, files or JSON.
+	if !isTerminal(os.Stdout) || jsonOutput {
+		cfmt.DisableColors()
+	}
+
+	conf, err := loadConfig()
+	if err != nil {
+		os.Exit(1)
+	}
+	session = engine.New(conf)
+	// Debug output would break the lines of JSON.
+	if jsonOutput {
+		session.SetOption("show_debug_process", false)
+	}
+
+	switch {
+	case flag.Arg(0) == "serve":
+		// lambdacalc serve --addr :8080 --timeout 10s
+		flags := flag.NewFlagSet("serve", flag.ExitOnError)
+		addr := flags.String("addr", ":8080", "address the server listens on")
+		timeout := flags.Duration("timeout", 10*time.Second, "time a request may take")
+		flags.Parse(flag.Args()[1:])
+		err = serve(*addr, *timeout)
+	case len(expressions) > 0:
+		err = batch(strings.NewReader(expressions.String()), *keepGoing, jsonOutput)
+	case flag.NArg() > 0:
+		err = batchFiles(flag.Args(), *keepGoing, jsonOutput)
+	case !isTerminal(os.Stdin):
+		err = batch(os.Stdin, *keepGoing, jsonOutput)
+	default:
+		cmdline(jsonOutput)
+	}
+	if err != nil {
+		os.Exit(1)
 	}
-	cmdline()
 }
 
 // Loads config from
 // Linux: ".config/labdacalc/config.toml" or Windows: "%APPDATA%/lambda-calc/config.toml"
 // If it is not able to do so it loads default config.
-func loadConfig() error {
+func loadConfig() (shared.Config, error) {
 	path := ""
 	switch runtime.GOOS {
 	case "linux":
 		homeDir, err := os.UserHomeDir()
 		if err != nil {
-			return err
+			return shared.Config{}, err
 		}
 		path = filepath.Join(homeDir, ".config", "lambda-calc")
 	case "windows":
 		appData := os.Getenv("APPDATA")
 		if appData == "" {
-			cfmt.Printf("{{Error:}}red|bold Unable to locat config file. APPDATA not set.")
-			return errors.New("no appdata")
+			cfmt.Fprintf(os.Stderr, "{{Error:}}::red|bold Unable to locat config file. APPDATA not set.\n")
+			return shared.Config{}, errors.New("no appdata")
 		}
 		path = filepath.Join(appData, "lambda-calc")
 	default:
-		cfmt.Println("{{Error:}}red|bold Unsuspected OS. I don't know how to find config file.")
-		shared.Conf = shared.GetDefualtConfig()
-		return nil
+		cfmt.Fprintln(os.Stderr, "{{Error:}}::red|bold Unsuspected OS. I don't know how to find config file.")
+		return shared.GetDefualtConfig(), nil
 	}
 
 	path = filepath.Join(path, "config.toml")
 
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		cfmt.Printf("config file not found: %s\n", path)
+		cfmt.Fprintf(os.Stderr, "config file not found: %s\n", path)
 
 		if err := createConfig(path); err != nil {
-			return err
+			return shared.Config{}, err
 		}
 
-		shared.Conf = shared.GetDefualtConfig()
-		return nil
+		return shared.GetDefualtConfig(), nil
 	}
 
-	if _, err := toml.DecodeFile(path, &shared.Conf); err != nil {
-		cfmt.Println("{{Error:}}::red|bold unable to load config file:\n", err)
-		shared.Conf = shared.GetDefualtConfig()
-		return nil
+	var conf shared.Config
+	if _, err := toml.DecodeFile(path, &conf); err != nil {
+		cfmt.Fprintln(os.Stderr, "{{Error:}}::red|bold unable to load config file:\n", err)
+		return shared.GetDefualtConfig(), nil
 	}
 
-	if shared.Conf.Version != shared.GetDefualtConfig().Version {
-		cfmt.Printf("{{Error:}}::red|bold Config file out of date.\n")
+	if conf.Version != shared.GetDefualtConfig().Version {
+		cfmt.Fprintf(os.Stderr, "{{Error:}}::red|bold Config file out of date.\n")
 		if err := createConfig(path); err != nil {
-			return err
+			return shared.Config{}, err
 		}
 	}
 
-	return nil
+	shared.FillDefaults(&conf)
+
+	// Invalid units are reported at the start, instead of only being unknown when they are used.
+	if err := conf.CheckUnits(); err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			cfmt.Fprintf(os.Stderr, "{{Error:}}::red|bold %s\n", line)
+		}
+		return shared.Config{}, err
+	}
+	return conf, nil
 }
 
 func createConfig(path string) error {
-	cfmt.Printf("Creating new config file at: %s\n", path)
+	cfmt.Fprintf(os.Stderr, "Creating new config file at: %s\n", path)
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
@@ -98,11 +157,11 @@ func createConfig(path string) error {
 	return nil
 }
 
-// REPL
-func cmdline() {
+// REPL, results are written as JSON with jsonOutput.
+func cmdline(jsonOutput bool) {
 	var (
 		historyFn = filepath.Join(os.TempDir(), ".liner_example_history")
-		names     = []string{"define", "solve", "clear", "exit", "help", "drop"}
+		names     = []string{"define", "solve", "clear", "exit", "help", "drop", "list", "latex", "diff", "integrate", "nintegrate", "nsolve", "limit", "series", "expand", "factor", "together", "apart", "cancel", "polydiv", "polygcd"}
 	)
 
 	line := liner.NewLiner()
@@ -126,7 +185,7 @@ func cmdline() {
 
 	for {
 		arrow := ""
-		if shared.Conf.Options["nerdfont"] {
+		if session.Config().Options["nerdfont"] {
 			cfmt.Println("{{}}::blue{{󰪚 Math}}::bgBlue|white{{}}::blue ")
 			arrow = "  ╰─▶ "
 		} else {
@@ -142,23 +201,17 @@ func cmdline() {
 			case "clear":
 				clear()
 			case "help":
-				cfmt.Printf(
-					`{{lambda-calc}}::cyan|bold | CLI
-Version: 0.0.1
-
-Available commands:
-
-help 		show a help screen with useful information.
-exit 		exit the CLI.
-define x = ...	define a variable with the value of the equation.
-drop x 		undefine a variable.
-list  	  list all currently defined shared.Variables.
-solve 		solve an equation by a variable if possible.
-
-`)
+				help()
 			default:
-				res, err := read(cmd)
-				if err == nil {
+				if jsonOutput {
+					result, _ := evaluateJSON(cmd)
+					writeJSON(result)
+					continue
+				}
+				res, err := session.Eval(cmd)
+				if err != nil {
+					report(os.Stdout, err, cmd)
+				} else {
 					cfmt.Printf("%v\n", res)
 				}
 			}
@@ -178,6 +231,62 @@ solve 		solve an equation by a variable if possible.
 	}
 }
 
+// Writes the error of a statement, errors with a position mark it below the input.
+// 2 + * 3 ->
+//
+//	2 + * 3
+//	    ^ unexpected '*'
+//	Error: Unable to parse tokens, unexpected '*'.
+func report(w io.Writer, err error, input string) {
+	var engineError *shared.Error
+	if !errors.As(err, &engineError) {
+		cfmt.Fprintf(w, "{{Error:}}::red|bold %v.\n", err)
+		return
+	}
+	if mark := engineError.Mark(input, ""); mark != "" {
+		fmt.Fprintln(w, input)
+		fmt.Fprintln(w, mark)
+	}
+	cfmt.Fprintf(w, "{{Error:}}::red|bold %s\n", engineError.Message)
+	if engineError.Hint != "" {
+		cfmt.Fprintf(w, "{{Hint:}}::yellow|bold %s.\n", engineError.Hint)
+	}
+}
+
+// Shows the available commands.
+func help() {
+	cfmt.Printf(
+		`{{lambda-calc}}::cyan|bold | CLI
+Version: 0.0.1
+
+Available commands:
+
+help 		show a help screen with useful information.
+exit 		exit the CLI.
+define x = ...	define a variable with the value of the equation.
+drop x 		undefine a variable.
+list  	  list all currently defined shared.Variables.
+solve ... for x	solve an equation by a variable if possible.
+solve ...; ... for x, y	solve a system of linear equations.
+diff ... by x	differentiate an expression by a variable.
+integrate ... by x [from a to b]	integrate an expression, optionally between two bounds.
+limit ... as x -> a	find the limit of an expression, a+ and a- approach a from one side.
+series ... around x = a to order n	expand an expression into a Taylor polynomial.
+expand ...	multiply out a polynomial and sort its terms.
+factor ...	factor a polynomial over the rationals.
+together ...	combine fractions into a single fraction.
+apart ...	decompose a fraction into partial fractions.
+cancel ...	cancel common factors of a fraction.
+polydiv ... by ...	divide two polynomials with remainder.
+polygcd ..., ...	find the greatest common divisor of polynomials.
+nintegrate ... by x from a to b	integrate an expression numerically between two bounds.
+nsolve ... for x near a	solve an equation numerically, starting at a value.
+latex ...	show an expression as LaTeX.
+latex: ...	read the rest of the line as LaTeX.
+
+`)
+}
+
 func clear() {
 	var cmd *exec.Cmd
 	switch runtime.GOOS {
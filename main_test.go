@@ -0,0 +1,14 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+)
+
+func TestMain(m *testing.M) {
+	// Colors are disabled once, cfmt counts every call.
+	cfmt.DisableColors()
+	os.Exit(m.Run())
+}
@@ -0,0 +1,141 @@
+package numeric
+
+import (
+	"lambdacalc/shared"
+	"math"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+)
+
+// Nodes of the 15 point Kronrod rule on [-1, 1], the odd entries are the nodes of the 7 point Gauss rule.
+var kronrodNodes = [8]float64{
+	0.991455371120812639206854697526329,
+	0.949107912342758524526189684047851,
+	0.864864423359769072789712788640926,
+	0.741531185599394439863864773280788,
+	0.586087235467691130294144845693013,
+	0.405845151377397166906606412076961,
+	0.207784955007898467600689403773245,
+	0.000000000000000000000000000000000,
+}
+
+// Weights of the 15 point Kronrod rule.
+var kronrodWeights = [8]float64{
+	0.022935322010529224963732008058970,
+	0.063092092629978553290700663189204,
+	0.104790010322250183839876322541518,
+	0.140653259715525918745189590510238,
+	0.169004726639267902826583426598550,
+	0.190350578064785409913256402421014,
+	0.204432940075298892414161999234649,
+	0.209482141084727828012999174891714,
+}
+
+// Weights of the 7 point Gauss rule, for the nodes 1, 3, 5 and 7.
+var gaussWeights = [4]float64{
+	0.129484966168869693270611432679082,
+	0.279705391489276667901467771423780,
+	0.381830050505118944950369775488975,
+	0.417959183673469387755102040816327,
+}
+
+// Part of the integration range with its integral and the estimated error.
+type interval struct {
+	from  float64
+	to    float64
+	value float64
+	error float64
+}
+
+// Integrate a tree by the given variable between two bounds with adaptive Gauss-Kronrod quadrature.
+// The interval with the largest error is halved, until the error is below the tolerance.
+// sin(x)^2 from 0 to pi -> 1.5707963267948966
+func Integrate(s *shared.State, node *shared.Node, variable string, from float64, to float64) (float64, error) {
+	if err := check(s, node, variable, "integrate numerically"); err != nil {
+		return 0, err
+	}
+	if math.IsNaN(from) || math.IsInf(from, 0) || math.IsNaN(to) || math.IsInf(to, 0) {
+		return 0, shared.NewError("infinite bounds", "Unable to integrate numerically, the bounds have to be finite.")
+	}
+	if from == to {
+		return 0, nil
+	}
+
+	f := Bind(s, node, variable)
+	tol := Tolerance(s)
+
+	first, x, err := kronrod(f, from, to)
+	if err != nil {
+		return 0, shared.NewError(shared.ErrorCode(err), "Unable to integrate numerically, %v at %s = %v.", err, variable, x)
+	}
+	intervals := []interval{first}
+
+	for i := 0; ; i++ {
+		value, estimate := 0.0, 0.0
+		worst := 0
+		for j, val := range intervals {
+			value += val.value
+			estimate += val.error
+			if val.error > intervals[worst].error {
+				worst = j
+			}
+		}
+		if estimate <= max(tol, tol*math.Abs(value)) {
+			return value, nil
+		}
+
+		// Debug
+		if s.Conf.Options["show_debug_process"] {
+			cfmt.Printf("{{Debug:}}::cyan|bold Subdivision %v: %v with an estimated error of %v.\n", i, value, estimate)
+		}
+
+		w := intervals[worst]
+		middle := w.from + (w.to-w.from)/2
+		if i >= Iterations(s) || middle == w.from || middle == w.to {
+			return 0, shared.NewError("no convergence", "Unable to integrate numerically, no convergence after %v subdivisions, the result %v has an estimated error of %v.", i, value, estimate)
+		}
+
+		left, x, err := kronrod(f, w.from, middle)
+		if err != nil {
+			return 0, shared.NewError(shared.ErrorCode(err), "Unable to integrate numerically, %v at %s = %v.", err, variable, x)
+		}
+		right, x, err := kronrod(f, middle, w.to)
+		if err != nil {
+			return 0, shared.NewError(shared.ErrorCode(err), "Unable to integrate numerically, %v at %s = %v.", err, variable, x)
+		}
+		intervals[worst] = left
+		intervals = append(intervals, right)
+	}
+}
+
+// Applies the Gauss-Kronrod rule to an interval, the difference of both rules is the error estimate.
+// If the function fails, the position is returned with the error.
+func kronrod(f Function, from float64, to float64) (interval, float64, error) {
+	center := (from + to) / 2
+	radius := (to - from) / 2
+
+	kronrodSum, gaussSum := 0.0, 0.0
+	for i, node := range kronrodNodes {
+		points := []float64{center - radius*node, center + radius*node}
+		if node == 0 {
+			points = points[:1]
+		}
+		for _, x := range points {
+			y, err := f(x)
+			if err != nil {
+				return interval{}, x, err
+			}
+			kronrodSum += kronrodWeights[i] * y
+			if i%2 == 1 {
+				gaussSum += gaussWeights[i/2] * y
+			}
+		}
+	}
+
+	return interval{
+		from:  from,
+		to:    to,
+		value: kronrodSum * radius,
+		error: math.Abs((kronrodSum - gaussSum) * radius),
+	}, 0, nil
+}
@@ -0,0 +1,49 @@
+package numeric
+
+import (
+	"lambdacalc/shared"
+	"math"
+	"testing"
+)
+
+func TestIntegrate(t *testing.T) {
+	tests := []struct {
+		input    string
+		from, to float64
+		want     float64
+	}{
+		{"x^2", 0, 3, 9},
+		{"sin(x)^2", 0, math.Pi, math.Pi / 2},
+		{"e^(-x^2)", -5, 5, math.Sqrt(math.Pi)},
+		{"sin(x^2)", 0, 1, 0.3102683017233811},
+		{"x", 2, 2, 0},
+		{"x", 1, 0, -0.5},
+		{"1/sqrt(x)", 0, 1, 2},
+	}
+	for _, test := range tests {
+		got, err := Integrate(state, parse(t, test.input), "x", test.from, test.to)
+		if err != nil {
+			t.Errorf("%s from %v to %v: unexpected error %v", test.input, test.from, test.to, err)
+			continue
+		}
+		if math.Abs(got-test.want) > 1e-8 {
+			t.Errorf("%s from %v to %v: got %v, want %v", test.input, test.from, test.to, got, test.want)
+		}
+	}
+}
+
+func TestIntegrateErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		from, to float64
+		code     string
+	}{
+		{"x", 0, math.Inf(1), "infinite bounds"},
+		{"x + y", 0, 1, "undefined variable"},
+	}
+	for _, test := range tests {
+		if got, err := Integrate(state, parse(t, test.input), "x", test.from, test.to); err == nil || shared.ErrorCode(err) != test.code {
+			t.Errorf("%s from %v to %v: got %v %v, want %s", test.input, test.from, test.to, got, err, test.code)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package numeric
+
+import (
+	"errors"
+	"lambdacalc/interpreter"
+	"lambdacalc/shared"
+	"math"
+)
+
+// A function of a single variable.
+type Function func(x float64) (float64, error)
+
+// Binds a variable of a tree, the function evaluates the tree with the variable set to x.
+// Results that are not finite count as errors, i.e.: 1/x at 0
+func Bind(s *shared.State, node *shared.Node, variable string) Function {
+	return func(x float64) (float64, error) {
+		res, err := interpreter.EvaluateWith(s, node, map[string]float64{variable: x})
+		if err != nil {
+			return 0, err
+		}
+		if math.IsNaN(res) || math.IsInf(res, 0) {
+			return 0, errors.New("not a finite number")
+		}
+		return res, nil
+	}
+}
+
+// Returns the tolerance set in the config, 10 -> 1e-10
+func Tolerance(s *shared.State) float64 {
+	return math.Pow(10, -float64(max(s.Conf.Settings["tolerance"], 1)))
+}
+
+// Returns the iteration limit set in the config.
+func Iterations(s *shared.State) int {
+	return max(s.Conf.Settings["max_iterations"], 1)
+}
+
+// Checks that the variable is the only undefined variable of the tree.
+func check(s *shared.State, node *shared.Node, variable string, action string) error {
+	for _, name := range s.FreeVariables(node) {
+		if name != variable {
+			return shared.NewError("undefined variable", "Unable to %s, undefined variable '%s'.", action, name)
+		}
+	}
+	return nil
+}
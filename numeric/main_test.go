@@ -0,0 +1,71 @@
+package numeric
+
+import (
+	"lambdacalc/lexer"
+	"lambdacalc/parser"
+	"lambdacalc/shared"
+	"math"
+	"strings"
+	"testing"
+)
+
+// State of the tests, with the default config. Tests defining names remove them again.
+var state = shared.NewState(shared.GetDefualtConfig())
+
+// Parses an expression or an equation for a test.
+func parse(t *testing.T, input string) *shared.Node {
+	t.Helper()
+	lexed, err := lexer.LexTokens(state, input)
+	if err != nil {
+		t.Fatalf("%s: unable to lex, %v", input, err)
+	}
+	parsed, err := parser.Parse(state, lexed)
+	if strings.Contains(input, "=") {
+		parsed, err = parser.SearchParse(state, lexed, parser.ASSERTION)
+	}
+	if err != nil {
+		t.Fatalf("%s: unable to parse, %v", input, err)
+	}
+	return parsed
+}
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		input string
+		start float64
+		want  float64
+	}{
+		{"x^2 = 2", 1, math.Sqrt2},
+		{"x^2 - 2", -1, -math.Sqrt2},
+		{"cos(x) = x", 0, 0.7390851332151607},
+		{"e^x = 10", 0, math.Log(10)},
+		{"x^3 - x - 1", 0, 1.324717957244746},
+		// Newton's method fails at the start, the root is found by a sign change.
+		{"abs(x) - 1", 0, 1},
+	}
+	for _, test := range tests {
+		got, err := Solve(state, parse(t, test.input), "x", test.start)
+		if err != nil {
+			t.Errorf("%s near %v: unexpected error %v", test.input, test.start, err)
+			continue
+		}
+		if math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s near %v: got %v, want %v", test.input, test.start, got, test.want)
+		}
+	}
+}
+
+func TestSolveErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		code  string
+	}{
+		{"x^2 + 1", "no convergence"},
+		{"x + y", "undefined variable"},
+	}
+	for _, test := range tests {
+		if got, err := Solve(state, parse(t, test.input), "x", 1); err == nil || shared.ErrorCode(err) != test.code {
+			t.Errorf("%s: got %v %v, want %s", test.input, got, err, test.code)
+		}
+	}
+}
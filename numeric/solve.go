@@ -0,0 +1,220 @@
+package numeric
+
+import (
+	"errors"
+	"fmt"
+	"lambdacalc/shared"
+	"math"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+)
+
+// Factor the search range grows by, while looking for a sign change.
+const BRACKET_GROWTH = 1.6
+
+// Solve an equation numerically, starting at the given value. An expression is solved for f(x) = 0.
+// Newton's method is tried first, if it fails a sign change is searched around the start,
+// that is narrowed down with Brent's method.
+// x^2 = 2 near 1 -> 1.4142135623730951
+func Solve(s *shared.State, node *shared.Node, variable string, start float64) (float64, error) {
+	if err := check(s, node, variable, "solve numerically"); err != nil {
+		return 0, err
+	}
+
+	// f(x) = g(x) -> f(x) - g(x) = 0
+	if node.OperationType == shared.EQUAL {
+		node = &shared.Node{
+			OperationType: shared.MINUS,
+			Value:         0.0,
+			Variable:      "",
+			LNode:         node.LNode,
+			RNode:         node.RNode,
+			Associative:   nil,
+			Rational:      nil,
+		}
+	}
+
+	f := Bind(s, node, variable)
+	tol := Tolerance(s)
+
+	root, last, residual, reason := newton(s, f, start, tol)
+	if reason == nil {
+		return root, nil
+	}
+
+	// Debug
+	if s.Conf.Options["show_debug_process"] {
+		cfmt.Printf("{{Debug:}}::cyan|bold Newton's method failed: %v, searching a sign change.\n", reason)
+	}
+
+	if a, b, ok := bracket(s, f, start); ok {
+		root, err := brent(s, f, a, b, tol*max(1, math.Abs(start)))
+		if err != nil {
+			return 0, shared.NewError(shared.ErrorCode(err), "Unable to solve numerically, %v between %s = %v and %s = %v.", err, variable, a, variable, b)
+		}
+		return root, nil
+	}
+
+	if math.IsNaN(residual) {
+		return 0, shared.NewError("no convergence", "Unable to solve numerically, %v at %s = %v and no sign change was found near %v.", reason, variable, last, start).WithHint("try to start at another value")
+	}
+	return 0, shared.NewError("no convergence", "Unable to solve numerically, %v at %s = %v with a residual of %v and no sign change was found near %v.", reason, variable, last, residual, start).WithHint("try to start at another value")
+}
+
+// Newton's method with a numerical derivative. Returns the root, or the reason why it failed
+// together with the last value and its residual.
+func newton(s *shared.State, f Function, x float64, tol float64) (float64, float64, float64, error) {
+	fx, err := f(x)
+	if err != nil {
+		return 0, x, math.NaN(), err
+	}
+
+	for i := 0; i < Iterations(s); i++ {
+		if fx == 0 {
+			return x, x, 0, nil
+		}
+
+		// Central difference, the step balances rounding and truncation errors.
+		h := math.Cbrt(2.2e-16) * max(1, math.Abs(x))
+		a, err := f(x + h)
+		if err != nil {
+			return 0, x, fx, err
+		}
+		b, err := f(x - h)
+		if err != nil {
+			return 0, x, fx, err
+		}
+		derivative := (a - b) / (2 * h)
+		if derivative == 0 {
+			return 0, x, fx, errors.New("the derivative is zero")
+		}
+
+		step := fx / derivative
+		next := x - step
+		if math.IsNaN(next) || math.IsInf(next, 0) {
+			return 0, x, fx, errors.New("the step is not a finite number")
+		}
+		fnext, err := f(next)
+		if err != nil {
+			return 0, next, math.NaN(), err
+		}
+		x, fx = next, fnext
+
+		if math.Abs(step) <= tol*max(1, math.Abs(x)) {
+			return x, x, fx, nil
+		}
+	}
+	return 0, x, fx, fmt.Errorf("no convergence after %v iterations", Iterations(s))
+}
+
+// Searches a sign change around the start, the search range grows with every iteration.
+// The closest interval [a, b], where the sign of f changes, is returned.
+func bracket(s *shared.State, f Function, start float64) (float64, float64, bool) {
+	h := 0.01 * max(1, math.Abs(start))
+	left, right := start, start
+	fl, errLeft := f(start)
+	fr, errRight := fl, errLeft
+
+	for i := 0; i < Iterations(s); i++ {
+		b := start + h
+		fb, err := f(b)
+		if err == nil && errRight == nil && changesSign(fr, fb) {
+			return right, b, true
+		}
+		right, fr, errRight = b, fb, err
+
+		a := start - h
+		fa, err := f(a)
+		if err == nil && errLeft == nil && changesSign(fa, fl) {
+			return a, left, true
+		}
+		left, fl, errLeft = a, fa, err
+
+		h *= BRACKET_GROWTH
+	}
+	return 0, 0, false
+}
+
+// Checks if two values have opposite signs. Zeros are not counted, they may come from an underflow. e^-800
+func changesSign(a float64, b float64) bool {
+	return (a < 0 && b > 0) || (a > 0 && b < 0)
+}
+
+// Brent's method, combining bisection with the secant method and inverse quadratic interpolation.
+// Needs f(a) and f(b) with different signs. Sign changes at poles or jumps are reported as errors.
+func brent(s *shared.State, f Function, a float64, b float64, tol float64) (float64, error) {
+	fa, err := f(a)
+	if err != nil {
+		return 0, err
+	}
+	fb, err := f(b)
+	if err != nil {
+		return 0, err
+	}
+	bound := min(math.Abs(fa), math.Abs(fb))
+
+	c, fc := b, fb
+	d, e := b-a, b-a
+	for i := 0; i < Iterations(s); i++ {
+		if (fb > 0 && fc > 0) || (fb < 0 && fc < 0) {
+			c, fc = a, fa
+			d = b - a
+			e = d
+		}
+		if math.Abs(fc) < math.Abs(fb) {
+			a, b, c = b, c, b
+			fa, fb, fc = fb, fc, fb
+		}
+
+		precision := 2*2.2e-16*math.Abs(b) + tol/2
+		middle := (c - b) / 2
+		if math.Abs(middle) <= precision || fb == 0 {
+			if math.Abs(fb) >= bound {
+				return 0, fmt.Errorf("the sign changes at a discontinuity near %v", b)
+			}
+			return b, nil
+		}
+
+		if math.Abs(e) >= precision && math.Abs(fa) > math.Abs(fb) {
+			// Interpolation
+			s := fb / fa
+			var p, q float64
+			if a == c {
+				p = 2 * middle * s
+				q = 1 - s
+			} else {
+				q = fa / fc
+				r := fb / fc
+				p = s * (2*middle*q*(q-r) - (b-a)*(r-1))
+				q = (q - 1) * (r - 1) * (s - 1)
+			}
+			if p > 0 {
+				q = -q
+			}
+			p = math.Abs(p)
+			if 2*p < min(3*middle*q-math.Abs(precision*q), math.Abs(e*q)) {
+				e = d
+				d = p / q
+			} else {
+				d = middle
+				e = d
+			}
+		} else {
+			// Bisection
+			d = middle
+			e = d
+		}
+
+		a, fa = b, fb
+		if math.Abs(d) > precision {
+			b += d
+		} else {
+			b += math.Copysign(precision, middle)
+		}
+		fb, err = f(b)
+		if err != nil {
+			return 0, fmt.Errorf("%v at %v", err, b)
+		}
+	}
+	return 0, fmt.Errorf("no convergence after %v iterations", Iterations(s))
+}
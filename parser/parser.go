@@ -1,7 +1,7 @@
 package parser
 
 import (
-	"errors"
+	"fmt"
 	"lambdacalc/shared"
 
 	"github.com/i582/cfmt/cmd/cfmt"
@@ -10,6 +10,7 @@ import (
 // This is a rewrite of the main.go -> parse function.
 
 type parser struct {
+	state        *shared.State
 	currentToken *shared.Token
 	tokens       []shared.Token
 	index        int
@@ -30,31 +31,73 @@ func (p *parser) advance() bool {
 	return true
 }
 
+// Returns the span of the current token, at the end of the input the position after the last token.
+func (p *parser) span() (int, int) {
+	// Behind the last token the span is empty, so it stays inside of the input.
+	if p.index >= len(p.tokens) {
+		last := p.tokens[len(p.tokens)-1]
+		end := last.Offset + len(last.String())
+		return end, end
+	}
+	return p.currentToken.Offset, p.currentToken.Offset + len(p.currentToken.String())
+}
+
+// Returns the span from an offset to the end of the last token read. i.e.: the call g(2)
+func (p *parser) spanFrom(start int) (int, int) {
+	last := p.tokens[min(p.index, len(p.tokens))-1]
+	return start, last.Offset + len(last.String())
+}
+
 func (p *parser) hasNext() bool {
 	return p.index < len(p.tokens)
 }
 
-func Parse(t []shared.Token) (*shared.Node, error) {
+func Parse(s *shared.State, t []shared.Token) (*shared.Node, error) {
+	if len(t) == 0 {
+		return nil, emptyExpression()
+	}
 	parserObject := parser{
+		state:        s,
 		currentToken: &t[0],
 		tokens:       t,
 		index:        0,
 	}
 
-	return parserObject.expression()
+	return parserObject.finish(parserObject.expression())
 }
 
 // Search Parse allows for searching differing top level patterns like
 // assertions or equality checks, both being denoted with an equal sign
 // in its structure.
-func SearchParse(t []shared.Token, m int) (*shared.Node, error) {
+func SearchParse(s *shared.State, t []shared.Token, m int) (*shared.Node, error) {
+	if len(t) == 0 {
+		return nil, emptyExpression()
+	}
 	parserObject := parser{
+		state:        s,
 		currentToken: &t[0],
 		tokens:       t,
 		index:        0,
 	}
 
-	return parserObject.topLevelStructures(m)
+	return parserObject.finish(parserObject.topLevelStructures(m))
+}
+
+// Checks that every token was read, tokens left after the top level are an error.
+// 2+3)*4 -> unexpected ')'
+func (p *parser) finish(node *shared.Node, err error) (*shared.Node, error) {
+	if err != nil {
+		return nil, err
+	}
+	if p.hasNext() {
+		return nil, shared.NewError("unexpected token", "Unable to parse tokens, unexpected '%s'.", p.currentToken).At(p.span())
+	}
+	return node, nil
+}
+
+// Error for an input without tokens, there is no position to mark.
+func emptyExpression() error {
+	return shared.NewError("empty expression", "Unable to parse tokens, empty expression.")
 }
 
 func (p *parser) topLevelStructures(mode int) (*shared.Node, error) {
@@ -63,27 +106,25 @@ func (p *parser) topLevelStructures(mode int) (*shared.Node, error) {
 		// Get A part.
 		a, err := p.expression()
 		if err != nil {
-			cfmt.Printf("{{Error:}}::red|bold Unable to parse assertion, fault assertion variable.")
-			return nil, errors.New("faulty assertion front")
+			// The error of the expression tells more than a faulty assertion.
+			return nil, err
 		}
 
 		// Check if equal sign is there, then skip it.
 		if p.currentToken.TokenType != shared.EQUAL {
-			cfmt.Printf("{{Error:}}::red|bold Unable to parse assertion, mssing assertion symbol.")
-			return nil, errors.New("expecting euqal symbol")
+			return nil, shared.NewError("expecting equal symbol", "Unable to parse assertion, missing assertion symbol.").At(p.span())
 		} else if !p.advance() {
-			cfmt.Printf("{{Error:}}::red|bold Unable to parse assertion, mssing assertion statement.")
-			return nil, errors.New("missing assertion statement")
+			return nil, shared.NewError("missing assertion statement", "Unable to parse assertion, missing assertion statement.").At(p.span())
 		}
 
 		// Get B part.
 		b, err := p.expression()
 		if err != nil {
-			cfmt.Printf("{{Error:}}::red|bold Unable to parse assertion, fault assertion equation.")
-			return nil, errors.New("faulty assertion end")
+			// The error of the expression tells more than a faulty assertion.
+			return nil, err
 		}
 
-		if shared.Conf.Options["show_debug_process"] {
+		if p.state.Conf.Options["show_debug_process"] {
 			cfmt.Printf("(parser 60:1 p.topLevelStructures) {{Debug:}}::cyan|bold Assigning %s the value %s.\n", shared.PrintATree(a), shared.PrintATree(b))
 		}
 
@@ -96,6 +137,7 @@ func (p *parser) topLevelStructures(mode int) (*shared.Node, error) {
 			LNode:         a,
 			RNode:         b,
 			Associative:   nil,
+			Rational:      nil,
 		}, nil
 	default:
 		return p.expression()
@@ -120,16 +162,10 @@ func (p *parser) expression() (*shared.Node, error) {
 				OperationType: shared.MINUS,
 				Value:         0,
 				Variable:      "",
-				LNode: &shared.Node{
-					OperationType: shared.NUMBER,
-					Value:         0.0,
-					Variable:      "",
-					LNode:         nil,
-					RNode:         nil,
-					Associative:   nil,
-				},
-				RNode:       newFactor,
-				Associative: nil,
+				LNode:         shared.NumberNode(0.0),
+				RNode:         newFactor,
+				Associative:   nil,
+				Rational:      nil,
 			}
 		}
 		addends = append(addends, newFactor)
@@ -146,25 +182,18 @@ func (p *parser) expression() (*shared.Node, error) {
 		if operand == 0 {
 			break
 		} else if !p.advance() {
-			break
+			// 3 + is missing its second operand.
+			return nil, shared.NewError("missing token", "Unable to parse tokens, expecting another token.").At(p.span())
 		}
 	}
 
 	switch len(addends) {
 	case 0:
-		cfmt.Println("{{Error:}}::red|bold Unable to parse tokens, expecting another token.")
-		return nil, errors.New("missing token")
+		return nil, shared.NewError("missing token", "Unable to parse tokens, expecting another token.").At(p.span())
 	case 1:
 		return addends[0], nil
 	default:
-		return &shared.Node{
-			OperationType: shared.PLUS,
-			Value:         0,
-			Variable:      "",
-			LNode:         nil,
-			RNode:         nil,
-			Associative:   addends,
-		}, nil
+		return shared.Add(addends...), nil
 	}
 }
 
@@ -181,55 +210,45 @@ func (p *parser) term() (*shared.Node, error) {
 		}
 
 		if operand == shared.DIVIDE {
-			newFactor = &shared.Node{
-				OperationType: shared.POWER,
-				Value:         0,
-				Variable:      "",
-				LNode:         newFactor,
-				RNode: &shared.Node{
-					OperationType: shared.NUMBER,
-					Value:         -1.0,
-					Variable:      "",
-					LNode:         nil,
-					RNode:         nil,
-					Associative:   nil,
-				},
-				Associative: nil,
-			}
+			newFactor = shared.Reciprocal(newFactor)
 		}
 		factors = append(factors, newFactor)
 
+		implicit := false
 		switch p.currentToken.TokenType {
 		case shared.DIVIDE:
 			operand = shared.DIVIDE
 		case shared.MULTIPLY:
 			operand = shared.MULTIPLY
+		case shared.NUMBER, shared.VARIABLE, shared.LPARENTHESES, shared.FUNCTION, shared.SQRT, shared.LBRACKET, shared.UNIT:
+			// Implicit multiplication i.e.: 2x or 2(x + y)
+			// Only if there are tokens left, otherwise the current token was already read.
+			if p.hasNext() {
+				operand = shared.MULTIPLY
+				implicit = true
+			} else {
+				operand = 0
+			}
 		default:
 			operand = 0
 		}
 
 		if operand == 0 {
 			break
+		} else if implicit {
+			continue
 		} else if !p.advance() {
-			break
+			return nil, shared.NewError("missing token", "Unable to parse tokens, expecting another token.").At(p.span())
 		}
 	}
 
 	switch len(factors) {
 	case 0:
-		cfmt.Println("{{Error:}}::red|bold Unable to parse tokens, expecting another token.")
-		return nil, errors.New("missing token")
+		return nil, shared.NewError("missing token", "Unable to parse tokens, expecting another token.").At(p.span())
 	case 1:
 		return factors[0], nil
 	default:
-		return &shared.Node{
-			OperationType: shared.MULTIPLY,
-			Value:         0,
-			Variable:      "",
-			LNode:         nil,
-			RNode:         nil,
-			Associative:   factors,
-		}, nil
+		return shared.Multiply(factors...), nil
 	}
 }
 
@@ -246,8 +265,7 @@ func (p *parser) factor() (*shared.Node, error) {
 	for {
 		if p.currentToken.TokenType == shared.POWER {
 			if !p.advance() {
-				cfmt.Println("{{Error:}}::red|bold Unable to parse tokens, expecting another token.")
-				return nil, errors.New("missing token")
+				return nil, shared.NewError("missing token", "Unable to parse tokens, expecting another token.").At(p.span())
 			}
 
 			exponent, err := p.literal()
@@ -255,14 +273,7 @@ func (p *parser) factor() (*shared.Node, error) {
 				return nil, err
 			}
 
-			result = &shared.Node{
-				OperationType: shared.POWER,
-				Value:         0.0,
-				Variable:      "",
-				LNode:         result,
-				RNode:         exponent,
-				Associative:   nil,
-			}
+			result = shared.Power(result, exponent)
 		} else {
 			return result, nil
 		}
@@ -280,66 +291,131 @@ func (p *parser) literal() (*shared.Node, error) {
 			LNode:         nil,
 			RNode:         nil,
 			Associative:   nil,
+			Rational:      p.currentToken.Rational,
 		}
-		if p.advance() {
+		// A unit belongs to its number i.e.: 1 km / 1 m -> (1 km) / (1 m)
+		if p.advance() && p.currentToken.TokenType == shared.UNIT {
+			node = &shared.Node{
+				OperationType: shared.MULTIPLY,
+				Value:         0.0,
+				Variable:      "",
+				LNode:         nil,
+				RNode:         nil,
+				Associative: []*shared.Node{node, {
+					OperationType: shared.UNIT,
+					Value:         0.0,
+					Variable:      p.currentToken.Variable,
+					LNode:         nil,
+					RNode:         nil,
+					Associative:   nil,
+					Rational:      nil,
+				}},
+				Rational: nil,
+			}
+			p.advance()
 		}
 		return node, nil
 	case shared.VARIABLE:
 		varName := p.currentToken.Variable
+		start := p.currentToken.Offset
+		end := start + len(varName)
 
-		// If tokens following the variable match the pattern of a function.
+		// If tokens following the variable match the pattern of a function. A space before the
+		// parentheses is a product, unless the function is defined. i.e.: s (s + 1)
 		if p.advance() {
-			if p.currentToken.TokenType == shared.LPARENTHESES {
-				// Jump over the parenthesis.
-				if !p.advance() {
-					cfmt.Println("{{Error:}}::red|bold unable to parse tokens, expecting another token.")
-					return nil, errors.New("missing token")
-				}
-
-				// Get all parameters of the function.
-				parameters, err := p.parameter()
+			_, defined := p.state.Functions[varName]
+			if p.currentToken.TokenType == shared.LPARENTHESES && (p.currentToken.Offset <= end || defined) {
+				parameters, err := p.arguments()
 				if err != nil {
 					return nil, err
 				}
 
-				if p.currentToken.TokenType == shared.RPARENTHESES {
-
-				}
-
 				// Check if the number of parameters matches a defined function.
-				if val, ok := shared.Functions[varName]; ok {
+				if val, ok := p.state.Functions[varName]; ok {
 					if len(val.Parameters) == len(parameters) {
 					} else {
-						cfmt.Printf("(parser 196:1 p.literal) {{Error:}}::bold|red Unable to parse tokens, incorrect amout of parameters.\n")
-						return nil, errors.New("unmatched parameters")
+						return nil, shared.NewError("unmatched parameters", "Unable to parse tokens, function '%s' expects %v parameters but got %v.", varName, len(val.Parameters), len(parameters)).At(p.spanFrom(start))
 					}
 				}
 
-				return &shared.Node{
-					OperationType: shared.FUNCTION,
-					Value:         0.0,
-					Variable:      varName,
-					LNode:         nil,
-					RNode:         nil,
-					Associative:   parameters,
-				}, nil
+				return shared.FunctionNode(varName, parameters...), nil
 			}
 		}
 
 		// Else parse the token.
+		return shared.VariableNode(varName), nil
+	case shared.FUNCTION:
+		// Built-in functions always require their parameters i.e.: sin(x)
+		name := p.currentToken.Variable
+		start := p.currentToken.Offset
+		if !p.advance() || p.currentToken.TokenType != shared.LPARENTHESES {
+			return nil, shared.NewError("missing parameters", "Unable to parse tokens, function '%s' expects parameters in parentheses.", name).At(start, start+len(name)).WithHint(fmt.Sprintf("write %s(x) instead of %s x", name, name))
+		}
+
+		parameters, err := p.arguments()
+		if err != nil {
+			return nil, err
+		}
+
+		if !shared.Builtins[name].Accepts(len(parameters)) {
+			return nil, shared.NewError("unmatched parameters", "Unable to parse tokens, function '%s' does not accept %v parameters.", name, len(parameters)).At(p.spanFrom(start))
+		}
+
+		return shared.FunctionNode(name, parameters...), nil
+	case shared.SQRT:
+		// sqrt(x) is the square root, sqrt(x, n) the n-th root.
+		start := p.currentToken.Offset
+		if !p.advance() || p.currentToken.TokenType != shared.LPARENTHESES {
+			return nil, shared.NewError("missing parameters", "Unable to parse tokens, sqrt expects parameters in parentheses.").At(start, start+len(p.state.Conf.Symbols["sqrt"])).WithHint("write sqrt(x) instead of sqrt x")
+		}
+
+		parameters, err := p.arguments()
+		if err != nil {
+			return nil, err
+		}
+
+		degree := shared.NumberNode(2.0)
+		switch len(parameters) {
+		case 1:
+		case 2:
+			degree = parameters[1]
+		default:
+			return nil, shared.NewError("unmatched parameters", "Unable to parse tokens, sqrt does not accept %v parameters.", len(parameters)).At(p.spanFrom(start))
+		}
+
 		return &shared.Node{
-			OperationType: shared.VARIABLE,
+			OperationType: shared.SQRT,
 			Value:         0.0,
-			Variable:      varName,
-			LNode:         nil,
-			RNode:         nil,
+			Variable:      "",
+			LNode:         degree,
+			RNode:         parameters[0],
 			Associative:   nil,
+			Rational:      nil,
+		}, nil
+	case shared.MINUS:
+		// Negation i.e.: -x or -2^2
+		if !p.advance() {
+			return nil, shared.NewError("missing token", "Unable to parse tokens, expecting another token.").At(p.span())
+		}
+
+		res, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+
+		return &shared.Node{
+			OperationType: shared.MINUS,
+			Value:         0.0,
+			Variable:      "",
+			LNode:         shared.ZeroNode(),
+			RNode:         res,
+			Associative:   nil,
+			Rational:      nil,
 		}, nil
 	case shared.LPARENTHESES:
 		// Advancing over the parenthesis to analyse its contents.
 		if !p.advance() {
-			cfmt.Println("{{Error:}}::red|bold Unable to parse tokens, expecting another token.")
-			return nil, errors.New("missing token")
+			return nil, shared.NewError("missing token", "Unable to parse tokens, expecting another token.").At(p.span())
 		}
 
 		// Get expression inside the parenthesis.
@@ -353,15 +429,50 @@ func (p *parser) literal() (*shared.Node, error) {
 			p.advance()
 			return res, nil
 		} else {
-			cfmt.Println("{{Error:}}::red|bold Unable to parse tokens, expecting closing parenthesis.")
-			return nil, errors.New("missing closing parenthesis")
+			return nil, shared.NewError("missing closing parenthesis", "Unable to parse tokens, expecting closing parenthesis.").At(p.span()).WithHint("every opening parenthesis needs a closing one")
 		}
+	case shared.LBRACKET:
+		return p.brackets()
+	case shared.UNIT:
+		// Units are read as a whole by the lexer i.e.: m/s^2
+		node := &shared.Node{
+			OperationType: shared.UNIT,
+			Value:         0.0,
+			Variable:      p.currentToken.Variable,
+			LNode:         nil,
+			RNode:         nil,
+			Associative:   nil,
+			Rational:      nil,
+		}
+		p.advance()
+		return node, nil
 	default:
-		cfmt.Printf("{{Error:}} Unable to parse tokens, unexpected token: %v\n", p.currentToken)
-		return nil, errors.New("unexpected token")
+		return nil, shared.NewError("unexpected token", "Unable to parse tokens, unexpected '%s'.", p.currentToken).At(p.span())
 	}
 }
 
+// Capture the parameters of a function call in parentheses i.e.: (x, y)
+func (p *parser) arguments() ([]*shared.Node, error) {
+	// Jump over the parenthesis.
+	if !p.advance() {
+		return nil, shared.NewError("missing token", "Unable to parse tokens, expecting another token.").At(p.span())
+	}
+
+	// Get all parameters of the function.
+	parameters, err := p.parameter()
+	if err != nil {
+		return nil, err
+	}
+
+	// Jump over the closing parenthesis.
+	if p.currentToken.TokenType != shared.RPARENTHESES {
+		return nil, shared.NewError("missing closing parenthesis", "Unable to parse tokens, expecting closing parenthesis.").At(p.span()).WithHint("every opening parenthesis needs a closing one")
+	}
+	p.advance()
+
+	return parameters, nil
+}
+
 func (p *parser) parameter() ([]*shared.Node, error) {
 	parameters := []*shared.Node{}
 	for {
@@ -370,11 +481,70 @@ func (p *parser) parameter() ([]*shared.Node, error) {
 			return []*shared.Node{}, err
 		}
 		parameters = append(parameters, expr)
-		if p.tokens[p.index].TokenType == shared.COMMA {
-			p.index++
+		if p.hasNext() && p.currentToken.TokenType == shared.COMMA {
+			if !p.advance() {
+				return []*shared.Node{}, shared.NewError("missing token", "Unable to parse tokens, expecting another parameter.").At(p.span())
+			}
 		} else {
 			break
 		}
 	}
 	return parameters, nil
 }
+
+// Capture a vector or a matrix in brackets i.e.: [1, 2] or [[1, 2], [3, 4]]
+// A list of vectors with the same length is a matrix, the vectors are its rows.
+func (p *parser) brackets() (*shared.Node, error) {
+	// Jump over the bracket.
+	if !p.advance() {
+		return nil, shared.NewError("missing token", "Unable to parse tokens, expecting another token.").At(p.span())
+	}
+
+	elements, err := p.parameter()
+	if err != nil {
+		return nil, err
+	}
+
+	// Jump over the closing bracket.
+	if p.currentToken.TokenType != shared.RBRACKET {
+		return nil, shared.NewError("missing closing bracket", "Unable to parse tokens, expecting closing bracket.").At(p.span()).WithHint("every opening bracket needs a closing one")
+	}
+	p.advance()
+
+	rows := 0
+	for _, val := range elements {
+		if val.OperationType == shared.VECTOR {
+			rows++
+		}
+	}
+	if rows == 0 {
+		return &shared.Node{
+			OperationType: shared.VECTOR,
+			Value:         0.0,
+			Variable:      "",
+			LNode:         nil,
+			RNode:         nil,
+			Associative:   elements,
+			Rational:      nil,
+		}, nil
+	}
+
+	// Every element of a matrix is a row of the same length.
+	for _, val := range elements {
+		if val.OperationType != shared.VECTOR {
+			return nil, shared.NewError("mixed matrix rows", "Unable to parse tokens, every row of a matrix has to be in brackets.").At(p.span())
+		}
+		if len(val.Associative) != len(elements[0].Associative) {
+			return nil, shared.NewError("unequal matrix rows", "Unable to parse tokens, the rows of a matrix have to be of the same length, got %v and %v.", len(elements[0].Associative), len(val.Associative)).At(p.span())
+		}
+	}
+	return &shared.Node{
+		OperationType: shared.MATRIX,
+		Value:         0.0,
+		Variable:      "",
+		LNode:         nil,
+		RNode:         nil,
+		Associative:   elements,
+		Rational:      nil,
+	}, nil
+}
@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"lambdacalc/lexer"
+	"lambdacalc/shared"
+	"testing"
+)
+
+// State of the tests, with the default config and without definitions.
+var state = shared.NewState(shared.GetDefualtConfig())
+
+// Inputs without tokens are an error instead of a tree.
+func TestParseEmpty(t *testing.T) {
+	if _, err := Parse(state, nil); err == nil || shared.ErrorCode(err) != "empty expression" {
+		t.Errorf("Parse: got %v, want empty expression", err)
+	}
+	if _, err := SearchParse(state, []shared.Token{}, ASSERTION); err == nil || shared.ErrorCode(err) != "empty expression" {
+		t.Errorf("SearchParse: got %v, want empty expression", err)
+	}
+}
+
+// Errors of a call mark the call from the function name to the closing parenthesis, errors
+// behind the last token stay inside of the input.
+func TestParseSpans(t *testing.T) {
+	tests := []struct {
+		input string
+		code  string
+		span  shared.Span
+	}{
+		{"sin 0", "missing parameters", shared.Span{Start: 0, End: 3}},
+		{"inv 2", "missing parameters", shared.Span{Start: 0, End: 3}},
+		{"2 + sqrt 4", "missing parameters", shared.Span{Start: 4, End: 8}},
+		{"sqrt(1,2,3)", "unmatched parameters", shared.Span{Start: 0, End: 11}},
+		{"1 + sin(1, 2)", "unmatched parameters", shared.Span{Start: 4, End: 13}},
+		{"sin(2", "missing closing parenthesis", shared.Span{Start: 5, End: 5}},
+		{"(1 + 2", "missing closing parenthesis", shared.Span{Start: 6, End: 6}},
+		{"2 + * 3", "unexpected token", shared.Span{Start: 4, End: 5}},
+		{"3 +", "missing token", shared.Span{Start: 3, End: 3}},
+		{"3 * ", "missing token", shared.Span{Start: 3, End: 3}},
+		{"2 /", "missing token", shared.Span{Start: 3, End: 3}},
+		{"2 ^", "missing token", shared.Span{Start: 3, End: 3}},
+		{"2+3)*4", "unexpected token", shared.Span{Start: 3, End: 4}},
+		{"1 = 2", "unexpected token", shared.Span{Start: 2, End: 3}},
+		{"1 ,2", "unexpected token", shared.Span{Start: 2, End: 3}},
+		{"[1,2] = [1,2]", "unexpected token", shared.Span{Start: 6, End: 7}},
+	}
+	for _, test := range tests {
+		lexed, err := lexer.LexTokens(state, test.input)
+		if err != nil {
+			t.Fatalf("%s: unable to lex, %v", test.input, err)
+		}
+		_, err = Parse(state, lexed)
+		e, ok := err.(*shared.Error)
+		if !ok || e.Code != test.code || e.Span == nil {
+			t.Errorf("%s: got %v, want %s", test.input, err, test.code)
+			continue
+		}
+		if *e.Span != test.span {
+			t.Errorf("%s: got span %v, want %v", test.input, *e.Span, test.span)
+		}
+	}
+}
+
+// Tokens left after an assertion are not dropped.
+func TestSearchParseLeftover(t *testing.T) {
+	lexed, err := lexer.LexTokens(state, "x = 1 = 2")
+	if err != nil {
+		t.Fatalf("x = 1 = 2: unable to lex, %v", err)
+	}
+	_, err = SearchParse(state, lexed, ASSERTION)
+	e, ok := err.(*shared.Error)
+	if !ok || e.Message != "Unable to parse tokens, unexpected '='." || e.Span == nil {
+		t.Fatalf("x = 1 = 2: got %v, want unexpected token", err)
+	}
+	if want := (shared.Span{Start: 6, End: 7}); *e.Span != want {
+		t.Errorf("x = 1 = 2: got span %v, want %v", *e.Span, want)
+	}
+}
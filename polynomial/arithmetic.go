@@ -0,0 +1,223 @@
+package polynomial
+
+import (
+	"math/big"
+	"slices"
+)
+
+// Checks if the polynomial is 0.
+func (p *Polynomial) IsZero() bool {
+	return len(p.Terms) == 0
+}
+
+// Checks if the polynomial does not depend on any variable.
+func (p *Polynomial) IsConstant() bool {
+	return len(p.Terms) == 0 || (len(p.Terms) == 1 && degree(p.Terms[0].Exponents) == 0)
+}
+
+// Checks if two polynomials are the same.
+func (p *Polynomial) Equal(q *Polynomial) bool {
+	return p.Sub(q).IsZero()
+}
+
+// Returns the coefficient of the first term, 0 for the zero polynomial.
+func (p *Polynomial) Leading() *big.Rat {
+	if p.IsZero() {
+		return new(big.Rat)
+	}
+	return new(big.Rat).Set(p.Terms[0].Coefficient)
+}
+
+// Highest total degree of a term, -1 for the zero polynomial. x^2*y + x -> 3
+func (p *Polynomial) TotalDegree() int {
+	if p.IsZero() {
+		return -1
+	}
+	return degree(p.Terms[0].Exponents)
+}
+
+// Highest exponent of a variable, -1 for the zero polynomial. x^2*y + y^3, y -> 3
+func (p *Polynomial) Degree(variable string) int {
+	if p.IsZero() {
+		return -1
+	}
+	i := slices.Index(p.Variables, variable)
+	if i == -1 {
+		return 0
+	}
+	res := 0
+	for _, term := range p.Terms {
+		res = max(res, term.Exponents[i])
+	}
+	return res
+}
+
+// Returns the variables, that appear in a term.
+func (p *Polynomial) Used() []string {
+	res := []string{}
+	for _, variable := range p.Variables {
+		if p.Degree(variable) > 0 {
+			res = append(res, variable)
+		}
+	}
+	return res
+}
+
+// Returns the coefficient of variable^k as a polynomial in the other variables.
+// x^2*y + 3x^2 + y, x, 2 -> y + 3
+func (p *Polynomial) Coefficient(variable string, k int) *Polynomial {
+	i := slices.Index(p.Variables, variable)
+	res := &Polynomial{Variables: p.Variables, Terms: []Term{}, state: p.state}
+	for _, term := range p.Terms {
+		if (i == -1 && k == 0) || (i != -1 && term.Exponents[i] == k) {
+			exponents := slices.Clone(term.Exponents)
+			if i != -1 {
+				exponents[i] = 0
+			}
+			res.Terms = append(res.Terms, Term{Coefficient: term.Coefficient, Exponents: exponents})
+		}
+	}
+	res.normalize()
+	return res
+}
+
+func (p *Polynomial) Add(q *Polynomial) *Polynomial {
+	p, q = align(p, q)
+	res := &Polynomial{Variables: p.Variables, Terms: append(slices.Clone(p.Terms), q.Terms...), state: p.state}
+	res.normalize()
+	return res
+}
+
+func (p *Polynomial) Sub(q *Polynomial) *Polynomial {
+	return p.Add(q.Scale(big.NewRat(-1, 1)))
+}
+
+func (p *Polynomial) Mul(q *Polynomial) *Polynomial {
+	p, q = align(p, q)
+	res := &Polynomial{Variables: p.Variables, Terms: []Term{}, state: p.state}
+	for _, a := range p.Terms {
+		p.state.StopIfCanceled()
+		for _, b := range q.Terms {
+			res.Terms = append(res.Terms, multiplyTerms(a, b))
+		}
+	}
+	res.normalize()
+	return res
+}
+
+// Multiplies every coefficient by a rational.
+func (p *Polynomial) Scale(c *big.Rat) *Polynomial {
+	res := &Polynomial{Variables: p.Variables, Terms: []Term{}, state: p.state}
+	for _, term := range p.Terms {
+		res.Terms = append(res.Terms, Term{Coefficient: new(big.Rat).Mul(term.Coefficient, c), Exponents: term.Exponents})
+	}
+	res.normalize()
+	return res
+}
+
+// Raises the polynomial to a non negative integer power by repeated squaring.
+func (p *Polynomial) Pow(n int) *Polynomial {
+	res := Constant(p.state, p.Variables, big.NewRat(1, 1))
+	base := p
+	for n > 0 {
+		if n%2 == 1 {
+			res = res.Mul(base)
+		}
+		base = base.Mul(base)
+		n /= 2
+	}
+	return res
+}
+
+// Divides two polynomials, if the divisor is a factor of the dividend.
+// x^2 - y^2, x + y -> x - y, true
+func (p *Polynomial) Divide(q *Polynomial) (*Polynomial, bool) {
+	p, q = align(p, q)
+	if q.IsZero() {
+		return nil, false
+	}
+
+	quotient := &Polynomial{Variables: p.Variables, Terms: []Term{}, state: p.state}
+	rest := p
+	for !rest.IsZero() {
+		// The leading term of the rest has to be a multiple of the leading term of the divisor.
+		term, ok := divideTerms(rest.Terms[0], q.Terms[0])
+		if !ok {
+			return nil, false
+		}
+		quotient.Terms = append(quotient.Terms, term)
+		rest = rest.Sub((&Polynomial{Variables: p.Variables, Terms: []Term{term}, state: p.state}).Mul(q))
+	}
+	quotient.normalize()
+	return quotient, true
+}
+
+// Rational content of the polynomial, the gcd of the numerators over the lcm of the denominators.
+// The sign makes the leading coefficient of the primitive part positive. -2x + 4/3 -> -2/3
+func (p *Polynomial) Content() *big.Rat {
+	if p.IsZero() {
+		return big.NewRat(1, 1)
+	}
+	num := new(big.Int)
+	denom := big.NewInt(1)
+	for _, term := range p.Terms {
+		num.GCD(nil, nil, num, new(big.Int).Abs(term.Coefficient.Num()))
+		g := new(big.Int).GCD(nil, nil, denom, term.Coefficient.Denom())
+		denom.Mul(denom, new(big.Int).Quo(term.Coefficient.Denom(), g))
+	}
+	res := new(big.Rat).SetFrac(num, denom)
+	if p.Terms[0].Coefficient.Sign() < 0 {
+		res.Neg(res)
+	}
+	return res
+}
+
+// Returns the polynomial divided by its content, with integer coefficients and a positive leading coefficient.
+// -2x + 4/3 -> 3x - 2
+func (p *Polynomial) Primitive() *Polynomial {
+	return p.Scale(new(big.Rat).Inv(p.Content()))
+}
+
+// Returns both polynomials in the union of their variables.
+func align(p, q *Polynomial) (*Polynomial, *Polynomial) {
+	if slices.Equal(p.Variables, q.Variables) {
+		return p, q
+	}
+	variables := sorted(append(slices.Clone(p.Variables), q.Variables...))
+	variables = slices.Compact(variables)
+	return p.extend(variables), q.extend(variables)
+}
+
+// Rewrites the terms for more variables, that include the current ones.
+func (p *Polynomial) extend(variables []string) *Polynomial {
+	res := &Polynomial{Variables: variables, Terms: []Term{}, state: p.state}
+	for _, term := range p.Terms {
+		exponents := make([]int, len(variables))
+		for i, variable := range p.Variables {
+			exponents[slices.Index(variables, variable)] = term.Exponents[i]
+		}
+		res.Terms = append(res.Terms, Term{Coefficient: term.Coefficient, Exponents: exponents})
+	}
+	res.normalize()
+	return res
+}
+
+func multiplyTerms(a, b Term) Term {
+	exponents := make([]int, len(a.Exponents))
+	for i := range exponents {
+		exponents[i] = a.Exponents[i] + b.Exponents[i]
+	}
+	return Term{Coefficient: new(big.Rat).Mul(a.Coefficient, b.Coefficient), Exponents: exponents}
+}
+
+// Divides two terms, if every exponent of the divisor is at most the one of the dividend.
+func divideTerms(a, b Term) (Term, bool) {
+	exponents := make([]int, len(a.Exponents))
+	for i := range exponents {
+		exponents[i] = a.Exponents[i] - b.Exponents[i]
+		if exponents[i] < 0 {
+			return Term{}, false
+		}
+	}
+	return Term{Coefficient: new(big.Rat).Quo(a.Coefficient, b.Coefficient), Exponents: exponents}, true
+}
@@ -0,0 +1,146 @@
+package polynomial
+
+import (
+	"math/big"
+	"slices"
+)
+
+// Divides two polynomials with remainder. The leading term of the divisor is divided out of the
+// terms of the rest, until no term of the rest is a multiple of it. For polynomials in one variable
+// this is long division. Reports false, if the divisor is zero.
+// x^3 - 1, x - 1 -> x^2 + x + 1, 0
+func (p *Polynomial) DivMod(q *Polynomial) (*Polynomial, *Polynomial, bool) {
+	p, q = align(p, q)
+	if q.IsZero() {
+		return nil, nil, false
+	}
+
+	quotient := &Polynomial{Variables: p.Variables, Terms: []Term{}, state: p.state}
+	remainder := &Polynomial{Variables: p.Variables, Terms: []Term{}, state: p.state}
+	rest := p
+	for !rest.IsZero() {
+		term, ok := divideTerms(rest.Terms[0], q.Terms[0])
+		if !ok {
+			// The leading term stays in the remainder.
+			remainder.Terms = append(remainder.Terms, rest.Terms[0])
+			rest = &Polynomial{Variables: p.Variables, Terms: rest.Terms[1:], state: p.state}
+			continue
+		}
+		quotient.Terms = append(quotient.Terms, term)
+		rest = rest.Sub((&Polynomial{Variables: p.Variables, Terms: []Term{term}, state: p.state}).Mul(q))
+	}
+	quotient.normalize()
+	remainder.normalize()
+	return quotient, remainder, true
+}
+
+// Pseudo-division in a variable, the coefficients are polynomials in the other variables. Returns
+// quotient and remainder of lc(q)^(deg p - deg q + 1) * p, which can be divided without fractions.
+// x^2 + y, y*x + 1, x -> y*x - 1, y^3 + 1
+func (p *Polynomial) PseudoDivide(q *Polynomial, variable string) (*Polynomial, *Polynomial, bool) {
+	p, q = align(p, q)
+	if q.IsZero() {
+		return nil, nil, false
+	}
+	if !slices.Contains(p.Variables, variable) {
+		variables := sorted(append(slices.Clone(p.Variables), variable))
+		p, q = p.extend(variables), q.extend(variables)
+	}
+
+	n := q.Degree(variable)
+	lc := q.Coefficient(variable, n)
+	quotient := Constant(p.state, p.Variables, new(big.Rat))
+	rest := p
+	e := max(p.Degree(variable)-n+1, 0)
+	for !rest.IsZero() && rest.Degree(variable) >= n {
+		m := rest.Degree(variable)
+		term := rest.Coefficient(variable, m).Mul(Variable(p.state, p.Variables, variable).Pow(m - n))
+		quotient = quotient.Mul(lc).Add(term)
+		rest = rest.Mul(lc).Sub(term.Mul(q))
+		e--
+	}
+	factor := lc.Pow(e)
+	return quotient.Mul(factor), rest.Mul(factor), true
+}
+
+// Greatest common divisor of two polynomials, it is primitive with a positive leading coefficient.
+// Polynomials in one variable use the Euclidean algorithm, polynomials in several variables the
+// subresultant algorithm in their first variable, with the contents calculated recursively.
+// x^2 - 1, x^2 + 2x + 1 -> x + 1, x^2*y - y, x*y + y -> x*y + y
+func GCD(p, q *Polynomial) *Polynomial {
+	p, q = align(p, q)
+	if p.IsZero() {
+		return q.Primitive()
+	}
+	if q.IsZero() {
+		return p.Primitive()
+	}
+
+	used := slices.Compact(sorted(append(p.Used(), q.Used()...)))
+	switch len(used) {
+	case 0:
+		return Constant(p.state, p.Variables, big.NewRat(1, 1))
+	case 1:
+		g := gcd(p.coefficients(used[0]), q.coefficients(used[0]))
+		return fromCoefficients(p.state, p.Variables, used[0], g).Primitive()
+	}
+
+	// gcd(p, q) = gcd(cont(p), cont(q)) * gcd(pp(p), pp(q)), in the main variable v.
+	v := used[0]
+	a, b := p, q
+	if a.Degree(v) < b.Degree(v) {
+		a, b = b, a
+	}
+	ca, cb := a.content(v), b.content(v)
+	content := GCD(ca, cb)
+	a, _ = a.Divide(ca)
+	b, _ = b.Divide(cb)
+
+	if b.Degree(v) == 0 {
+		return content.Primitive()
+	}
+	res := subresultant(a, b, v)
+	return content.Mul(res.primitivePart(v)).Primitive()
+}
+
+// Last non constant remainder of the subresultant remainder sequence in a variable. The scaling of
+// the remainders keeps the coefficients from growing, while every division stays exact.
+func subresultant(a, b *Polynomial, v string) *Polynomial {
+	g := Constant(a.state, a.Variables, big.NewRat(1, 1))
+	h := Constant(a.state, a.Variables, big.NewRat(1, 1))
+	for {
+		delta := a.Degree(v) - b.Degree(v)
+		_, r, _ := a.PseudoDivide(b, v)
+		if r.IsZero() {
+			return b
+		}
+		if r.Degree(v) == 0 {
+			return Constant(a.state, a.Variables, big.NewRat(1, 1))
+		}
+
+		a = b
+		b, _ = r.Divide(g.Mul(h.Pow(delta)))
+		g = a.Coefficient(v, a.Degree(v))
+		// h = g^delta / h^(delta - 1)
+		if delta > 0 {
+			h, _ = g.Pow(delta).Divide(h.Pow(delta - 1))
+		}
+	}
+}
+
+// Greatest common divisor of the coefficients in a variable. x*y^2 + x, y -> x
+func (p *Polynomial) content(v string) *Polynomial {
+	res := Constant(p.state, p.Variables, new(big.Rat))
+	for k := 0; k <= p.Degree(v); k++ {
+		if c := p.Coefficient(v, k); !c.IsZero() {
+			res = GCD(res, c)
+		}
+	}
+	return res
+}
+
+// Divides the polynomial by its content in a variable. x*y^2 + x, y -> y^2 + 1
+func (p *Polynomial) primitivePart(v string) *Polynomial {
+	res, _ := p.Divide(p.content(v))
+	return res
+}
@@ -0,0 +1,47 @@
+package polynomial
+
+import (
+	"lambdacalc/shared"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+)
+
+// Multiplies out a polynomial and writes it in normal form. The terms are sorted by their degree
+// and the variables in alphabetical order, like terms are combined.
+// (x + y)^2 -> x^2 + 2xy + y^2
+func Expand(s *shared.State, node *shared.Node) (*shared.Node, error) {
+	p, err := FromNode(s, node)
+	if err != nil {
+		return nil, err
+	}
+
+	// Debug
+	if s.Conf.Options["show_debug_process"] {
+		cfmt.Printf("{{Debug:}}::cyan|bold Variables: %v\n", p.Variables)
+	}
+
+	return p.ToNode(), nil
+}
+
+// Checks if two trees are the same polynomial. Every variable is a symbol here, defined variables
+// and constants are not inserted. Reports false, if a tree is not a polynomial.
+// (x + 1)^2, x^2 + 2x + 1 -> true
+func Equal(s *shared.State, a, b *shared.Node) bool {
+	p, ok := FromNodes(s, a, b)
+	return ok && p[0].Equal(p[1])
+}
+
+// Returns the names of all variables in the tree.
+func names(node *shared.Node) []string {
+	if node == nil {
+		return []string{}
+	}
+	if node.OperationType == shared.VARIABLE {
+		return []string{node.Variable}
+	}
+	res := append(names(node.LNode), names(node.RNode)...)
+	for _, val := range node.Associative {
+		res = append(res, names(val)...)
+	}
+	return res
+}
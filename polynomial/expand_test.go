@@ -0,0 +1,58 @@
+package polynomial
+
+import (
+	"lambdacalc/shared"
+	"testing"
+)
+
+// Expanded polynomials are sorted by degree, so equal polynomials are written the same way.
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"(x+1)^2", "((x^2)+(2*x)+1)"},
+		{"(1+x)^2", "((x^2)+(2*x)+1)"},
+		{"(x-y)*(x+y)", "((x^2)-(y^2))"},
+		{"(a+b)^3", "((a^3)+(3*(a^2)*b)+(3*a*(b^2))+(b^3))"},
+		{"(b+a)^3", "((a^3)+(3*(a^2)*b)+(3*a*(b^2))+(b^3))"},
+		{"2*(x+3)", "((2*x)+6)"},
+		{"(x^2+1)^2 - 1", "((x^4)+(2*(x^2)))"},
+		{"(x+1)*(x-1) - x^2", "-1"},
+	}
+	for _, test := range tests {
+		got, err := Expand(state, parseNode(t, test.expression))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.expression, err)
+			continue
+		}
+		if res := shared.PrintATree(got); res != test.want {
+			t.Errorf("%s: got %s, want %s", test.expression, res, test.want)
+		}
+	}
+
+	for _, expression := range []string{"(x+1)^-1", "sin(x)*(x+1)"} {
+		if _, err := Expand(state, parseNode(t, expression)); err == nil {
+			t.Errorf("%s: want an error", expression)
+		}
+	}
+}
+
+// Polynomials are equal if they have the same normal form, no matter how they are written.
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"(x+1)^2", "x^2+2x+1", true},
+		{"x*y", "y*x", true},
+		{"(x+1)*(x-1)", "x^2 - 1", true},
+		{"x+1", "x+2", false},
+		{"sin(x)", "sin(x)", false},
+	}
+	for _, test := range tests {
+		if got := Equal(state, parseNode(t, test.a), parseNode(t, test.b)); got != test.want {
+			t.Errorf("%s, %s: got %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,433 @@
+package polynomial
+
+import (
+	"lambdacalc/shared"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+)
+
+// Most combinations of divisors, that are tried when searching a factor with Kronecker's method.
+const MAX_COMBINATIONS = 20000
+
+// Integers with more bits are not divided into their divisors. 2^40 ≈ 10^12
+const MAX_DIVISOR_BITS = 40
+
+// An irreducible factor of a polynomial and how often it divides it.
+type Irreducible struct {
+	Polynomial   *Polynomial
+	Multiplicity int
+}
+
+// Factors a polynomial over the rationals into a constant and powers of irreducible polynomials.
+// Returns the product of the factors, a single factor is returned as it is.
+// x^3 - x -> x * (x - 1) * (x + 1), x^2 + 2x + 1 -> (x + 1)^2
+func Factor(s *shared.State, node *shared.Node) (*shared.Node, error) {
+	p, err := FromNode(s, node)
+	if err != nil {
+		return nil, err
+	}
+
+	// Debug
+	if s.Conf.Options["show_debug_process"] {
+		cfmt.Printf("{{Debug:}}::cyan|bold Polynomial: %s\n", shared.PrintATree(p.ToNode()))
+	}
+
+	constant, factors := p.Factors()
+	res := []*shared.Node{}
+	if constant.Cmp(big.NewRat(1, 1)) != 0 || len(factors) == 0 {
+		c := rationalNode(s, constant)
+		if c.OperationType == shared.MULTIPLY && len(factors) > 0 {
+			res = append(res, c.Associative...)
+		} else {
+			res = append(res, c)
+		}
+	}
+	for _, f := range factors {
+		if f.Multiplicity == 1 {
+			res = append(res, f.Polynomial.ToNode())
+		} else {
+			res = append(res, shared.Power(f.Polynomial.ToNode(), shared.NumberNode(float64(f.Multiplicity))))
+		}
+	}
+	if len(res) == 1 {
+		return res[0], nil
+	}
+	return shared.Multiply(res...), nil
+}
+
+// Returns the constant factor and the irreducible factors of the polynomial. The factors have
+// integer coefficients and a positive leading coefficient, they are ordered by their degree and length.
+// 2x^2 - 2 -> 2, [x - 1, x + 1]
+func (p *Polynomial) Factors() (*big.Rat, []Irreducible) {
+	if p.IsConstant() {
+		return p.Leading(), []Irreducible{}
+	}
+
+	rest, variables := splitMonomial(p.Primitive())
+	res := []Irreducible{}
+	for _, f := range append(variables, factorAll(rest)...) {
+		i := slices.IndexFunc(res, func(other Irreducible) bool {
+			return other.Polynomial.Equal(f)
+		})
+		if i == -1 {
+			res = append(res, Irreducible{Polynomial: f, Multiplicity: 1})
+		} else {
+			res[i].Multiplicity++
+		}
+	}
+	slices.SortStableFunc(res, func(a, b Irreducible) int {
+		if a.Polynomial.TotalDegree() != b.Polynomial.TotalDegree() {
+			return a.Polynomial.TotalDegree() - b.Polynomial.TotalDegree()
+		}
+		if len(a.Polynomial.Terms) != len(b.Polynomial.Terms) {
+			return len(a.Polynomial.Terms) - len(b.Polynomial.Terms)
+		}
+		return strings.Compare(shared.PrintATree(a.Polynomial.ToNode()), shared.PrintATree(b.Polynomial.ToNode()))
+	})
+
+	// The constant factor makes the leading coefficients match.
+	product := Constant(p.state, p.Variables, big.NewRat(1, 1))
+	for _, f := range res {
+		product = product.Mul(f.Polynomial.Pow(f.Multiplicity))
+	}
+	return new(big.Rat).Quo(p.Leading(), product.Leading()), res
+}
+
+// Splits off the highest power of each variable, that divides every term.
+// Returns the rest and the variables once for each power. x^3*y + x^2 -> x*y + 1, [x x]
+func splitMonomial(p *Polynomial) (*Polynomial, []*Polynomial) {
+	if p.IsZero() {
+		return p, []*Polynomial{}
+	}
+	exponents := slices.Clone(p.Terms[0].Exponents)
+	for _, term := range p.Terms {
+		for i, val := range term.Exponents {
+			exponents[i] = min(exponents[i], val)
+		}
+	}
+
+	variables := []*Polynomial{}
+	for i, val := range exponents {
+		for range val {
+			variables = append(variables, Variable(p.state, p.Variables, p.Variables[i]))
+		}
+	}
+	rest, _ := p.Divide(&Polynomial{
+		Variables: p.Variables,
+		Terms:     []Term{{Coefficient: big.NewRat(1, 1), Exponents: exponents}},
+		state:     p.state,
+	})
+	return rest, variables
+}
+
+// Factors a primitive polynomial into irreducible primitive factors, repeated factors appear multiple times.
+func factorAll(p *Polynomial) []*Polynomial {
+	p, res := splitMonomial(p.Primitive())
+	if p.IsConstant() {
+		return res
+	}
+
+	used := p.Used()
+	if len(used) == 1 {
+		return append(res, factorUnivariate(p, used[0])...)
+	}
+	return append(res, factorMultivariate(p, used)...)
+}
+
+// Factors a polynomial in one variable. The square free parts are split into linear factors
+// with the rational root theorem and into factors of higher degree with Kronecker's method.
+// x^4 - 1 -> (x - 1) * (x + 1) * (x^2 + 1)
+func factorUnivariate(p *Polynomial, variable string) []*Polynomial {
+	res := []*Polynomial{}
+	for i, part := range squareFree(p.coefficients(variable)) {
+		if len(part) <= 1 {
+			continue
+		}
+		for _, f := range split(p.state, primitive(p.state, part)) {
+			for range i + 1 {
+				res = append(res, fromCoefficients(p.state, p.Variables, variable, f))
+			}
+		}
+	}
+	return res
+}
+
+// Splits a square free polynomial with integer coefficients into irreducible factors.
+func split(s *shared.State, a []*big.Rat) [][]*big.Rat {
+	if len(a) <= 2 {
+		return [][]*big.Rat{a}
+	}
+	if root, ok := rationalRoot(a); ok {
+		// x = p/q -> qx - p
+		linear := []*big.Rat{new(big.Rat).Neg(new(big.Rat).SetInt(root.Num())), new(big.Rat).SetInt(root.Denom())}
+		quotient, _ := divmod(a, linear)
+		return append([][]*big.Rat{linear}, split(s, primitive(s, quotient))...)
+	}
+	for d := 2; d <= (len(a)-1)/2; d++ {
+		if f, ok := kronecker(s, a, d); ok {
+			quotient, _ := divmod(a, f)
+			return append(split(s, f), split(s, primitive(s, quotient))...)
+		}
+	}
+	return [][]*big.Rat{a}
+}
+
+// Finds a rational root p/q of a polynomial with integer coefficients, where p divides the
+// constant coefficient and q the leading coefficient.
+// 6x^2 + 5x + 1 -> -1/3
+func rationalRoot(a []*big.Rat) (*big.Rat, bool) {
+	if a[0].Sign() == 0 {
+		return new(big.Rat), true
+	}
+	numerators, ok := divisors(a[0].Num())
+	if !ok {
+		return nil, false
+	}
+	denominators, ok := divisors(lead(a).Num())
+	if !ok {
+		return nil, false
+	}
+
+	for _, q := range denominators {
+		for _, p := range numerators {
+			for _, sign := range []int64{1, -1} {
+				root := new(big.Rat).SetFrac(new(big.Int).Mul(p, big.NewInt(sign)), q)
+				if evaluate(a, root).Sign() == 0 {
+					return root, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// Searches a factor of the given degree with Kronecker's method. The values of a factor at
+// d + 1 integers divide the values of the polynomial there, so every combination of divisors
+// is interpolated and tried. The polynomial must not have rational roots.
+// x^4 + 4, 2 -> x^2 - 2x + 2
+func kronecker(s *shared.State, a []*big.Rat, d int) ([]*big.Rat, bool) {
+	// Integers, where the value has few divisors, lead to fewer combinations.
+	type point struct {
+		x        *big.Rat
+		divisors []*big.Int
+	}
+	candidates := []point{}
+	for i := 0; i <= 4*(d+1); i++ {
+		x := big.NewRat(int64((i+1)/2), 1)
+		if i%2 == 0 {
+			x.Neg(x)
+		}
+		value := evaluate(a, x)
+		list, ok := divisors(value.Num())
+		if ok && value.Sign() != 0 {
+			candidates = append(candidates, point{x: x, divisors: list})
+		}
+	}
+	if len(candidates) < d+1 {
+		return nil, false
+	}
+	slices.SortStableFunc(candidates, func(a, b point) int {
+		return len(a.divisors) - len(b.divisors)
+	})
+	points := candidates[:d+1]
+
+	// The sign of the first value is fixed, the negated factor is the same factor.
+	combinations := 1
+	for i, val := range points {
+		combinations *= len(val.divisors) * min(i+1, 2)
+		if combinations > MAX_COMBINATIONS {
+			return nil, false
+		}
+	}
+
+	xs := []*big.Rat{}
+	for _, val := range points {
+		xs = append(xs, val.x)
+	}
+	basis := [][]*big.Rat{}
+	for i := range xs {
+		basis = append(basis, lagrange(xs, i))
+	}
+
+	// Every digit of the counter selects a divisor and its sign.
+	counter := make([]int, len(points))
+	for {
+		factor := []*big.Rat{}
+		for i, val := range points {
+			divisor := new(big.Rat).SetInt(val.divisors[counter[i]/2])
+			if counter[i]%2 == 1 {
+				divisor.Neg(divisor)
+			}
+			factor = addScaled(factor, basis[i], divisor)
+		}
+		if len(factor) == d+1 && isInteger(factor) {
+			if _, rest := divmod(a, factor); len(rest) == 0 {
+				return primitive(s, factor), true
+			}
+		}
+
+		i := 0
+		for ; i < len(counter); i++ {
+			if i == 0 {
+				counter[i] += 2
+			} else {
+				counter[i]++
+			}
+			if counter[i] < 2*len(points[i].divisors) {
+				break
+			}
+			counter[i] = 0
+		}
+		if i == len(counter) {
+			return nil, false
+		}
+	}
+}
+
+// Lagrange basis polynomial of the i-th point, which is 1 there and 0 at the other points.
+func lagrange(xs []*big.Rat, i int) []*big.Rat {
+	res := []*big.Rat{big.NewRat(1, 1)}
+	for j, val := range xs {
+		if i == j {
+			continue
+		}
+		// (t - x_j) / (x_i - x_j)
+		denom := new(big.Rat).Sub(xs[i], val)
+		next := make([]*big.Rat, len(res)+1)
+		for k := range next {
+			next[k] = new(big.Rat)
+		}
+		for k, c := range res {
+			next[k+1].Add(next[k+1], new(big.Rat).Quo(c, denom))
+			next[k].Sub(next[k], new(big.Rat).Quo(new(big.Rat).Mul(c, val), denom))
+		}
+		res = next
+	}
+	return res
+}
+
+// a + c * b
+func addScaled(a, b []*big.Rat, c *big.Rat) []*big.Rat {
+	scaled := make([]*big.Rat, len(b))
+	for i, val := range b {
+		scaled[i] = new(big.Rat).Neg(new(big.Rat).Mul(val, c))
+	}
+	return subCoefficients(a, scaled)
+}
+
+func isInteger(a []*big.Rat) bool {
+	for _, c := range a {
+		if !c.IsInt() {
+			return false
+		}
+	}
+	return true
+}
+
+// Coefficients divided by their content. 2x^2 - 4/3 -> 3x^2 - 2
+func primitive(s *shared.State, a []*big.Rat) []*big.Rat {
+	return fromCoefficients(s, []string{""}, "", a).Primitive().coefficients("")
+}
+
+// Returns the positive divisors of an integer, if it is small enough to find them. 12 -> 1 2 3 4 6 12
+func divisors(n *big.Int) ([]*big.Int, bool) {
+	if n.BitLen() > MAX_DIVISOR_BITS || n.Sign() == 0 {
+		return nil, false
+	}
+	m := new(big.Int).Abs(n).Int64()
+	small := []*big.Int{}
+	large := []*big.Int{}
+	for i := int64(1); i*i <= m; i++ {
+		if m%i == 0 {
+			small = append(small, big.NewInt(i))
+			if i*i != m {
+				large = append([]*big.Int{big.NewInt(m / i)}, large...)
+			}
+		}
+	}
+	return append(small, large...), true
+}
+
+// Factors a polynomial in several variables. A polynomial linear in a variable, a*v + b, is
+// split by the factors that a shares with b, otherwise it is irreducible. A polynomial quadratic
+// in a variable is split with the quadratic formula, if its discriminant is a square.
+// a*x + a*y + b*x + b*y -> (x + y) * (a + b), x^2 - y^2 -> (x - y) * (x + y)
+func factorMultivariate(p *Polynomial, used []string) []*Polynomial {
+	for _, v := range used {
+		switch p.Degree(v) {
+		case 1:
+			for _, f := range factorAll(p.Coefficient(v, 1)) {
+				if rest, ok := p.Divide(f); ok {
+					return append([]*Polynomial{f}, factorAll(rest)...)
+				}
+			}
+			return []*Polynomial{p}
+		case 2:
+			if res, ok := quadratic(p, v); ok {
+				return res
+			}
+		}
+	}
+	return []*Polynomial{p}
+}
+
+// Splits a*v^2 + b*v + c into (2a*v + b - s) * (2a*v + b + s) / 4a, where s^2 = b^2 - 4ac.
+func quadratic(p *Polynomial, v string) ([]*Polynomial, bool) {
+	a, b, c := p.Coefficient(v, 2), p.Coefficient(v, 1), p.Coefficient(v, 0)
+	s, ok := b.Mul(b).Sub(a.Mul(c).Scale(big.NewRat(4, 1))).squareRoot()
+	if !ok {
+		return nil, false
+	}
+
+	base := a.Scale(big.NewRat(2, 1)).Mul(Variable(p.state, p.Variables, v)).Add(b)
+	for _, f := range []*Polynomial{base.Sub(s), base.Add(s)} {
+		f, _ = splitMonomial(f.Primitive())
+		if f.IsConstant() {
+			continue
+		}
+		if rest, ok := p.Divide(f); ok && !rest.IsConstant() {
+			return append(factorAll(f), factorAll(rest)...), true
+		}
+	}
+	return nil, false
+}
+
+// Returns the square root of a polynomial, if it is the square of a polynomial. The leading term
+// of the root is the root of the leading term, each further term cancels the leading term of the rest.
+// x^2 + 2xy + y^2 -> x + y
+func (p *Polynomial) squareRoot() (*Polynomial, bool) {
+	if p.IsZero() {
+		return p, true
+	}
+
+	lead := p.Terms[0]
+	c, ok := shared.RationalRoot(lead.Coefficient, 2)
+	if !ok {
+		return nil, false
+	}
+	exponents := make([]int, len(lead.Exponents))
+	for i, val := range lead.Exponents {
+		if val%2 != 0 {
+			return nil, false
+		}
+		exponents[i] = val / 2
+	}
+
+	root := &Polynomial{Variables: p.Variables, Terms: []Term{{Coefficient: c, Exponents: exponents}}, state: p.state}
+	double := Term{Coefficient: new(big.Rat).Mul(c, big.NewRat(2, 1)), Exponents: exponents}
+	for {
+		rest := p.Sub(root.Mul(root))
+		if rest.IsZero() {
+			return root, true
+		}
+		term, ok := divideTerms(rest.Terms[0], double)
+		// The new term has to be smaller than all terms of the root.
+		if !ok || compare(term.Exponents, root.Terms[len(root.Terms)-1].Exponents) <= 0 {
+			return nil, false
+		}
+		root = root.Add(&Polynomial{Variables: p.Variables, Terms: []Term{term}, state: p.state})
+	}
+}
@@ -0,0 +1,41 @@
+package polynomial
+
+import (
+	"lambdacalc/shared"
+	"testing"
+)
+
+// Polynomials are written as a product of irreducible factors over the rationals.
+func TestFactor(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"x^2 - 1", "((x+1)*(x-1))"},
+		{"x^2 + 2xy + y^2", "((x+y)^2)"},
+		{"2x^2 + 4x", "(2*x*(x+2))"},
+		{"0.5x^2 - 0.5", "(0.5*(x+1)*(x-1))"},
+		{"6x^2 + 11x - 21", "(((6*x)-7)*(x+3))"},
+		{"x^3 - 8", "((x-2)*((x^2)+(2*x)+4))"},
+		{"x^4 + 4", "(((x^2)+(2*x)+2)*((x^2)-(2*x)+2))"},
+		{"x^6 - 1", "((x+1)*(x-1)*((x^2)+x+1)*((x^2)-x+1))"},
+		{"x^2*y - y", "(y*(x+1)*(x-1))"},
+		{"x^2 + 1", "((x^2)+1)"},
+		{"5", "5"},
+		{"0", "0"},
+	}
+	for _, test := range tests {
+		got, err := Factor(state, parseNode(t, test.expression))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.expression, err)
+			continue
+		}
+		if res := shared.PrintATree(got); res != test.want {
+			t.Errorf("%s: got %s, want %s", test.expression, res, test.want)
+		}
+	}
+
+	if _, err := Factor(state, parseNode(t, "sin(x)")); err == nil {
+		t.Errorf("sin(x): want an error")
+	}
+}
@@ -0,0 +1,269 @@
+package polynomial
+
+import (
+	"errors"
+	"lambdacalc/interpreter"
+	"lambdacalc/shared"
+	"math/big"
+	"slices"
+)
+
+// Largest power of a polynomial, that is multiplied out. (x + 1)^64
+const MAX_EXPONENT = 64
+
+// A term c * x^a * y^b, the exponents are in the order of the polynomial's variables.
+type Term struct {
+	Coefficient *big.Rat
+	Exponents   []int
+}
+
+// A polynomial with rational coefficients in alphabetically sorted variables. The terms are kept
+// in descending order by their degree, like terms are combined and zero terms are removed.
+// 3x^2*y + 2x - 1 -> [x y], 3 [2 1], 2 [1 0], -1 [0 0]
+// The state is the one of the statement, the polynomial was made for. Arithmetic stops, once
+// the statement is canceled.
+type Polynomial struct {
+	Variables []string
+	Terms     []Term
+	state     *shared.State
+}
+
+// Converts a tree into a polynomial in its undefined variables. Defined variables are inserted,
+// constant parts have to be rational and powers need non negative integer exponents.
+// (x + 1)^2 -> x^2 + 2x + 1
+func FromNode(s *shared.State, node *shared.Node) (*Polynomial, error) {
+	p, err := convert(s, node, sorted(s.FreeVariables(node)), 0)
+	if err == errNotPolynomial {
+		return nil, shared.NewError("not a polynomial", "Unable to convert to polynomial, '%s' is not a polynomial with rational coefficients.", shared.PrintATree(node))
+	}
+	return p, err
+}
+
+// Error of a tree, that is no polynomial. The caller names the tree in its message.
+var errNotPolynomial = errors.New("not a polynomial")
+
+func convert(s *shared.State, node *shared.Node, variables []string, depth int) (*Polynomial, error) {
+	if depth >= interpreter.MAX_CALL_DEPTH {
+		return nil, errNotPolynomial
+	}
+
+	// Constant parts are calculated exactly. i.e.: 3^-1, sqrt(4)
+	if node.OperationType != shared.NUMBER && !containsAny(node, variables) {
+		val, exact, err := interpreter.EvaluateExact(s, node)
+		if err != nil || !exact {
+			return nil, errNotPolynomial
+		}
+		return Constant(s, variables, val), nil
+	}
+
+	switch node.OperationType {
+	case shared.NUMBER:
+		val := shared.NumberRational(node)
+		if val == nil {
+			return nil, errNotPolynomial
+		}
+		return Constant(s, variables, val), nil
+	case shared.VARIABLE:
+		if !slices.Contains(variables, node.Variable) {
+			if val, ok := s.Variables[node.Variable]; ok {
+				return convert(s, &val, variables, depth+1)
+			}
+			return nil, errNotPolynomial
+		}
+		return Variable(s, variables, node.Variable), nil
+	case shared.PLUS, shared.MULTIPLY:
+		res := Constant(s, variables, big.NewRat(0, 1))
+		if node.OperationType == shared.MULTIPLY {
+			res = Constant(s, variables, big.NewRat(1, 1))
+		}
+		for _, val := range node.Associative {
+			p, err := convert(s, val, variables, depth)
+			if err != nil {
+				return nil, err
+			}
+			if node.OperationType == shared.PLUS {
+				res = res.Add(p)
+			} else {
+				res = res.Mul(p)
+			}
+		}
+		return res, nil
+	case shared.MINUS:
+		a, err := convert(s, node.LNode, variables, depth)
+		if err != nil {
+			return nil, err
+		}
+		b, err := convert(s, node.RNode, variables, depth)
+		if err != nil {
+			return nil, err
+		}
+		return a.Sub(b), nil
+	case shared.DIVIDE:
+		// Only division by a constant keeps a polynomial.
+		a, err := convert(s, node.LNode, variables, depth)
+		if err != nil {
+			return nil, err
+		}
+		b, err := convert(s, node.RNode, variables, depth)
+		if err != nil {
+			return nil, err
+		}
+		if !b.IsConstant() || b.IsZero() {
+			return nil, errNotPolynomial
+		}
+		return a.Scale(new(big.Rat).Inv(b.Terms[0].Coefficient)), nil
+	case shared.POWER:
+		exponent, err := convert(s, node.RNode, variables, depth)
+		if err != nil {
+			return nil, err
+		}
+		if !exponent.IsConstant() {
+			return nil, errNotPolynomial
+		}
+		n := new(big.Rat)
+		if !exponent.IsZero() {
+			n = exponent.Terms[0].Coefficient
+		}
+		if !n.IsInt() || n.Sign() < 0 {
+			return nil, errNotPolynomial
+		}
+		base, err := convert(s, node.LNode, variables, depth)
+		if err != nil {
+			return nil, err
+		}
+		// Constant powers were calculated above, only powers of variables are limited.
+		if n.Num().Cmp(big.NewInt(MAX_EXPONENT)) > 0 {
+			return nil, shared.NewError("exponent limit exceeded", "Unable to convert to polynomial, the exponent %s exceeds the limit of %d.", n.RatString(), MAX_EXPONENT)
+		}
+		return base.Pow(int(n.Num().Int64())), nil
+	}
+	return nil, errNotPolynomial
+}
+
+// The constant polynomial c.
+func Constant(s *shared.State, variables []string, c *big.Rat) *Polynomial {
+	p := &Polynomial{
+		Variables: variables,
+		Terms:     []Term{{Coefficient: new(big.Rat).Set(c), Exponents: make([]int, len(variables))}},
+		state:     s,
+	}
+	p.normalize()
+	return p
+}
+
+// The polynomial consisting of only one of the variables.
+func Variable(s *shared.State, variables []string, variable string) *Polynomial {
+	exponents := make([]int, len(variables))
+	exponents[slices.Index(variables, variable)] = 1
+	return &Polynomial{
+		Variables: variables,
+		Terms:     []Term{{Coefficient: big.NewRat(1, 1), Exponents: exponents}},
+		state:     s,
+	}
+}
+
+// Converts the polynomial into a sum of terms in descending order.
+// x^2 + 2x + 1 -> (x^2)+(2*x)+1
+func (p *Polynomial) ToNode() *shared.Node {
+	if p.IsZero() {
+		return shared.NumberNode(0.0)
+	}
+
+	addends := []*shared.Node{}
+	for _, term := range p.Terms {
+		addends = append(addends, p.termNode(term))
+	}
+	if len(addends) == 1 {
+		return addends[0]
+	}
+	return shared.Add(addends...)
+}
+
+// c * x^a * y^b, a coefficient of one is left out.
+func (p *Polynomial) termNode(term Term) *shared.Node {
+	factors := []*shared.Node{}
+	for i, exponent := range term.Exponents {
+		switch {
+		case exponent == 1:
+			factors = append(factors, shared.VariableNode(p.Variables[i]))
+		case exponent > 1:
+			factors = append(factors, shared.Power(shared.VariableNode(p.Variables[i]), shared.NumberNode(float64(exponent))))
+		}
+	}
+
+	if len(factors) > 0 && term.Coefficient.Cmp(big.NewRat(1, 1)) == 0 {
+		if len(factors) == 1 {
+			return factors[0]
+		}
+		return shared.Multiply(factors...)
+	}
+
+	coefficient := rationalNode(p.state, term.Coefficient)
+	if len(factors) == 0 {
+		return coefficient
+	}
+	if coefficient.OperationType == shared.MULTIPLY {
+		return shared.Multiply(append(coefficient.Associative, factors...)...)
+	}
+	return shared.Multiply(append([]*shared.Node{coefficient}, factors...)...)
+}
+
+// Sorts the terms, combines like terms and removes zeros.
+func (p *Polynomial) normalize() {
+	slices.SortStableFunc(p.Terms, func(a, b Term) int {
+		return compare(a.Exponents, b.Exponents)
+	})
+	p.state.StopIfCanceled()
+
+	terms := []Term{}
+	for _, term := range p.Terms {
+		if n := len(terms); n > 0 && compare(terms[n-1].Exponents, term.Exponents) == 0 {
+			terms[n-1].Coefficient = new(big.Rat).Add(terms[n-1].Coefficient, term.Coefficient)
+			continue
+		}
+		terms = append(terms, Term{Coefficient: new(big.Rat).Set(term.Coefficient), Exponents: term.Exponents})
+	}
+
+	p.Terms = slices.DeleteFunc(terms, func(term Term) bool {
+		return term.Coefficient.Sign() == 0
+	})
+}
+
+// Order of the terms, higher degrees come first and equal degrees are ordered by the
+// exponents of the variables in alphabetical order. x^2 > x*y > y^2 > x > 1
+func compare(a, b []int) int {
+	if da, db := degree(a), degree(b); da != db {
+		return db - da
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return b[i] - a[i]
+		}
+	}
+	return 0
+}
+
+func degree(exponents []int) int {
+	sum := 0
+	for _, val := range exponents {
+		sum += val
+	}
+	return sum
+}
+
+// Checks if any of the variables appears in the tree.
+func containsAny(node *shared.Node, variables []string) bool {
+	for _, val := range variables {
+		if shared.ContainsVariable(node, val) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns a sorted copy of the names.
+func sorted(names []string) []string {
+	res := slices.Clone(names)
+	slices.Sort(res)
+	return res
+}
@@ -0,0 +1,102 @@
+package polynomial
+
+import (
+	"lambdacalc/lexer"
+	"lambdacalc/parser"
+	"lambdacalc/shared"
+	"testing"
+)
+
+// State of the tests, with the default config. Tests defining names remove them again.
+var state = shared.NewState(shared.GetDefualtConfig())
+
+// Parses an expression for a test.
+func parseNode(t *testing.T, input string) *shared.Node {
+	t.Helper()
+	lexed, err := lexer.LexTokens(state, input)
+	if err != nil {
+		t.Fatalf("%s: unable to lex, %v", input, err)
+	}
+	parsed, err := parser.Parse(state, lexed)
+	if err != nil {
+		t.Fatalf("%s: unable to parse, %v", input, err)
+	}
+	return parsed
+}
+
+// Parses an expression and converts it into a polynomial for a test.
+func parse(t *testing.T, input string) *Polynomial {
+	t.Helper()
+	p, err := FromNode(state, parseNode(t, input))
+	if err != nil {
+		t.Fatalf("%s: %v", input, err)
+	}
+	return p
+}
+
+// Powers above the limit are reported as such, not as a tree that is no polynomial.
+func TestFromNodeErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		code  string
+	}{
+		{"x^100 - 1", "exponent limit exceeded"},
+		{"(x + 1)^65", "exponent limit exceeded"},
+		{"sin(x)", "not a polynomial"},
+		{"x^-1", "not a polynomial"},
+	}
+	for _, test := range tests {
+		if _, err := FromNode(state, parseNode(t, test.input)); err == nil || shared.ErrorCode(err) != test.code {
+			t.Errorf("%s: got %v, want %s", test.input, err, test.code)
+		}
+	}
+	if _, err := FromNode(state, parseNode(t, "x^64 + 2^100")); err != nil {
+		t.Errorf("x^64 + 2^100: unexpected error %v", err)
+	}
+}
+
+func TestDivMod(t *testing.T) {
+	tests := []struct {
+		p, q                string
+		quotient, remainder string
+	}{
+		{"x^3 + 2x + 5", "x^2 + 1", "x", "(x+5)"},
+		{"x^2 - 1", "x - 1", "(x+1)", "0"},
+		{"x^2 - y^2", "x + y", "(x-y)", "0"},
+		{"3", "2", "1.5", "0"},
+	}
+	for _, test := range tests {
+		quotient, remainder, ok := parse(t, test.p).DivMod(parse(t, test.q))
+		if !ok {
+			t.Errorf("%s by %s: unable to divide", test.p, test.q)
+			continue
+		}
+		if got := shared.PrintATree(quotient.ToNode()); got != test.quotient {
+			t.Errorf("%s by %s: got q = %s, want %s", test.p, test.q, got, test.quotient)
+		}
+		if got := shared.PrintATree(remainder.ToNode()); got != test.remainder {
+			t.Errorf("%s by %s: got r = %s, want %s", test.p, test.q, got, test.remainder)
+		}
+	}
+
+	if _, _, ok := parse(t, "x").DivMod(parse(t, "0")); ok {
+		t.Errorf("x by 0: got a result, want none")
+	}
+}
+
+func TestGCD(t *testing.T) {
+	tests := []struct {
+		p, q string
+		want string
+	}{
+		{"x^2 - y^2", "x^3 - y^3", "(x-y)"},
+		{"x^2 - 1", "x^2 + 2x + 1", "(x+1)"},
+		{"x^2 + 1", "x - 1", "1"},
+		{"0", "2x + 2", "(x+1)"},
+	}
+	for _, test := range tests {
+		if got := shared.PrintATree(GCD(parse(t, test.p), parse(t, test.q)).ToNode()); got != test.want {
+			t.Errorf("gcd(%s, %s): got %s, want %s", test.p, test.q, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package polynomial
+
+import (
+	"lambdacalc/shared"
+	"math/big"
+	"slices"
+)
+
+// A fraction numerator / denominator^power of a partial fraction decomposition.
+// The denominator is irreducible and the numerator has a lower degree.
+type PartialFraction struct {
+	Numerator   *Polynomial
+	Denominator *Polynomial
+	Power       int
+}
+
+// Converts trees into polynomials in the union of their variables. Every variable is a symbol,
+// defined variables and constants are not inserted. Reports false, if a tree is not a polynomial.
+// x + 1, y -> [x y]: x + 1, y
+func FromNodes(s *shared.State, nodes ...*shared.Node) ([]*Polynomial, bool) {
+	all := []string{}
+	for _, val := range nodes {
+		all = append(all, names(val)...)
+	}
+	variables := slices.Compact(sorted(all))
+
+	res := []*Polynomial{}
+	for _, val := range nodes {
+		p, err := convert(s, val, variables, 0)
+		if err != nil {
+			return nil, false
+		}
+		res = append(res, p)
+	}
+	return res, true
+}
+
+// Divides numerator and denominator of a fraction by their greatest common divisor.
+// The denominator is made primitive with a positive leading coefficient, its content moves
+// into the numerator. Reports false, if nothing could be cancelled.
+// x^2 - 1, 2x + 2 -> 1/2 x - 1/2, x + 1
+func Cancel(p, q *Polynomial) (*Polynomial, *Polynomial, bool) {
+	p, q = align(p, q)
+	g := GCD(p, q)
+	if g.IsConstant() || q.IsZero() {
+		return nil, nil, false
+	}
+
+	numerator, _ := p.Divide(g)
+	denominator, _ := q.Divide(g)
+	content := denominator.Content()
+	return numerator.Scale(new(big.Rat).Inv(content)), denominator.Primitive(), true
+}
+
+// Decomposes a fraction of polynomials in one variable into a polynomial and partial fractions
+// over the irreducible factors of the denominator. Reports false, if the polynomials depend on
+// more than one variable or the denominator is constant.
+// 1 / (x^2 - 1) -> 0, [1/2 / (x - 1), -1/2 / (x + 1)]
+func Apart(p, q *Polynomial) (*Polynomial, []PartialFraction, bool) {
+	p, q = align(p, q)
+	used := slices.Compact(sorted(append(p.Used(), q.Used()...)))
+	if len(used) != 1 || q.IsConstant() {
+		return nil, nil, false
+	}
+	variable := used[0]
+
+	whole, rest := divmod(p.coefficients(variable), q.coefficients(variable))
+	constant, factors := q.Factors()
+	rest = scaleCoefficients(rest, new(big.Rat).Inv(constant))
+
+	fractions := []PartialFraction{}
+	for i, f := range factors {
+		// rest / q = ... + a / f^k + ..., where a = rest * (q / f^k)^-1 mod f^k
+		power := f.Polynomial.Pow(f.Multiplicity).coefficients(variable)
+		others := []*big.Rat{big.NewRat(1, 1)}
+		for j, g := range factors {
+			if i != j {
+				others = mulCoefficients(others, g.Polynomial.Pow(g.Multiplicity).coefficients(variable))
+			}
+		}
+		_, inverse, _ := extendedGCD(others, power)
+		_, a := divmod(mulCoefficients(rest, inverse), power)
+
+		// a = a_0 + a_1 f + a_2 f^2 + ... -> a_0 / f^k + a_1 / f^(k-1) + ...
+		base := f.Polynomial.coefficients(variable)
+		for k := f.Multiplicity; k > 0 && len(a) > 0; k-- {
+			quotient, remainder := divmod(a, base)
+			if len(remainder) > 0 {
+				fractions = append(fractions, PartialFraction{
+					Numerator:   fromCoefficients(p.state, p.Variables, variable, remainder),
+					Denominator: f.Polynomial,
+					Power:       k,
+				})
+			}
+			a = quotient
+		}
+	}
+	return fromCoefficients(p.state, p.Variables, variable, whole), fractions, true
+}
@@ -0,0 +1,186 @@
+package polynomial
+
+import (
+	"lambdacalc/shared"
+	"math/big"
+	"slices"
+)
+
+// Univariate polynomials are dense lists of coefficients, the index is the degree.
+// 3x^2 - 1 -> [-1 0 3]
+
+// Returns the coefficients of a polynomial, that only depends on the given variable.
+func (p *Polynomial) coefficients(variable string) []*big.Rat {
+	res := make([]*big.Rat, max(p.Degree(variable), 0)+1)
+	for i := range res {
+		res[i] = new(big.Rat)
+	}
+	i := slices.Index(p.Variables, variable)
+	for _, term := range p.Terms {
+		k := 0
+		if i != -1 {
+			k = term.Exponents[i]
+		}
+		res[k].Add(res[k], term.Coefficient)
+	}
+	return trim(res)
+}
+
+// Converts coefficients back into a polynomial in the given variables.
+func fromCoefficients(s *shared.State, variables []string, variable string, coefficients []*big.Rat) *Polynomial {
+	i := slices.Index(variables, variable)
+	res := &Polynomial{Variables: variables, Terms: []Term{}, state: s}
+	for k, c := range coefficients {
+		exponents := make([]int, len(variables))
+		exponents[i] = k
+		res.Terms = append(res.Terms, Term{Coefficient: c, Exponents: exponents})
+	}
+	res.normalize()
+	return res
+}
+
+// Removes leading zero coefficients.
+func trim(a []*big.Rat) []*big.Rat {
+	for len(a) > 0 && a[len(a)-1].Sign() == 0 {
+		a = a[:len(a)-1]
+	}
+	return a
+}
+
+func lead(a []*big.Rat) *big.Rat {
+	return a[len(a)-1]
+}
+
+func subCoefficients(a, b []*big.Rat) []*big.Rat {
+	res := make([]*big.Rat, max(len(a), len(b)))
+	for i := range res {
+		res[i] = new(big.Rat)
+		if i < len(a) {
+			res[i].Add(res[i], a[i])
+		}
+		if i < len(b) {
+			res[i].Sub(res[i], b[i])
+		}
+	}
+	return trim(res)
+}
+
+// Polynomial division with remainder over the rationals.
+// x^2 + 1, x + 1 -> x - 1, 2
+func divmod(a, b []*big.Rat) ([]*big.Rat, []*big.Rat) {
+	rest := make([]*big.Rat, len(a))
+	for i, c := range a {
+		rest[i] = new(big.Rat).Set(c)
+	}
+	if len(a) < len(b) {
+		return []*big.Rat{}, rest
+	}
+
+	quotient := make([]*big.Rat, len(a)-len(b)+1)
+	for k := len(quotient) - 1; k >= 0; k-- {
+		c := new(big.Rat).Quo(rest[k+len(b)-1], lead(b))
+		quotient[k] = c
+		for i, val := range b {
+			rest[k+i].Sub(rest[k+i], new(big.Rat).Mul(c, val))
+		}
+	}
+	return trim(quotient), trim(rest[:len(b)-1])
+}
+
+func derivativeOf(a []*big.Rat) []*big.Rat {
+	res := []*big.Rat{}
+	for k := 1; k < len(a); k++ {
+		res = append(res, new(big.Rat).Mul(a[k], big.NewRat(int64(k), 1)))
+	}
+	return trim(res)
+}
+
+// Monic greatest common divisor with the Euclidean algorithm.
+func gcd(a, b []*big.Rat) []*big.Rat {
+	for len(b) > 0 {
+		_, rest := divmod(a, b)
+		a, b = b, rest
+	}
+	return monic(a)
+}
+
+func monic(a []*big.Rat) []*big.Rat {
+	if len(a) == 0 {
+		return a
+	}
+	res := make([]*big.Rat, len(a))
+	for i, c := range a {
+		res[i] = new(big.Rat).Quo(c, lead(a))
+	}
+	return res
+}
+
+// Value of the polynomial at a rational with Horner's method.
+func evaluate(a []*big.Rat, x *big.Rat) *big.Rat {
+	res := new(big.Rat)
+	for k := len(a) - 1; k >= 0; k-- {
+		res.Mul(res, x)
+		res.Add(res, a[k])
+	}
+	return res
+}
+
+// Splits a polynomial into square free parts with Yun's algorithm, the i-th part is the
+// product of the factors with multiplicity i. (x - 1)^2 * (x + 1) -> [x + 1, x - 1]
+func squareFree(a []*big.Rat) [][]*big.Rat {
+	res := [][]*big.Rat{}
+	d := derivativeOf(a)
+	g := gcd(a, d)
+	b, _ := divmod(a, g)
+	c, _ := divmod(d, g)
+	for len(b) > 1 {
+		d = subCoefficients(c, derivativeOf(b))
+		part := gcd(b, d)
+		res = append(res, part)
+		b, _ = divmod(b, part)
+		c, _ = divmod(d, part)
+	}
+	return res
+}
+
+// Extended Euclidean algorithm, returns the monic gcd g and s, t with s*a + t*b = g.
+// x^2 - 1, x + 2 -> 1, 1/3, -1/3 x + 2/3
+func extendedGCD(a, b []*big.Rat) ([]*big.Rat, []*big.Rat, []*big.Rat) {
+	s0, s1 := []*big.Rat{big.NewRat(1, 1)}, []*big.Rat{}
+	t0, t1 := []*big.Rat{}, []*big.Rat{big.NewRat(1, 1)}
+	for len(b) > 0 {
+		quotient, rest := divmod(a, b)
+		a, b = b, rest
+		s0, s1 = s1, subCoefficients(s0, mulCoefficients(quotient, s1))
+		t0, t1 = t1, subCoefficients(t0, mulCoefficients(quotient, t1))
+	}
+	if len(a) == 0 {
+		return a, s0, t0
+	}
+	c := new(big.Rat).Inv(lead(a))
+	return scaleCoefficients(a, c), scaleCoefficients(s0, c), scaleCoefficients(t0, c)
+}
+
+func mulCoefficients(a, b []*big.Rat) []*big.Rat {
+	if len(a) == 0 || len(b) == 0 {
+		return []*big.Rat{}
+	}
+	res := make([]*big.Rat, len(a)+len(b)-1)
+	for i := range res {
+		res[i] = new(big.Rat)
+	}
+	for i, x := range a {
+		for j, y := range b {
+			res[i+j].Add(res[i+j], new(big.Rat).Mul(x, y))
+		}
+	}
+	return trim(res)
+}
+
+func scaleCoefficients(a []*big.Rat, c *big.Rat) []*big.Rat {
+	res := make([]*big.Rat, len(a))
+	for i, val := range a {
+		res[i] = new(big.Rat).Mul(val, c)
+	}
+	return trim(res)
+}
@@ -0,0 +1,16 @@
+package polynomial
+
+import (
+	"lambdacalc/shared"
+	"math/big"
+)
+
+// Returns the tree for a coefficient. When decimals are kept, fractions are a product themselves,
+// otherwise they are approximated. 1/3 -> 1 * 3^-1 or 0.3333333333333333
+func rationalNode(s *shared.State, r *big.Rat) *shared.Node {
+	if s.Conf.KeepDecimals() {
+		return shared.RationalNode(r)
+	}
+	f, _ := r.Float64()
+	return shared.NumberNode(f)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"lambdacalc/engine"
+	"lambdacalc/shared"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+)
+
+const (
+	// Sessions that were not used for this long are removed.
+	SESSION_IDLE = 30 * time.Minute
+	// Maximum number of sessions kept at the same time.
+	MAX_SESSIONS = 1000
+	// Maximum size of the body of a request in bytes.
+	MAX_BODY_SIZE = 1 << 20
+)
+
+// Evaluates statements over HTTP. Every session has its own context, so definitions of one
+// client are not visible to others.
+type server struct {
+	conf     shared.Config
+	timeout  time.Duration
+	idle     time.Duration
+	limit    int
+	mu       sync.Mutex
+	sessions map[string]*serverSession
+}
+
+// Context of a session and the time it was last used.
+type serverSession struct {
+	calc *engine.Context
+	used time.Time
+}
+
+// Body of a request, the fields used depend on the endpoint. Without a session a new one is created.
+// {"session":"4f2a...","input":"2x+x"}
+type serverRequest struct {
+	Session     string `json:"session"`
+	Input       string `json:"input"`
+	Declaration string `json:"declaration"`
+	Name        string `json:"name"`
+	Variables   string `json:"variables"`
+}
+
+// Response of the server, the result is written like in the JSON output of the CLI.
+type serverResponse struct {
+	Session string `json:"session,omitempty"`
+	jsonResult
+	Definitions map[string]string `json:"definitions,omitempty"`
+}
+
+// Creates a server, calculations taking longer than the timeout are stopped.
+func newServer(conf shared.Config, timeout time.Duration) *server {
+	return &server{
+		conf:     conf,
+		timeout:  timeout,
+		idle:     SESSION_IDLE,
+		limit:    MAX_SESSIONS,
+		mu:       sync.Mutex{},
+		sessions: make(map[string]*serverSession),
+	}
+}
+
+// Returns the handler of the server. /simplify and /latex do not use a session. Every endpoint
+// stops its calculation once the request times out.
+//
+//	POST /evaluate {"input":"define x = 3"}, {"input":"2x+x"}
+//	POST /simplify {"input":"2x+x"}
+//	POST /define   {"declaration":"f(x)","input":"x^2"}
+//	POST /drop     {"name":"x"}
+//	POST /list     {}
+//	POST /solve    {"input":"2x+3=7","variables":"x"}
+//	POST /latex    {"input":"x^2/2"}
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /evaluate", s.handle(true, true, func(ctx context.Context, calc *engine.Context, req serverRequest) (serverResponse, error) {
+		result, err := calc.EvaluateContext(ctx, req.Input)
+		res := newServerResponse(req.Input, result.Output, err)
+		if err == nil {
+			res.setCalculation(result.Calculation)
+		}
+		return res, err
+	}))
+	mux.HandleFunc("POST /simplify", s.handle(false, true, func(ctx context.Context, calc *engine.Context, req serverRequest) (serverResponse, error) {
+		res, err := calc.SimplifyContext(ctx, req.Input)
+		return newServerResponse(req.Input, res, err), err
+	}))
+	mux.HandleFunc("POST /define", s.handle(true, true, func(ctx context.Context, calc *engine.Context, req serverRequest) (serverResponse, error) {
+		res, err := calc.DefineContext(ctx, req.Declaration, req.Input)
+		return newServerResponse(req.Input, res, err), err
+	}))
+	mux.HandleFunc("POST /drop", s.handle(true, false, func(ctx context.Context, calc *engine.Context, req serverRequest) (serverResponse, error) {
+		res, err := calc.DropContext(ctx, req.Name)
+		return newServerResponse(req.Name, res, err), err
+	}))
+	mux.HandleFunc("POST /list", s.handle(true, false, func(ctx context.Context, calc *engine.Context, req serverRequest) (serverResponse, error) {
+		definitions, err := calc.DefinitionsContext(ctx)
+		res := newServerResponse("", "", err)
+		res.Definitions = definitions
+		return res, err
+	}))
+	mux.HandleFunc("POST /solve", s.handle(true, true, func(ctx context.Context, calc *engine.Context, req serverRequest) (serverResponse, error) {
+		res, err := calc.SolveContext(ctx, req.Input, req.Variables)
+		return newServerResponse(req.Input, res, err), err
+	}))
+	mux.HandleFunc("POST /latex", s.handle(false, true, func(ctx context.Context, calc *engine.Context, req serverRequest) (serverResponse, error) {
+		res, err := calc.LatexContext(ctx, req.Input)
+		return newServerResponse(req.Input, res, err), err
+	}))
+	return mux
+}
+
+// Decodes a request, finds its session and writes the response. Errors of the statement are
+// answered with 422, invalid requests with 400, too large ones with 413, unknown sessions with 404
+// and timeouts with 503. Endpoints reading an input answer requests with an empty input as invalid.
+// A new session is only kept, once its first request succeeded.
+func (s *server) handle(stateful bool, input bool, run func(ctx context.Context, calc *engine.Context, req serverRequest) (serverResponse, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var req serverRequest
+		r.Body = http.MaxBytesReader(w, r.Body, MAX_BODY_SIZE)
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+			} else {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+			writeResponse(w, newServerError("invalid request", "Unable to read request, "+err.Error()+"."))
+			return
+		}
+		if input && strings.TrimSpace(req.Input) == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			writeResponse(w, newServerError("invalid request", "Unable to read request, the input is empty."))
+			return
+		}
+
+		id, calc := "", engine.New(s.conf)
+		if stateful && req.Session != "" {
+			var err error
+			calc, err = s.session(req.Session)
+			if err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				writeResponse(w, newServerError(shared.ErrorCode(err), err.Error()))
+				return
+			}
+			id = req.Session
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+		defer cancel()
+		res, err := run(ctx, calc, req)
+		if ctx.Err() == context.DeadlineExceeded {
+			res = newServerError("timeout", "Unable to answer request, the request timed out.")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else if err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		} else if stateful && id == "" {
+			id = s.add(calc)
+		}
+		res.Session = id
+		writeResponse(w, res)
+	}
+}
+
+// Returns the context of a session. Only IDs created by the server are known, sessions idle for
+// too long are removed.
+func (s *server) session(id string) (*engine.Context, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.expire(now)
+
+	val, ok := s.sessions[id]
+	if !ok {
+		return nil, shared.NewError("unknown session", "Unable to find session, the session '%s' does not exist or expired.", id)
+	}
+	val.used = now
+	return val.calc, nil
+}
+
+// Keeps the context as a new session and returns its ID. At the limit of sessions the one used
+// least recently is removed.
+func (s *server) add(calc *engine.Context) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.expire(now)
+
+	for len(s.sessions) >= s.limit {
+		oldest := ""
+		for key, val := range s.sessions {
+			if oldest == "" || val.used.Before(s.sessions[oldest].used) {
+				oldest = key
+			}
+		}
+		delete(s.sessions, oldest)
+	}
+	id := newSessionID()
+	s.sessions[id] = &serverSession{calc: calc, used: now}
+	return id
+}
+
+// Removes the sessions idle for too long, the lock has to be held.
+func (s *server) expire(now time.Time) {
+	for key, val := range s.sessions {
+		if now.Sub(val.used) > s.idle {
+			delete(s.sessions, key)
+		}
+	}
+}
+
+// Creates a random session ID of 32 hex digits.
+func newSessionID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// Creates a response with the result or the error of a statement.
+func newServerResponse(input string, result string, err error) serverResponse {
+	res := serverResponse{
+		Session: "",
+		jsonResult: jsonResult{
+			Input:      input,
+			Parsed:     nil,
+			Simplified: nil,
+			Result:     strings.TrimSpace(result),
+			Value:      nil,
+			Real:       nil,
+			Imag:       nil,
+			Error:      nil,
+		},
+		Definitions: nil,
+	}
+	if err != nil {
+		res.Result = ""
+		res.Error = newJSONError(err, input)
+	}
+	return res
+}
+
+// Creates a response for an error of the server itself, not of a statement.
+func newServerError(kind string, message string) serverResponse {
+	res := newServerResponse("", "", nil)
+	res.Error = &jsonError{Kind: kind, Message: message, Column: 0, Span: nil, Hint: ""}
+	return res
+}
+
+// Writes a response as a single line of JSON.
+func writeResponse(w http.ResponseWriter, res serverResponse) {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(res); err != nil {
+		cfmt.Fprintf(os.Stderr, "{{Error:}}::red|bold Unable to write response, %v.\n", err)
+	}
+}
+
+// Starts the server, it runs until it fails.
+// lambdacalc serve --addr :8080
+func serve(addr string, timeout time.Duration) error {
+	cfmt.Printf("{{Notice:}}::blue|bold Serving on %s.\n", addr)
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           newServer(session.Config(), timeout).handler(),
+		ReadHeaderTimeout: timeout,
+	}
+	if err := srv.ListenAndServe(); err != nil {
+		cfmt.Fprintf(os.Stderr, "{{Error:}}::red|bold Unable to serve, %v.\n", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"lambdacalc/shared"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Sends a request to the server and decodes the response.
+func post(t *testing.T, url string, body string) (int, serverResponse) {
+	t.Helper()
+	r, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	defer r.Body.Close()
+
+	var res serverResponse
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		t.Fatalf("POST %s: unable to decode response, %v", url, err)
+	}
+	return r.StatusCode, res
+}
+
+// Definitions are kept in the session that made them.
+func TestServerSession(t *testing.T) {
+	srv := httptest.NewServer(newServer(shared.GetDefualtConfig(), 10*time.Second).handler())
+	defer srv.Close()
+
+	status, res := post(t, srv.URL+"/evaluate", `{"input":"define x = 3"}`)
+	if status != http.StatusOK || res.Session == "" {
+		t.Fatalf("define: got %d with session %q", status, res.Session)
+	}
+	session := res.Session
+
+	status, res = post(t, srv.URL+"/evaluate", `{"session":"`+session+`","input":"2x + x"}`)
+	if status != http.StatusOK || res.Result != "9" || res.Session != session {
+		t.Errorf("2x + x: got %d %q in session %q", status, res.Result, res.Session)
+	}
+
+	// A new session with a failed request is not kept.
+	status, res = post(t, srv.URL+"/evaluate", `{"input":"2x + x"}`)
+	if status != http.StatusUnprocessableEntity || res.Session != "" {
+		t.Errorf("2x + x in a new session: got %d %q with session %q", status, res.Result, res.Session)
+	}
+}
+
+// Sessions are only created by the server.
+func TestServerUnknownSession(t *testing.T) {
+	srv := httptest.NewServer(newServer(shared.GetDefualtConfig(), 10*time.Second).handler())
+	defer srv.Close()
+
+	status, res := post(t, srv.URL+"/evaluate", `{"session":"mine","input":"1 + 1"}`)
+	if status != http.StatusNotFound || res.Error == nil || res.Error.Kind != "unknown session" {
+		t.Errorf("got %d %+v", status, res.Error)
+	}
+}
+
+// /simplify and /latex answer without a session.
+func TestServerStateless(t *testing.T) {
+	s := newServer(shared.GetDefualtConfig(), 10*time.Second)
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	for _, endpoint := range []string{"/simplify", "/latex"} {
+		status, res := post(t, srv.URL+endpoint, `{"session":"mine","input":"2x + x"}`)
+		if status != http.StatusOK || res.Session != "" {
+			t.Errorf("%s: got %d with session %q", endpoint, status, res.Session)
+		}
+	}
+	if len(s.sessions) != 0 {
+		t.Errorf("got %d sessions, want 0", len(s.sessions))
+	}
+}
+
+// Sessions are removed after being idle, at the limit of sessions the least recently used one is removed.
+func TestServerSessionLimits(t *testing.T) {
+	s := newServer(shared.GetDefualtConfig(), 10*time.Second)
+	s.limit = 2
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	_, first := post(t, srv.URL+"/evaluate", `{"input":"1"}`)
+	_, second := post(t, srv.URL+"/evaluate", `{"input":"1"}`)
+	s.sessions[second.Session].used = time.Now().Add(-time.Minute)
+	if status, _ := post(t, srv.URL+"/evaluate", `{"input":"1"}`); status != http.StatusOK {
+		t.Errorf("third session: got %d, want %d", status, http.StatusOK)
+	}
+	if status, _ := post(t, srv.URL+"/evaluate", `{"session":"`+second.Session+`","input":"1"}`); status != http.StatusNotFound {
+		t.Errorf("least recently used session: got %d, want %d", status, http.StatusNotFound)
+	}
+
+	s.sessions[first.Session].used = time.Now().Add(-2 * s.idle)
+	if status, _ := post(t, srv.URL+"/evaluate", `{"session":"`+first.Session+`","input":"1"}`); status != http.StatusNotFound {
+		t.Errorf("expired session: got %d, want %d", status, http.StatusNotFound)
+	}
+	if len(s.sessions) != 1 {
+		t.Errorf("got %d sessions, want 1", len(s.sessions))
+	}
+}
+
+// A calculation that times out is stopped, so other sessions are answered.
+func TestServerTimeout(t *testing.T) {
+	srv := httptest.NewServer(newServer(shared.GetDefualtConfig(), 500*time.Millisecond).handler())
+	defer srv.Close()
+
+	status, res := post(t, srv.URL+"/evaluate", `{"input":"expand (x+y+z+1)^40"}`)
+	if status != http.StatusServiceUnavailable || res.Error == nil || res.Error.Kind != "timeout" || res.Session != "" {
+		t.Fatalf("expand: got %d with session %q and error %+v", status, res.Session, res.Error)
+	}
+
+	status, res = post(t, srv.URL+"/evaluate", `{"input":"1 + 1"}`)
+	if status != http.StatusOK || res.Result != "2" {
+		t.Errorf("1 + 1: got %d %q %+v", status, res.Result, res.Error)
+	}
+}
+
+// A slow definition does not hold up the requests of other sessions and stops, once its request is canceled.
+func TestServerSlowDefine(t *testing.T) {
+	srv := httptest.NewServer(newServer(shared.GetDefualtConfig(), time.Minute).handler())
+	defer srv.Close()
+
+	product := "(a+b+c+d+1)(a+b+c+d+2)(a+b+c+d+3)(a+b+c+d+4)(a+b+c+d+5)(a+b+c+d+6)(a+b+c+d+7)"
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "POST", srv.URL+"/define", strings.NewReader(`{"declaration":"y","input":"`+product+`"}`))
+	if err != nil {
+		t.Fatalf("define: %v", err)
+	}
+	defined := make(chan error)
+	go func() {
+		r, err := http.DefaultClient.Do(req)
+		if err == nil {
+			r.Body.Close()
+		}
+		defined <- err
+	}()
+
+	// The definition takes far longer than the timeout of the client, if the sessions took turns.
+	client := &http.Client{Timeout: 5 * time.Second}
+	r, err := client.Post(srv.URL+"/evaluate", "application/json", strings.NewReader(`{"input":"1 + 1"}`))
+	if err != nil {
+		t.Fatalf("1 + 1: %v", err)
+	}
+	r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		t.Errorf("1 + 1: got %d, want %d", r.StatusCode, http.StatusOK)
+	}
+
+	cancel()
+	if err := <-defined; err == nil {
+		t.Errorf("define: got a response, want canceled")
+	}
+}
+
+// Bodies above the size limit are not read.
+func TestServerBodyLimit(t *testing.T) {
+	srv := httptest.NewServer(newServer(shared.GetDefualtConfig(), 10*time.Second).handler())
+	defer srv.Close()
+
+	body := `{"input":"` + strings.Repeat("1+", MAX_BODY_SIZE) + `1"}`
+	status, res := post(t, srv.URL+"/evaluate", body)
+	if status != http.StatusRequestEntityTooLarge || res.Error == nil || res.Session != "" {
+		t.Errorf("got %d %+v", status, res.Error)
+	}
+}
+
+// Requests without an input are invalid, instead of being read as an empty statement.
+func TestServerEmptyInput(t *testing.T) {
+	srv := httptest.NewServer(newServer(shared.GetDefualtConfig(), 10*time.Second).handler())
+	defer srv.Close()
+
+	tests := []struct {
+		endpoint string
+		body     string
+		status   int
+	}{
+		{"/evaluate", `{"input":""}`, http.StatusBadRequest},
+		{"/evaluate", `{"input":"   "}`, http.StatusBadRequest},
+		{"/simplify", `{"input":""}`, http.StatusBadRequest},
+		{"/define", `{"declaration":"x","input":" "}`, http.StatusBadRequest},
+		{"/solve", `{"input":"","variables":"x"}`, http.StatusBadRequest},
+		{"/latex", `{"input":"\t"}`, http.StatusBadRequest},
+		{"/drop", `{"name":"  "}`, http.StatusUnprocessableEntity},
+	}
+	for _, test := range tests {
+		status, res := post(t, srv.URL+test.endpoint, test.body)
+		if status != test.status || res.Error == nil {
+			t.Errorf("%s %s: got %d %+v, want %d", test.endpoint, test.body, status, res.Error, test.status)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package shared
+
+import (
+	"errors"
+	"math"
+)
+
+// Built-in functions are recognised by the lexer and evaluated by the interpreter.
+// Exact functions always return a rational result for rational parameters,
+// so the simplifier is allowed to fold them into constants.
+type Builtin struct {
+	MinParameters int
+	MaxParameters int // -1 for any number of parameters
+	Exact         bool
+	Evaluate      func(params []float64) (float64, error)
+}
+
+// Wraps a function with a single parameter.
+func unary(f func(float64) float64) func([]float64) (float64, error) {
+	return func(params []float64) (float64, error) {
+		return f(params[0]), nil
+	}
+}
+
+// Wraps a function with a single parameter, that is only defined in [min, max].
+func bounded(f func(float64) float64, min float64, max float64) func([]float64) (float64, error) {
+	return func(params []float64) (float64, error) {
+		if params[0] < min || params[0] > max {
+			return 0, errors.New("parameter out of domain")
+		}
+		return f(params[0]), nil
+	}
+}
+
+// Wraps a logarithm, which is only defined for positive numbers.
+func logarithm(f func(float64) float64) func([]float64) (float64, error) {
+	return func(params []float64) (float64, error) {
+		if params[0] <= 0 {
+			return 0, errors.New("logarithm of a non positive number")
+		}
+		return f(params[0]), nil
+	}
+}
+
+func isInteger(x float64) bool {
+	return x == math.Trunc(x) && !math.IsInf(x, 0)
+}
+
+func gcd(a, b float64) float64 {
+	a, b = math.Abs(a), math.Abs(b)
+	for b != 0 {
+		a, b = b, math.Mod(a, b)
+	}
+	return a
+}
+
+var Builtins = map[string]Builtin{
+	// Trigonometry
+	"sin":   {1, 1, false, unary(math.Sin)},
+	"cos":   {1, 1, false, unary(math.Cos)},
+	"tan":   {1, 1, false, unary(math.Tan)},
+	"asin":  {1, 1, false, bounded(math.Asin, -1, 1)},
+	"acos":  {1, 1, false, bounded(math.Acos, -1, 1)},
+	"atan":  {1, 1, false, unary(math.Atan)},
+	"sinh":  {1, 1, false, unary(math.Sinh)},
+	"cosh":  {1, 1, false, unary(math.Cosh)},
+	"tanh":  {1, 1, false, unary(math.Tanh)},
+	"asinh": {1, 1, false, unary(math.Asinh)},
+	"acosh": {1, 1, false, bounded(math.Acosh, 1, math.Inf(1))},
+	"atanh": {1, 1, false, bounded(math.Atanh, -1, 1)},
+	"atan2": {2, 2, false, func(params []float64) (float64, error) {
+		return math.Atan2(params[0], params[1]), nil
+	}},
+
+	// Logarithms and exponentials
+	"ln":   {1, 1, false, logarithm(math.Log)},
+	"log2": {1, 1, false, logarithm(math.Log2)},
+	"exp":  {1, 1, false, unary(math.Exp)},
+	// log(x) is the logarithm to base 10, log(x, b) to base b.
+	"log": {1, 2, false, func(params []float64) (float64, error) {
+		if params[0] <= 0 {
+			return 0, errors.New("logarithm of a non positive number")
+		}
+		if len(params) == 1 {
+			return math.Log10(params[0]), nil
+		}
+		if params[1] <= 0 || params[1] == 1 {
+			return 0, errors.New("invalid logarithm base")
+		}
+		return math.Log(params[0]) / math.Log(params[1]), nil
+	}},
+
+	// Rounding
+	"abs":   {1, 1, true, unary(math.Abs)},
+	"floor": {1, 1, true, unary(math.Floor)},
+	"ceil":  {1, 1, true, unary(math.Ceil)},
+	"round": {1, 1, true, unary(math.Round)},
+
+	// Complex numbers, only real numbers reach these functions outside of complex mode.
+	"re":   {1, 1, true, unary(func(x float64) float64 { return x })},
+	"im":   {1, 1, true, unary(func(x float64) float64 { return 0 })},
+	"conj": {1, 1, true, unary(func(x float64) float64 { return x })},
+	"arg": {1, 1, false, unary(func(x float64) float64 {
+		if x < 0 {
+			return math.Pi
+		}
+		return 0
+	})},
+
+	// Comparison
+	"min": {1, -1, true, func(params []float64) (float64, error) {
+		res := params[0]
+		for _, val := range params[1:] {
+			res = math.Min(res, val)
+		}
+		return res, nil
+	}},
+	"max": {1, -1, true, func(params []float64) (float64, error) {
+		res := params[0]
+		for _, val := range params[1:] {
+			res = math.Max(res, val)
+		}
+		return res, nil
+	}},
+
+	// Number theory
+	"gcd": {2, -1, true, func(params []float64) (float64, error) {
+		res := 0.0
+		for _, val := range params {
+			if !isInteger(val) {
+				return 0, errors.New("gcd of a non integer")
+			}
+			res = gcd(res, val)
+		}
+		return res, nil
+	}},
+	"lcm": {2, -1, true, func(params []float64) (float64, error) {
+		res := 1.0
+		for _, val := range params {
+			if !isInteger(val) {
+				return 0, errors.New("lcm of a non integer")
+			}
+			if val == 0 {
+				return 0, nil
+			}
+			res = math.Abs(res*val) / gcd(res, val)
+		}
+		return res, nil
+	}},
+	"factorial": {1, 1, true, func(params []float64) (float64, error) {
+		if !isInteger(params[0]) || params[0] < 0 {
+			return 0, errors.New("factorial of a negative or non integer number")
+		}
+		// Anything above 170! does not fit into a float64.
+		if params[0] > 170 {
+			return math.Inf(1), nil
+		}
+		res := 1.0
+		for i := 2.0; i <= params[0]; i++ {
+			res *= i
+		}
+		return res, nil
+	}},
+}
+
+// Checks if a built-in function accepts the given number of parameters.
+func (b Builtin) Accepts(n int) bool {
+	return n >= b.MinParameters && (b.MaxParameters == -1 || n <= b.MaxParameters)
+}
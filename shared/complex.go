@@ -0,0 +1,70 @@
+package shared
+
+import (
+	"math"
+	"strconv"
+)
+
+// In complex mode the imaginary unit is a variable with the configured name, that can not be defined.
+
+// Returns the name of the imaginary unit, or an empty string if complex numbers are disabled.
+func (conf Config) ImaginaryUnit() string {
+	if !conf.Options["complex"] {
+		return ""
+	}
+	return conf.Symbols["imaginary"]
+}
+
+// Returns the tree for a complex number.
+// 3+0i -> 3, 0+2i -> 2 * i, 1+i -> 1 + i, 1-i -> 1 + (-1 * i)
+func (conf Config) ComplexNode(c complex128) *Node {
+	number := func(value float64) *Node {
+		return NumberNode(value)
+	}
+	if imag(c) == 0 {
+		return number(real(c))
+	}
+
+	imaginary := VariableNode(conf.Symbols["imaginary"])
+	if imag(c) != 1 {
+		imaginary = Multiply(number(imag(c)), imaginary)
+	}
+	if real(c) == 0 {
+		return imaginary
+	}
+	return Add(number(real(c)), imaginary)
+}
+
+// Formats a complex number as a + bi, or as r * e^(θi) in polar form.
+// Real numbers are formatted like any other number.
+func (conf Config) FormatComplex(c complex128, polar bool) string {
+	unit := conf.Symbols["imaginary"]
+	format := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	if imag(c) == 0 {
+		return format(real(c))
+	}
+	if polar {
+		r := math.Hypot(real(c), imag(c))
+		theta := math.Atan2(imag(c), real(c))
+		return format(r) + " * e^(" + format(theta) + unit + ")"
+	}
+
+	// 1i -> i, -1i -> -i
+	im := format(math.Abs(imag(c)))
+	if im == "1" {
+		im = ""
+	}
+	if real(c) == 0 {
+		if imag(c) < 0 {
+			return "-" + im + unit
+		}
+		return im + unit
+	}
+	if imag(c) < 0 {
+		return format(real(c)) + " - " + im + unit
+	}
+	return format(real(c)) + " + " + im + unit
+}
@@ -0,0 +1,24 @@
+package shared
+
+import "testing"
+
+func TestFormatComplex(t *testing.T) {
+	tests := []struct {
+		value complex128
+		polar bool
+		want  string
+	}{
+		{-1, false, "-1"},
+		{2i, false, "2i"},
+		{-1i, false, "-i"},
+		{2 - 3i, false, "2 - 3i"},
+		{-0.5 + 2.5i, false, "-0.5 + 2.5i"},
+		{5, true, "5"},
+		{-1i, true, "1 * e^(-1.5707963267948966i)"},
+	}
+	for _, test := range tests {
+		if got := GetDefualtConfig().FormatComplex(test.value, test.polar); got != test.want {
+			t.Errorf("%v: got %s, want %s", test.value, got, test.want)
+		}
+	}
+}
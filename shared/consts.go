@@ -1,5 +1,7 @@
 package shared
 
+import "maps"
+
 const (
 	NUMBER       = iota // 0
 	PLUS         = iota // 1
@@ -14,6 +16,11 @@ const (
 	VARIABLE     = iota // 10
 	COMMA        = iota // 11
 	FUNCTION     = iota // 12
+	LBRACKET     = iota // 13
+	RBRACKET     = iota // 14
+	VECTOR       = iota // 15
+	MATRIX       = iota // 16
+	UNIT         = iota // 17
 )
 
 func GetDefualtConfig() Config {
@@ -22,6 +29,18 @@ func GetDefualtConfig() Config {
 		Options: map[string]bool{
 			"nerdfont":           true,
 			"show_debug_process": false,
+			"show_latex":         false,
+			"latex_input":        false,
+			"strict_identifiers": false,
+			"exact":              false,
+			"show_decimal":       true,
+			"complex":            false,
+			"polar":              false,
+		},
+		Settings: map[string]int{
+			"precision":      0,
+			"tolerance":      10,
+			"max_iterations": 100,
 		},
 		Symbols: map[string]string{
 			"decimal_split":   ".",
@@ -34,8 +53,12 @@ func GetDefualtConfig() Config {
 			"sqrt":            "sqrt",
 			"l_parentheses":   "(",
 			"r_parentheses":   ")",
+			"l_brackets":      "[",
+			"r_brackets":      "]",
 			"equal":           "=",
+			"imaginary":       "i",
 		},
+		Units: map[string]string{},
 		Constants: map[string]float64{
 			"pi":  3.14159265358979323846264338327950288419716939937510582097494459,
 			"phi": 1.61803398874989484820458683436563811772030917980576286213544862,
@@ -43,3 +66,48 @@ func GetDefualtConfig() Config {
 		},
 	}
 }
+
+// Options, settings and symbols missing in the config file use their default value.
+// Constants are not filled in, so they can be removed from the config.
+func FillDefaults(conf *Config) {
+	defaults := GetDefualtConfig()
+	if conf.Options == nil {
+		conf.Options = map[string]bool{}
+	}
+	for key, val := range defaults.Options {
+		if _, ok := conf.Options[key]; !ok {
+			conf.Options[key] = val
+		}
+	}
+	if conf.Settings == nil {
+		conf.Settings = map[string]int{}
+	}
+	for key, val := range defaults.Settings {
+		if _, ok := conf.Settings[key]; !ok {
+			conf.Settings[key] = val
+		}
+	}
+	if conf.Symbols == nil {
+		conf.Symbols = map[string]string{}
+	}
+	for key, val := range defaults.Symbols {
+		if _, ok := conf.Symbols[key]; !ok {
+			conf.Symbols[key] = val
+		}
+	}
+	if conf.Units == nil {
+		conf.Units = map[string]string{}
+	}
+}
+
+// Copies a config, so changing the copy does not change the original.
+func (conf Config) Clone() Config {
+	return Config{
+		Version:   conf.Version,
+		Options:   maps.Clone(conf.Options),
+		Settings:  maps.Clone(conf.Settings),
+		Symbols:   maps.Clone(conf.Symbols),
+		Constants: maps.Clone(conf.Constants),
+		Units:     maps.Clone(conf.Units),
+	}
+}
@@ -0,0 +1,102 @@
+package shared
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Part of the input an error refers to, given by byte offsets. The end is exclusive.
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Error of the engine. The code names the error, the message is written for the user and the
+// hint may suggest a fix. The span is nil, if the error has no position in the input.
+// 2 + * 3 -> unexpected token, Unable to parse tokens, unexpected '*'., {4 5}
+type Error struct {
+	Code    string
+	Message string
+	Span    *Span
+	Hint    string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Creates an error with a formatted message, without position and hint.
+func NewError(code string, format string, a ...any) *Error {
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, a...),
+		Span:    nil,
+		Hint:    "",
+	}
+}
+
+// Error of a division by zero, every evaluator reports it with the same message. 1/(x - x)
+func DivisionByZero() *Error {
+	return NewError("divide by 0", "Unable to calculate output, division by zero.")
+}
+
+// Sets the part of the input the error refers to. An empty span points between two characters,
+// i.e.: behind the input for a missing token.
+func (e *Error) At(start, end int) *Error {
+	e.Span = &Span{Start: start, End: max(end, start)}
+	return e
+}
+
+// Sets a hint on how to fix the error.
+func (e *Error) WithHint(hint string) *Error {
+	e.Hint = hint
+	return e
+}
+
+// Returns the column the error starts at in the input, counting characters from 1.
+// 0 if the position is unknown. π + * 2 -> 5
+func (e *Error) Column(input string) int {
+	if e.Span == nil {
+		return 0
+	}
+	return utf8.RuneCountInString(input[:min(e.Span.Start, len(input))]) + 1
+}
+
+// Moves the span of an error by an offset, used when a part of a statement was read on its own.
+// solve 2 + * 3 = 0 for x, 6 -> the '*' is at 10 instead of 4
+func Shift(err error, offset int) error {
+	if e, ok := err.(*Error); ok && e.Span != nil {
+		e.Span = &Span{Start: e.Span.Start + offset, End: e.Span.End + offset}
+	}
+	return err
+}
+
+// Marks the span of an error below the input. The label is the part of the message after the
+// first comma, i.e.: 2 + * 3 ->
+//
+//	2 + * 3
+//	    ^ unexpected '*'
+func (e *Error) Mark(input string, indent string) string {
+	if e.Span == nil || e.Span.Start > len(input) {
+		return ""
+	}
+	_, label, found := strings.Cut(e.Message, ", ")
+	if !found {
+		label = e.Message
+	}
+	label = strings.TrimSuffix(label, ".")
+
+	// The span is given in bytes, the marks are placed by characters. i.e.: π + * 2
+	end := min(e.Span.End, len(input))
+	carets := utf8.RuneCountInString(input[e.Span.Start:end])
+	return indent + strings.Repeat(" ", utf8.RuneCountInString(input[:e.Span.Start])) + strings.Repeat("^", max(carets, 1)) + " " + label
+}
+
+// Returns the code of an error, other errors are their own code.
+func ErrorCode(err error) string {
+	if e, ok := err.(*Error); ok {
+		return e.Code
+	}
+	return err.Error()
+}
@@ -1,9 +0,0 @@
-package shared
-
-var Conf Config
-
-var Variables map[string]Node = make(map[string]Node)
-
-// var VariableOccurrence []string
-
-var Functions map[string]Function = make(map[string]Function)
@@ -0,0 +1,50 @@
+package shared
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+)
+
+// Names of the operations in the JSON form of a tree.
+var OperationNames = map[int]string{
+	NUMBER:   "number",
+	PLUS:     "plus",
+	MINUS:    "minus",
+	MULTIPLY: "multiply",
+	DIVIDE:   "divide",
+	POWER:    "power",
+	SQRT:     "sqrt",
+	VARIABLE: "variable",
+	FUNCTION: "function",
+	VECTOR:   "vector",
+	MATRIX:   "matrix",
+	UNIT:     "unit",
+}
+
+// Writes a tree as JSON, only the fields used by the operation are written.
+// 2x -> {"type":"multiply","children":[{"type":"number","value":2},{"type":"variable","name":"x"}]}
+func (node *Node) MarshalJSON() ([]byte, error) {
+	res := struct {
+		Type     string   `json:"type"`
+		Value    *float64 `json:"value,omitempty"`
+		Name     string   `json:"name,omitempty"`
+		Left     *Node    `json:"left,omitempty"`
+		Right    *Node    `json:"right,omitempty"`
+		Children []*Node  `json:"children,omitempty"`
+	}{
+		Type:     OperationNames[node.OperationType],
+		Value:    nil,
+		Name:     node.Variable,
+		Left:     node.LNode,
+		Right:    node.RNode,
+		Children: node.Associative,
+	}
+	// JSON has no infinity, those numbers are written by name. +Inf
+	if node.OperationType == NUMBER && (math.IsInf(node.Value, 0) || math.IsNaN(node.Value)) {
+		res.Name = strconv.FormatFloat(node.Value, 'f', -1, 64)
+	} else if node.OperationType == NUMBER {
+		res.Value = &node.Value
+	}
+	return json.Marshal(res)
+}
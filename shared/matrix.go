@@ -0,0 +1,149 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A matrix of numbers stored by rows. Vectors are matrices with a single column,
+// they are written as a flat list. [1, 2] -> [[1] [2]]
+type Matrix struct {
+	Values [][]float64
+	Vector bool
+}
+
+// Built-in functions of matrices and vectors. They are known to the lexer and parser like the other
+// built-in functions, but the interpreter evaluates them with matrix values.
+var MatrixBuiltins = map[string]Builtin{
+	"transpose": {1, 1, false, matrixOnly},
+	"det":       {1, 1, false, matrixOnly},
+	"inv":       {1, 1, false, matrixOnly},
+	"rank":      {1, 1, false, matrixOnly},
+	"dot":       {2, 2, false, matrixOnly},
+	"cross":     {2, 2, false, matrixOnly},
+}
+
+func init() {
+	for name, val := range MatrixBuiltins {
+		Builtins[name] = val
+	}
+}
+
+func matrixOnly(params []float64) (float64, error) {
+	return 0, errors.New("expects a matrix or a vector")
+}
+
+// Creates a matrix of zeros.
+func NewMatrix(rows, columns int) *Matrix {
+	values := make([][]float64, rows)
+	for i := range values {
+		values[i] = make([]float64, columns)
+	}
+	return &Matrix{Values: values, Vector: false}
+}
+
+func (m *Matrix) Rows() int {
+	return len(m.Values)
+}
+
+func (m *Matrix) Columns() int {
+	if len(m.Values) == 0 {
+		return 0
+	}
+	return len(m.Values[0])
+}
+
+// Describes the dimension for error messages. i.e.: 2x3 matrix, vector of length 3
+func (m *Matrix) Dimension() string {
+	if m.Vector {
+		return fmt.Sprintf("vector of length %v", m.Rows())
+	}
+	return fmt.Sprintf("%vx%v matrix", m.Rows(), m.Columns())
+}
+
+// Returns the tree of a matrix, vectors are a single list.
+// [[1 2] [3 4]] -> [[1, 2], [3, 4]]
+func MatrixNode(m *Matrix) *Node {
+	number := func(value float64) *Node {
+		return NumberNode(value)
+	}
+	list := func(values []float64) *Node {
+		elements := []*Node{}
+		for _, val := range values {
+			elements = append(elements, number(val))
+		}
+		return &Node{
+			OperationType: VECTOR,
+			Value:         0.0,
+			Variable:      "",
+			LNode:         nil,
+			RNode:         nil,
+			Associative:   elements,
+			Rational:      nil,
+		}
+	}
+
+	if m.Vector {
+		values := []float64{}
+		for _, row := range m.Values {
+			values = append(values, row[0])
+		}
+		return list(values)
+	}
+	rows := []*Node{}
+	for _, row := range m.Values {
+		rows = append(rows, list(row))
+	}
+	return &Node{
+		OperationType: MATRIX,
+		Value:         0.0,
+		Variable:      "",
+		LNode:         nil,
+		RNode:         nil,
+		Associative:   rows,
+		Rational:      nil,
+	}
+}
+
+// Checks if a tree calculates with matrices, either directly, through defined variables and
+// functions, or by calling a matrix function. i.e.: det(A)
+func (s *State) ContainsMatrix(node *Node) bool {
+	return s.contains(node, func(n *Node) bool {
+		_, builtin := MatrixBuiltins[n.Variable]
+		return n.OperationType == VECTOR || n.OperationType == MATRIX || (n.OperationType == FUNCTION && builtin)
+	}, map[string]bool{})
+}
+
+// Checks if a node of the tree, of the defined variables or of the called functions matches.
+func (s *State) contains(node *Node, match func(*Node) bool, visited map[string]bool) bool {
+	if node == nil {
+		return false
+	}
+	if match(node) {
+		return true
+	}
+	switch node.OperationType {
+	case VARIABLE:
+		// Every defined name is only checked once, which also stops at recursive definitions.
+		if val, ok := s.Variables[node.Variable]; ok && !visited[node.Variable] {
+			visited[node.Variable] = true
+			return s.contains(&val, match, visited)
+		}
+	case FUNCTION:
+		if val, ok := s.Functions[node.Variable]; ok && !visited[node.Variable] {
+			visited[node.Variable] = true
+			if s.contains(val.Equation, match, visited) {
+				return true
+			}
+		}
+	}
+	if s.contains(node.LNode, match, visited) || s.contains(node.RNode, match, visited) {
+		return true
+	}
+	for _, val := range node.Associative {
+		if s.contains(val, match, visited) {
+			return true
+		}
+	}
+	return false
+}
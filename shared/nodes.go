@@ -0,0 +1,91 @@
+package shared
+
+// Constructors for the nodes the packages build trees from.
+
+// Returns the node of a number.
+func NumberNode(value float64) *Node {
+	return &Node{
+		OperationType: NUMBER,
+		Value:         value,
+		Variable:      "",
+		LNode:         nil,
+		RNode:         nil,
+		Associative:   nil,
+		Rational:      nil,
+	}
+}
+
+// Returns the node of a variable.
+func VariableNode(name string) *Node {
+	return &Node{
+		OperationType: VARIABLE,
+		Value:         0.0,
+		Variable:      name,
+		LNode:         nil,
+		RNode:         nil,
+		Associative:   nil,
+		Rational:      nil,
+	}
+}
+
+// a + b + ...
+func Add(addends ...*Node) *Node {
+	return &Node{
+		OperationType: PLUS,
+		Value:         0.0,
+		Variable:      "",
+		LNode:         nil,
+		RNode:         nil,
+		Associative:   addends,
+		Rational:      nil,
+	}
+}
+
+// a * b * ...
+func Multiply(factors ...*Node) *Node {
+	return &Node{
+		OperationType: MULTIPLY,
+		Value:         0.0,
+		Variable:      "",
+		LNode:         nil,
+		RNode:         nil,
+		Associative:   factors,
+		Rational:      nil,
+	}
+}
+
+// -1 * a
+func Negate(node *Node) *Node {
+	return Multiply(NumberNode(-1.0), node)
+}
+
+// a^b
+func Power(base *Node, exponent *Node) *Node {
+	return &Node{
+		OperationType: POWER,
+		Value:         0.0,
+		Variable:      "",
+		LNode:         base,
+		RNode:         exponent,
+		Associative:   nil,
+		Rational:      nil,
+	}
+}
+
+// a^-1
+func Reciprocal(node *Node) *Node {
+	return Power(node, NumberNode(-1.0))
+}
+
+// name(a, b, ...)
+func FunctionNode(name string, params ...*Node) *Node {
+	return &Node{
+		OperationType: FUNCTION,
+		Value:         0.0,
+		Variable:      name,
+		LNode:         nil,
+		RNode:         nil,
+		Associative:   params,
+		Rational:      nil,
+	}
+}
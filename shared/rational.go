@@ -0,0 +1,308 @@
+package shared
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Numbers in the tree are stored as float64. In exact mode a number stands for
+// the decimal it is written as, so 0.1 is exactly 1/10 and not the closest float.
+// Numbers without a float64 of the same decimal keep their exact value in the node.
+
+// Maximum exponent that is calculated exactly, larger powers are approximated.
+const MAX_EXACT_EXPONENT = 4096
+
+// Checks if numbers stand for the decimal they are written as. This is the case in exact
+// mode and when calculating with a higher precision, where a float64 would lose digits.
+func (conf Config) KeepDecimals() bool {
+	return conf.Options["exact"] || conf.Settings["precision"] > 0
+}
+
+// Converts a number to the rational of its shortest decimal representation.
+// Returns nil for infinite numbers and NaN.
+// 0.1 -> 1/10
+func Rational(value float64) *big.Rat {
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return nil
+	}
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(value, 'g', -1, 64))
+	if !ok {
+		return nil
+	}
+	return r
+}
+
+// Checks if a rational can be stored in a number without losing precision.
+// 1/4 -> true, 1/3 -> false
+func IsDecimal(r *big.Rat) bool {
+	f, _ := r.Float64()
+	d := Rational(f)
+	return d != nil && d.Cmp(r) == 0
+}
+
+// Returns the exact value of a number node, see Rational. Returns nil for infinite numbers and NaN.
+// 10^30 + 1 -> 1000000000000000000000000000001
+func NumberRational(node *Node) *big.Rat {
+	if node.Rational != nil {
+		return new(big.Rat).Set(node.Rational)
+	}
+	return Rational(node.Value)
+}
+
+// Returns the node of a number, the exact value is kept if it is no decimal of a float64.
+// 0.5 -> 0.5, 10^30 + 1 -> 1e30 with the exact value
+func ExactNumber(r *big.Rat) *Node {
+	f, _ := r.Float64()
+	node := NumberNode(f)
+	if !IsDecimal(r) {
+		node.Rational = new(big.Rat).Set(r)
+	}
+	return node
+}
+
+// Writes a number, exact values are written with all their digits.
+// 1e30 -> 1000000000000000000000000000000, 0.1000000000000000000001
+func FormatNumber(node *Node) string {
+	if r := node.Rational; r != nil {
+		if r.IsInt() {
+			return r.Num().String()
+		}
+		// Exact values of the input are decimals, so their denominator has at most as many digits.
+		return strings.TrimRight(strings.TrimRight(r.FloatString(len(r.Denom().String())), "0"), ".")
+	}
+	return strconv.FormatFloat(node.Value, 'f', -1, 64)
+}
+
+// Checks if two numbers are equal, including their exact value.
+func sameNumber(a, b *Node) bool {
+	if a.Rational != nil || b.Rational != nil {
+		x, y := NumberRational(a), NumberRational(b)
+		return x != nil && y != nil && x.Cmp(y) == 0
+	}
+	return a.Value == b.Value
+}
+
+// Returns the tree for a rational, fractions that are not decimals become a multiplication.
+// Integers are single numbers, so they keep all their digits.
+// 1/4 -> 0.25, 1/3 -> 1 * 3^-1, 10^30 + 1 -> 1000000000000000000000000000001
+func RationalNode(r *big.Rat) *Node {
+	if IsDecimal(r) || r.IsInt() {
+		return ExactNumber(r)
+	}
+
+	return &Node{
+		OperationType: MULTIPLY,
+		Value:         0.0,
+		Variable:      "",
+		LNode:         nil,
+		RNode:         nil,
+		Associative: []*Node{
+			ExactNumber(new(big.Rat).SetInt(r.Num())),
+			{
+				OperationType: POWER,
+				Value:         0.0,
+				Variable:      "",
+				LNode:         ExactNumber(new(big.Rat).SetInt(r.Denom())),
+				RNode:         NumberNode(-1.0),
+				Associative:   nil,
+				Rational:      nil,
+			},
+		},
+		Rational: nil,
+	}
+}
+
+// Formats a rational as a reduced fraction. 14/24 -> 7/12, 4/2 -> 2
+func FormatRational(r *big.Rat) string {
+	return r.RatString()
+}
+
+// Returns the n-th root of an integer, if it is an integer.
+// 27, 3 -> 3, true
+func integerRoot(x *big.Int, n int64) (*big.Int, bool) {
+	if x.Sign() < 0 {
+		if n%2 == 0 {
+			return nil, false
+		}
+		root, ok := integerRoot(new(big.Int).Neg(x), n)
+		if !ok {
+			return nil, false
+		}
+		return root.Neg(root), true
+	}
+	if n == 2 {
+		root := new(big.Int).Sqrt(x)
+		return root, new(big.Int).Mul(root, root).Cmp(x) == 0
+	}
+
+	// The estimate of the float root is only off by a small amount.
+	f, _ := new(big.Float).SetInt(x).Float64()
+	estimate := math.Round(math.Pow(f, 1/float64(n)))
+	if math.IsInf(estimate, 0) {
+		return nil, false
+	}
+	for _, offset := range []float64{0, -1, 1} {
+		root, _ := big.NewFloat(estimate + offset).Int(nil)
+		if root.Sign() >= 0 && new(big.Int).Exp(root, big.NewInt(n), nil).Cmp(x) == 0 {
+			return root, true
+		}
+	}
+	return nil, false
+}
+
+// Returns the n-th root of a rational, if it is rational.
+// 4/9, 2 -> 2/3, true
+func RationalRoot(r *big.Rat, n int64) (*big.Rat, bool) {
+	if n <= 0 {
+		return nil, false
+	}
+	num, ok := integerRoot(r.Num(), n)
+	if !ok {
+		return nil, false
+	}
+	denom, ok := integerRoot(r.Denom(), n)
+	if !ok {
+		return nil, false
+	}
+	return new(big.Rat).SetFrac(num, denom), true
+}
+
+// Raises a rational to a rational power. The second result is false,
+// if the result is irrational or too large to be calculated exactly.
+// 4, 3/2 -> 8, true
+func RationalPower(base *big.Rat, exponent *big.Rat) (*big.Rat, bool, error) {
+	if !exponent.Denom().IsInt64() || !exponent.Num().IsInt64() {
+		return nil, false, nil
+	}
+	p, q := exponent.Num().Int64(), exponent.Denom().Int64()
+	if p > MAX_EXACT_EXPONENT || p < -MAX_EXACT_EXPONENT {
+		return nil, false, nil
+	}
+
+	if base.Sign() == 0 {
+		if p < 0 {
+			return nil, false, errors.New("divide by 0")
+		}
+		if p == 0 {
+			return big.NewRat(1, 1), true, nil
+		}
+		return new(big.Rat), true, nil
+	}
+
+	root, ok := RationalRoot(base, q)
+	if !ok {
+		return nil, false, nil
+	}
+
+	res := new(big.Rat).SetFrac(
+		new(big.Int).Exp(root.Num(), big.NewInt(abs(p)), nil),
+		new(big.Int).Exp(root.Denom(), big.NewInt(abs(p)), nil),
+	)
+	if p < 0 {
+		res.Inv(res)
+	}
+	return res, true, nil
+}
+
+func abs(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Floor of a rational. 7/2 -> 3, -7/2 -> -4
+func floor(r *big.Rat) *big.Int {
+	res := new(big.Int)
+	mod := new(big.Int)
+	res.DivMod(r.Num(), r.Denom(), mod)
+	return res
+}
+
+// Exact versions of the built-in functions, that always return a rational result.
+var RationalBuiltins = map[string]func(params []*big.Rat) (*big.Rat, error){
+	"re": func(params []*big.Rat) (*big.Rat, error) {
+		return new(big.Rat).Set(params[0]), nil
+	},
+	"im": func(params []*big.Rat) (*big.Rat, error) {
+		return new(big.Rat), nil
+	},
+	"conj": func(params []*big.Rat) (*big.Rat, error) {
+		return new(big.Rat).Set(params[0]), nil
+	},
+	"abs": func(params []*big.Rat) (*big.Rat, error) {
+		return new(big.Rat).Abs(params[0]), nil
+	},
+	"floor": func(params []*big.Rat) (*big.Rat, error) {
+		return new(big.Rat).SetInt(floor(params[0])), nil
+	},
+	"ceil": func(params []*big.Rat) (*big.Rat, error) {
+		// ceil(x) = -floor(-x)
+		res := floor(new(big.Rat).Neg(params[0]))
+		return new(big.Rat).SetInt(res.Neg(res)), nil
+	},
+	"round": func(params []*big.Rat) (*big.Rat, error) {
+		// Halves are rounded away from zero like math.Round.
+		half := big.NewRat(1, 2)
+		if params[0].Sign() < 0 {
+			res := floor(new(big.Rat).Add(new(big.Rat).Neg(params[0]), half))
+			return new(big.Rat).SetInt(res.Neg(res)), nil
+		}
+		return new(big.Rat).SetInt(floor(new(big.Rat).Add(params[0], half))), nil
+	},
+	"min": func(params []*big.Rat) (*big.Rat, error) {
+		res := params[0]
+		for _, val := range params[1:] {
+			if val.Cmp(res) < 0 {
+				res = val
+			}
+		}
+		return new(big.Rat).Set(res), nil
+	},
+	"max": func(params []*big.Rat) (*big.Rat, error) {
+		res := params[0]
+		for _, val := range params[1:] {
+			if val.Cmp(res) > 0 {
+				res = val
+			}
+		}
+		return new(big.Rat).Set(res), nil
+	},
+	"gcd": func(params []*big.Rat) (*big.Rat, error) {
+		res := new(big.Int)
+		for _, val := range params {
+			if !val.IsInt() {
+				return nil, errors.New("gcd of a non integer")
+			}
+			res.GCD(nil, nil, res, new(big.Int).Abs(val.Num()))
+		}
+		return new(big.Rat).SetInt(res), nil
+	},
+	"lcm": func(params []*big.Rat) (*big.Rat, error) {
+		res := big.NewInt(1)
+		for _, val := range params {
+			if !val.IsInt() {
+				return nil, errors.New("lcm of a non integer")
+			}
+			if val.Sign() == 0 {
+				return new(big.Rat), nil
+			}
+			x := new(big.Int).Abs(val.Num())
+			divisor := new(big.Int).GCD(nil, nil, res, x)
+			res.Mul(res, x).Quo(res, divisor)
+		}
+		return new(big.Rat).SetInt(res), nil
+	},
+	"factorial": func(params []*big.Rat) (*big.Rat, error) {
+		if !params[0].IsInt() || params[0].Sign() < 0 {
+			return nil, errors.New("factorial of a negative or non integer number")
+		}
+		if !params[0].Num().IsInt64() || params[0].Num().Int64() > MAX_EXACT_EXPONENT {
+			return nil, errors.New("factorial too large")
+		}
+		return new(big.Rat).SetInt(new(big.Int).MulRange(1, params[0].Num().Int64())), nil
+	},
+}
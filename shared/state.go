@@ -0,0 +1,58 @@
+package shared
+
+import "context"
+
+// State of a calculation: the config, the defined variables and functions and the context of the
+// running statement. Every context of the engine has its own state, which is passed to the
+// packages calculating its statements, so contexts can be used at the same time.
+type State struct {
+	Conf      Config
+	Variables map[string]Node
+	Functions map[string]Function
+	// Context of the running statement. Long calculations check it and stop, once it is done,
+	// i.e.: a request of the server timed out.
+	Running context.Context
+}
+
+// Creates a state with the config and without any definitions, that is never canceled.
+func NewState(conf Config) *State {
+	return &State{
+		Conf:      conf,
+		Variables: make(map[string]Node),
+		Functions: make(map[string]Function),
+		Running:   context.Background(),
+	}
+}
+
+// Returns a copy of the state for a statement running with ctx. The copy shares the config and
+// the definitions with the state.
+func (s *State) With(ctx context.Context) *State {
+	res := *s
+	res.Running = ctx
+	return &res
+}
+
+// Returns an error, if the running statement was canceled.
+func (s *State) Canceled() error {
+	if s.Running.Err() != nil {
+		return NewError("canceled", "Unable to finish calculation, the calculation was canceled.")
+	}
+	return nil
+}
+
+// Value a canceled calculation panics with.
+type cancellation struct{}
+
+// Stops a canceled statement in code that can not return an error, i.e.: polynomial arithmetic.
+// The engine recovers from it with the error of Canceled, outside of the engine nothing is canceled.
+func (s *State) StopIfCanceled() {
+	if s.Running.Err() != nil {
+		panic(cancellation{})
+	}
+}
+
+// Checks if a recovered value comes from StopIfCanceled.
+func IsCancellation(r any) bool {
+	_, ok := r.(cancellation)
+	return ok
+}
@@ -1,18 +1,63 @@
 package shared
 
+import (
+	"math/big"
+	"strconv"
+)
+
 type Config struct {
 	Version   string
 	Options   map[string]bool
+	Settings  map[string]int
 	Symbols   map[string]string
 	Constants map[string]float64
+	Units     map[string]string
 }
 
+// A token of the input, the offset is the byte position it starts at.
+// Numbers a float64 can not hold keep their exact value in exact mode and with a higher precision.
+// The text is the part of the input it was read from, it is empty for tokens added while reading. i.e.: \frac
 type Token struct {
 	TokenType int
 	Value     float64
 	Variable  string
+	Offset    int
+	Rational  *big.Rat
+	Text      string
+}
+
+// Writes a token as it appears in the input. i.e.: *, 2.5, sin
+// Tokens, that were not read from the input, are written in the default symbols.
+func (t Token) String() string {
+	if t.Text != "" {
+		return t.Text
+	}
+	switch t.TokenType {
+	case NUMBER:
+		return strconv.FormatFloat(t.Value, 'f', -1, 64)
+	case VARIABLE, FUNCTION, UNIT:
+		return t.Variable
+	}
+	symbols := map[int]string{
+		PLUS:         "plus",
+		MINUS:        "minus",
+		MULTIPLY:     "multiply",
+		DIVIDE:       "divide",
+		POWER:        "power",
+		SQRT:         "sqrt",
+		LPARENTHESES: "l_parentheses",
+		RPARENTHESES: "r_parentheses",
+		EQUAL:        "equal",
+		COMMA:        "parameter_split",
+		LBRACKET:     "l_brackets",
+		RBRACKET:     "r_brackets",
+	}
+	return GetDefualtConfig().Symbols[symbols[t.TokenType]]
 }
 
+// A node of the tree. The value of a number is approximated, if a float64 can not hold it,
+// in exact mode and with a higher precision its exact value is kept as a rational.
+// 10^30 + 1 -> Value: 1e30, Rational: 1000000000000000000000000000001
 type Node struct {
 	OperationType int
 	Value         float64
@@ -20,6 +65,7 @@ type Node struct {
 	LNode         *Node
 	RNode         *Node
 	Associative   []*Node
+	Rational      *big.Rat
 }
 
 type Function struct {
@@ -0,0 +1,315 @@
+package shared
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Exponents of the SI base units kg, m, s, A, K, mol and cd. m/s^2 -> [0 1 -2 0 0 0 0]
+type Dimension [7]int
+
+// A unit is a multiple of a product of base units. km -> 1000 m
+type Unit struct {
+	Factor    float64
+	Dimension Dimension
+}
+
+// Maximum depth of user defined units, that are defined by other user defined units.
+const MAX_UNIT_DEPTH = 32
+
+// Error of a unit expression, the reason is the message and the symbol the part that could not
+// be read. unknown unit, zz
+type unitError struct {
+	reason string
+	symbol string
+}
+
+func (e *unitError) Error() string {
+	return e.reason
+}
+
+var baseUnits = []string{"kg", "m", "s", "A", "K", "mol", "cd"}
+
+// Built-in units by name, the ones marked with prefix accept SI prefixes. i.e.: km, ms, kWh
+var builtinUnits = map[string]struct {
+	Unit
+	prefix bool
+}{
+	// Base units, the kilogram is the gram with a prefix.
+	"g":   {Unit{1e-3, Dimension{1, 0, 0, 0, 0, 0, 0}}, true},
+	"m":   {Unit{1, Dimension{0, 1, 0, 0, 0, 0, 0}}, true},
+	"s":   {Unit{1, Dimension{0, 0, 1, 0, 0, 0, 0}}, true},
+	"A":   {Unit{1, Dimension{0, 0, 0, 1, 0, 0, 0}}, true},
+	"K":   {Unit{1, Dimension{0, 0, 0, 0, 1, 0, 0}}, true},
+	"mol": {Unit{1, Dimension{0, 0, 0, 0, 0, 1, 0}}, true},
+	"cd":  {Unit{1, Dimension{0, 0, 0, 0, 0, 0, 1}}, true},
+
+	// Derived units
+	"N":   {Unit{1, Dimension{1, 1, -2, 0, 0, 0, 0}}, true},
+	"J":   {Unit{1, Dimension{1, 2, -2, 0, 0, 0, 0}}, true},
+	"W":   {Unit{1, Dimension{1, 2, -3, 0, 0, 0, 0}}, true},
+	"Pa":  {Unit{1, Dimension{1, -1, -2, 0, 0, 0, 0}}, true},
+	"Hz":  {Unit{1, Dimension{0, 0, -1, 0, 0, 0, 0}}, true},
+	"C":   {Unit{1, Dimension{0, 0, 1, 1, 0, 0, 0}}, true},
+	"V":   {Unit{1, Dimension{1, 2, -3, -1, 0, 0, 0}}, true},
+	"Ohm": {Unit{1, Dimension{1, 2, -3, -2, 0, 0, 0}}, true},
+	"L":   {Unit{1e-3, Dimension{0, 3, 0, 0, 0, 0, 0}}, true},
+	"Wh":  {Unit{3600, Dimension{1, 2, -2, 0, 0, 0, 0}}, true},
+	"eV":  {Unit{1.602176634e-19, Dimension{1, 2, -2, 0, 0, 0, 0}}, true},
+	"bar": {Unit{1e5, Dimension{1, -1, -2, 0, 0, 0, 0}}, true},
+
+	// Time
+	"min": {Unit{60, Dimension{0, 0, 1, 0, 0, 0, 0}}, false},
+	"h":   {Unit{3600, Dimension{0, 0, 1, 0, 0, 0, 0}}, false},
+	"day": {Unit{86400, Dimension{0, 0, 1, 0, 0, 0, 0}}, false},
+
+	// Imperial units
+	"in":  {Unit{0.0254, Dimension{0, 1, 0, 0, 0, 0, 0}}, false},
+	"ft":  {Unit{0.3048, Dimension{0, 1, 0, 0, 0, 0, 0}}, false},
+	"yd":  {Unit{0.9144, Dimension{0, 1, 0, 0, 0, 0, 0}}, false},
+	"mi":  {Unit{1609.344, Dimension{0, 1, 0, 0, 0, 0, 0}}, false},
+	"lb":  {Unit{0.45359237, Dimension{1, 0, 0, 0, 0, 0, 0}}, false},
+	"oz":  {Unit{0.028349523125, Dimension{1, 0, 0, 0, 0, 0, 0}}, false},
+	"mph": {Unit{0.44704, Dimension{0, 1, -1, 0, 0, 0, 0}}, false},
+	"atm": {Unit{101325, Dimension{1, -1, -2, 0, 0, 0, 0}}, false},
+	"cal": {Unit{4.184, Dimension{1, 2, -2, 0, 0, 0, 0}}, true},
+}
+
+// SI prefixes, u is written for micro.
+var prefixes = map[string]float64{
+	"Y": 1e24, "Z": 1e21, "E": 1e18, "P": 1e15, "T": 1e12, "G": 1e9, "M": 1e6, "k": 1e3, "h": 1e2, "da": 1e1,
+	"d": 1e-1, "c": 1e-2, "m": 1e-3, "u": 1e-6, "n": 1e-9, "p": 1e-12, "f": 1e-15, "a": 1e-18, "z": 1e-21, "y": 1e-24,
+}
+
+// Named units, results with their dimension are written with them.
+var derivedNames = []string{"N", "J", "W", "Pa", "C", "V", "Ohm"}
+
+// Returns a unit by name. Built-in units come first, then user defined units from the config
+// and last built-in units with an SI prefix. km -> 1000 m
+func (conf Config) LookupUnit(name string) (Unit, bool) {
+	res, err := conf.lookupUnit(name, 0)
+	return res, err == nil
+}
+
+func (conf Config) lookupUnit(name string, depth int) (Unit, error) {
+	if val, ok := builtinUnits[name]; ok {
+		return val.Unit, nil
+	}
+	if definition, ok := conf.Units[name]; ok {
+		return conf.parseDefinition(definition, depth+1)
+	}
+	for prefix, factor := range prefixes {
+		if rest, found := strings.CutPrefix(name, prefix); found {
+			if val, ok := builtinUnits[rest]; ok && val.prefix {
+				return Unit{Factor: factor * val.Factor, Dimension: val.Dimension}, nil
+			}
+		}
+	}
+	return Unit{}, &unitError{reason: "unknown unit", symbol: name}
+}
+
+// Reads a user defined unit, a number followed by a unit expression. 201.168 m
+func (conf Config) parseDefinition(definition string, depth int) (Unit, error) {
+	if depth > MAX_UNIT_DEPTH {
+		return Unit{}, errors.New("unit definition too deep")
+	}
+	definition = strings.TrimSpace(definition)
+	j := 0
+	for j < len(definition) && (unicode.IsDigit(rune(definition[j])) || strings.ContainsRune(".eE+-", rune(definition[j]))) {
+		// An e only belongs to the number, if a digit or sign follows. 1e3 m, but not 1 eV
+		if (definition[j] == 'e' || definition[j] == 'E') && (j+1 >= len(definition) || !strings.ContainsRune("0123456789+-", rune(definition[j+1]))) {
+			break
+		}
+		j++
+	}
+
+	factor := 1.0
+	if j > 0 {
+		val, err := strconv.ParseFloat(definition[:j], 64)
+		if err != nil {
+			return Unit{}, &unitError{reason: "invalid number", symbol: definition[:j]}
+		}
+		factor = val
+	}
+	res, err := conf.parseUnit(definition[j:], depth)
+	if err != nil {
+		return Unit{}, err
+	}
+	res.Factor *= factor
+	return res, nil
+}
+
+// Checks the user defined units of the config, every definition has to consist of a number and
+// known units. All invalid definitions are reported.
+// fortnight = "14 d" -> Unable to read unit 'fortnight', unknown unit 'd'.
+func (conf Config) CheckUnits() error {
+	names := []string{}
+	for name := range conf.Units {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	errs := []error{}
+	for _, name := range names {
+		_, err := conf.parseDefinition(conf.Units[name], 1)
+		var symbolError *unitError
+		if errors.As(err, &symbolError) {
+			errs = append(errs, NewError("invalid unit definition", "Unable to read unit '%s', %s '%s'.", name, symbolError.reason, symbolError.symbol))
+		} else if err != nil {
+			errs = append(errs, NewError("invalid unit definition", "Unable to read unit '%s', %v.", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Parses a unit expression of unit names with integer exponents. A slash divides by the following
+// unit, a star or a space multiplies with it. kg*m/s^2 -> 1 kg m s^-2, km/h -> 1/3.6 m s^-1
+func (conf Config) ParseUnit(expression string) (Unit, error) {
+	return conf.parseUnit(expression, 0)
+}
+
+func (conf Config) parseUnit(expression string, depth int) (Unit, error) {
+	res := Unit{Factor: 1, Dimension: Dimension{}}
+	sign := 1
+	found := false
+	i := 0
+	for i < len(expression) {
+		c := rune(expression[i])
+		switch {
+		case unicode.IsSpace(c) || c == '*':
+			i++
+		case c == '/':
+			sign = -1
+			i++
+		case unicode.IsLetter(c):
+			j := i
+			for j < len(expression) && unicode.IsLetter(rune(expression[j])) {
+				j++
+			}
+			unit, err := conf.lookupUnit(expression[i:j], depth)
+			if err != nil {
+				return Unit{}, err
+			}
+
+			// Optional integer exponent. s^2, s^-1
+			exponent := 1
+			if j < len(expression) && expression[j] == '^' {
+				k := j + 1
+				if k < len(expression) && expression[k] == '-' {
+					k++
+				}
+				for k < len(expression) && unicode.IsDigit(rune(expression[k])) {
+					k++
+				}
+				val, err := strconv.Atoi(expression[j+1 : k])
+				if err != nil {
+					return Unit{}, err
+				}
+				exponent = val
+				j = k
+			}
+
+			exponent *= sign
+			res.Factor *= math.Pow(unit.Factor, float64(exponent))
+			for d := range res.Dimension {
+				res.Dimension[d] += unit.Dimension[d] * exponent
+			}
+			sign = 1
+			found = true
+			i = j
+		default:
+			return Unit{}, &unitError{reason: "invalid unit", symbol: string(c)}
+		}
+	}
+	if !found {
+		return Unit{}, errors.New("missing unit")
+	}
+	return res, nil
+}
+
+// Returns the length of the unit expression at the start of the string, 0 if there is none.
+// A unit name is followed by an optional exponent and more units after a star, slash or space.
+// m/s^2 + 1 -> 6, km to mi -> 2
+func (conf Config) UnitLength(str string) int {
+	length := 0
+	i := 0
+	for {
+		j := i
+		for j < len(str) && unicode.IsLetter(rune(str[j])) {
+			j++
+		}
+		if j == i {
+			return length
+		}
+		if _, ok := conf.LookupUnit(str[i:j]); !ok {
+			return length
+		}
+		if j+1 < len(str) && str[j] == '^' {
+			k := j + 1
+			if str[k] == '-' {
+				k++
+			}
+			digits := k
+			for k < len(str) && unicode.IsDigit(rune(str[k])) {
+				k++
+			}
+			if k > digits {
+				j = k
+			}
+		}
+		length = j
+
+		// The next unit may follow after an operator or a single space.
+		if j < len(str) && (str[j] == '*' || str[j] == '/' || str[j] == ' ') {
+			i = j + 1
+		} else {
+			return length
+		}
+	}
+}
+
+// Checks if a dimension has only zero exponents.
+func (d Dimension) IsZero() bool {
+	return d == Dimension{}
+}
+
+// Writes a dimension with the named derived units or with base units.
+// [1 1 -2 0 0 0 0] -> N, [0 1 -1 0 0 0 0] -> m/s
+func (d Dimension) String() string {
+	for _, name := range derivedNames {
+		if builtinUnits[name].Dimension == d {
+			return name
+		}
+	}
+
+	numerator := []string{}
+	denominator := []string{}
+	for i, exponent := range d {
+		part := baseUnits[i]
+		if exponent > 1 || exponent < -1 {
+			part += "^" + strconv.Itoa(max(exponent, -exponent))
+		}
+		if exponent > 0 {
+			numerator = append(numerator, part)
+		} else if exponent < 0 {
+			denominator = append(denominator, part)
+		}
+	}
+
+	str := strings.Join(numerator, "*")
+	if len(numerator) == 0 {
+		str = "1"
+	}
+	if len(denominator) > 0 {
+		str += "/" + strings.Join(denominator, "/")
+	}
+	return str
+}
+
+// Checks if a tree contains a unit, directly or through defined variables and functions.
+func (s *State) ContainsUnit(node *Node) bool {
+	return s.contains(node, func(n *Node) bool { return n.OperationType == UNIT }, map[string]bool{})
+}
@@ -0,0 +1,35 @@
+package shared_test
+
+import (
+	"lambdacalc/shared"
+	"strings"
+	"testing"
+)
+
+// Invalid user defined units name the unit and the part that could not be read.
+func TestCheckUnits(t *testing.T) {
+	tests := []struct {
+		units map[string]string
+		want  []string
+	}{
+		{map[string]string{"furlong": "201.168 m", "fortnight": "14 day"}, nil},
+		{map[string]string{"furlong": "201.168 m", "speed": "1 furlong/h"}, nil},
+		{map[string]string{"fortnight": "14 d"}, []string{"Unable to read unit 'fortnight', unknown unit 'd'."}},
+		{map[string]string{"bad": "3 zz", "worse": "2 bad"}, []string{"Unable to read unit 'bad', unknown unit 'zz'.", "Unable to read unit 'worse', unknown unit 'zz'."}},
+		{map[string]string{"odd": "3 m#"}, []string{"Unable to read unit 'odd', invalid unit '#'."}},
+		{map[string]string{"none": "3"}, []string{"Unable to read unit 'none', missing unit."}},
+		{map[string]string{"a": "2 b", "b": "2 a"}, []string{"Unable to read unit 'a', unit definition too deep.", "Unable to read unit 'b', unit definition too deep."}},
+	}
+	for _, test := range tests {
+		conf := shared.GetDefualtConfig()
+		conf.Units = test.units
+		err := conf.CheckUnits()
+		got := []string{}
+		if err != nil {
+			got = strings.Split(err.Error(), "\n")
+		}
+		if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+			t.Errorf("%v: got %q, want %q", test.units, got, test.want)
+		}
+	}
+}
@@ -1,29 +1,36 @@
 package shared
 
 import (
-	"strconv"
+	"math/big"
+	"slices"
 
 	"github.com/i582/cfmt/cmd/cfmt"
 )
 
 func ZeroNode() *Node {
-	return &Node{
-		OperationType: NUMBER,
-		Value:         0.0,
-		Variable:      "",
-		LNode:         nil,
-		RNode:         nil,
-		Associative:   nil,
-	}
+	return NumberNode(0.0)
 }
 
+// Compares the structure of two trees, the order of addends and factors does not matter.
+// Equal polynomials written differently are not equal, see polynomial.Equal.
+// (x + 1)^2, x^2 + 2x + 1 -> false
 func IsEqual(a, b *Node) bool {
 	if a.OperationType != b.OperationType {
 		return false
 	} else if a.OperationType == NUMBER {
-		return a.Value == b.Value
-	} else if a.OperationType == DIVIDE {
+		return sameNumber(a, b)
+	} else if a.OperationType == DIVIDE || a.OperationType == MINUS || a.OperationType == POWER || a.OperationType == SQRT || a.OperationType == EQUAL {
 		return IsEqual(a.LNode, b.LNode) && IsEqual(a.RNode, b.RNode)
+	} else if a.OperationType == FUNCTION {
+		if a.Variable != b.Variable || len(a.Associative) != len(b.Associative) {
+			return false
+		}
+		for i := range a.Associative {
+			if !IsEqual(a.Associative[i], b.Associative[i]) {
+				return false
+			}
+		}
+		return true
 	} else if a.OperationType == VARIABLE {
 		return a.Variable == b.Variable
 	} else if a.OperationType == MULTIPLY || a.OperationType == PLUS {
@@ -92,19 +99,19 @@ func PrintATree(node *Node) string {
 	str := ""
 	switch node.OperationType {
 	case NUMBER:
-		str += strconv.FormatFloat(node.Value, 'f', -1, 64)
+		str += FormatNumber(node)
 	case VARIABLE:
-		if val, ok := Variables[node.Variable]; ok {
-			str += PrintATree(&val)
-		} else {
-			str += node.Variable
-		}
+		str += node.Variable
 	case PLUS:
 		str += "("
 		for i, val := range node.Associative {
-			str += PrintATree(val)
-			if i != len(node.Associative)-1 {
-				str += "+"
+			// Negative addends are written after a minus. x + -1 -> x-1
+			if positive, ok := positiveAddend(val); ok && i > 0 {
+				str += "-" + PrintATree(positive)
+			} else if i > 0 {
+				str += "+" + PrintATree(val)
+			} else {
+				str += PrintATree(val)
 			}
 		}
 		str += ")"
@@ -143,6 +150,17 @@ func PrintATree(node *Node) string {
 		str += ")"
 	case COMMA:
 		str += ","
+	case UNIT:
+		str += node.Variable
+	case VECTOR, MATRIX:
+		str += "["
+		for i, val := range node.Associative {
+			str += PrintATree(val)
+			if i != len(node.Associative)-1 {
+				str += ", "
+			}
+		}
+		str += "]"
 	case FUNCTION:
 		str += node.Variable
 		str += "("
@@ -157,17 +175,43 @@ func PrintATree(node *Node) string {
 	return str
 }
 
+// Returns the positive form of a negative number or of a product starting with one.
+// -3 -> 3, -2*x -> 2*x, -1*x -> x
+func positiveAddend(node *Node) (*Node, bool) {
+	switch node.OperationType {
+	case NUMBER:
+		if r := NumberRational(node); r != nil && r.Sign() < 0 {
+			return RationalNode(new(big.Rat).Neg(r)), true
+		} else if r == nil && node.Value < 0 {
+			return NumberNode(-node.Value), true
+		}
+	case MULTIPLY:
+		if len(node.Associative) < 2 || node.Associative[0].OperationType != NUMBER {
+			return nil, false
+		}
+		factor, ok := positiveAddend(node.Associative[0])
+		if !ok {
+			return nil, false
+		}
+		rest := node.Associative[1:]
+		if factor.OperationType == NUMBER && factor.Rational == nil && factor.Value == 1 {
+			if len(rest) == 1 {
+				return rest[0], true
+			}
+			return Multiply(rest...), true
+		}
+		return Multiply(append([]*Node{factor}, rest...)...), true
+	}
+	return nil, false
+}
+
 func PrintTree(node *Node) string {
 	str := ""
 	switch node.OperationType {
 	case NUMBER:
-		str += strconv.FormatFloat(node.Value, 'f', -1, 64)
+		str += FormatNumber(node)
 	case VARIABLE:
-		if val, ok := Variables[node.Variable]; ok {
-			str += PrintTree(&val)
-		} else {
-			str += node.Variable
-		}
+		str += node.Variable
 	case PLUS:
 		str += "("
 		str += PrintTree(node.LNode)
@@ -206,6 +250,17 @@ func PrintTree(node *Node) string {
 		str += ")"
 	case COMMA:
 		str += ","
+	case UNIT:
+		str += node.Variable
+	case VECTOR, MATRIX:
+		str += "["
+		for i, val := range node.Associative {
+			str += PrintTree(val)
+			if i != len(node.Associative)-1 {
+				str += ", "
+			}
+		}
+		str += "]"
 	case FUNCTION:
 		str += node.Variable
 		str += "("
@@ -219,3 +274,133 @@ func PrintTree(node *Node) string {
 	}
 	return str
 }
+
+// Checks if a variable with the given name appears anywhere in the tree.
+func ContainsVariable(node *Node, variable string) bool {
+	if node == nil {
+		return false
+	}
+	if node.OperationType == VARIABLE && node.Variable == variable {
+		return true
+	}
+	if ContainsVariable(node.LNode, variable) || ContainsVariable(node.RNode, variable) {
+		return true
+	}
+	for _, val := range node.Associative {
+		if ContainsVariable(val, variable) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the names of all variables in the tree, that are neither defined nor constants, in order of appearance.
+// The imaginary unit is a constant in complex mode.
+func (s *State) FreeVariables(node *Node) []string {
+	names := []string{}
+	var walk func(*Node)
+	walk = func(n *Node) {
+		if n == nil {
+			return
+		}
+		if n.OperationType == VARIABLE {
+			_, defined := s.Variables[n.Variable]
+			_, constant := s.Conf.Constants[n.Variable]
+			constant = constant || n.Variable == s.Conf.ImaginaryUnit()
+			if !defined && !constant && !slices.Contains(names, n.Variable) {
+				names = append(names, n.Variable)
+			}
+		}
+		walk(n.LNode)
+		walk(n.RNode)
+		for _, val := range n.Associative {
+			walk(val)
+		}
+	}
+	walk(node)
+	return names
+}
+
+// Returns a copy of the tree with the defined variables replaced by their values, to write it
+// without the definitions. (a+x) with a = 2 -> (2+x)
+func (s *State) Inline(node *Node) *Node {
+	if node == nil {
+		return nil
+	}
+	if node.OperationType == VARIABLE {
+		if val, ok := s.Variables[node.Variable]; ok {
+			return s.Inline(&val)
+		}
+	}
+
+	res := *node
+	res.LNode = s.Inline(node.LNode)
+	res.RNode = s.Inline(node.RNode)
+	res.Associative = []*Node{}
+	for _, val := range node.Associative {
+		res.Associative = append(res.Associative, s.Inline(val))
+	}
+	return &res
+}
+
+// Returns a defined variable that depends on itself, directly or through the functions it calls,
+// or an empty string. Parameters of a function shadow the variables in its equation.
+// x = x + 1 -> x, a = b with b = a -> a
+func (s *State) SelfDependentVariable() string {
+	names := []string{}
+	for name := range s.Variables {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		val := s.Variables[name]
+		if s.dependsOn(&val, name, nil, map[string]bool{}) {
+			return name
+		}
+	}
+	return ""
+}
+
+// Checks if a tree reads the variable, following defined variables and called functions once.
+// Bound names are the parameters of the function the tree belongs to.
+func (s *State) dependsOn(node *Node, name string, bound []string, visited map[string]bool) bool {
+	if node == nil {
+		return false
+	}
+	switch node.OperationType {
+	case VARIABLE:
+		if slices.Contains(bound, node.Variable) {
+			return false
+		}
+		if node.Variable == name {
+			return true
+		}
+		if val, ok := s.Variables[node.Variable]; ok && !visited[node.Variable] {
+			visited[node.Variable] = true
+			if s.dependsOn(&val, name, nil, visited) {
+				return true
+			}
+		}
+	case FUNCTION:
+		// Functions are marked with parentheses, they may share their name with a variable.
+		if val, ok := s.Functions[node.Variable]; ok && !visited[node.Variable+"()"] {
+			visited[node.Variable+"()"] = true
+			parameters := []string{}
+			for _, param := range val.Parameters {
+				parameters = append(parameters, param.Variable)
+			}
+			if s.dependsOn(val.Equation, name, parameters, visited) {
+				return true
+			}
+		}
+	}
+	if s.dependsOn(node.LNode, name, bound, visited) || s.dependsOn(node.RNode, name, bound, visited) {
+		return true
+	}
+	for _, val := range node.Associative {
+		if s.dependsOn(val, name, bound, visited) {
+			return true
+		}
+	}
+	return false
+}
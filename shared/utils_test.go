@@ -0,0 +1,83 @@
+package shared_test
+
+import (
+	"lambdacalc/lexer"
+	"lambdacalc/parser"
+	"lambdacalc/shared"
+	"testing"
+)
+
+// Parses an expression for a test.
+func parse(t *testing.T, input string) *shared.Node {
+	t.Helper()
+	s := shared.NewState(shared.GetDefualtConfig())
+	lexed, err := lexer.LexSymbols(s, input)
+	if err != nil {
+		t.Fatalf("%s: unable to lex, %v", input, err)
+	}
+	parsed, err := parser.Parse(s, lexed)
+	if err != nil {
+		t.Fatalf("%s: unable to parse, %v", input, err)
+	}
+	return parsed
+}
+
+// Trees are compared by their structure, equal polynomials written differently are not equal.
+func TestIsEqual(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"x + 1", "1 + x", true},
+		{"2x*y", "y*x*2", true},
+		{"(x + 1)^2", "x^2 + 2x + 1", false},
+		{"(x - y)(x + y)", "x^2 - y^2", false},
+		{"x", "y", false},
+		{"sin(x)", "sin(x)", true},
+		{"sin(x + x)", "sin(2x)", false},
+	}
+	for _, test := range tests {
+		a, b := parse(t, test.a), parse(t, test.b)
+		if got := shared.IsEqual(a, b); got != test.equal {
+			t.Errorf("IsEqual(%s, %s) = %v, want %v", test.a, test.b, got, test.equal)
+		}
+		// The result does not depend on the order of the arguments.
+		if got := shared.IsEqual(b, a); got != test.equal {
+			t.Errorf("IsEqual(%s, %s) = %v, want %v", test.b, test.a, got, test.equal)
+		}
+	}
+}
+
+// Negative addends after the first are written after a minus.
+func TestPrintNegativeAddends(t *testing.T) {
+	tests := []struct {
+		node *shared.Node
+		want string
+	}{
+		{shared.Add(shared.VariableNode("x"), shared.NumberNode(-1)), "(x-1)"},
+		{shared.Add(shared.VariableNode("x"), shared.Multiply(shared.NumberNode(-1), shared.VariableNode("y"))), "(x-y)"},
+		{shared.Add(shared.NumberNode(3), shared.Multiply(shared.NumberNode(-2), shared.VariableNode("z"))), "(3-(2*z))"},
+		{shared.Add(shared.NumberNode(-1), shared.VariableNode("x")), "(-1+x)"},
+		{shared.Add(shared.VariableNode("x"), shared.Multiply(shared.VariableNode("y"), shared.NumberNode(-2))), "(x+(y*-2))"},
+	}
+	for _, test := range tests {
+		if got := shared.PrintATree(test.node); got != test.want {
+			t.Errorf("got %s, want %s", got, test.want)
+		}
+	}
+}
+
+// Defined variables are replaced by their values in a copy, the tree itself is kept.
+func TestInline(t *testing.T) {
+	s := shared.NewState(shared.GetDefualtConfig())
+	s.Variables["a"] = *parse(t, "2")
+	s.Variables["b"] = *parse(t, "a + 1")
+
+	node := parse(t, "b*x + a")
+	if got := shared.PrintATree(s.Inline(node)); got != "(((2+1)*x)+2)" {
+		t.Errorf("Inline(b*x + a) = %s, want (((2+1)*x)+2)", got)
+	}
+	if got := shared.PrintATree(node); got != "((b*x)+a)" {
+		t.Errorf("b*x + a after Inline = %s, want ((b*x)+a)", got)
+	}
+}
@@ -0,0 +1,367 @@
+package simplifier
+
+import (
+	"lambdacalc/polynomial"
+	"lambdacalc/shared"
+	"math/big"
+)
+
+// A factor base^-exponent of a denominator.
+type denominator struct {
+	base     *shared.Node
+	exponent float64
+}
+
+// Splits a term into the factors of its numerator and its denominator. Numbers with negative
+// exponents are coefficients and stay in the numerator.
+// 2 * x * (x + 1)^-2 -> [2 x], [(x + 1) 2]
+func fraction(node *shared.Node) ([]*shared.Node, []denominator) {
+	numerators := []*shared.Node{}
+	denominators := []denominator{}
+
+	factors := []*shared.Node{node}
+	if node.OperationType == shared.MULTIPLY {
+		factors = node.Associative
+	}
+	for _, val := range factors {
+		switch {
+		case val.OperationType == shared.POWER && isNumber(val.RNode) && val.RNode.Value < 0 && !isNumber(val.LNode):
+			denominators = append(denominators, denominator{base: val.LNode, exponent: -val.RNode.Value})
+		case val.OperationType == shared.MINUS && isZero(val.LNode):
+			// 0 - x = -1 * x
+			n, d := fraction(val.RNode)
+			numerators = append(append(numerators, shared.NumberNode(-1.0)), n...)
+			denominators = append(denominators, d...)
+		default:
+			numerators = append(numerators, val)
+		}
+	}
+	return numerators, denominators
+}
+
+// Returns the product of the numerator factors and the denominators as negative powers.
+func fractionNode(numerators []*shared.Node, denominators []denominator) *shared.Node {
+	factors := []*shared.Node{}
+	for _, val := range numerators {
+		factors = append(factors, shared.Clone(val))
+	}
+	for _, val := range denominators {
+		factors = append(factors, shared.Power(shared.Clone(val.base), shared.NumberNode(-val.exponent)))
+	}
+	switch len(factors) {
+	case 0:
+		return shared.NumberNode(1.0)
+	case 1:
+		return factors[0]
+	}
+	return shared.Multiply(factors...)
+}
+
+// Returns the product of the denominators with positive exponents.
+func denominatorNode(denominators []denominator) *shared.Node {
+	factors := []*shared.Node{}
+	for _, val := range denominators {
+		if val.exponent == 1 {
+			factors = append(factors, shared.Clone(val.base))
+		} else {
+			factors = append(factors, shared.Power(shared.Clone(val.base), shared.NumberNode(val.exponent)))
+		}
+	}
+	if len(factors) == 1 {
+		return factors[0]
+	}
+	return shared.Multiply(factors...)
+}
+
+// Transforms the fractions of a tree with the TOGETHER, APART or CANCEL rules. Every factor of
+// the result is simplified and polynomials are written in normal form, dividing by zero is an
+// error.
+// apart 1/((x-1)^2) -> ((x-1)^-2), cancel 1/0 -> division by zero
+func SimplifyFractions(s *shared.State, node *shared.Node, mode int) (*shared.Node, error) {
+	// A zero denominator would be lost by multiplying with zero. cancel 0/(x-x)
+	if dividesByZero(s, node) {
+		return nil, shared.DivisionByZero()
+	}
+	res, err := Simplify(s, node, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	addends := []*shared.Node{res}
+	if res.OperationType == shared.PLUS {
+		addends = res.Associative
+	}
+	terms := []*shared.Node{}
+	for _, val := range addends {
+		term, err := tidyFraction(s, val)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	res = terms[0]
+	if len(terms) > 1 {
+		res = shared.Add(terms...)
+	}
+	if dividesByZero(s, res) {
+		return nil, shared.DivisionByZero()
+	}
+	return res, nil
+}
+
+// Checks if a tree contains a negative power of zero, polynomials are zero if their normal
+// form is. 1 + 0^-1 -> true, (x-x)^-1 -> true
+func dividesByZero(s *shared.State, node *shared.Node) bool {
+	if node == nil {
+		return false
+	}
+	if node.OperationType == shared.POWER && isZeroPolynomial(s, node.LNode) && isNumber(node.RNode) && node.RNode.Value < 0 {
+		return true
+	}
+	if node.OperationType == shared.DIVIDE && isZeroPolynomial(s, node.RNode) {
+		return true
+	}
+	for _, val := range node.Associative {
+		if dividesByZero(s, val) {
+			return true
+		}
+	}
+	return dividesByZero(s, node.LNode) || dividesByZero(s, node.RNode)
+}
+
+// Checks if a tree is zero or a polynomial, whose terms cancel. x - x -> true
+func isZeroPolynomial(s *shared.State, node *shared.Node) bool {
+	if isZero(node) {
+		return true
+	}
+	p, ok := polynomial.FromNodes(s, node)
+	return ok && p[0].IsZero()
+}
+
+// Simplifies the factors of a fraction, numbers in the numerator are combined.
+// 2 * (x+(0-1))^-1 * 0.5 -> (x-1)^-1
+func tidyFraction(s *shared.State, node *shared.Node) (*shared.Node, error) {
+	numerators, denominators := fraction(node)
+
+	coefficient := big.NewRat(1, 1)
+	factors := []*shared.Node{}
+	for _, val := range numerators {
+		factor, err := tidyFactor(s, val)
+		if err != nil {
+			return nil, err
+		}
+		if r := multiplyRational(s, coefficient, factor); isNumber(factor) && r != nil {
+			coefficient = r
+		} else {
+			factors = append(factors, factor)
+		}
+	}
+	if coefficient.Cmp(big.NewRat(1, 1)) != 0 || len(factors)+len(denominators) == 0 {
+		factors = append(numberFactors(s, coefficient), factors...)
+	}
+
+	// (x - 1)^2 as a denominator is written as (x - 1) with exponent 2.
+	bases := []denominator{}
+	for _, val := range denominators {
+		base, exponent := val.base, val.exponent
+		if base.OperationType == shared.POWER && isNumber(base.RNode) {
+			base, exponent = base.LNode, exponent*base.RNode.Value
+		}
+		base, err := tidyFactor(s, base)
+		if err != nil {
+			return nil, err
+		}
+		bases = append(bases, denominator{base: base, exponent: exponent})
+	}
+	return fractionNode(factors, bases), nil
+}
+
+// Writes a polynomial in normal form, other factors are simplified like an expression.
+// x+(0-1) -> x-1
+func tidyFactor(s *shared.State, node *shared.Node) (*shared.Node, error) {
+	if p, ok := polynomial.FromNodes(s, node); ok {
+		return p[0].ToNode(), nil
+	}
+	unwound, err := Simplify(s, shared.Clone(node), UNWIND)
+	if err != nil {
+		return nil, err
+	}
+	return Simplify(s, unwound, REWIND)
+}
+
+// Combines a sum of fractions into a single fraction over the least common denominator.
+// Polynomials in the result are written in normal form.
+// a/b + c/d = (a*d + c*b) / (b*d), 1/x + 1/(x+1) = (2x + 1) / (x * (x + 1))
+func simplifyTogether(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
+	addends := []*shared.Node{}
+	switch node.OperationType {
+	case shared.PLUS:
+		addends = node.Associative
+	case shared.MINUS:
+		addends = []*shared.Node{node.LNode, shared.Negate(node.RNode)}
+	default:
+		return nil, false, nil
+	}
+
+	numerators := [][]*shared.Node{}
+	denominators := [][]denominator{}
+	common := []denominator{}
+	for _, val := range addends {
+		n, d := fraction(val)
+		numerators = append(numerators, n)
+		denominators = append(denominators, d)
+
+		// Equal bases are only counted with their highest exponent.
+		for _, factor := range d {
+			i := findDenominator(s, common, factor.base)
+			if i == -1 {
+				common = append(common, factor)
+			} else if factor.exponent > common[i].exponent {
+				common[i].exponent = factor.exponent
+			}
+		}
+	}
+	if len(common) == 0 {
+		return nil, false, nil
+	}
+
+	// Each numerator is multiplied by the factors missing in its denominator.
+	terms := []*shared.Node{}
+	for i, n := range numerators {
+		missing := []denominator{}
+		for _, factor := range common {
+			exponent := factor.exponent
+			if j := findDenominator(s, denominators[i], factor.base); j != -1 {
+				exponent -= denominators[i][j].exponent
+			}
+			if exponent > 0 {
+				missing = append(missing, denominator{base: factor.base, exponent: exponent})
+			}
+		}
+		if len(missing) == 0 {
+			terms = append(terms, fractionNode(n, nil))
+		} else {
+			terms = append(terms, fractionNode(append(n, denominatorNode(missing)), nil))
+		}
+	}
+
+	numerator := shared.Add(terms...)
+	if p, ok := polynomial.FromNodes(s, numerator); ok {
+		numerator = p[0].ToNode()
+	}
+	for i, val := range common {
+		if p, ok := polynomial.FromNodes(s, val.base); ok {
+			common[i].base = p[0].ToNode()
+		}
+	}
+	return fractionNode([]*shared.Node{numerator}, common), true, nil
+}
+
+// Cancels common polynomial factors of the numerator and the denominator, the remaining
+// denominator is written as a product of its irreducible factors.
+// (x^2 - 1) / (x^2 + x) = (x - 1) / x
+func simplifyCancel(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
+	if node.OperationType != shared.MULTIPLY && node.OperationType != shared.POWER {
+		return nil, false, nil
+	}
+	numerators, denominators := fraction(node)
+	if len(denominators) == 0 {
+		return nil, false, nil
+	}
+
+	// Only factors, that are polynomials, can be cancelled. i.e.: not sin(x)
+	others := []*shared.Node{}
+	factors := []*shared.Node{}
+	for _, val := range numerators {
+		if _, ok := polynomial.FromNodes(s, val); ok {
+			factors = append(factors, val)
+		} else {
+			others = append(others, val)
+		}
+	}
+	remaining := []denominator{}
+	divisors := []denominator{}
+	for _, val := range denominators {
+		if _, ok := polynomial.FromNodes(s, val.base); ok && val.exponent == float64(int(val.exponent)) {
+			divisors = append(divisors, val)
+		} else {
+			remaining = append(remaining, val)
+		}
+	}
+	if len(divisors) == 0 {
+		return nil, false, nil
+	}
+
+	p, ok := polynomial.FromNodes(s, fractionNode(factors, nil), denominatorNode(divisors))
+	if !ok {
+		return nil, false, nil
+	}
+	numerator, rest, ok := polynomial.Cancel(p[0], p[1])
+	if !ok {
+		return nil, false, nil
+	}
+
+	if !numerator.IsConstant() || numerator.Leading().Cmp(big.NewRat(1, 1)) != 0 || len(others) == 0 {
+		others = append(others, numerator.ToNode())
+	}
+	_, irreducible := rest.Factors()
+	for _, val := range irreducible {
+		remaining = append(remaining, denominator{base: val.Polynomial.ToNode(), exponent: float64(val.Multiplicity)})
+	}
+	return fractionNode(others, remaining), true, nil
+}
+
+// Decomposes a fraction of polynomials in one variable into a polynomial and partial fractions.
+// x / (x^2 - 1) = 1/2 / (x - 1) + 1/2 / (x + 1)
+func simplifyApart(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
+	if node.OperationType != shared.MULTIPLY {
+		return nil, false, nil
+	}
+	numerators, denominators := fraction(node)
+	if len(denominators) == 0 {
+		return nil, false, nil
+	}
+
+	p, ok := polynomial.FromNodes(s, fractionNode(numerators, nil), denominatorNode(denominators))
+	if !ok {
+		return nil, false, nil
+	}
+	whole, fractions, ok := polynomial.Apart(p[0], p[1])
+	if !ok {
+		return nil, false, nil
+	}
+
+	// A fraction over the power of a single irreducible polynomial is already decomposed.
+	if whole.IsZero() && len(fractions) == 1 && fractions[0].Denominator.TotalDegree()*fractions[0].Power == p[1].TotalDegree() {
+		return nil, false, nil
+	}
+
+	terms := []*shared.Node{}
+	if !whole.IsZero() {
+		terms = append(terms, whole.ToNode())
+	}
+	for _, val := range fractions {
+		terms = append(terms, fractionNode(
+			[]*shared.Node{val.Numerator.ToNode()},
+			[]denominator{{base: val.Denominator.ToNode(), exponent: float64(val.Power)}},
+		))
+	}
+	switch len(terms) {
+	case 0:
+		return shared.NumberNode(0.0), true, nil
+	case 1:
+		return terms[0], true, nil
+	}
+	return shared.Add(terms...), true, nil
+}
+
+// Returns the index of the denominator with the given base, -1 if there is none. Bases are the
+// same, if they are the same polynomial. (x + 1)^2, x^2 + 2x + 1 -> same
+func findDenominator(s *shared.State, denominators []denominator, base *shared.Node) int {
+	for i, val := range denominators {
+		if shared.IsEqual(val.base, base) || polynomial.Equal(s, val.base, base) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,75 @@
+package simplifier
+
+import (
+	"lambdacalc/lexer"
+	"lambdacalc/parser"
+	"lambdacalc/shared"
+	"testing"
+)
+
+// State of the tests, with the default config. Tests defining names remove them again.
+var state = shared.NewState(shared.GetDefualtConfig())
+
+// Parses an expression for a test.
+func parse(t *testing.T, input string) *shared.Node {
+	t.Helper()
+	lexed, err := lexer.LexTokens(state, input)
+	if err != nil {
+		t.Fatalf("%s: unable to lex, %v", input, err)
+	}
+	parsed, err := parser.Parse(state, lexed)
+	if err != nil {
+		t.Fatalf("%s: unable to parse, %v", input, err)
+	}
+	return parsed
+}
+
+func TestSimplifyFractions(t *testing.T) {
+	tests := []struct {
+		mode       int
+		expression string
+		want       string
+	}{
+		{TOGETHER, "1/x + 1/(x + 1)", "(((2*x)+1)*(x^-1)*((x+1)^-1))"},
+		{TOGETHER, "1/x - 1/x^2", "((x-1)*(x^-2))"},
+		{TOGETHER, "1/x + 1/(2x)", "(1.5*(x^-1))"},
+		{CANCEL, "(x^2 - 1)/(x^2 + x)", "((x-1)*(x^-1))"},
+		{CANCEL, "x/x", "1"},
+		{CANCEL, "sin(x)/x", "(sin(x)*(x^-1))"},
+		{APART, "(x^3 + 1)/(x^2 - 1)", "(x+((x-1)^-1))"},
+		{APART, "x/(x^2 - 1)", "((0.5*((x+1)^-1))+(0.5*((x-1)^-1)))"},
+		{APART, "1/((x-1)^2)", "((x-1)^-2)"},
+		{APART, "1/(x^2 + 1)", "(((x^2)+1)^-1)"},
+	}
+	for _, test := range tests {
+		got, err := SimplifyFractions(state, parse(t, test.expression), test.mode)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.expression, err)
+			continue
+		}
+		if res := shared.PrintATree(got); res != test.want {
+			t.Errorf("%s: got %s, want %s", test.expression, res, test.want)
+		}
+	}
+}
+
+func TestSimplifyFractionsByZero(t *testing.T) {
+	tests := []struct {
+		mode       int
+		expression string
+	}{
+		{TOGETHER, "1/(x-x)"},
+		{TOGETHER, "1/x + 1/0"},
+		{CANCEL, "1/0"},
+		{CANCEL, "x/(2x - x - x)"},
+		{APART, "1/(x-x)"},
+		{CANCEL, "0/0"},
+		{CANCEL, "0/(x-x)"},
+		{TOGETHER, "0/(x-x) + 1"},
+	}
+	for _, test := range tests {
+		if _, err := SimplifyFractions(state, parse(t, test.expression), test.mode); err == nil || shared.ErrorCode(err) != "divide by 0" {
+			t.Errorf("%s: got %v, want divide by 0", test.expression, err)
+		}
+	}
+}
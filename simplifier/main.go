@@ -7,10 +7,13 @@ import (
 )
 
 // The simplify function reads a node and traverses along its branches to find rules of a specified ruleset to apply.
-func Simplify(node *shared.Node, mode int) (*shared.Node, error) {
+func Simplify(s *shared.State, node *shared.Node, mode int) (*shared.Node, error) {
 	if node == nil {
 		return nil, nil
 	}
+	if err := s.Canceled(); err != nil {
+		return nil, err
+	}
 
 	var err error
 	switch node.OperationType {
@@ -18,11 +21,11 @@ func Simplify(node *shared.Node, mode int) (*shared.Node, error) {
 		for i := 0; i < len(node.Associative); i++ {
 			val := node.Associative[i]
 			simp := &shared.Node{}
-			simp, err = Simplify(val, mode)
+			simp, err = Simplify(s, val, mode)
 			if err != nil {
 				return nil, err
 			}
-			if simp.OperationType == node.OperationType {
+			if simp.OperationType == node.OperationType && node.OperationType != shared.FUNCTION {
 
 				node.Associative = removeFromNodeArray(node.Associative, i)
 				i--
@@ -34,22 +37,22 @@ func Simplify(node *shared.Node, mode int) (*shared.Node, error) {
 	case shared.VARIABLE, shared.NUMBER:
 		return node, nil
 	default:
-		node.RNode, err = Simplify(node.RNode, mode)
+		node.RNode, err = Simplify(s, node.RNode, mode)
 		if err != nil {
 			return nil, err
 		}
 
-		node.LNode, err = Simplify(node.LNode, mode)
+		node.LNode, err = Simplify(s, node.LNode, mode)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	for i, rule := range RuleSets[mode] {
-		if newNode, changed, err := rule(node); changed && err == nil {
+		if newNode, changed, err := rule(s, node); changed && err == nil {
 
 			// Debug
-			if shared.Conf.Options["show_debug_process"] {
+			if s.Conf.Options["show_debug_process"] {
 				cfmt.Printf("{{Notice:}}::blue|bold matched rule pattern %v, changed: ", i)
 				cfmt.Printf("%s", shared.PrintATree(node))
 				cfmt.Printf(" to ")
@@ -57,7 +60,7 @@ func Simplify(node *shared.Node, mode int) (*shared.Node, error) {
 				cfmt.Println("")
 			}
 
-			return Simplify(newNode, mode)
+			return Simplify(s, newNode, mode)
 			// return newNode, nil
 		} else if err != nil {
 			return nil, err
@@ -68,13 +71,16 @@ func Simplify(node *shared.Node, mode int) (*shared.Node, error) {
 }
 
 // Rule Type.
-type RewriteRule func(*shared.Node) (*shared.Node, bool, error)
+type RewriteRule func(*shared.State, *shared.Node) (*shared.Node, bool, error)
 
 // Constant index in rule set
 const (
-	UNWIND = 0
-	REWIND = 1
-	SOLVE  = 2
+	UNWIND   = 0
+	REWIND   = 1
+	SOLVE    = 2
+	TOGETHER = 3
+	APART    = 4
+	CANCEL   = 5
 )
 
 // Unwind rule try to bring the equation to point were all
@@ -100,6 +106,7 @@ var UnwindRules = []RewriteRule{
 	simplifyMultCollect,  // 11
 	simplifyDefact,       // 12
 	simplifyConstantFold, // 13
+	simplifyPowOne,       // 14
 }
 
 var RewindRules = []RewriteRule{
@@ -118,12 +125,14 @@ var RewindRules = []RewriteRule{
 	simplifyPowZero,      // 8
 	simplifyMultPow,      // 9
 	simplifyRefact,
+	simplifyPowOne,
 }
 
 var SolveRules = []RewriteRule{
 	// Basic elimination
 	simplifyAddZero,
 	simplifySubZero,
+	simplifySingleAdd,
 	simplifyMultZero,
 	simplifyMultOne,
 	simplifyDivOne,
@@ -131,16 +140,57 @@ var SolveRules = []RewriteRule{
 	simplifyDivSelf,
 
 	// Power Rules
-	simplifyPowSelf,
-	simplifyAddPow,
+	simplifyPowZero,
 	simplifyMultPow,
 
 	// Eval
+	simplifyAddCollect,
+	simplifyMultCollect,
 	simplifyConstantFold,
+	simplifyPowOne,
+}
+
+// Rational functions are combined into a single fraction, that is cancelled afterwards.
+var TogetherRules = []RewriteRule{
+	simplifyAddZero,
+	simplifySubZero,
+	simplifySingleAdd,
+	simplifyMultZero,
+	simplifyMultOne,
+	simplifyPowZero,
+	simplifyPowOne,
+	simplifyTogether,
+	simplifyCancel,
+}
+
+// The decomposition comes first, before the factors of a fraction are changed.
+var ApartRules = []RewriteRule{
+	simplifyApart,
+	simplifyAddZero,
+	simplifySubZero,
+	simplifySingleAdd,
+	simplifyMultZero,
+	simplifyMultOne,
+	simplifyPowZero,
+	simplifyPowOne,
+}
+
+var CancelRules = []RewriteRule{
+	simplifyAddZero,
+	simplifySubZero,
+	simplifySingleAdd,
+	simplifyMultZero,
+	simplifyMultOne,
+	simplifyPowZero,
+	simplifyPowOne,
+	simplifyCancel,
 }
 
 var RuleSets = [][]RewriteRule{
 	UnwindRules,
 	RewindRules,
 	SolveRules,
+	TogetherRules,
+	ApartRules,
+	CancelRules,
 }
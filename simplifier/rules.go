@@ -1,61 +1,59 @@
 package simplifier
 
 import (
-	"errors"
 	"lambdacalc/interpreter"
 	"lambdacalc/shared"
+	"math"
+	"math/big"
 	"slices"
 
 	"github.com/i582/cfmt/cmd/cfmt"
 )
 
 // x + 0 = x
-func simplifyAddZero(node *shared.Node) (*shared.Node, bool, error) {
-	if node.OperationType == shared.PLUS {
-		changed := false
-		for i, val := range node.Associative {
-			if isZero(val) {
-				changed = true
-				node.Associative = removeFromNodeArray(node.Associative, i)
-				i--
+func simplifyAddZero(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
+	if node.OperationType == shared.PLUS && slices.ContainsFunc(node.Associative, isZero) {
+		// The addends are copied, other trees can share the array of the old ones.
+		addends := []*shared.Node{}
+		for _, val := range node.Associative {
+			if !isZero(val) {
+				addends = append(addends, val)
 			}
 		}
-		if changed {
-			return node, true, nil
+		if len(addends) == 0 {
+			return shared.NumberNode(0.0), true, nil
 		}
+		node.Associative = addends
+		return node, true, nil
 	}
 	return nil, false, nil
 }
 
 // x - 0 = x
-func simplifySubZero(node *shared.Node) (*shared.Node, bool, error) {
+func simplifySubZero(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
 	if node.OperationType == shared.MINUS {
 		if isZero(node.RNode) {
-			return node.RNode, true, nil
+			return node.LNode, true, nil
 		}
 	}
 	return nil, false, nil
 }
 
-// x * 0 = 0
-func simplifyMultZero(node *shared.Node) (*shared.Node, bool, error) {
+// x * 0 = 0, unless another factor divides by zero. 0 * 0^-1
+func simplifyMultZero(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
 	if node.OperationType == shared.MULTIPLY {
 		if slices.ContainsFunc(node.Associative, isZero) {
-			return &shared.Node{
-				OperationType: shared.NUMBER,
-				Value:         0.0,
-				Variable:      "",
-				LNode:         nil,
-				RNode:         nil,
-				Associative:   nil,
-			}, true, nil
+			if dividesByZero(s, node) {
+				return nil, false, shared.DivisionByZero()
+			}
+			return shared.NumberNode(0.0), true, nil
 		}
 	}
 	return nil, false, nil
 }
 
 // x * 1 = x
-func simplifyMultOne(node *shared.Node) (*shared.Node, bool, error) {
+func simplifyMultOne(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
 	if node.OperationType == shared.MULTIPLY {
 		changed := false
 		for i := 0; i < len(node.Associative); i++ {
@@ -66,6 +64,10 @@ func simplifyMultOne(node *shared.Node) (*shared.Node, bool, error) {
 				i--
 			}
 		}
+		// 1 * 1 = 1
+		if changed && len(node.Associative) == 0 {
+			return shared.NumberNode(1.0), true, nil
+		}
 		if changed {
 			return node, true, nil
 		}
@@ -74,7 +76,7 @@ func simplifyMultOne(node *shared.Node) (*shared.Node, bool, error) {
 }
 
 // x / 1 = x
-func simplifyDivOne(node *shared.Node) (*shared.Node, bool, error) {
+func simplifyDivOne(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
 	if node.OperationType == shared.DIVIDE {
 		if isNumber(node.RNode) && node.RNode.Value == 1 {
 			return shared.Clone(node.LNode), true, nil
@@ -85,21 +87,13 @@ func simplifyDivOne(node *shared.Node) (*shared.Node, bool, error) {
 
 // 0 / x = 0 (x != 0)
 // LATER: Maybe add assumptions
-func simplifyZeroDiv(node *shared.Node) (*shared.Node, bool, error) {
+func simplifyZeroDiv(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
 	if node.OperationType == shared.DIVIDE {
 		if isNumber(node.LNode) && node.LNode.Value == 0 {
-			if val, err := interpreter.Evaluate(node.RNode, true); err == nil && val != 0 {
-				return &shared.Node{
-					OperationType: shared.NUMBER,
-					Value:         0.0,
-					Variable:      "",
-					LNode:         nil,
-					RNode:         nil,
-					Associative:   nil,
-				}, true, nil
+			if val, err := interpreter.Evaluate(s, node.RNode); err == nil && val != 0 {
+				return shared.NumberNode(0.0), true, nil
 			} else if val == 0 {
-				cfmt.Printf("{{Error:}}::red|bold Unable to simplify calculation, possible devision by zero.\n")
-				return nil, false, errors.New("divide by 0")
+				return nil, false, shared.DivisionByZero()
 			}
 		}
 	}
@@ -107,17 +101,10 @@ func simplifyZeroDiv(node *shared.Node) (*shared.Node, bool, error) {
 }
 
 // x / x = 1
-func simplifyDivSelf(node *shared.Node) (*shared.Node, bool, error) {
+func simplifyDivSelf(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
 	if node.OperationType == shared.DIVIDE {
 		if shared.IsEqual(node.RNode, node.LNode) {
-			return &shared.Node{
-				OperationType: shared.NUMBER,
-				Value:         1.0,
-				Variable:      "",
-				LNode:         nil,
-				RNode:         nil,
-				Associative:   nil,
-			}, true, nil
+			return shared.NumberNode(1.0), true, nil
 		}
 	}
 	return nil, false, nil
@@ -125,7 +112,7 @@ func simplifyDivSelf(node *shared.Node) (*shared.Node, bool, error) {
 
 // (+(a*b)) = (a*b)
 // After collection of addition, clean up
-func simplifySingleAdd(node *shared.Node) (*shared.Node, bool, error) {
+func simplifySingleAdd(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
 	if node.OperationType == shared.PLUS || node.OperationType == shared.MULTIPLY {
 		if len(node.Associative) == 1 {
 			return node.Associative[0], true, nil
@@ -136,7 +123,7 @@ func simplifySingleAdd(node *shared.Node) (*shared.Node, bool, error) {
 
 // Collect all terms in `shared.PLUS` operations.
 // 2 + 4 + a + b + a = 6 + 2a + b
-func simplifyAddCollect(n *shared.Node) (*shared.Node, bool, error) {
+func simplifyAddCollect(s *shared.State, n *shared.Node) (*shared.Node, bool, error) {
 	if n.OperationType == shared.PLUS {
 		node := shared.Clone(n)
 		changed := false
@@ -144,14 +131,19 @@ func simplifyAddCollect(n *shared.Node) (*shared.Node, bool, error) {
 		nNumOp := 0
 		nVarOp := 0
 
-		result := 0.0
+		result := new(big.Rat)
 		varMap := make(map[string]float64)
 
 		for i := 0; i < len(node.Associative); i++ {
 			val := node.Associative[i]
 			switch val.OperationType {
 			case shared.NUMBER:
-				result += val.Value
+				// Infinite numbers can not be collected.
+				value := rational(s, val)
+				if value == nil {
+					continue
+				}
+				result.Add(result, value)
 				node.Associative = removeFromNodeArray(node.Associative, i)
 				nNumOp++
 				i--
@@ -173,15 +165,15 @@ func simplifyAddCollect(n *shared.Node) (*shared.Node, bool, error) {
 					i--
 				}
 			case shared.MULTIPLY:
-				num := 1.0
+				num := big.NewRat(1, 1)
 				newVal := shared.Clone(val)
 
 				// Knowing that if we found a previous multiple of this term, it should already be simplified.
 				// Searching for next multiple of the current term.
 				for y := i + 1; y < len(node.Associative); y++ {
 					// If we find a term that is a multiple..
-					if ok, fact := getMultiple(val, node.Associative[y]); ok {
-						if shared.Conf.Options["show_debug_process"] {
+					if ok, fact := getMultiple(s, val, node.Associative[y]); ok {
+						if s.Conf.Options["show_debug_process"] {
 							cfmt.Printf("(Simplifier - 648:8 - simplifyAddCollect) {{Debug:}}::cyan|bold Found factor in addends: ")
 							cfmt.Printf("%s", shared.PrintATree(val))
 							cfmt.Printf(" / ")
@@ -189,51 +181,31 @@ func simplifyAddCollect(n *shared.Node) (*shared.Node, bool, error) {
 							cfmt.Printf(" = ")
 							cfmt.Printf("%v\n", fact)
 						}
-						num += fact
+						num.Add(num, fact)
 						node.Associative = removeFromNodeArray(node.Associative, y)
 						y--
 						nVarOp += 2
 						changed = true
 					}
 				}
-				if num != 1 {
-					newVal.Associative = append(newVal.Associative, &shared.Node{
-						OperationType: shared.NUMBER,
-						Value:         num,
-						Variable:      "",
-						LNode:         nil,
-						RNode:         nil,
-						Associative:   nil,
-					})
+				if num.Cmp(big.NewRat(1, 1)) != 0 {
+					newVal.Associative = append(newVal.Associative, numberFactors(s, num)...)
 				}
 				node.Associative[i] = newVal
 			}
 		}
-		if result != 0.0 {
-			node.Associative = append(node.Associative, &shared.Node{
-				OperationType: shared.NUMBER,
-				Value:         result,
-				Variable:      "",
-				LNode:         nil,
-				RNode:         nil,
-				Associative:   nil,
-			})
+		if result.Sign() != 0 {
+			node.Associative = append(node.Associative, rationalNode(s, result))
 			changed = true
 		}
 		if len(varMap) >= 1 {
-			for key, fact := range varMap {
+			for _, key := range sortedKeys(varMap) {
+				fact := varMap[key]
 				switch fact {
 				case 0:
 					changed = true
 				case 1:
-					node.Associative = append(node.Associative, &shared.Node{
-						OperationType: shared.VARIABLE,
-						Value:         0.0,
-						Variable:      key,
-						LNode:         nil,
-						RNode:         nil,
-						Associative:   nil,
-					})
+					node.Associative = append(node.Associative, shared.VariableNode(key))
 					changed = true
 				default:
 					mult := &shared.Node{
@@ -250,6 +222,7 @@ func simplifyAddCollect(n *shared.Node) (*shared.Node, bool, error) {
 								LNode:         nil,
 								RNode:         nil,
 								Associative:   nil,
+								Rational:      nil,
 							},
 							{
 								OperationType: shared.NUMBER,
@@ -258,8 +231,10 @@ func simplifyAddCollect(n *shared.Node) (*shared.Node, bool, error) {
 								LNode:         nil,
 								RNode:         nil,
 								Associative:   nil,
+								Rational:      nil,
 							},
 						},
+						Rational: nil,
 					}
 					node.Associative = append(node.Associative, mult)
 					changed = true
@@ -275,7 +250,7 @@ func simplifyAddCollect(n *shared.Node) (*shared.Node, bool, error) {
 
 // Collect all terms in `shared.MULTIPLY` operations.
 // a * a * b * 2 * 5 = 10 * b * a^2
-func simplifyMultCollect(n *shared.Node) (*shared.Node, bool, error) {
+func simplifyMultCollect(s *shared.State, n *shared.Node) (*shared.Node, bool, error) {
 	if n.OperationType == shared.MULTIPLY {
 		node := shared.Clone(n)
 		changed := false
@@ -283,14 +258,22 @@ func simplifyMultCollect(n *shared.Node) (*shared.Node, bool, error) {
 		nNumOp := 0
 		nVarOp := 0
 
-		result := 1.0
+		result := big.NewRat(1, 1)
+		// Numbers that were collected into the result.
+		numbers := []*shared.Node{}
 		varMap := make(map[string]float64)
 
 		for i := 0; i < len(node.Associative); i++ {
 			val := node.Associative[i]
 			switch val.OperationType {
 			case shared.NUMBER:
-				result *= val.Value
+				// Infinite numbers can not be collected.
+				value := rational(s, val)
+				if value == nil {
+					continue
+				}
+				result.Mul(result, value)
+				numbers = append(numbers, val)
 				node.Associative = removeFromNodeArray(node.Associative, i)
 				nNumOp++
 				i--
@@ -304,7 +287,7 @@ func simplifyMultCollect(n *shared.Node) (*shared.Node, bool, error) {
 			case shared.MINUS:
 				if val.RNode.OperationType == shared.VARIABLE {
 					varMap[val.RNode.Variable]++
-					result *= -1.0
+					result.Neg(result)
 					node.Associative = removeFromNodeArray(node.Associative, i)
 					if varMap[val.RNode.Variable] != 1 {
 						nVarOp++
@@ -322,72 +305,47 @@ func simplifyMultCollect(n *shared.Node) (*shared.Node, bool, error) {
 					i--
 
 				} else if val.LNode.OperationType == shared.NUMBER && val.RNode.OperationType == shared.NUMBER && val.RNode.Value == -1 {
-					result = result / val.LNode.Value
+					// Division by zero is left for the interpreter.
+					value := rational(s, val.LNode)
+					if value == nil || value.Sign() == 0 {
+						continue
+					}
+					result.Quo(result, value)
+					numbers = append(numbers, val)
 					node.Associative = removeFromNodeArray(node.Associative, i)
 					nNumOp++
 					i--
 				}
 			}
 		}
-		if nNumOp >= 2 && nVarOp >= 2 && result == 1.0 {
+		one := result.Cmp(big.NewRat(1, 1)) == 0
+		if nNumOp >= 2 && one {
+			// The numbers cancel out. i.e.: 2 * 0.5 * ln(x)
+			if len(node.Associative) == 0 && len(varMap) == 0 {
+				node.Associative = append(node.Associative, numberFactors(s, result)...)
+			}
 			changed = true
-		} else if result != 1 {
-			node.Associative = append(node.Associative, &shared.Node{
-				OperationType: shared.NUMBER,
-				Value:         result,
-				Variable:      "",
-				LNode:         nil,
-				RNode:         nil,
-				Associative:   nil,
-			})
+		} else if !one {
+			factors := numberFactors(s, result)
+			// A fraction that is already reduced stays the same. i.e.: 2 * 3^-1
+			if sameFactors(numbers, factors) {
+				nNumOp = 0
+			}
+			node.Associative = append(node.Associative, factors...)
 			changed = true
 		}
 		if len(varMap) >= 1 {
-			for key, fact := range varMap {
+			for _, key := range sortedKeys(varMap) {
+				fact := varMap[key]
 				if fact == 1 {
-					node.Associative = append(node.Associative, &shared.Node{
-						OperationType: shared.VARIABLE,
-						Value:         0.0,
-						Variable:      key,
-						LNode:         nil,
-						RNode:         nil,
-						Associative:   nil,
-					})
+					node.Associative = append(node.Associative, shared.VariableNode(key))
 					changed = true
 					// If the Variables multiply together to x^0, replace them with 1
 				} else if fact == 0 {
-					node.Associative = append(node.Associative, &shared.Node{
-						OperationType: shared.NUMBER,
-						Value:         1.0,
-						Variable:      "",
-						LNode:         nil,
-						RNode:         nil,
-						Associative:   nil,
-					})
+					node.Associative = append(node.Associative, shared.NumberNode(1.0))
 					changed = true
 				} else {
-					mult := &shared.Node{
-						OperationType: shared.POWER,
-						Value:         0.0,
-						Variable:      "",
-						LNode: &shared.Node{
-							OperationType: shared.VARIABLE,
-							Value:         0.0,
-							Variable:      key,
-							LNode:         nil,
-							RNode:         nil,
-							Associative:   nil,
-						},
-						RNode: &shared.Node{
-							OperationType: shared.NUMBER,
-							Value:         fact,
-							Variable:      "",
-							LNode:         nil,
-							RNode:         nil,
-							Associative:   nil,
-						},
-						Associative: nil,
-					}
+					mult := shared.Power(shared.VariableNode(key), shared.NumberNode(fact))
 					node.Associative = append(node.Associative, mult)
 					changed = true
 				}
@@ -402,7 +360,7 @@ func simplifyMultCollect(n *shared.Node) (*shared.Node, bool, error) {
 
 // x * (x + y + z) = x^2 + x*y + z*y
 // (a+b) * (a+b) = a^2 + 2ab + b^2
-func simplifyDefact(node *shared.Node) (*shared.Node, bool, error) {
+func simplifyDefact(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
 	if node.OperationType == shared.MULTIPLY {
 		// Exit if we are at the end of the tree.
 		end := true
@@ -415,17 +373,10 @@ func simplifyDefact(node *shared.Node) (*shared.Node, bool, error) {
 			return nil, false, nil
 		}
 
-		res := &shared.Node{
-			OperationType: shared.NUMBER,
-			Value:         1.0,
-			Variable:      "",
-			LNode:         nil,
-			RNode:         nil,
-			Associative:   nil,
-		}
+		res := shared.NumberNode(1.0)
 
 		for _, val := range node.Associative {
-			res = multiplyNodes(res, val)
+			res = multiplyNodes(s, res, val)
 		}
 		return res, true, nil
 	}
@@ -435,7 +386,7 @@ func simplifyDefact(node *shared.Node) (*shared.Node, bool, error) {
 // Finds the commen factor of all addends and puts it outside.
 // abc + acd + ade -> a * (bc + cd + de)
 // abc + a^2cd + ade -> a * (bc + acd + de)
-func simplifyRefact(node *shared.Node) (*shared.Node, bool, error) {
+func simplifyRefact(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
 	if node.OperationType == shared.PLUS {
 		// If there are less then 2 factors it doesn't matter if we refactor.
 		if len(node.Associative) < 2 {
@@ -457,6 +408,7 @@ func simplifyRefact(node *shared.Node) (*shared.Node, bool, error) {
 				LNode:         nil,
 				RNode:         nil,
 				Associative:   []*shared.Node{},
+				Rational:      nil,
 			}
 
 			// Addition Term inside the parenthesis.
@@ -467,6 +419,7 @@ func simplifyRefact(node *shared.Node) (*shared.Node, bool, error) {
 				LNode:         nil,
 				RNode:         nil,
 				Associative:   []*shared.Node{},
+				Rational:      nil,
 			}
 
 			// Rest of the addition that is not
@@ -477,11 +430,18 @@ func simplifyRefact(node *shared.Node) (*shared.Node, bool, error) {
 				LNode:         nil,
 				RNode:         nil,
 				Associative:   []*shared.Node{},
+				Rational:      nil,
 			}
 
 			// If the addend is already a multiplication, search for each factor individually.
 			// Else just search for the common factor.
-			if val.OperationType != shared.MULTIPLY {
+			if val.OperationType == shared.NUMBER {
+				continue
+			} else if val.OperationType != shared.MULTIPLY {
+				// Only variables cancel out again after factoring, see canRefact.
+				if !canRefact(val) {
+					continue
+				}
 
 				// Search for the common factor.
 				if ok, fact, newRest, newAddends := findCommonFactor(val, node.Associative); ok {
@@ -508,6 +468,10 @@ func simplifyRefact(node *shared.Node) (*shared.Node, bool, error) {
 
 				// Search for each factor individually.
 				for _, factor := range val.Associative {
+					// Numbers are common factors of all numbers, factoring them out never terminates.
+					if factor.OperationType == shared.NUMBER || !canRefact(factor) {
+						continue
+					}
 
 					// If we found a common factor.
 					if ok, fact, newRest, newAddends := findCommonFactor(factor, addends); ok {
@@ -537,23 +501,10 @@ func simplifyRefact(node *shared.Node) (*shared.Node, bool, error) {
 					LNode:         nil,
 					RNode:         nil,
 					Associative:   []*shared.Node{},
+					Rational:      nil,
 				}
 				for _, fact := range factors.Associative {
-					divisor.Associative = append(divisor.Associative, &shared.Node{
-						OperationType: shared.POWER,
-						Value:         0.0,
-						Variable:      "",
-						LNode:         fact,
-						RNode: &shared.Node{
-							OperationType: shared.NUMBER,
-							Value:         -1.0,
-							Variable:      "",
-							LNode:         nil,
-							RNode:         nil,
-							Associative:   nil,
-						},
-						Associative: nil,
-					})
+					divisor.Associative = append(divisor.Associative, shared.Reciprocal(fact))
 				}
 
 				// Add the divisor into every term in the parenthesis.
@@ -578,6 +529,16 @@ func simplifyRefact(node *shared.Node) (*shared.Node, bool, error) {
 	return nil, false, nil
 }
 
+// Checks if a factor can be put outside of the parenthesis. The factored terms
+// are divided by the factor, which only cancels out for variables and numerical powers of them.
+// x, x^2 -> true, sqrt(x), f(x), x^y -> false
+func canRefact(node *shared.Node) bool {
+	if node.OperationType == shared.POWER {
+		return isNumber(node.RNode) && canRefact(node.LNode)
+	}
+	return node.OperationType == shared.VARIABLE
+}
+
 // Check if a given factor appears in all factors. If it does, also return resulting rest.
 func findCommonFactor(node *shared.Node, addends []*shared.Node) (bool, *shared.Node, []*shared.Node, []*shared.Node) {
 	rest := []*shared.Node{}
@@ -620,6 +581,10 @@ func canFactor(a, b *shared.Node) bool {
 			}
 		}
 	case shared.POWER:
+		// x^y / x does not cancel out, so only numerical powers contain their base.
+		if !isNumber(b.RNode) {
+			return shared.IsEqual(a, b)
+		}
 		return canFactor(a, b.LNode)
 	default:
 		return shared.IsEqual(a, b)
@@ -627,18 +592,21 @@ func canFactor(a, b *shared.Node) bool {
 	return false
 }
 
+// x^1 = x
+func simplifyPowOne(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
+	if node.OperationType == shared.POWER {
+		if isNumber(node.RNode) && node.RNode.Value == 1 {
+			return node.LNode, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
 // x^0 = 1.0
-func simplifyPowZero(node *shared.Node) (*shared.Node, bool, error) {
+func simplifyPowZero(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
 	if node.OperationType == shared.POWER {
 		if isNumber(node.RNode) && isZero(node.RNode) {
-			return &shared.Node{
-				OperationType: shared.NUMBER,
-				Value:         1.0,
-				Variable:      "",
-				LNode:         nil,
-				RNode:         nil,
-				Associative:   nil,
-			}, true, nil
+			return shared.NumberNode(1.0), true, nil
 		}
 	}
 	return nil, false, nil
@@ -646,24 +614,10 @@ func simplifyPowZero(node *shared.Node) (*shared.Node, bool, error) {
 
 // x * x = x^2
 // Currently Unfunctional
-func simplifyPowSelf(node *shared.Node) (*shared.Node, bool, error) {
+func simplifyPowSelf(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
 	if node.OperationType == shared.MULTIPLY {
 		if shared.IsEqual(node.RNode, node.LNode) {
-			return &shared.Node{
-				OperationType: shared.POWER,
-				Value:         0.0,
-				Variable:      "",
-				LNode:         node.LNode,
-				RNode: &shared.Node{
-					OperationType: shared.NUMBER,
-					Value:         2.0,
-					Variable:      "",
-					LNode:         nil,
-					RNode:         nil,
-					Associative:   nil,
-				},
-				Associative: nil,
-			}, true, nil
+			return shared.Power(node.LNode, shared.NumberNode(2.0)), true, nil
 		}
 	}
 	return nil, false, nil
@@ -671,7 +625,7 @@ func simplifyPowSelf(node *shared.Node) (*shared.Node, bool, error) {
 
 // x^y + x^z = x^(y+z)
 // Currently Unfunctional
-func simplifyAddPow(node *shared.Node) (*shared.Node, bool, error) {
+func simplifyAddPow(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
 	if node.OperationType == shared.MULTIPLY || node.OperationType == shared.DIVIDE {
 		if node.LNode.OperationType == shared.POWER && node.RNode.OperationType == shared.POWER {
 			if shared.IsEqual(node.LNode.LNode, node.RNode.LNode) {
@@ -684,6 +638,7 @@ func simplifyAddPow(node *shared.Node) (*shared.Node, bool, error) {
 						LNode:         node.LNode.RNode,
 						RNode:         node.RNode.RNode,
 						Associative:   nil,
+						Rational:      nil,
 					}
 				} else {
 					op = &shared.Node{
@@ -693,16 +648,10 @@ func simplifyAddPow(node *shared.Node) (*shared.Node, bool, error) {
 						LNode:         node.LNode.RNode,
 						RNode:         node.RNode.RNode,
 						Associative:   nil,
+						Rational:      nil,
 					}
 				}
-				return &shared.Node{
-					OperationType: shared.POWER,
-					Value:         0.0,
-					Variable:      "",
-					LNode:         node.LNode.LNode,
-					RNode:         op,
-					Associative:   nil,
-				}, true, nil
+				return shared.Power(node.LNode.LNode, op), true, nil
 			}
 		}
 	}
@@ -710,24 +659,11 @@ func simplifyAddPow(node *shared.Node) (*shared.Node, bool, error) {
 }
 
 // (x^y)^z = x^(y*z)
-func simplifyMultPow(node *shared.Node) (*shared.Node, bool, error) {
+func simplifyMultPow(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
 	if node.OperationType == shared.POWER {
 		if node.LNode.OperationType == shared.POWER {
-			op := &shared.Node{
-				OperationType: shared.MULTIPLY,
-				Value:         0.0,
-				Variable:      "",
-				LNode:         nil,
-				RNode:         nil,
-				Associative:   []*shared.Node{node.LNode.RNode, node.RNode}}
-			return &shared.Node{
-				OperationType: shared.POWER,
-				Value:         0.0,
-				Variable:      "",
-				LNode:         node.LNode.LNode,
-				RNode:         op,
-				Associative:   nil,
-			}, true, nil
+			op := shared.Multiply(node.LNode.RNode, node.RNode)
+			return shared.Power(node.LNode.LNode, op), true, nil
 		}
 	}
 	return nil, false, nil
@@ -735,7 +671,7 @@ func simplifyMultPow(node *shared.Node) (*shared.Node, bool, error) {
 
 // x * z + y * z = (x + y) * z
 // Currently Unfunctional
-func simplifyMultFact(node *shared.Node) (*shared.Node, bool, error) {
+func simplifyMultFact(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
 	if node.OperationType == shared.PLUS || node.OperationType == shared.MINUS {
 		if node.LNode.OperationType == shared.MULTIPLY && node.RNode.OperationType == shared.MULTIPLY {
 			if shared.IsEqual(node.LNode.LNode, node.RNode.LNode) {
@@ -746,6 +682,7 @@ func simplifyMultFact(node *shared.Node) (*shared.Node, bool, error) {
 					LNode:         shared.Clone(node.LNode.RNode),
 					RNode:         shared.Clone(node.RNode.RNode),
 					Associative:   nil,
+					Rational:      nil,
 				}
 				return &shared.Node{
 					OperationType: shared.MULTIPLY,
@@ -754,6 +691,7 @@ func simplifyMultFact(node *shared.Node) (*shared.Node, bool, error) {
 					LNode:         &op,
 					RNode:         node.LNode.LNode,
 					Associative:   nil,
+					Rational:      nil,
 				}, true, nil
 
 			} else if shared.IsEqual(node.LNode.LNode, node.RNode.RNode) {
@@ -764,6 +702,7 @@ func simplifyMultFact(node *shared.Node) (*shared.Node, bool, error) {
 					LNode:         shared.Clone(node.LNode.RNode),
 					RNode:         shared.Clone(node.RNode.LNode),
 					Associative:   nil,
+					Rational:      nil,
 				}
 				return &shared.Node{
 					OperationType: shared.MULTIPLY,
@@ -772,6 +711,7 @@ func simplifyMultFact(node *shared.Node) (*shared.Node, bool, error) {
 					LNode:         &op,
 					RNode:         node.LNode.LNode,
 					Associative:   nil,
+					Rational:      nil,
 				}, true, nil
 
 			} else if shared.IsEqual(node.LNode.RNode, node.RNode.LNode) {
@@ -782,6 +722,7 @@ func simplifyMultFact(node *shared.Node) (*shared.Node, bool, error) {
 					LNode:         shared.Clone(node.LNode.LNode),
 					RNode:         shared.Clone(node.RNode.RNode),
 					Associative:   nil,
+					Rational:      nil,
 				}
 				return &shared.Node{
 					OperationType: shared.MULTIPLY,
@@ -790,6 +731,7 @@ func simplifyMultFact(node *shared.Node) (*shared.Node, bool, error) {
 					LNode:         &op,
 					RNode:         node.LNode.RNode,
 					Associative:   nil,
+					Rational:      nil,
 				}, true, nil
 
 			} else if shared.IsEqual(node.LNode.RNode, node.RNode.RNode) {
@@ -800,6 +742,7 @@ func simplifyMultFact(node *shared.Node) (*shared.Node, bool, error) {
 					LNode:         shared.Clone(node.LNode.LNode),
 					RNode:         shared.Clone(node.RNode.LNode),
 					Associative:   nil,
+					Rational:      nil,
 				}
 				return &shared.Node{
 					OperationType: shared.MULTIPLY,
@@ -808,6 +751,7 @@ func simplifyMultFact(node *shared.Node) (*shared.Node, bool, error) {
 					LNode:         &op,
 					RNode:         node.LNode.RNode,
 					Associative:   nil,
+					Rational:      nil,
 				}, true, nil
 			}
 		}
@@ -818,7 +762,7 @@ func simplifyMultFact(node *shared.Node) (*shared.Node, bool, error) {
 // x / z + y / z = (x + y) / z
 // x / z + x / y = x / (z + y)
 // Currently Unfunctional
-func simplifyDivFact(node *shared.Node) (*shared.Node, bool, error) {
+func simplifyDivFact(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
 	if node.OperationType == shared.PLUS || node.OperationType == shared.MINUS {
 		if node.LNode.OperationType == shared.DIVIDE && node.RNode.OperationType == shared.DIVIDE {
 			if shared.IsEqual(node.LNode.LNode, node.RNode.LNode) {
@@ -829,6 +773,7 @@ func simplifyDivFact(node *shared.Node) (*shared.Node, bool, error) {
 					LNode:         shared.Clone(node.LNode.RNode),
 					RNode:         shared.Clone(node.RNode.RNode),
 					Associative:   nil,
+					Rational:      nil,
 				}
 				return &shared.Node{
 					OperationType: shared.DIVIDE,
@@ -837,6 +782,7 @@ func simplifyDivFact(node *shared.Node) (*shared.Node, bool, error) {
 					LNode:         &op,
 					RNode:         node.LNode.LNode,
 					Associative:   nil,
+					Rational:      nil,
 				}, true, nil
 
 			} else if shared.IsEqual(node.LNode.RNode, node.RNode.RNode) {
@@ -847,6 +793,7 @@ func simplifyDivFact(node *shared.Node) (*shared.Node, bool, error) {
 					LNode:         shared.Clone(node.LNode.LNode),
 					RNode:         shared.Clone(node.RNode.LNode),
 					Associative:   nil,
+					Rational:      nil,
 				}
 				return &shared.Node{
 					OperationType: shared.DIVIDE,
@@ -855,6 +802,7 @@ func simplifyDivFact(node *shared.Node) (*shared.Node, bool, error) {
 					LNode:         &op,
 					RNode:         node.LNode.RNode,
 					Associative:   nil,
+					Rational:      nil,
 				}, true, nil
 			}
 		}
@@ -863,55 +811,103 @@ func simplifyDivFact(node *shared.Node) (*shared.Node, bool, error) {
 }
 
 // eval everything that cannont produce an irational number
-func simplifyConstantFold(node *shared.Node) (*shared.Node, bool, error) {
+func simplifyConstantFold(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
+	if s.Conf.KeepDecimals() {
+		return foldRational(s, node)
+	}
+
 	switch node.OperationType {
 	case shared.VARIABLE, shared.NUMBER:
 		return nil, false, nil
 	case shared.DIVIDE:
 		if isNumber(node.LNode) && isNumber(node.RNode) {
-			return &shared.Node{
-				OperationType: shared.NUMBER,
-				Value:         node.LNode.Value / node.RNode.Value,
-				Variable:      "",
-				LNode:         nil,
-				RNode:         nil,
-				Associative:   nil,
-			}, true, nil
+			return shared.NumberNode(node.LNode.Value / node.RNode.Value), true, nil
 		}
 	case shared.MULTIPLY, shared.PLUS:
 		res := 0.0
+		if node.OperationType == shared.MULTIPLY {
+			res = 1.0
+		}
 		for _, val := range node.Associative {
-			if isNumber(val) {
+			if isNumber(val) && node.OperationType == shared.MULTIPLY {
+				res *= val.Value
+			} else if isNumber(val) {
 				res += val.Value
 			} else {
 				return nil, false, nil
 			}
 		}
 
-		if shared.Conf.Options["show_debug_process"] {
+		if s.Conf.Options["show_debug_process"] {
 			cfmt.Printf("{{Debug:}}::cyan|bold All values are numbers.\n")
 		}
 
-		return &shared.Node{
-			OperationType: shared.NUMBER,
-			Value:         res,
-			Variable:      "",
-			LNode:         nil,
-			RNode:         nil,
-			Associative:   nil,
-		}, true, nil
+		return shared.NumberNode(res), true, nil
+	case shared.FUNCTION:
+		// Only fold built-in functions that keep the result rational, i.e.: abs(-2) but not sin(2)
+		if builtin, ok := shared.Builtins[node.Variable]; ok && builtin.Exact {
+			params := []float64{}
+			for _, val := range node.Associative {
+				if !isNumber(val) {
+					return nil, false, nil
+				}
+				params = append(params, val.Value)
+			}
+			if !builtin.Accepts(len(params)) {
+				return nil, false, nil
+			}
+
+			res, err := builtin.Evaluate(params)
+			if err != nil {
+				return nil, false, shared.NewError(err.Error(), "Unable to simplify calculation, %v in '%s'.", err, node.Variable)
+			}
+			return shared.NumberNode(res), true, nil
+		}
+	case shared.POWER:
+		// Only integer exponents keep the result rational.
+		if isNumber(node.LNode) && isNumber(node.RNode) && node.RNode.Value == math.Trunc(node.RNode.Value) && node.LNode.Value != 0 {
+			return shared.NumberNode(math.Pow(node.LNode.Value, node.RNode.Value)), true, nil
+		}
 	case shared.MINUS:
 		if isNumber(node.LNode) && isNumber(node.RNode) {
-			return &shared.Node{
-				OperationType: shared.NUMBER,
-				Value:         node.LNode.Value - node.RNode.Value,
-				Variable:      "",
-				LNode:         nil,
-				RNode:         nil,
-				Associative:   nil,
-			}, true, nil
+			return shared.NumberNode(node.LNode.Value - node.RNode.Value), true, nil
 		}
 	default:
 	}
 	return nil, false, nil
 }
+
+// Constant folding in exact mode and with a higher precision, only results that can be written as a decimal are folded.
+// 1 / 4 = 0.25, but 1 / 3 stays a fraction.
+func foldRational(s *shared.State, node *shared.Node) (*shared.Node, bool, error) {
+	switch node.OperationType {
+	case shared.DIVIDE, shared.MINUS, shared.POWER:
+		if !isNumber(node.LNode) || !isNumber(node.RNode) {
+			return nil, false, nil
+		}
+	case shared.MULTIPLY, shared.PLUS:
+		for _, val := range node.Associative {
+			if !isNumber(val) {
+				return nil, false, nil
+			}
+		}
+	case shared.FUNCTION:
+		if _, ok := shared.RationalBuiltins[node.Variable]; !ok {
+			return nil, false, nil
+		}
+		for _, val := range node.Associative {
+			if !isNumber(val) {
+				return nil, false, nil
+			}
+		}
+	default:
+		return nil, false, nil
+	}
+
+	// Errors like a division by zero are reported when the result is calculated.
+	res, exact, err := interpreter.EvaluateExact(s, node)
+	if err != nil || !exact || !isFoldable(s, res) {
+		return nil, false, nil
+	}
+	return rationalNode(s, res), true, nil
+}
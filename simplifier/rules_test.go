@@ -0,0 +1,50 @@
+package simplifier
+
+import (
+	"lambdacalc/shared"
+	"testing"
+)
+
+// Zeros are removed from sums without losing the addends behind them.
+func TestSimplifyZeros(t *testing.T) {
+	tests := []struct {
+		node *shared.Node
+		want string
+	}{
+		{shared.Add(shared.NumberNode(0.0), shared.NumberNode(0.0), shared.VariableNode("a")), "a"},
+		{shared.Add(shared.VariableNode("x"), shared.NumberNode(0.0), shared.NumberNode(0.0), shared.VariableNode("y")), "(x+y)"},
+		{shared.Add(shared.NumberNode(0.0), shared.NumberNode(0.0)), "0"},
+		{shared.Multiply(shared.NumberNode(-1.0), shared.Add(shared.NumberNode(0.0), shared.NumberNode(0.0), shared.Negate(shared.VariableNode("a")))), "a"},
+		{&shared.Node{
+			OperationType: shared.MINUS,
+			Value:         0.0,
+			Variable:      "",
+			LNode:         shared.VariableNode("x"),
+			RNode:         shared.NumberNode(0.0),
+			Associative:   nil,
+			Rational:      nil,
+		}, "x"},
+	}
+	for _, mode := range []int{SOLVE, UNWIND, REWIND} {
+		for _, test := range tests {
+			input := shared.PrintATree(test.node)
+			got, err := Simplify(state, shared.Clone(test.node), mode)
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", input, err)
+				continue
+			}
+			if res := shared.PrintATree(got); res != test.want {
+				t.Errorf("mode %d, %s: got %s, want %s", mode, input, res, test.want)
+			}
+		}
+	}
+}
+
+// A factor of zero does not hide a division by zero. 0/0 -> 0 * 0^-1
+func TestSimplifyZeroTimesDivisionByZero(t *testing.T) {
+	for _, mode := range []int{SOLVE, UNWIND, REWIND} {
+		if _, err := Simplify(state, parse(t, "0/0"), mode); err == nil || shared.ErrorCode(err) != "divide by 0" {
+			t.Errorf("mode %d, 0/0: got %v, want divide by 0", mode, err)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package simplifier
 
 import (
 	"lambdacalc/shared"
+	"math"
+	"math/big"
+	"slices"
 
 	"github.com/i582/cfmt/cmd/cfmt"
 )
@@ -15,6 +18,7 @@ func isNumber(n *shared.Node) bool {
 }
 
 // Similar to shared.IsEqual, but it returns true if a and b are factors of each other
+// The factor is calculated exactly, so 3a and a give 1/3 and not 0.333...
 // 1, 2 -> true, 2
 // a, 2a -> true, 2
 // a, a -> true, 1
@@ -22,32 +26,39 @@ func isNumber(n *shared.Node) bool {
 // abcd, abcd -> true, 1
 // 2ab, ab -> true, 0.5
 // a + b, 2a + 2b -> true, 2
-func getMultiple(a, b *shared.Node) (bool, float64) {
+func getMultiple(s *shared.State, a, b *shared.Node) (bool, *big.Rat) {
 	if a.OperationType != b.OperationType {
 		if a.OperationType == shared.VARIABLE && b.OperationType == shared.MULTIPLY {
 			if ok, factor, variable := getNumFactor(b); ok {
 				if variable.Variable == a.Variable {
-					return true, factor.Value
+					return quotient(rational(s, factor), big.NewRat(1, 1))
 				}
 			}
 		} else if b.OperationType == shared.VARIABLE && a.OperationType == shared.MULTIPLY {
 			if ok, factor, variable := getNumFactor(a); ok {
 				if variable.Variable == b.Variable {
-					return true, 1 / factor.Value
+					return quotient(big.NewRat(1, 1), rational(s, factor))
 				}
 			}
 		} else if a.OperationType == shared.MINUS {
-			ok, x := getMultiple(a.RNode, b)
-			return ok, x * -1
+			ok, x := getMultiple(s, a.RNode, b)
+			if !ok {
+				return false, nil
+			}
+			return ok, x.Neg(x)
 		} else if b.OperationType == shared.MINUS {
-			ok, x := getMultiple(a, b.RNode)
-			return ok, x * -1
+			ok, x := getMultiple(s, a, b.RNode)
+			if !ok {
+				return false, nil
+			}
+			return ok, x.Neg(x)
 		}
-		return false, 0.0
+		return false, nil
 
 		// Check
 	} else if a.OperationType == shared.MULTIPLY {
-		factor := 1.0
+		factor := big.NewRat(1, 1)
+		divisor := big.NewRat(1, 1)
 		// Map for checking if a shared.Node already appeared in the other term.
 		alreadySeenB := make(map[*shared.Node]int)
 
@@ -55,7 +66,7 @@ func getMultiple(a, b *shared.Node) (bool, float64) {
 		// We shouldn't be able to see the same factor twice, because previously we simplified all duplicate factors to powers?
 		for _, bVal := range b.Associative {
 			if bVal.OperationType == shared.NUMBER {
-				factor = factor * bVal.Value
+				factor = multiplyRational(s, factor, bVal)
 			} else {
 				alreadySeenB[bVal] = 0
 			}
@@ -67,7 +78,7 @@ func getMultiple(a, b *shared.Node) (bool, float64) {
 
 			// If current aVal is a Number divide the factor.
 			if aVal.OperationType == shared.NUMBER {
-				factor = factor / aVal.Value
+				divisor = multiplyRational(s, divisor, aVal)
 				continue
 			}
 
@@ -89,7 +100,7 @@ func getMultiple(a, b *shared.Node) (bool, float64) {
 			}
 			// If we did not find a value in term b and it is not a number, we do not have a multple of the other term.
 			if !found {
-				return false, 0
+				return false, nil
 			}
 		}
 
@@ -100,25 +111,26 @@ func getMultiple(a, b *shared.Node) (bool, float64) {
 			}
 			// If we have not seen a factor of term b in a, return false.
 			if alreadySeenB[bVal] < 1 {
-				return false, 0
+				return false, nil
 			}
 		}
 		// Else return true and the factor.
-		return true, factor
+		return quotient(factor, divisor)
 	} else if a.OperationType == shared.PLUS {
-		factor := 1.0
-		isFactorDefined := false
+		factor := big.NewRat(1, 1)
+		divisor := big.NewRat(1, 1)
+		var multiple *big.Rat
 		used := make(map[*shared.Node]bool)
 
 		for _, x := range b.Associative {
 			if x.OperationType == shared.NUMBER {
-				factor = factor * x.Value
+				factor = multiplyRational(s, factor, x)
 			}
 		}
 
 		for _, x := range a.Associative {
 			if x.OperationType == shared.NUMBER {
-				factor = factor / x.Value
+				divisor = multiplyRational(s, divisor, x)
 				continue
 			}
 
@@ -133,32 +145,115 @@ func getMultiple(a, b *shared.Node) (bool, float64) {
 					continue
 				}
 
-				if ok, fact := getMultiple(x, y); ok {
-					if isFactorDefined && factor == fact {
+				if ok, fact := getMultiple(s, x, y); ok {
+					if multiple != nil && multiple.Cmp(fact) == 0 {
 						contains = true
 						used[y] = true
 						break
-					} else if !isFactorDefined {
-						isFactorDefined = true
-						factor = fact
+					} else if multiple == nil {
+						multiple = fact
 						contains = true
 						used[y] = true
 						break
 					} else {
-						return false, 0
+						return false, nil
 					}
 				}
 			}
 			if !contains {
-				return false, 0.0
+				return false, nil
 			}
 		}
-		return true, factor
+		if multiple == nil {
+			return quotient(factor, divisor)
+		}
+		return true, multiple
 
 	} else if a.OperationType == shared.NUMBER {
-		return true, a.Value / b.Value
+		return quotient(rational(s, a), rational(s, b))
+	}
+	return false, nil
+}
+
+// Converts a number to a rational, infinite numbers result in nil.
+// In exact mode and with a higher precision a number is its exact value, otherwise
+// the exact value of the float is used, so the result is rounded the same way as float arithmetic.
+func rational(s *shared.State, node *shared.Node) *big.Rat {
+	if s.Conf.KeepDecimals() {
+		return shared.NumberRational(node)
+	}
+	if math.IsInf(node.Value, 0) || math.IsNaN(node.Value) {
+		return nil
+	}
+	return new(big.Rat).SetFloat64(node.Value)
+}
+
+// Multiplies a rational by a number, infinite numbers result in nil.
+func multiplyRational(s *shared.State, r *big.Rat, node *shared.Node) *big.Rat {
+	x := rational(s, node)
+	if r == nil || x == nil {
+		return nil
+	}
+	return x.Mul(x, r)
+}
+
+// Returns the exact quotient of two rationals, fails for a division by zero.
+// 1, 3 -> true, 1/3
+func quotient(a, b *big.Rat) (bool, *big.Rat) {
+	if a == nil || b == nil || b.Sign() == 0 {
+		return false, nil
+	}
+	return true, new(big.Rat).Quo(a, b)
+}
+
+// Checks if a rational number can be written into the tree.
+// In exact mode and with a higher precision only decimals and integers are allowed, fractions like 1/3 are kept as a multiplication.
+func isFoldable(s *shared.State, r *big.Rat) bool {
+	return !s.Conf.KeepDecimals() || shared.IsDecimal(r) || r.IsInt()
+}
+
+// Returns the factors that represent a rational number.
+// 0.5 -> [0.5], in exact mode: 1/3 -> [3^-1], 2/3 -> [2, 3^-1]
+// If the reciprocal of the denominator is a decimal it is used directly,
+// otherwise constant folding would change it again. 3/20 -> [3, 0.05]
+func numberFactors(s *shared.State, r *big.Rat) []*shared.Node {
+	node := shared.RationalNode(r)
+	if !isFoldable(s, r) {
+		if reciprocal := new(big.Rat).SetFrac(big.NewInt(1), r.Denom()); shared.IsDecimal(reciprocal) {
+			node.Associative[1] = shared.RationalNode(reciprocal)
+		}
+		if r.Num().IsInt64() && r.Num().Int64() == 1 {
+			return node.Associative[1:]
+		}
+		return node.Associative
 	}
-	return false, 0.0
+	if s.Conf.KeepDecimals() {
+		return []*shared.Node{shared.ExactNumber(r)}
+	}
+	f, _ := r.Float64()
+	return []*shared.Node{{
+		OperationType: shared.NUMBER,
+		Value:         f,
+		Variable:      "",
+		LNode:         nil,
+		RNode:         nil,
+		Associative:   nil,
+		Rational:      nil,
+	}}
+}
+
+// Returns the node of a rational number, see numberFactors.
+func rationalNode(s *shared.State, r *big.Rat) *shared.Node {
+	factors := numberFactors(s, r)
+	if len(factors) == 1 {
+		return factors[0]
+	}
+	return shared.Multiply(factors...)
+}
+
+// Checks if two lists contain the same factors.
+func sameFactors(a, b []*shared.Node) bool {
+	return shared.IsEqual(shared.Multiply(a...), shared.Multiply(b...))
 }
 
 // Returns wether there is a factor of a variable, and if so than it also returns the factor and the variable.
@@ -202,9 +297,9 @@ func isEndNode(node *shared.Node) bool {
 // Apply Multiplycation
 // a * b = ab
 // a * (a + b) = a * a + a * b
-func multiplyNodes(x *shared.Node, y *shared.Node) *shared.Node {
+func multiplyNodes(s *shared.State, x *shared.Node, y *shared.Node) *shared.Node {
 
-	if shared.Conf.Options["show_debug_process"] {
+	if s.Conf.Options["show_debug_process"] {
 		cfmt.Printf("(Simplifier - 275:6 - multiplyNodes) {{Debug:}}::cyan|bold Multiplying ")
 		cfmt.Printf("%s", shared.PrintATree(x))
 		cfmt.Printf(" and ")
@@ -213,13 +308,13 @@ func multiplyNodes(x *shared.Node, y *shared.Node) *shared.Node {
 	}
 
 	if x.OperationType == shared.NUMBER && x.Value == 1 {
-		if shared.Conf.Options["show_debug_process"] {
+		if s.Conf.Options["show_debug_process"] {
 			cfmt.Printf("%s", shared.PrintATree(y))
 			cfmt.Printf("\n")
 		}
 		return y
 	} else if y.OperationType == shared.NUMBER && y.Value == 1 {
-		if shared.Conf.Options["show_debug_process"] {
+		if s.Conf.Options["show_debug_process"] {
 			cfmt.Printf("%s", shared.PrintATree(x))
 			cfmt.Printf("\n")
 		}
@@ -232,18 +327,13 @@ func multiplyNodes(x *shared.Node, y *shared.Node) *shared.Node {
 		Variable:      "",
 		LNode:         nil,
 		RNode:         nil,
-		Associative:   []*shared.Node{}}
+		Associative:   []*shared.Node{},
+		Rational:      nil,
+	}
 
 	// Both are at the end of operation
 	if isEndNode(x) && isEndNode(y) {
-		res = &shared.Node{
-			OperationType: shared.MULTIPLY,
-			Value:         0.0,
-			Variable:      "",
-			LNode:         nil,
-			RNode:         nil,
-			Associative:   []*shared.Node{x, y},
-		}
+		res = shared.Multiply(x, y)
 
 		// x is added into the multiply operation of y
 	} else if y.OperationType == shared.MULTIPLY && isEndNode(x) {
@@ -258,25 +348,13 @@ func multiplyNodes(x *shared.Node, y *shared.Node) *shared.Node {
 		// x is multiplied by every number in the y operation
 	} else if y.OperationType == shared.PLUS && isEndNode(x) {
 		for _, val := range y.Associative {
-			res.Associative = append(res.Associative, &shared.Node{
-				OperationType: shared.MULTIPLY,
-				Value:         0.0,
-				Variable:      "",
-				LNode:         nil,
-				RNode:         nil,
-				Associative:   []*shared.Node{x, val}})
+			res.Associative = append(res.Associative, shared.Multiply(x, val))
 		}
 
 		// x is multiplied by every number in the y operation
 	} else if x.OperationType == shared.PLUS && isEndNode(y) {
 		for _, val := range x.Associative {
-			res.Associative = append(res.Associative, &shared.Node{
-				OperationType: shared.MULTIPLY,
-				Value:         0.0,
-				Variable:      "",
-				LNode:         nil,
-				RNode:         nil,
-				Associative:   []*shared.Node{y, val}})
+			res.Associative = append(res.Associative, shared.Multiply(y, val))
 		}
 
 	} else if x.OperationType == shared.PLUS && y.OperationType == shared.MULTIPLY {
@@ -296,19 +374,23 @@ func multiplyNodes(x *shared.Node, y *shared.Node) *shared.Node {
 	} else {
 		for _, xVal := range x.Associative {
 			for _, yVal := range y.Associative {
-				res.Associative = append(res.Associative, &shared.Node{
-					OperationType: shared.MULTIPLY,
-					Value:         0.0,
-					Variable:      "",
-					LNode:         nil,
-					RNode:         nil,
-					Associative:   []*shared.Node{xVal, yVal}})
+				res.Associative = append(res.Associative, shared.Multiply(xVal, yVal))
 			}
 		}
 	}
-	if shared.Conf.Options["show_debug_process"] {
+	if s.Conf.Options["show_debug_process"] {
 		cfmt.Printf("%s", shared.PrintATree(res))
 		cfmt.Printf("\n")
 	}
 	return res
 }
+
+// Returns the keys of a map in alphabetical order, so results do not depend on the order of the map.
+func sortedKeys(m map[string]float64) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -1,7 +1,329 @@
 package solver
 
-import "lambdacalc/shared"
+import (
+	"lambdacalc/interpreter"
+	"lambdacalc/shared"
+	"lambdacalc/simplifier"
+	"math"
+	"math/big"
+	"math/cmplx"
 
-func Solve(node *shared.Node) (bool, error) {
-	return true, nil
+	"github.com/i582/cfmt/cmd/cfmt"
+)
+
+// Possible outcomes of solving an equation.
+const (
+	SOLVED   = iota
+	NONE     = iota
+	NO_REAL  = iota
+	INFINITE = iota
+)
+
+type Solution struct {
+	Variable string
+	State    int
+	Values   []*shared.Node
+}
+
+// Solve an equation of the form a = b by the given variable.
+// The equation is brought into the form p(x) = 0 by moving all terms across the equal sign.
+// Currently only linear and quadratic equations can be solved.
+func Solve(s *shared.State, node *shared.Node, variable string) (*Solution, error) {
+	if node == nil || node.OperationType != shared.EQUAL {
+		return nil, shared.NewError("not an equation", "Unable to solve equation, missing equal sign.")
+	}
+
+	if s.ContainsMatrix(node) {
+		return nil, shared.NewError("matrix equation", "Unable to solve equation, equations of matrices are not supported.")
+	}
+
+	equation := substituteVariables(s, shared.Clone(node), variable)
+
+	// a = b -> a + (-1 * b) = 0
+	moved := &shared.Node{
+		OperationType: shared.PLUS,
+		Value:         0.0,
+		Variable:      "",
+		LNode:         nil,
+		RNode:         nil,
+		Associative: []*shared.Node{
+			equation.LNode,
+			{
+				OperationType: shared.MULTIPLY,
+				Value:         0.0,
+				Variable:      "",
+				LNode:         nil,
+				RNode:         nil,
+				Associative:   []*shared.Node{shared.NumberNode(-1.0), equation.RNode},
+				Rational:      nil,
+			},
+		},
+		Rational: nil,
+	}
+
+	p, err := toPolynomial(moved, variable)
+	if err != nil {
+		return nil, shared.NewError(shared.ErrorCode(err), "Unable to solve equation, it is not a polynomial in '%s'.", variable)
+	}
+
+	c, err := coefficients(s, p)
+	if err != nil {
+		return nil, err
+	}
+
+	// Debug
+	if s.Conf.Options["show_debug_process"] {
+		for d, val := range c {
+			cfmt.Printf("{{Debug:}}::cyan|bold coefficient of %s^%v: %s\n", variable, d, shared.PrintATree(val))
+		}
+	}
+
+	switch degree(c) {
+	case 0:
+		return solveConstant(s, c, variable)
+	case 1:
+		return solveLinear(s, c, variable)
+	case 2:
+		return solveQuadratic(s, c, variable)
+	default:
+		return nil, shared.NewError("unsupported degree", "Unable to solve equation, only linear and quadratic equations are supported.")
+	}
+}
+
+// c = 0
+func solveConstant(s *shared.State, c map[int]*shared.Node, variable string) (*Solution, error) {
+	constant, err := interpreter.Evaluate(s, coefficient(c, 0))
+	if err != nil {
+		return nil, shared.NewError("missing variable", "Unable to solve equation, '%s' does not appear in the equation.", variable)
+	}
+
+	if constant == 0 {
+		return &Solution{Variable: variable, State: INFINITE, Values: nil}, nil
+	}
+	return &Solution{Variable: variable, State: NONE, Values: nil}, nil
+}
+
+// ax + b = 0 -> x = -b / a
+func solveLinear(s *shared.State, c map[int]*shared.Node, variable string) (*Solution, error) {
+	a := coefficient(c, 1)
+	b := coefficient(c, 0)
+
+	value, err := simplifier.Simplify(s, shared.Multiply(shared.NumberNode(-1.0), b, reciprocal(a)), simplifier.SOLVE)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Solution{Variable: variable, State: SOLVED, Values: []*shared.Node{evaluateIfPossible(s, value)}}, nil
+}
+
+// ax^2 + bx + c = 0 -> x = (-b ± sqrt(b^2 - 4ac)) / 2a
+func solveQuadratic(s *shared.State, c map[int]*shared.Node, variable string) (*Solution, error) {
+	a := coefficient(c, 2)
+	b := coefficient(c, 1)
+	k := coefficient(c, 0)
+
+	// In exact mode and with a higher precision rational solutions are kept as fractions.
+	if s.Conf.KeepDecimals() {
+		if values, ok := exactQuadratic(s, a, b, k); ok {
+			return &Solution{Variable: variable, State: SOLVED, Values: values}, nil
+		}
+	}
+
+	// Purely numerical equations can be checked for real solutions.
+	if a.OperationType == shared.NUMBER && b.OperationType == shared.NUMBER && k.OperationType == shared.NUMBER {
+		discriminant := b.Value*b.Value - 4*a.Value*k.Value
+		switch {
+		case discriminant < 0 && !s.Conf.Options["complex"]:
+			return &Solution{Variable: variable, State: NO_REAL, Values: nil}, nil
+		case discriminant < 0:
+			// Complex conjugate solutions: (-b ± i sqrt(-discriminant)) / 2a
+			re := -b.Value / (2 * a.Value)
+			im := math.Sqrt(-discriminant) / (2 * a.Value)
+			return &Solution{
+				Variable: variable,
+				State:    SOLVED,
+				Values: []*shared.Node{
+					s.Conf.ComplexNode(complex(re, im)),
+					s.Conf.ComplexNode(complex(re, -im)),
+				},
+			}, nil
+		case s.Conf.KeepDecimals():
+			// Irrational solutions are kept as roots, so they can be calculated exactly or with a higher precision.
+		case discriminant == 0:
+			// Adding 0 turns -0 into 0, i.e.: x^2 = 0 -> x = 0
+			return &Solution{
+				Variable: variable,
+				State:    SOLVED,
+				Values:   []*shared.Node{shared.NumberNode(-b.Value/(2*a.Value) + 0)},
+			}, nil
+		default:
+			root := math.Sqrt(discriminant)
+			return &Solution{
+				Variable: variable,
+				State:    SOLVED,
+				Values: []*shared.Node{
+					shared.NumberNode((-b.Value + root) / (2 * a.Value)),
+					shared.NumberNode((-b.Value - root) / (2 * a.Value)),
+				},
+			}, nil
+		}
+	}
+
+	// b^2 - 4ac
+	discriminant := &shared.Node{
+		OperationType: shared.PLUS,
+		Value:         0.0,
+		Variable:      "",
+		LNode:         nil,
+		RNode:         nil,
+		Associative: []*shared.Node{
+			{
+				OperationType: shared.POWER,
+				Value:         0.0,
+				Variable:      "",
+				LNode:         shared.Clone(b),
+				RNode:         shared.NumberNode(2.0),
+				Associative:   nil,
+				Rational:      nil,
+			},
+			{
+				OperationType: shared.MULTIPLY,
+				Value:         0.0,
+				Variable:      "",
+				LNode:         nil,
+				RNode:         nil,
+				Associative:   []*shared.Node{shared.NumberNode(-4.0), shared.Clone(a), shared.Clone(k)},
+				Rational:      nil,
+			},
+		},
+		Rational: nil,
+	}
+
+	values := []*shared.Node{}
+	for _, sign := range []float64{1.0, -1.0} {
+		// (-b ± discriminant^0.5) * (2a)^-1
+		value, err := simplifier.Simplify(s, &shared.Node{
+			OperationType: shared.MULTIPLY,
+			Value:         0.0,
+			Variable:      "",
+			LNode:         nil,
+			RNode:         nil,
+			Associative: []*shared.Node{
+				{
+					OperationType: shared.PLUS,
+					Value:         0.0,
+					Variable:      "",
+					LNode:         nil,
+					RNode:         nil,
+					Associative: []*shared.Node{
+						{
+							OperationType: shared.MULTIPLY,
+							Value:         0.0,
+							Variable:      "",
+							LNode:         nil,
+							RNode:         nil,
+							Associative:   []*shared.Node{shared.NumberNode(-1.0), shared.Clone(b)},
+							Rational:      nil,
+						},
+						{
+							OperationType: shared.MULTIPLY,
+							Value:         0.0,
+							Variable:      "",
+							LNode:         nil,
+							RNode:         nil,
+							Associative: []*shared.Node{
+								shared.NumberNode(sign),
+								{
+									OperationType: shared.POWER,
+									Value:         0.0,
+									Variable:      "",
+									LNode:         shared.Clone(discriminant),
+									RNode:         shared.NumberNode(0.5),
+									Associative:   nil,
+									Rational:      nil,
+								},
+							},
+							Rational: nil,
+						},
+					},
+					Rational: nil,
+				},
+				reciprocal(shared.Multiply(shared.NumberNode(2.0), shared.Clone(a))),
+			},
+			Rational: nil,
+		}, simplifier.SOLVE)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, evaluateIfPossible(s, value))
+	}
+
+	return &Solution{Variable: variable, State: SOLVED, Values: values}, nil
+}
+
+// Solves a numerical quadratic equation with rationals, fails if the solutions are not rational.
+// x^2 - 1/4 = 0 -> 1/2, -1/2
+func exactQuadratic(s *shared.State, a, b, k *shared.Node) ([]*shared.Node, bool) {
+	ra, aExact, err := interpreter.EvaluateExact(s, a)
+	if err != nil || !aExact {
+		return nil, false
+	}
+	rb, bExact, err := interpreter.EvaluateExact(s, b)
+	if err != nil || !bExact {
+		return nil, false
+	}
+	rk, kExact, err := interpreter.EvaluateExact(s, k)
+	if err != nil || !kExact {
+		return nil, false
+	}
+
+	// b^2 - 4ac
+	discriminant := new(big.Rat).Mul(rb, rb)
+	discriminant.Sub(discriminant, new(big.Rat).Mul(big.NewRat(4, 1), new(big.Rat).Mul(ra, rk)))
+	if discriminant.Sign() < 0 {
+		return nil, false
+	}
+	root, ok := shared.RationalRoot(discriminant, 2)
+	if !ok {
+		return nil, false
+	}
+
+	denominator := new(big.Rat).Mul(big.NewRat(2, 1), ra)
+	values := []*shared.Node{}
+	for _, sign := range []int64{1, -1} {
+		value := new(big.Rat).Mul(big.NewRat(sign, 1), root)
+		value.Sub(value, rb).Quo(value, denominator)
+		values = append(values, shared.RationalNode(value))
+		if discriminant.Sign() == 0 {
+			break
+		}
+	}
+	return values, true
+}
+
+// x -> x^-1
+func reciprocal(node *shared.Node) *shared.Node {
+	return shared.Reciprocal(shared.Clone(node))
+}
+
+// Replace a tree by its value, if it does not contain any undefined variables.
+// In exact mode and with a higher precision rational values are kept as a fraction.
+// In complex mode values are evaluated as complex numbers. i.e.: (-4)^0.5 -> 2 * i
+func evaluateIfPossible(s *shared.State, node *shared.Node) *shared.Node {
+	if s.Conf.Options["complex"] {
+		if val, err := interpreter.EvaluateComplex(s, node); err == nil && !cmplx.IsNaN(val) {
+			return s.Conf.ComplexNode(val)
+		}
+		return node
+	}
+	if s.Conf.KeepDecimals() {
+		if val, exact, err := interpreter.EvaluateExact(s, node); err == nil && exact {
+			return shared.RationalNode(val)
+		}
+		return node
+	}
+	if val, err := interpreter.Evaluate(s, node); err == nil && !math.IsNaN(val) {
+		return shared.NumberNode(val)
+	}
+	return node
 }
@@ -0,0 +1,84 @@
+package solver
+
+import (
+	"lambdacalc/lexer"
+	"lambdacalc/parser"
+	"lambdacalc/shared"
+	"slices"
+	"testing"
+)
+
+// State of the tests, with the default config. Tests defining names remove them again.
+var state = shared.NewState(shared.GetDefualtConfig())
+
+// Parses an equation for a test.
+func parse(t *testing.T, input string) *shared.Node {
+	t.Helper()
+	lexed, err := lexer.LexTokens(state, input)
+	if err != nil {
+		t.Fatalf("%s: unable to lex, %v", input, err)
+	}
+	parsed, err := parser.SearchParse(state, lexed, parser.ASSERTION)
+	if err != nil {
+		t.Fatalf("%s: unable to parse, %v", input, err)
+	}
+	return parsed
+}
+
+// Writes the values of a solution for a comparison.
+func printValues(values []*shared.Node) []string {
+	res := []string{}
+	for _, val := range values {
+		res = append(res, shared.PrintATree(val))
+	}
+	return res
+}
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		equation string
+		state    int
+		values   []string
+	}{
+		{"2*x + 4 = 0", SOLVED, []string{"-2"}},
+		{"x = 3*x - 8", SOLVED, []string{"4"}},
+		{"x^2 - 5*x + 6 = 0", SOLVED, []string{"3", "2"}},
+		{"x^2 = 4", SOLVED, []string{"2", "-2"}},
+		{"x^2 = 0", SOLVED, []string{"0"}},
+		{"x^2 + 2*x + 1 = 0", SOLVED, []string{"-1"}},
+		{"x^2 + 1 = 0", NO_REAL, []string{}},
+		{"x + 1 = x", NONE, []string{}},
+		{"x = x", INFINITE, []string{}},
+		{"a*x = b", SOLVED, []string{"((a^-1)*b)"}},
+	}
+	for _, test := range tests {
+		got, err := Solve(state, parse(t, test.equation), "x")
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.equation, err)
+			continue
+		}
+		if values := printValues(got.Values); got.State != test.state || !slices.Equal(values, test.values) {
+			t.Errorf("%s: got state %d %v, want state %d %v", test.equation, got.State, values, test.state, test.values)
+		}
+	}
+}
+
+func TestSolveErrors(t *testing.T) {
+	tests := []struct {
+		equation string
+		code     string
+	}{
+		{"x^3 = 1", "unsupported degree"},
+		{"sin(x) = 0", "not a polynomial"},
+		{"a = 1", "missing variable"},
+		{"[1, 2] = x", "matrix equation"},
+	}
+	for _, test := range tests {
+		if _, err := Solve(state, parse(t, test.equation), "x"); err == nil || shared.ErrorCode(err) != test.code {
+			t.Errorf("%s: got %v, want %s", test.equation, err, test.code)
+		}
+	}
+	if _, err := Solve(state, shared.VariableNode("x"), "x"); err == nil || shared.ErrorCode(err) != "not an equation" {
+		t.Errorf("x: got %v, want not an equation", err)
+	}
+}
@@ -0,0 +1,369 @@
+package solver
+
+import (
+	"lambdacalc/interpreter"
+	poly "lambdacalc/polynomial"
+	"lambdacalc/shared"
+	"lambdacalc/simplifier"
+	"math"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/i582/cfmt/cmd/cfmt"
+)
+
+// Values of the symbolic elimination below this size are treated as zero.
+const EPSILON = 1e-12
+
+// Solution of a system of equations, Values holds the value of every variable in the same order.
+// With infinitely many solutions the free variables are their own value and the others depend on them.
+type SystemSolution struct {
+	Variables []string
+	State     int
+	Values    []*shared.Node
+	Free      []string
+}
+
+// Solve a system of linear equations by the given variables with Gaussian elimination.
+// The coefficients are exact rationals, if every coefficient can be evaluated exactly.
+// Otherwise free variables and parameters appear in the values.
+// 2x + y = 5; x - y = 1 -> x = 2, y = 1
+func SolveSystem(s *shared.State, nodes []*shared.Node, variables []string) (*SystemSolution, error) {
+	if len(nodes) == 0 {
+		return nil, shared.NewError("missing equation", "Unable to solve system, missing equation.")
+	}
+	rows := [][]*shared.Node{}
+	for _, node := range nodes {
+		if node == nil || node.OperationType != shared.EQUAL {
+			return nil, shared.NewError("not an equation", "Unable to solve system, missing equal sign.")
+		}
+		if s.ContainsMatrix(node) {
+			return nil, shared.NewError("matrix equation", "Unable to solve system, equations of matrices are not supported.")
+		}
+		row, err := linearCoefficients(s, node, variables)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+
+	// Debug
+	if s.Conf.Options["show_debug_process"] {
+		for _, row := range rows {
+			entries := []string{}
+			for _, val := range row {
+				entries = append(entries, shared.PrintATree(val))
+			}
+			cfmt.Printf("{{Debug:}}::cyan|bold row: [%s]\n", strings.Join(entries, " "))
+		}
+	}
+
+	// Numbers and parameters, that form polynomials, are eliminated exactly. i.e.: a*x, pi*x
+	if m, ok := polynomialMatrix(s, rows); ok {
+		if pivots, consistent, ok := reducePolynomial(s, m); ok {
+			return systemSolution(variables, pivots, consistent, func(i, j int) *shared.Node {
+				// The pivot of a row is not 1, but a common multiple. x_j = (b - a*x_k) / pivot
+				numerator := m[i][j]
+				if j < len(variables) {
+					numerator = numerator.Scale(big.NewRat(-1, 1))
+				}
+				return quotientNode(numerator, m[i][pivots[i]])
+			}, func(i, j int) bool {
+				return m[i][j].IsZero()
+			}), nil
+		}
+	}
+
+	// Other coefficients are kept as trees. i.e.: sin(a)
+	m := rows
+	pivots, consistent, err := reduceSymbolic(s, m)
+	if err != nil {
+		return nil, err
+	}
+	return systemSolution(variables, pivots, consistent, func(i, j int) *shared.Node {
+		if j < len(variables) {
+			return negate(m[i][j])
+		}
+		return m[i][j]
+	}, func(i, j int) bool {
+		return isZeroNode(s, m[i][j])
+	}), nil
+}
+
+// Returns the row of an equation in the augmented matrix, the coefficients of the variables
+// followed by the constant on the right side. 2x + y = 5 -> [2 1 5]
+func linearCoefficients(s *shared.State, node *shared.Node, variables []string) ([]*shared.Node, error) {
+	equation := shared.Clone(node)
+	for _, variable := range variables {
+		equation = substituteVariables(s, equation, variable)
+	}
+
+	// a = b -> a + (-1 * b)
+	rest := &shared.Node{
+		OperationType: shared.PLUS,
+		Value:         0.0,
+		Variable:      "",
+		LNode:         nil,
+		RNode:         nil,
+		Associative: []*shared.Node{
+			equation.LNode,
+			{
+				OperationType: shared.MULTIPLY,
+				Value:         0.0,
+				Variable:      "",
+				LNode:         nil,
+				RNode:         nil,
+				Associative:   []*shared.Node{shared.NumberNode(-1.0), equation.RNode},
+				Rational:      nil,
+			},
+		},
+		Rational: nil,
+	}
+
+	// The terms without the variable are split by the next variable.
+	row := []*shared.Node{}
+	for _, variable := range variables {
+		p, err := toPolynomial(rest, variable)
+		if err != nil {
+			return nil, shared.NewError(shared.ErrorCode(err), "Unable to solve system, it is not a polynomial in '%s'.", variable)
+		}
+		c, err := coefficients(s, p)
+		if err != nil {
+			return nil, err
+		}
+
+		a := coefficient(c, 1)
+		if degree(c) > 1 || containsAny(a, variables) {
+			return nil, shared.NewError("not linear", "Unable to solve system, it is not linear in '%s'.", variable)
+		}
+		row = append(row, a)
+
+		rest = shared.Add(append([]*shared.Node{shared.NumberNode(0.0)}, p[0]...)...)
+	}
+
+	// a*x + b = 0 -> a*x = -b
+	c, err := coefficients(s, polynomial{0: {rest}}.scale(shared.NumberNode(-1.0)))
+	if err != nil {
+		return nil, err
+	}
+	return append(row, coefficient(c, 0)), nil
+}
+
+// Converts the entries of the augmented matrix into polynomials in their parameters.
+// Without exact mode decimals are read as their shortest fraction. 0.1 -> 1/10
+func polynomialMatrix(s *shared.State, rows [][]*shared.Node) ([][]*poly.Polynomial, bool) {
+	entries := []*shared.Node{}
+	for _, row := range rows {
+		entries = append(entries, row...)
+	}
+	p, ok := poly.FromNodes(s, entries...)
+	if !ok {
+		return nil, false
+	}
+
+	m := [][]*poly.Polynomial{}
+	for i, row := range rows {
+		m = append(m, p[i*len(row):(i+1)*len(row)])
+	}
+	return m, true
+}
+
+// Brings the augmented matrix into reduced row echelon form with fraction free Gauss-Jordan
+// elimination, every division is exact and the pivots of all rows end up equal.
+// Returns the pivot column of every non zero row and whether the system is consistent,
+// i.e.: no row 0 = c with c != 0. Reports false, if a division is not exact.
+func reducePolynomial(s *shared.State, m [][]*poly.Polynomial) ([]int, bool, bool) {
+	columns := len(m[0]) - 1
+	previous := poly.Constant(s, m[0][0].Variables, big.NewRat(1, 1))
+	pivots := []int{}
+	for j := 0; j < columns && len(pivots) < len(m); j++ {
+		r := len(pivots)
+		i := r
+		for i < len(m) && m[i][j].IsZero() {
+			i++
+		}
+		if i == len(m) {
+			continue
+		}
+		m[r], m[i] = m[i], m[r]
+
+		// row_i = (pivot * row_i - a_ij * row_r) / previous pivot
+		pivot := m[r][j]
+		for i := range m {
+			if i == r {
+				continue
+			}
+			factor := m[i][j]
+			for k := range m[i] {
+				val, ok := pivot.Mul(m[i][k]).Sub(factor.Mul(m[r][k])).Divide(previous)
+				if !ok {
+					return nil, false, false
+				}
+				m[i][k] = val
+			}
+		}
+		previous = pivot
+		pivots = append(pivots, j)
+	}
+
+	for i := len(pivots); i < len(m); i++ {
+		if !m[i][columns].IsZero() {
+			return pivots, false, true
+		}
+	}
+	return pivots, true, true
+}
+
+// Returns the fraction of two polynomials after cancelling their common factors.
+// x^2 - 1, 2x + 2 -> (1/2 x - 1/2)
+func quotientNode(p, q *poly.Polynomial) *shared.Node {
+	if numerator, denominator, ok := poly.Cancel(p, q); ok {
+		p, q = numerator, denominator
+	} else {
+		p, q = p.Scale(new(big.Rat).Inv(q.Content())), q.Primitive()
+	}
+	if q.IsConstant() {
+		return p.Scale(new(big.Rat).Inv(q.Leading())).ToNode()
+	}
+	if p.IsConstant() && p.Leading().Cmp(big.NewRat(1, 1)) == 0 {
+		return reciprocal(q.ToNode())
+	}
+	return shared.Multiply(p.ToNode(), reciprocal(q.ToNode()))
+}
+
+// Reduced row echelon form of a matrix of trees, every step is simplified and evaluated if possible.
+// Entries, that can not be evaluated, are assumed to be non zero. i.e.: a
+func reduceSymbolic(s *shared.State, m [][]*shared.Node) ([]int, bool, error) {
+	columns := len(m[0]) - 1
+	pivots := []int{}
+	for j := 0; j < columns && len(pivots) < len(m); j++ {
+		r := len(pivots)
+		i := r
+		for i < len(m) && isZeroNode(s, m[i][j]) {
+			i++
+		}
+		if i == len(m) {
+			continue
+		}
+		m[r], m[i] = m[i], m[r]
+
+		pivot := m[r][j]
+		for k := range m[r] {
+			val, err := simplifyEntry(s, []*shared.Node{m[r][k], reciprocal(pivot)}, nil)
+			if err != nil {
+				return nil, false, err
+			}
+			m[r][k] = val
+		}
+		for i := range m {
+			if i == r || isZeroNode(s, m[i][j]) {
+				continue
+			}
+			factor := m[i][j]
+			for k := range m[i] {
+				val, err := simplifyEntry(s, []*shared.Node{shared.NumberNode(-1.0), factor, m[r][k]}, m[i][k])
+				if err != nil {
+					return nil, false, err
+				}
+				m[i][k] = val
+			}
+		}
+		pivots = append(pivots, j)
+	}
+
+	for i := len(pivots); i < len(m); i++ {
+		if !isZeroNode(s, m[i][columns]) {
+			return pivots, false, nil
+		}
+	}
+	return pivots, true, nil
+}
+
+// Simplifies the product of the factors plus an optional addend.
+func simplifyEntry(s *shared.State, factors []*shared.Node, addend *shared.Node) (*shared.Node, error) {
+	node := shared.Multiply(factors...)
+	if addend != nil {
+		node = shared.Add(addend, node)
+	}
+	res, err := simplifier.Simplify(s, shared.Clone(node), simplifier.SOLVE)
+	if err != nil {
+		return nil, err
+	}
+	return evaluateIfPossible(s, res), nil
+}
+
+// Checks if a tree evaluates to zero, up to rounding errors of the floating point numbers.
+func isZeroNode(s *shared.State, node *shared.Node) bool {
+	val, err := interpreter.Evaluate(s, node)
+	return err == nil && math.Abs(val) < EPSILON
+}
+
+// Reads the solution from a matrix in reduced row echelon form. Every pivot variable equals
+// the constant of its row minus the free variables times their coefficients, term returns
+// these parts of row i for column j.
+// x + 2z = 3, y - z = 1 -> x = 3 - 2z, y = 1 + z, z is free
+func systemSolution(variables []string, pivots []int, consistent bool, term func(i, j int) *shared.Node, isZero func(i, j int) bool) *SystemSolution {
+	if !consistent {
+		return &SystemSolution{Variables: variables, State: NONE, Values: nil, Free: nil}
+	}
+
+	free := []string{}
+	values := make([]*shared.Node, len(variables))
+	for j, variable := range variables {
+		if !slices.Contains(pivots, j) {
+			free = append(free, variable)
+			values[j] = shared.VariableNode(variable)
+		}
+	}
+
+	for i, j := range pivots {
+		terms := []*shared.Node{}
+		if !isZero(i, len(variables)) {
+			terms = append(terms, term(i, len(variables)))
+		}
+		for k := range variables {
+			if slices.Contains(pivots, k) || isZero(i, k) {
+				continue
+			}
+			factor := term(i, k)
+			if factor.OperationType == shared.NUMBER && factor.Value == 1 {
+				terms = append(terms, shared.VariableNode(variables[k]))
+				continue
+			}
+			terms = append(terms, shared.Multiply(factor, shared.VariableNode(variables[k])))
+		}
+
+		switch len(terms) {
+		case 0:
+			values[j] = shared.NumberNode(0.0)
+		case 1:
+			values[j] = terms[0]
+		default:
+			values[j] = shared.Add(terms...)
+		}
+	}
+
+	if len(free) > 0 {
+		return &SystemSolution{Variables: variables, State: INFINITE, Values: values, Free: free}
+	}
+	return &SystemSolution{Variables: variables, State: SOLVED, Values: values, Free: nil}
+}
+
+// Negates a number or a fraction. 2 -> -2, 1 * 3^-1 -> -1 * 3^-1
+func negate(node *shared.Node) *shared.Node {
+	if node.OperationType == shared.NUMBER {
+		return shared.NumberNode(-node.Value)
+	}
+	return shared.Negate(node)
+}
+
+// Checks if a tree contains one of the variables.
+func containsAny(node *shared.Node, variables []string) bool {
+	for _, variable := range variables {
+		if shared.ContainsVariable(node, variable) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package solver
+
+import (
+	"lambdacalc/shared"
+	"slices"
+	"testing"
+)
+
+// Parameters are kept on the side of the constants. x + y = a; x - y = b -> x = (a + b) / 2
+func TestSolveSystem(t *testing.T) {
+	tests := []struct {
+		equations []string
+		variables []string
+		state     int
+		values    []string
+		free      []string
+	}{
+		{[]string{"2*x + y = 5", "x - y = 1"}, []string{"x", "y"}, SOLVED, []string{"2", "1"}, []string{}},
+		{[]string{"x + y = 1", "x + y = 2"}, []string{"x", "y"}, NONE, []string{}, []string{}},
+		{[]string{"x + 2*z = 3", "y - z = 1"}, []string{"x", "y", "z"}, INFINITE, []string{"(3-(2*z))", "(1+z)", "z"}, []string{"z"}},
+		{[]string{"x + y = a", "x - y = b"}, []string{"x", "y"}, SOLVED, []string{"((0.5*a)+(0.5*b))", "((0.5*a)-(0.5*b))"}, []string{}},
+		{[]string{"2*x + y = a", "x = 1"}, []string{"x", "y"}, SOLVED, []string{"1", "(a-2)"}, []string{}},
+	}
+	for _, test := range tests {
+		nodes := []*shared.Node{}
+		for _, val := range test.equations {
+			nodes = append(nodes, parse(t, val))
+		}
+		got, err := SolveSystem(state, nodes, test.variables)
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", test.equations, err)
+			continue
+		}
+		free := append([]string{}, got.Free...)
+		if values := printValues(got.Values); got.State != test.state || !slices.Equal(values, test.values) || !slices.Equal(free, test.free) {
+			t.Errorf("%v: got state %d %v free %v, want state %d %v free %v", test.equations, got.State, values, free, test.state, test.values, test.free)
+		}
+	}
+}
+
+func TestSolveSystemWithoutEquations(t *testing.T) {
+	if _, err := SolveSystem(state, nil, []string{"x"}); err == nil || shared.ErrorCode(err) != "missing equation" {
+		t.Errorf("got %v, want missing equation", err)
+	}
+}
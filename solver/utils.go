@@ -0,0 +1,169 @@
+package solver
+
+import (
+	"errors"
+	"lambdacalc/interpreter"
+	"lambdacalc/shared"
+	"lambdacalc/simplifier"
+	"slices"
+)
+
+// Terms of a polynomial sorted by the degree of the variable they belong to.
+// 3x^2 + ax + 2 -> {2: [3], 1: [a], 0: [2]}
+type polynomial map[int][]*shared.Node
+
+// Replace all defined variables, except the one we are solving for, by their value.
+func substituteVariables(s *shared.State, node *shared.Node, variable string) *shared.Node {
+	if node == nil {
+		return nil
+	}
+	if node.OperationType == shared.VARIABLE && node.Variable != variable {
+		if val, ok := s.Variables[node.Variable]; ok {
+			return substituteVariables(s, shared.Clone(&val), variable)
+		}
+	}
+
+	node.LNode = substituteVariables(s, node.LNode, variable)
+	node.RNode = substituteVariables(s, node.RNode, variable)
+	for i, val := range node.Associative {
+		node.Associative[i] = substituteVariables(s, val, variable)
+	}
+	return node
+}
+
+// Multiply every term of a polynomial by a factor.
+func (p polynomial) scale(factor *shared.Node) polynomial {
+	res := polynomial{}
+	for degree, terms := range p {
+		for _, term := range terms {
+			res[degree] = append(res[degree], shared.Multiply(shared.Clone(factor), shared.Clone(term)))
+		}
+	}
+	return res
+}
+
+// Multiply two polynomials term by term.
+// (x + 1) * (x + 2) -> {2: [1*1], 1: [1*2, 1*1], 0: [1*2]}
+func (p polynomial) multiply(q polynomial) polynomial {
+	res := polynomial{}
+	for aDegree, aTerms := range p {
+		for bDegree, bTerms := range q {
+			for _, a := range aTerms {
+				for _, b := range bTerms {
+					res[aDegree+bDegree] = append(res[aDegree+bDegree], shared.Multiply(shared.Clone(a), shared.Clone(b)))
+				}
+			}
+		}
+	}
+	return res
+}
+
+// Split a tree into the terms of a polynomial in the given variable.
+// Fails if the variable appears in a way that is not polynomial, i.e.: x^-1 or f(x).
+func toPolynomial(node *shared.Node, variable string) (polynomial, error) {
+	if !shared.ContainsVariable(node, variable) {
+		return polynomial{0: {shared.Clone(node)}}, nil
+	}
+
+	switch node.OperationType {
+	case shared.VARIABLE:
+		return polynomial{1: {shared.NumberNode(1.0)}}, nil
+	case shared.PLUS:
+		res := polynomial{}
+		for _, val := range node.Associative {
+			p, err := toPolynomial(val, variable)
+			if err != nil {
+				return nil, err
+			}
+			for degree, terms := range p {
+				res[degree] = append(res[degree], terms...)
+			}
+		}
+		return res, nil
+	case shared.MINUS:
+		a, err := toPolynomial(node.LNode, variable)
+		if err != nil {
+			return nil, err
+		}
+		b, err := toPolynomial(node.RNode, variable)
+		if err != nil {
+			return nil, err
+		}
+		for degree, terms := range b.scale(shared.NumberNode(-1.0)) {
+			a[degree] = append(a[degree], terms...)
+		}
+		return a, nil
+	case shared.MULTIPLY:
+		res := polynomial{0: {shared.NumberNode(1.0)}}
+		for _, val := range node.Associative {
+			p, err := toPolynomial(val, variable)
+			if err != nil {
+				return nil, err
+			}
+			res = res.multiply(p)
+		}
+		return res, nil
+	case shared.POWER:
+		if node.RNode.OperationType == shared.NUMBER && node.RNode.Value >= 0 && node.RNode.Value == float64(int(node.RNode.Value)) {
+			base, err := toPolynomial(node.LNode, variable)
+			if err != nil {
+				return nil, err
+			}
+			res := polynomial{0: {shared.NumberNode(1.0)}}
+			for i := 0; i < int(node.RNode.Value); i++ {
+				res = res.multiply(base)
+			}
+			return res, nil
+		}
+	}
+	return nil, errors.New("not a polynomial")
+}
+
+// Combine the terms of every degree into a single simplified coefficient.
+// Coefficients that evaluate to zero are dropped.
+func coefficients(s *shared.State, p polynomial) (map[int]*shared.Node, error) {
+	res := make(map[int]*shared.Node)
+	for degree, terms := range p {
+		coefficient := shared.Add(terms...)
+
+		// Numerical coefficients are evaluated directly, everything else is simplified.
+		// In exact mode and with a higher precision fractions are kept and approximations like pi are simplified. i.e.: 1/3 -> 1 * 3^-1
+		if s.Conf.KeepDecimals() {
+			if val, exact, err := interpreter.EvaluateExact(s, coefficient); err == nil && exact {
+				if val.Sign() != 0 {
+					res[degree] = shared.RationalNode(val)
+				}
+				continue
+			}
+		} else if val, err := interpreter.Evaluate(s, coefficient); err == nil {
+			if val != 0 {
+				res[degree] = shared.NumberNode(val)
+			}
+			continue
+		}
+
+		coefficient, err := simplifier.Simplify(s, coefficient, simplifier.SOLVE)
+		if err != nil {
+			return nil, err
+		}
+		res[degree] = coefficient
+	}
+	return res, nil
+}
+
+// Highest degree of a polynomial with non zero coefficient.
+func degree(c map[int]*shared.Node) int {
+	degrees := []int{0}
+	for d := range c {
+		degrees = append(degrees, d)
+	}
+	return slices.Max(degrees)
+}
+
+// Returns the coefficient of a degree or zero if it does not exist.
+func coefficient(c map[int]*shared.Node, degree int) *shared.Node {
+	if val, ok := c[degree]; ok {
+		return val
+	}
+	return shared.NumberNode(0.0)
+}